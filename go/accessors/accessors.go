@@ -0,0 +1,137 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package accessors reads typed values from FHIR protos by element path.
+package accessors
+
+import (
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/fhir/go/internal/element"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// segmentPattern matches an element name with an optional list index.
+var segmentPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_]*)(?:\[(\d+)\])?$`)
+
+// QuantityValue returns the value and unit of the Quantity at path in msg, a
+// resource or a ContainedResource wrapping one. The path is a dotted list of
+// element names with optional list indices, optionally starting with the
+// resource type, e.g. "Observation.component[1].value". A choice element
+// resolves to its active value, and may also be named by type, e.g.
+// "valueQuantity", to require that type. A list element without an index
+// selects its first item. The unit is the Quantity's code, or its
+// human-readable unit if it has no code.
+//
+// It returns false if an element on the path is absent, the path does not
+// name a Quantity, or the Quantity has no valid value.
+func QuantityValue(msg proto.Message, path string) (*big.Rat, string, bool) {
+	m, ok := resolve(msg.ProtoReflect(), path)
+	if !ok || !element.QuantityTypes[m.Descriptor().Name()] {
+		return nil, "", false
+	}
+	value, ok := new(big.Rat).SetString(element.PrimitiveString(m, "value"))
+	if !ok {
+		return nil, "", false
+	}
+	unit := element.PrimitiveString(m, "code")
+	if unit == "" {
+		unit = element.PrimitiveString(m, "unit")
+	}
+	return value, unit, true
+}
+
+// resolve returns the element at path in the resource held by m.
+func resolve(m protoreflect.Message, path string) (protoreflect.Message, bool) {
+	m = element.UnwrapContained(m)
+	if m == nil {
+		return nil, false
+	}
+	segments := strings.Split(path, ".")
+	if segments[0] == string(m.Descriptor().Name()) {
+		segments = segments[1:]
+	}
+	for _, s := range segments {
+		match := segmentPattern.FindStringSubmatch(s)
+		if match == nil {
+			return nil, false
+		}
+		index := 0
+		if match[2] != "" {
+			var err error
+			if index, err = strconv.Atoi(match[2]); err != nil {
+				return nil, false
+			}
+		}
+		if m = child(m, match[1], index); m == nil {
+			return nil, false
+		}
+	}
+	return m, true
+}
+
+// child returns item index of the element name of m, resolving choice values
+// and contained resources, or nil if it is absent.
+func child(m protoreflect.Message, name string, index int) protoreflect.Message {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.Get(i)
+		if f.Message() == nil {
+			continue
+		}
+		jsonName := f.JSONName()
+		if jsonName != name && !strings.HasPrefix(name, jsonName) {
+			continue
+		}
+		v := item(m, f, index)
+		if v == nil {
+			continue
+		}
+		if element.IsChoice(v.Descriptor()) {
+			active := v.WhichOneof(v.Descriptor().Oneofs().Get(0))
+			if active == nil {
+				return nil
+			}
+			typed := jsonName + strings.ToUpper(active.JSONName()[:1]) + active.JSONName()[1:]
+			if name != jsonName && name != typed {
+				continue
+			}
+			v = v.Get(active).Message()
+		} else if name != jsonName {
+			continue
+		}
+		return element.UnwrapContained(v)
+	}
+	return nil
+}
+
+// item returns item index of the message field f of m, treating a singular
+// field as a list of one, or nil if it is absent.
+func item(m protoreflect.Message, f protoreflect.FieldDescriptor, index int) protoreflect.Message {
+	if !f.IsList() {
+		if index != 0 || !m.Has(f) {
+			return nil
+		}
+		return m.Get(f).Message()
+	}
+	l := m.Get(f).List()
+	if index >= l.Len() {
+		return nil
+	}
+	return l.Get(index).Message()
+}
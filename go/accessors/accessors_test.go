@@ -0,0 +1,107 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accessors
+
+import (
+	"math/big"
+	"testing"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+)
+
+func component(value *r4observationpb.Observation_Component_ValueX) *r4observationpb.Observation_Component {
+	return &r4observationpb.Observation_Component{Value: value}
+}
+
+func testObservation() *r4observationpb.Observation {
+	return &r4observationpb.Observation{
+		Value: &r4observationpb.Observation_ValueX{
+			Choice: &r4observationpb.Observation_ValueX_Quantity{Quantity: &d4pb.Quantity{
+				Value: &d4pb.Decimal{Value: "72"},
+				Unit:  &d4pb.String{Value: "beats/minute"},
+			}},
+		},
+		ReferenceRange: []*r4observationpb.Observation_ReferenceRange{{
+			Low: &d4pb.SimpleQuantity{Value: &d4pb.Decimal{Value: "60"}, Code: &d4pb.Code{Value: "/min"}},
+		}},
+		Component: []*r4observationpb.Observation_Component{
+			component(&r4observationpb.Observation_Component_ValueX{
+				Choice: &r4observationpb.Observation_Component_ValueX_StringValue{StringValue: &d4pb.String{Value: "n/a"}},
+			}),
+			component(&r4observationpb.Observation_Component_ValueX{
+				Choice: &r4observationpb.Observation_Component_ValueX_Quantity{Quantity: &d4pb.Quantity{
+					Value: &d4pb.Decimal{Value: "120.5"},
+					Unit:  &d4pb.String{Value: "millimeter of mercury"},
+					Code:  &d4pb.Code{Value: "mm[Hg]"},
+				}},
+			}),
+		},
+	}
+}
+
+func TestQuantityValue(t *testing.T) {
+	obs := testObservation()
+	tests := []struct {
+		path  string
+		value *big.Rat
+		unit  string
+	}{
+		{"Observation.component[1].value", big.NewRat(241, 2), "mm[Hg]"},
+		{"component[1].value", big.NewRat(241, 2), "mm[Hg]"},
+		{"Observation.component[1].valueQuantity", big.NewRat(241, 2), "mm[Hg]"},
+		{"Observation.value", big.NewRat(72, 1), "beats/minute"},
+		{"Observation.valueQuantity", big.NewRat(72, 1), "beats/minute"},
+		{"Observation.referenceRange.low", big.NewRat(60, 1), "/min"},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			value, unit, ok := QuantityValue(obs, test.path)
+			if !ok {
+				t.Fatalf("QuantityValue(%q) not found", test.path)
+			}
+			if value.Cmp(test.value) != 0 || unit != test.unit {
+				t.Errorf("QuantityValue(%q) = %v, %q; want %v, %q", test.path, value, unit, test.value, test.unit)
+			}
+		})
+	}
+
+	cr := &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Observation{Observation: obs}}
+	if value, _, ok := QuantityValue(cr, "Observation.component[1].value"); !ok || value.Cmp(big.NewRat(241, 2)) != 0 {
+		t.Errorf("QuantityValue(ContainedResource) = %v, %v; want 241/2, true", value, ok)
+	}
+}
+
+func TestQuantityValue_NotFound(t *testing.T) {
+	obs := testObservation()
+	for _, path := range []string{
+		"Observation.component[0].value",
+		"Observation.component[0].valueQuantity",
+		"Observation.component[1].valueString",
+		"Observation.component[2].value",
+		"Observation.component[1].value.value",
+		"Observation.code",
+		"Observation.status",
+		"Observation.referenceRange.high",
+		"Observation.nope",
+		"Observation..value",
+		"Observation.component[x].value",
+	} {
+		if value, unit, ok := QuantityValue(obs, path); ok {
+			t.Errorf("QuantityValue(%q) = %v, %q; want not found", path, value, unit)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package analytics converts FHIR R4 resources into flattened JSON suitable
+// for loading into warehouses such as BigQuery or Spanner.
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+)
+
+const r4Package = "google.fhir.r4.core"
+
+// ToBigQueryJSON serializes an R4 resource into the analytics JSON used for
+// warehouse ingestion. It builds on the SQL-on-FHIR analytics format of
+// jsonformat.NewAnalyticsMarshaller with two differences: choice elements are
+// emitted under their type-suffixed name, e.g. "valueQuantity", and resolved
+// references are split into "resourceType" and "id" keys. Like the analytics
+// format, the output is not valid FHIR JSON.
+func ToBigQueryJSON(msg proto.Message) ([]byte, error) {
+	rm := msg.ProtoReflect()
+	if oneof := rm.Descriptor().Oneofs().ByName("oneof_resource"); oneof != nil {
+		f := rm.WhichOneof(oneof)
+		if f == nil {
+			return nil, fmt.Errorf("empty %v", rm.Descriptor().FullName())
+		}
+		rm = rm.Get(f).Message()
+	}
+	if rm.Descriptor().ParentFile().Package() != r4Package {
+		return nil, fmt.Errorf("unsupported message %v, want an R4 resource", rm.Descriptor().FullName())
+	}
+	m, err := jsonformat.NewAnalyticsMarshaller(0, fhirversion.R4)
+	if err != nil {
+		return nil, err
+	}
+	data, err := m.MarshalResource(rm.Interface())
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	// Keep decimals in their original lexical form.
+	d.UseNumber()
+	var obj map[string]any
+	if err := d.Decode(&obj); err != nil {
+		return nil, err
+	}
+	rewriteObject(rm, obj)
+	return json.Marshal(obj)
+}
+
+// rewriteObject rewrites the analytics JSON obj of the message pb in place.
+func rewriteObject(pb protoreflect.Message, obj map[string]any) {
+	if proto.HasExtension(pb.Descriptor().Options(), apb.E_FhirReferenceType) {
+		splitReference(pb.Descriptor(), obj)
+		return
+	}
+	pb.Range(func(f protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		key := f.JSONName()
+		val, ok := obj[key]
+		if !ok || f.Message() == nil {
+			return true
+		}
+		if f.IsList() {
+			items, ok := val.([]any)
+			if !ok {
+				return true
+			}
+			l := v.List()
+			for i := 0; i < l.Len() && i < len(items); i++ {
+				rewriteValue(l.Get(i).Message(), items[i])
+			}
+			return true
+		}
+		cm := v.Message()
+		if proto.GetExtension(f.Message().Options(), apb.E_IsChoiceType).(bool) {
+			active := cm.WhichOneof(cm.Descriptor().Oneofs().Get(0))
+			inner, ok := val.(map[string]any)
+			if active == nil || !ok {
+				return true
+			}
+			delete(obj, key)
+			name := active.JSONName()
+			if innerVal, ok := inner[name]; ok {
+				obj[key+strings.ToUpper(name[:1])+name[1:]] = innerVal
+				rewriteValue(cm.Get(active).Message(), innerVal)
+			}
+			return true
+		}
+		rewriteValue(cm, val)
+		return true
+	})
+}
+
+func rewriteValue(pb protoreflect.Message, val any) {
+	if obj, ok := val.(map[string]any); ok {
+		rewriteObject(pb, obj)
+	}
+}
+
+// splitReference replaces a typed reference id, such as "patientId", with
+// "resourceType" and "id" keys.
+func splitReference(d protoreflect.MessageDescriptor, obj map[string]any) {
+	fields := d.Oneofs().ByName("reference").Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.Get(i)
+		typ := proto.GetExtension(f.Options(), apb.E_ReferencedFhirType).(string)
+		if typ == "" {
+			continue
+		}
+		if id, ok := obj[f.JSONName()]; ok {
+			delete(obj, f.JSONName())
+			obj["resourceType"] = typ
+			obj["id"] = id
+			return
+		}
+	}
+}
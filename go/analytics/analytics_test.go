@@ -0,0 +1,95 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+func TestToBigQueryJSON(t *testing.T) {
+	obs := &r4observationpb.Observation{
+		Id: &d4pb.Id{Value: "o1"},
+		Status: &r4observationpb.Observation_StatusCode{
+			Value: c4pb.ObservationStatusCode_FINAL,
+		},
+		Code: &d4pb.CodeableConcept{
+			Coding: []*d4pb.Coding{{
+				System: &d4pb.Uri{Value: "http://loinc.org"},
+				Code:   &d4pb.Code{Value: "8867-4"},
+			}},
+		},
+		Subject: &d4pb.Reference{
+			Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: "Patient/p1"}},
+			Display:   &d4pb.String{Value: "Jane Doe"},
+		},
+		Value: &r4observationpb.Observation_ValueX{
+			Choice: &r4observationpb.Observation_ValueX_Quantity{
+				Quantity: &d4pb.Quantity{
+					Value: &d4pb.Decimal{Value: "72.50"},
+					Unit:  &d4pb.String{Value: "beats/minute"},
+				},
+			},
+		},
+	}
+	want := `{
+		"id": "o1",
+		"status": "final",
+		"code": {"coding": [{"system": "http://loinc.org", "code": "8867-4"}]},
+		"subject": {"resourceType": "Patient", "id": "p1", "display": "Jane Doe"},
+		"valueQuantity": {"value": 72.50, "unit": "beats/minute"}
+	}`
+
+	tests := []struct {
+		name string
+		msg  proto.Message
+	}{
+		{"resource", obs},
+		{"contained resource", &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Observation{Observation: obs}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ToBigQueryJSON(test.msg)
+			if err != nil {
+				t.Fatalf("ToBigQueryJSON() failed: %v", err)
+			}
+			var gotObj, wantObj any
+			if err := json.Unmarshal(got, &gotObj); err != nil {
+				t.Fatalf("ToBigQueryJSON() returned invalid JSON %s: %v", got, err)
+			}
+			if err := json.Unmarshal([]byte(want), &wantObj); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(wantObj, gotObj); diff != "" {
+				t.Errorf("ToBigQueryJSON() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestToBigQueryJSON_UnsupportedVersion(t *testing.T) {
+	if _, err := ToBigQueryJSON(&r3pb.Observation{}); err == nil {
+		t.Errorf("ToBigQueryJSON() of STU3 resource succeeded, want error")
+	}
+}
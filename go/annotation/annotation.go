@@ -0,0 +1,79 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package annotation builds and reads FHIR R4 Annotation elements, the notes
+// attached to resources such as Observation.note.
+package annotation
+
+import (
+	"time"
+
+	"github.com/google/fhir/go/internal/fhirtime"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// New returns an Annotation with the given text, authored by author at when.
+// A nil author or a zero when leaves the respective element unset. The time
+// is recorded with second precision, or microsecond precision if when has a
+// fractional second, in the time zone of when.
+func New(text string, author *d4pb.Reference, when time.Time) *d4pb.Annotation {
+	a := &d4pb.Annotation{
+		Text: &d4pb.Markdown{Value: text},
+	}
+	if author != nil {
+		a.Author = &d4pb.Annotation_AuthorX{
+			Choice: &d4pb.Annotation_AuthorX_Reference{Reference: author},
+		}
+	}
+	if !when.IsZero() {
+		precision := d4pb.DateTime_SECOND
+		if when.Nanosecond() != 0 {
+			precision = d4pb.DateTime_MICROSECOND
+		}
+		a.Time = &d4pb.DateTime{
+			ValueUs:   when.UnixMicro(),
+			Timezone:  fhirtime.Timezone(when),
+			Precision: precision,
+		}
+	}
+	return a
+}
+
+// AuthorReference returns the author of a, or false if a has no author or
+// its author is given as a string.
+func AuthorReference(a *d4pb.Annotation) (*d4pb.Reference, bool) {
+	ref := a.GetAuthor().GetReference()
+	return ref, ref != nil
+}
+
+// AuthorString returns the author of a given as free text, or false if a has
+// no author or its author is given as a reference.
+func AuthorString(a *d4pb.Annotation) (string, bool) {
+	s := a.GetAuthor().GetStringValue()
+	return s.GetValue(), s != nil
+}
+
+// Time returns when a was made, or false if it has no time.
+func Time(a *d4pb.Annotation) (time.Time, bool) {
+	dt := a.GetTime()
+	if dt == nil {
+		return time.Time{}, false
+	}
+	t := time.UnixMicro(dt.GetValueUs())
+	if loc, err := fhirtime.Location(dt.GetTimezone()); err == nil {
+		t = t.In(loc)
+	}
+	return t, true
+}
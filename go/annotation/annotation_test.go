@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+func TestNew(t *testing.T) {
+	author := &d4pb.Reference{
+		Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "dr1"}},
+	}
+	when := time.Date(2023, 3, 4, 10, 30, 0, 0, time.FixedZone("", -5*60*60))
+
+	a := New("Patient reports dizziness.", author, when)
+
+	want := &d4pb.Annotation{
+		Text: &d4pb.Markdown{Value: "Patient reports dizziness."},
+		Author: &d4pb.Annotation_AuthorX{
+			Choice: &d4pb.Annotation_AuthorX_Reference{Reference: author},
+		},
+		Time: &d4pb.DateTime{
+			ValueUs:   when.UnixMicro(),
+			Timezone:  "-05:00",
+			Precision: d4pb.DateTime_SECOND,
+		},
+	}
+	if diff := cmp.Diff(want, a, protocmp.Transform()); diff != "" {
+		t.Errorf("New() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	if got, ok := AuthorReference(a); !ok || !proto.Equal(got, author) {
+		t.Errorf("AuthorReference() = %v, %v; want %v, true", got, ok, author)
+	}
+	if got, ok := AuthorString(a); ok {
+		t.Errorf("AuthorString() = %q, want not ok", got)
+	}
+	got, ok := Time(a)
+	if !ok || !got.Equal(when) {
+		t.Errorf("Time() = %v, %v; want %v, true", got, ok, when)
+	}
+	if _, offset := got.Zone(); offset != -5*60*60 {
+		t.Errorf("Time() offset = %d, want %d", offset, -5*60*60)
+	}
+}
+
+func TestNew_Minimal(t *testing.T) {
+	a := New("note", nil, time.Time{})
+	if a.GetAuthor() != nil || a.GetTime() != nil {
+		t.Errorf("New() with no author or time = %v, want only text set", a)
+	}
+	if _, ok := AuthorReference(a); ok {
+		t.Errorf("AuthorReference() ok, want not ok")
+	}
+	if _, ok := Time(a); ok {
+		t.Errorf("Time() ok, want not ok")
+	}
+}
+
+func TestAuthorString(t *testing.T) {
+	a := &d4pb.Annotation{
+		Author: &d4pb.Annotation_AuthorX{
+			Choice: &d4pb.Annotation_AuthorX_StringValue{StringValue: &d4pb.String{Value: "Nurse on duty"}},
+		},
+	}
+	if got, ok := AuthorString(a); !ok || got != "Nurse on duty" {
+		t.Errorf("AuthorString() = %q, %v; want %q, true", got, ok, "Nurse on duty")
+	}
+	if got, ok := AuthorReference(a); ok {
+		t.Errorf("AuthorReference() = %v, want not ok", got)
+	}
+}
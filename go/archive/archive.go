@@ -0,0 +1,73 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive reads FHIR resources from archives of JSON files.
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+)
+
+// ReadArchive reads the zip archive of the given size from r and unmarshals
+// each of its ".json" files with u, in the order they appear in the archive.
+// Each file must hold a single resource, which is returned as a
+// ContainedResource of the version of u. Other files and directories are
+// skipped. A file that can't be read or unmarshalled gives an error naming
+// it, and reading continues with the next file; an archive that can't be
+// opened at all gives a single error.
+func ReadArchive(r io.ReaderAt, size int64, u *jsonformat.Unmarshaller) ([]proto.Message, []error) {
+	if u == nil {
+		return nil, []error{errors.New("missing unmarshaller")}
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, []error{fmt.Errorf("opening zip archive: %w", err)}
+	}
+	var resources []proto.Message
+	var errs []error
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !strings.EqualFold(path.Ext(f.Name), ".json") {
+			continue
+		}
+		res, err := readFile(f, u)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.Name, err))
+			continue
+		}
+		resources = append(resources, res)
+	}
+	return resources, errs
+}
+
+// readFile unmarshals the resource held in the archive file f.
+func readFile(f *zip.File, u *jsonformat.Unmarshaller) (proto.Message, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	in, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	return u.Unmarshal(in)
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+// zipOf returns a zip archive holding files, given as alternating names and
+// contents. Names ending in "/" are written as directories.
+func zipOf(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		w, err := zw.Create(files[i])
+		if err != nil {
+			t.Fatalf("zip.Create(%q) failed: %v", files[i], err)
+		}
+		if _, err := w.Write([]byte(files[i+1])); err != nil {
+			t.Fatalf("writing %q failed: %v", files[i], err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip.Close() failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func newUnmarshaller(t *testing.T) *jsonformat.Unmarshaller {
+	t.Helper()
+	u, err := jsonformat.NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("NewUnmarshaller() failed: %v", err)
+	}
+	return u
+}
+
+func TestReadArchive(t *testing.T) {
+	b := zipOf(t,
+		"patient.json", `{"resourceType":"Patient","id":"p1"}`,
+		"results/", "",
+		"results/observation.JSON", `{"resourceType":"Observation","id":"o1","status":"final","code":{"text":"x"}}`,
+		"results/bad.json", `{"resourceType":"Observation",`,
+		"README.txt", "not a resource",
+	)
+	got, errs := ReadArchive(bytes.NewReader(b), int64(len(b)), newUnmarshaller(t))
+	want := []proto.Message{
+		&r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{
+			Patient: &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}},
+		}},
+		&r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Observation{
+			Observation: &r4observationpb.Observation{
+				Id:     &d4pb.Id{Value: "o1"},
+				Status: &r4observationpb.Observation_StatusCode{Value: c4pb.ObservationStatusCode_FINAL},
+				Code:   &d4pb.CodeableConcept{Text: &d4pb.String{Value: "x"}},
+			},
+		}},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("ReadArchive() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "results/bad.json: ") {
+		t.Errorf("ReadArchive() errors = %v, want one for results/bad.json", errs)
+	}
+}
+
+func TestReadArchive_Errors(t *testing.T) {
+	b := zipOf(t, "patient.json", `{"resourceType":"Patient","id":"p1"}`)
+	tests := []struct {
+		name string
+		in   []byte
+		u    *jsonformat.Unmarshaller
+	}{
+		{
+			name: "not a zip archive",
+			in:   []byte(`{"resourceType":"Patient"}`),
+			u:    newUnmarshaller(t),
+		},
+		{
+			name: "nil unmarshaller",
+			in:   b,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, errs := ReadArchive(bytes.NewReader(test.in), int64(len(test.in)), test.u)
+			if len(got) != 0 || len(errs) != 1 {
+				t.Errorf("ReadArchive() = %v, %v, want no resources and one error", got, errs)
+			}
+		})
+	}
+}
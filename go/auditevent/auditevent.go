@@ -0,0 +1,125 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auditevent builds FHIR R4 AuditEvent resources recording RESTful
+// interactions with a FHIR server.
+package auditevent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/fhir/go/internal/fhirtime"
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4auditeventpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/audit_event_go_proto"
+)
+
+const (
+	auditEventTypeSystem     = "http://terminology.hl7.org/CodeSystem/audit-event-type"
+	restfulInteractionSystem = "http://hl7.org/fhir/restful-interaction"
+)
+
+// actionCodes maps FHIR restful interactions to the AuditEvent action they
+// perform. Interactions not listed, such as transaction and operation, are
+// executions.
+var actionCodes = map[string]c4pb.AuditEventActionCode_Value{
+	"create":           c4pb.AuditEventActionCode_C,
+	"read":             c4pb.AuditEventActionCode_R,
+	"vread":            c4pb.AuditEventActionCode_R,
+	"history-instance": c4pb.AuditEventActionCode_R,
+	"history-type":     c4pb.AuditEventActionCode_R,
+	"history-system":   c4pb.AuditEventActionCode_R,
+	"search-type":      c4pb.AuditEventActionCode_R,
+	"search-system":    c4pb.AuditEventActionCode_R,
+	"capabilities":     c4pb.AuditEventActionCode_R,
+	"update":           c4pb.AuditEventActionCode_U,
+	"patch":            c4pb.AuditEventActionCode_U,
+	"delete":           c4pb.AuditEventActionCode_D,
+}
+
+// outcomeCodes maps the FHIR AuditEvent outcome codes to their values.
+var outcomeCodes = map[string]c4pb.AuditEventOutcomeCode_Value{
+	"0":  c4pb.AuditEventOutcomeCode_SUCCESS,
+	"4":  c4pb.AuditEventOutcomeCode_MINOR_FAILURE,
+	"8":  c4pb.AuditEventOutcomeCode_SERIOUS_FAILURE,
+	"12": c4pb.AuditEventOutcomeCode_MAJOR_FAILURE,
+}
+
+// ForInteraction returns an R4 AuditEvent recording that agent performed the
+// FHIR restful interaction action, e.g. "read" or "update", on entity at
+// when. The event has the "rest" type, the interaction as its subtype and
+// the matching C/R/U/D/E action; interactions that don't read or change a
+// resource are recorded as executions. outcome is a FHIR AuditEvent outcome
+// code: "0" for success, or "4", "8" or "12" for minor, serious and major
+// failures. An empty outcome leaves the outcome unset, and any other value
+// is an error. agent is the requestor and is also recorded as the observer
+// of the event. A nil entity is omitted. when is recorded in its time zone,
+// with the coarsest of second, millisecond and microsecond precision that
+// represents it exactly.
+func ForInteraction(action, outcome string, agent, entity *d4pb.Reference, when time.Time) (proto.Message, error) {
+	actionCode, ok := actionCodes[action]
+	if !ok {
+		actionCode = c4pb.AuditEventActionCode_E
+	}
+	ae := &r4auditeventpb.AuditEvent{
+		Type: &d4pb.Coding{
+			System:  &d4pb.Uri{Value: auditEventTypeSystem},
+			Code:    &d4pb.Code{Value: "rest"},
+			Display: &d4pb.String{Value: "RESTful Operation"},
+		},
+		Subtype: []*d4pb.Coding{{
+			System: &d4pb.Uri{Value: restfulInteractionSystem},
+			Code:   &d4pb.Code{Value: action},
+		}},
+		Action:   &r4auditeventpb.AuditEvent_ActionCode{Value: actionCode},
+		Recorded: instant(when),
+		Agent: []*r4auditeventpb.AuditEvent_Agent{{
+			Who:       agent,
+			Requestor: &d4pb.Boolean{Value: true},
+		}},
+		Source: &r4auditeventpb.AuditEvent_Source{
+			Observer: agent,
+		},
+	}
+	if outcome != "" {
+		o, ok := outcomeCodes[outcome]
+		if !ok {
+			return nil, fmt.Errorf("invalid AuditEvent outcome %q", outcome)
+		}
+		ae.Outcome = &r4auditeventpb.AuditEvent_OutcomeCode{Value: o}
+	}
+	if entity != nil {
+		ae.Entity = []*r4auditeventpb.AuditEvent_Entity{{What: entity}}
+	}
+	return ae, nil
+}
+
+// instant returns t as an Instant with the coarsest precision that holds it.
+func instant(t time.Time) *d4pb.Instant {
+	precision := d4pb.Instant_SECOND
+	switch {
+	case t.Nanosecond()%int(time.Millisecond) == 0 && t.Nanosecond() != 0:
+		precision = d4pb.Instant_MILLISECOND
+	case t.Nanosecond() != 0:
+		precision = d4pb.Instant_MICROSECOND
+	}
+	return &d4pb.Instant{
+		ValueUs:   t.UnixMicro(),
+		Timezone:  fhirtime.Timezone(t),
+		Precision: precision,
+	}
+}
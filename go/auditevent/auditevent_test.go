@@ -0,0 +1,124 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditevent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/fhir/go/jsonformat/fhirvalidate"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4auditeventpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/audit_event_go_proto"
+)
+
+func TestForInteraction(t *testing.T) {
+	agent := &d4pb.Reference{
+		Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "dr1"}},
+	}
+	entity := &d4pb.Reference{
+		Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}},
+	}
+	tests := []struct {
+		name          string
+		action        string
+		outcome       string
+		entity        *d4pb.Reference
+		when          time.Time
+		wantAction    c4pb.AuditEventActionCode_Value
+		wantOutcome   c4pb.AuditEventOutcomeCode_Value
+		wantPrecision d4pb.Instant_Precision
+		wantTimezone  string
+	}{
+		{
+			name:          "successful read",
+			action:        "read",
+			outcome:       "0",
+			entity:        entity,
+			when:          time.Date(2023, 3, 4, 10, 30, 0, 0, time.UTC),
+			wantAction:    c4pb.AuditEventActionCode_R,
+			wantOutcome:   c4pb.AuditEventOutcomeCode_SUCCESS,
+			wantPrecision: d4pb.Instant_SECOND,
+			wantTimezone:  "Z",
+		},
+		{
+			name:          "failed update",
+			action:        "update",
+			outcome:       "8",
+			entity:        entity,
+			when:          time.Date(2023, 3, 4, 10, 30, 0, 250*int(time.Millisecond), time.FixedZone("", -5*60*60)),
+			wantAction:    c4pb.AuditEventActionCode_U,
+			wantOutcome:   c4pb.AuditEventOutcomeCode_SERIOUS_FAILURE,
+			wantPrecision: d4pb.Instant_MILLISECOND,
+			wantTimezone:  "-05:00",
+		},
+		{
+			name:          "no outcome",
+			action:        "delete",
+			entity:        entity,
+			when:          time.Date(2023, 3, 4, 10, 30, 0, 0, time.UTC),
+			wantAction:    c4pb.AuditEventActionCode_D,
+			wantPrecision: d4pb.Instant_SECOND,
+			wantTimezone:  "Z",
+		},
+		{
+			name:          "transaction without entity",
+			action:        "transaction",
+			outcome:       "0",
+			when:          time.Date(2023, 3, 4, 10, 30, 0, 1000, time.UTC),
+			wantAction:    c4pb.AuditEventActionCode_E,
+			wantOutcome:   c4pb.AuditEventOutcomeCode_SUCCESS,
+			wantPrecision: d4pb.Instant_MICROSECOND,
+			wantTimezone:  "Z",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg, err := ForInteraction(test.action, test.outcome, agent, test.entity, test.when)
+			if err != nil {
+				t.Fatalf("ForInteraction() failed: %v", err)
+			}
+			if err := fhirvalidate.Validate(msg); err != nil {
+				t.Fatalf("Validate() of the AuditEvent failed: %v", err)
+			}
+			ae := msg.(*r4auditeventpb.AuditEvent)
+			if got := ae.GetAction().GetValue(); got != test.wantAction {
+				t.Errorf("action = %v, want %v", got, test.wantAction)
+			}
+			if got := ae.GetOutcome().GetValue(); got != test.wantOutcome {
+				t.Errorf("outcome = %v, want %v", got, test.wantOutcome)
+			}
+			if got := ae.GetSubtype()[0].GetCode().GetValue(); got != test.action {
+				t.Errorf("subtype = %q, want %q", got, test.action)
+			}
+			rec := ae.GetRecorded()
+			if rec.GetValueUs() != test.when.UnixMicro() || rec.GetPrecision() != test.wantPrecision || rec.GetTimezone() != test.wantTimezone {
+				t.Errorf("recorded = %v, want %v with precision %v in %q", rec, test.when, test.wantPrecision, test.wantTimezone)
+			}
+			if got := len(ae.GetEntity()); (test.entity != nil) != (got == 1) {
+				t.Errorf("got %d entities for entity %v", got, test.entity)
+			}
+		})
+	}
+}
+
+func TestForInteraction_InvalidOutcome(t *testing.T) {
+	for _, outcome := range []string{"1", "success", "00"} {
+		if got, err := ForInteraction("read", outcome, nil, nil, time.Now()); err == nil {
+			t.Errorf("ForInteraction(outcome %q) = %v, want error", outcome, got)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bundle provides helpers for assembling and inspecting FHIR R4
+// Bundle resources.
+package bundle
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+)
+
+// asBundle returns msg as an R4 Bundle, unwrapping a ContainedResource if
+// necessary.
+func asBundle(msg proto.Message) (*r4pb.Bundle, error) {
+	switch b := msg.(type) {
+	case *r4pb.Bundle:
+		return b, nil
+	case *r4pb.ContainedResource:
+		if bundle := b.GetBundle(); bundle != nil {
+			return bundle, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported message %T, want an R4 Bundle", msg)
+}
+
+// wrapResource returns r wrapped in a ContainedResource. r may already be a
+// ContainedResource, in which case it is returned unchanged. A nil r, including
+// a typed nil such as (*Patient)(nil), is an error.
+func wrapResource(r proto.Message) (*r4pb.ContainedResource, error) {
+	if r == nil || !r.ProtoReflect().IsValid() {
+		return nil, errors.New("nil resource")
+	}
+	if cr, ok := r.(*r4pb.ContainedResource); ok {
+		return cr, nil
+	}
+	cr := &r4pb.ContainedResource{}
+	rcr := cr.ProtoReflect()
+	name := r.ProtoReflect().Descriptor().FullName()
+	fields := rcr.Descriptor().Oneofs().ByName("oneof_resource").Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.Get(i)
+		if f.Message().FullName() == name {
+			rcr.Set(f, protoreflect.ValueOfMessage(r.ProtoReflect()))
+			return cr, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported resource %T, want an R4 resource", r)
+}
+
+// unwrapResource returns the resource held by cr, or nil if cr is empty.
+func unwrapResource(cr *r4pb.ContainedResource) proto.Message {
+	if cr == nil {
+		return nil
+	}
+	rcr := cr.ProtoReflect()
+	f := rcr.WhichOneof(rcr.Descriptor().Oneofs().ByName("oneof_resource"))
+	if f == nil {
+		return nil
+	}
+	return rcr.Get(f).Message().Interface()
+}
+
+// resourceTypeAndID returns the resource type and logical id of r.
+func resourceTypeAndID(r proto.Message) (string, string) {
+	rm := r.ProtoReflect()
+	var id string
+	if f := rm.Descriptor().Fields().ByName("id"); f != nil && rm.Has(f) {
+		idm := rm.Get(f).Message()
+		id = idm.Get(idm.Descriptor().Fields().ByName("value")).String()
+	}
+	return string(rm.Descriptor().Name()), id
+}
@@ -0,0 +1,233 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/fhir/go/internal/walk"
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+)
+
+// entryArrayBytes is the size of the entry array's name and brackets in
+// compact FHIR JSON, including the comma separating it from the element
+// before it.
+const entryArrayBytes = len(`,"entry":[]`)
+
+// Chunk splits an R4 Bundle into Bundles of the same type holding at most
+// maxEntries entries and at most maxBytes bytes when marshalled as compact
+// FHIR JSON. A limit of zero or less is not enforced. Entries are kept in
+// their original order within each chunk, and only the type of the Bundle is
+// copied to the chunks.
+//
+// Entries that reference one another, by fullUrl or as "Type/id", are kept in
+// the same chunk where they fit within the limits together; groups that do not
+// are split across chunks in entry order. An error is returned if a single
+// entry exceeds maxBytes.
+func Chunk(bundle proto.Message, maxEntries int, maxBytes int) ([]proto.Message, error) {
+	b, err := asBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSizer()
+	if err != nil {
+		return nil, err
+	}
+	shell := &r4pb.Bundle{Type: proto.Clone(b.GetType()).(*r4pb.Bundle_TypeCode)}
+	out, err := s.m.MarshalResource(shell)
+	if err != nil {
+		return nil, err
+	}
+	overhead := len(out) + entryArrayBytes
+	entries := b.GetEntry()
+	sizes := make([]int, len(entries))
+	for i, e := range entries {
+		if sizes[i], err = s.size(e); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if maxBytes > 0 && overhead+sizes[i] > maxBytes {
+			return nil, fmt.Errorf("entry %d is %d bytes, which exceeds the maximum of %d bytes per Bundle", i, sizes[i], maxBytes)
+		}
+	}
+	groups, err := entryGroups(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &chunker{maxEntries: maxEntries, maxBytes: maxBytes, overhead: overhead, sizes: sizes}
+	for _, g := range groups {
+		if !c.fits(g) {
+			c.flush()
+		}
+		if c.fits(g) {
+			for _, i := range g {
+				c.add(i)
+			}
+			continue
+		}
+		// The group is too large for a chunk of its own, so split it.
+		for _, i := range g {
+			if !c.fits([]int{i}) {
+				c.flush()
+			}
+			c.add(i)
+		}
+	}
+	c.flush()
+
+	chunks := make([]proto.Message, len(c.chunks))
+	for i, idx := range c.chunks {
+		chunk := proto.Clone(shell).(*r4pb.Bundle)
+		for _, j := range idx {
+			chunk.Entry = append(chunk.Entry, proto.Clone(entries[j]).(*r4pb.Bundle_Entry))
+		}
+		chunks[i] = chunk
+	}
+	return chunks, nil
+}
+
+// chunker accumulates entry indices into chunks within the limits.
+type chunker struct {
+	maxEntries, maxBytes, overhead int
+	sizes                          []int
+
+	cur    []int
+	bytes  int
+	chunks [][]int
+}
+
+// fits reports whether the entries g can be added to the current chunk
+// within the limits.
+func (c *chunker) fits(g []int) bool {
+	bytes := c.bytes
+	if len(c.cur) == 0 {
+		bytes = c.overhead - 1
+	}
+	for _, i := range g {
+		bytes += c.sizes[i] + 1
+	}
+	return (c.maxEntries <= 0 || len(c.cur)+len(g) <= c.maxEntries) && (c.maxBytes <= 0 || bytes <= c.maxBytes)
+}
+
+func (c *chunker) add(i int) {
+	if len(c.cur) == 0 {
+		c.bytes = c.overhead
+	} else {
+		c.bytes++
+	}
+	c.cur = append(c.cur, i)
+	c.bytes += c.sizes[i]
+}
+
+func (c *chunker) flush() {
+	if len(c.cur) == 0 {
+		return
+	}
+	sort.Ints(c.cur)
+	c.chunks = append(c.chunks, c.cur)
+	c.cur, c.bytes = nil, 0
+}
+
+// entryGroups partitions the indices of entries into groups of entries linked
+// by references, directly or transitively. Groups are ordered by their first
+// entry, and the indices within each are ascending.
+func entryGroups(entries []*r4pb.Bundle_Entry) ([][]int, error) {
+	targets := map[string]int{}
+	for i, e := range entries {
+		if url := e.GetFullUrl().GetValue(); url != "" {
+			targets[url] = i
+		}
+		if r := unwrapResource(e.GetResource()); r != nil {
+			if typ, id := resourceTypeAndID(r); id != "" {
+				targets[typ+"/"+id] = i
+			}
+		}
+	}
+
+	parent := make([]int, len(entries))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i, e := range entries {
+		if e.GetResource() == nil {
+			continue
+		}
+		refs, err := referenceURIs(e.GetResource())
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		for _, ref := range refs {
+			if j, ok := targets[ref]; ok {
+				// Join under the lower index so each root is its group's first entry.
+				ri, rj := find(i), find(j)
+				if ri > rj {
+					ri, rj = rj, ri
+				}
+				parent[rj] = ri
+			}
+		}
+	}
+
+	var groups [][]int
+	index := map[int]int{}
+	for i := range entries {
+		root := find(i)
+		g, ok := index[root]
+		if !ok {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups, nil
+}
+
+// referenceURIs returns the references held by a resource as URIs, with
+// typed references rendered as "Type/id".
+func referenceURIs(cr *r4pb.ContainedResource) ([]string, error) {
+	var uris []string
+	err := walk.Walk(cr, func(_ string, m protoreflect.Message) error {
+		ref, ok := m.Interface().(*d4pb.Reference)
+		if !ok {
+			return nil
+		}
+		if ref.GetUri() == nil {
+			dr, err := jsonformat.NewDenormalizedReference(ref)
+			if err != nil {
+				return err
+			}
+			ref = dr.(*d4pb.Reference)
+		}
+		if uri := ref.GetUri().GetValue(); uri != "" {
+			uris = append(uris, uri)
+		}
+		return nil
+	})
+	return uris, err
+}
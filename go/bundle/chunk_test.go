@@ -0,0 +1,143 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+// chunkableBundle returns a transaction of 50 Patients followed by an
+// Observation about each, referencing its Patient by fullUrl.
+func chunkableBundle(t *testing.T) *r4pb.Bundle {
+	t.Helper()
+	var patients, observations []*r4pb.Bundle_Entry
+	for i := 0; i < 50; i++ {
+		url := fmt.Sprintf("urn:uuid:00000000-0000-0000-0000-%012d", i)
+		p := postEntry(t, &r4patientpb.Patient{
+			Name: []*d4pb.HumanName{{Family: &d4pb.String{Value: strings.Repeat("x", i)}}},
+		}, "Patient")
+		p.FullUrl = &d4pb.Uri{Value: url}
+		patients = append(patients, p)
+		observations = append(observations, postEntry(t, &r4observationpb.Observation{
+			Status:  &r4observationpb.Observation_StatusCode{Value: c4pb.ObservationStatusCode_FINAL},
+			Code:    &d4pb.CodeableConcept{Text: &d4pb.String{Value: "heart rate"}},
+			Subject: &d4pb.Reference{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: url}}},
+		}, "Observation"))
+	}
+	return bundleOfType(c4pb.BundleTypeCode_TRANSACTION, append(patients, observations...)...)
+}
+
+func TestChunk(t *testing.T) {
+	b := chunkableBundle(t)
+	m, err := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("jsonformat.NewMarshaller() failed: %v", err)
+	}
+	const maxEntries, maxBytes = 15, 2000
+
+	got, err := Chunk(b, maxEntries, maxBytes)
+	if err != nil {
+		t.Fatalf("Chunk() failed: %v", err)
+	}
+	if len(got) < 2 {
+		t.Fatalf("Chunk() returned %d chunks, want several", len(got))
+	}
+	chunkOf := map[string]int{}
+	var n int
+	for i, msg := range got {
+		chunk := msg.(*r4pb.Bundle)
+		if typ := chunk.GetType().GetValue(); typ != c4pb.BundleTypeCode_TRANSACTION {
+			t.Errorf("chunk %d has type %v, want TRANSACTION", i, typ)
+		}
+		if len(chunk.GetEntry()) > maxEntries {
+			t.Errorf("chunk %d has %d entries, want at most %d", i, len(chunk.GetEntry()), maxEntries)
+		}
+		out, err := m.MarshalResource(chunk)
+		if err != nil {
+			t.Fatalf("MarshalResource() of chunk %d failed: %v", i, err)
+		}
+		if len(out) > maxBytes {
+			t.Errorf("chunk %d is %d bytes, want at most %d", i, len(out), maxBytes)
+		}
+		for _, e := range chunk.GetEntry() {
+			if url := e.GetFullUrl().GetValue(); url != "" {
+				chunkOf[url] = i
+			}
+			if ref := e.GetResource().GetObservation().GetSubject().GetUri().GetValue(); ref != "" {
+				if c, ok := chunkOf[ref]; !ok || c != i {
+					t.Errorf("Observation referencing %s is in chunk %d, apart from its Patient", ref, i)
+				}
+			}
+			n++
+		}
+	}
+	if n != 100 {
+		t.Errorf("Chunk() returned %d entries in all, want 100", n)
+	}
+	if len(chunkOf) != 50 {
+		t.Errorf("Chunk() returned %d Patients in all, want 50", len(chunkOf))
+	}
+}
+
+func TestChunk_Unlimited(t *testing.T) {
+	b := chunkableBundle(t)
+	got, err := Chunk(b, 0, 0)
+	if err != nil {
+		t.Fatalf("Chunk() failed: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Chunk() returned %d chunks, want 1", len(got))
+	}
+	if !proto.Equal(got[0], b) {
+		t.Errorf("Chunk() = %v, want the whole Bundle", got[0])
+	}
+}
+
+func TestChunk_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		bundle   proto.Message
+		maxBytes int
+	}{
+		{
+			name:     "entry larger than limit",
+			bundle:   chunkableBundle(t),
+			maxBytes: 100,
+		},
+		{
+			name:   "not a bundle",
+			bundle: &r4patientpb.Patient{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Chunk(test.bundle, 10, test.maxBytes); err == nil {
+				t.Errorf("Chunk() succeeded, want error")
+			}
+		})
+	}
+}
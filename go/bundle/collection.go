@@ -0,0 +1,54 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+)
+
+// NewCollection builds an R4 collection Bundle holding resources, which are
+// R4 resources or ContainedResources wrapping them, as its entries in order.
+// Each entry gets a new random "urn:uuid:" fullUrl and no request, as
+// collections are not processed by servers. The resources are not copied.
+func NewCollection(resources ...proto.Message) (proto.Message, error) {
+	entries := make([]*r4pb.Bundle_Entry, 0, len(resources))
+	for i, r := range resources {
+		cr, err := wrapResource(r)
+		if err != nil {
+			return nil, fmt.Errorf("resource %d: %w", i, err)
+		}
+		if unwrapResource(cr) == nil {
+			return nil, fmt.Errorf("resource %d: empty resource", i)
+		}
+		fullURL, err := newUUIDURL()
+		if err != nil {
+			return nil, fmt.Errorf("resource %d: %w", i, err)
+		}
+		entries = append(entries, &r4pb.Bundle_Entry{
+			FullUrl:  &d4pb.Uri{Value: fullURL},
+			Resource: cr,
+		})
+	}
+	return &r4pb.Bundle{
+		Type:  &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_COLLECTION},
+		Entry: entries,
+	}, nil
+}
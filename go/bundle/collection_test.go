@@ -0,0 +1,119 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func TestNewCollection(t *testing.T) {
+	resources := []proto.Message{
+		&r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}},
+		&r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Observation{
+			Observation: &r4observationpb.Observation{Id: &d4pb.Id{Value: "o1"}},
+		}},
+		// Resources without an id are allowed in collections.
+		&r4patientpb.Patient{Active: &d4pb.Boolean{Value: true}},
+	}
+	got, err := NewCollection(resources...)
+	if err != nil {
+		t.Fatalf("NewCollection() failed: %v", err)
+	}
+	if errs := ValidateBundleStructure(got); len(errs) != 0 {
+		t.Errorf("ValidateBundleStructure(NewCollection()) = %v, want no errors", errs)
+	}
+	if errs := CheckFullURLUniqueness(got); len(errs) != 0 {
+		t.Errorf("CheckFullURLUniqueness(NewCollection()) = %v, want no errors", errs)
+	}
+	b := got.(*r4pb.Bundle)
+	if b.GetType().GetValue() != c4pb.BundleTypeCode_COLLECTION {
+		t.Errorf("NewCollection() type = %v, want COLLECTION", b.GetType().GetValue())
+	}
+	if len(b.GetEntry()) != len(resources) {
+		t.Fatalf("NewCollection() has %d entries, want %d", len(b.GetEntry()), len(resources))
+	}
+	for i, e := range b.GetEntry() {
+		if fullURL := e.GetFullUrl().GetValue(); !strings.HasPrefix(fullURL, "urn:uuid:") {
+			t.Errorf("entry %d fullUrl = %q, want a urn:uuid: URL", i, fullURL)
+		}
+		if e.GetRequest() != nil {
+			t.Errorf("entry %d has request %v, want none", i, e.GetRequest())
+		}
+		want := resources[i]
+		if cr, ok := want.(*r4pb.ContainedResource); ok {
+			want = unwrapResource(cr)
+		}
+		if diff := cmp.Diff(want, unwrapResource(e.GetResource()), protocmp.Transform()); diff != "" {
+			t.Errorf("entry %d resource returned unexpected diff (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestNewCollection_Empty(t *testing.T) {
+	got, err := NewCollection()
+	if err != nil {
+		t.Fatalf("NewCollection() failed: %v", err)
+	}
+	want := bundleOfType(c4pb.BundleTypeCode_COLLECTION)
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("NewCollection() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewCollection_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource proto.Message
+	}{
+		{
+			name:     "not a resource",
+			resource: &r4pb.Bundle_Entry{},
+		},
+		{
+			name:     "empty contained resource",
+			resource: &r4pb.ContainedResource{},
+		},
+		{
+			name:     "nil resource",
+			resource: nil,
+		},
+		{
+			name:     "typed nil resource",
+			resource: (*r4patientpb.Patient)(nil),
+		},
+		{
+			name:     "typed nil contained resource",
+			resource: (*r4pb.ContainedResource)(nil),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewCollection(test.resource); err == nil {
+				t.Errorf("NewCollection() succeeded, want error")
+			}
+		})
+	}
+}
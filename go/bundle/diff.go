@@ -0,0 +1,83 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"fmt"
+
+	"github.com/google/fhir/go/resource"
+	"google.golang.org/protobuf/proto"
+)
+
+// DiffBundles compares the resources of two R4 Bundles, matched by resource
+// type and id. It returns the resources of new that are not in old, those of
+// new that differ from their counterpart in old, and those of old that are not
+// in new, each in bundle order. Resources are compared with resource.Equal,
+// so differences in meta alone do not count as changes. Entries without a
+// resource are ignored; it is an error for a resource to have no id or to
+// appear twice in the same bundle.
+func DiffBundles(old, new proto.Message) (added, changed, removed []proto.Message, err error) {
+	oldResources, oldKeys, err := resourcesByKey(old)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("old bundle: %w", err)
+	}
+	newResources, newKeys, err := resourcesByKey(new)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("new bundle: %w", err)
+	}
+	for _, key := range newKeys {
+		o, ok := oldResources[key]
+		switch {
+		case !ok:
+			added = append(added, newResources[key])
+		case !resource.Equal(o, newResources[key]):
+			changed = append(changed, newResources[key])
+		}
+	}
+	for _, key := range oldKeys {
+		if _, ok := newResources[key]; !ok {
+			removed = append(removed, oldResources[key])
+		}
+	}
+	return added, changed, removed, nil
+}
+
+// resourcesByKey returns the resources of bundle by "Type/id", along with the
+// keys in bundle order.
+func resourcesByKey(bundle proto.Message) (map[string]proto.Message, []string, error) {
+	b, err := asBundle(bundle)
+	if err != nil {
+		return nil, nil, err
+	}
+	resources := map[string]proto.Message{}
+	var keys []string
+	for i, e := range b.GetEntry() {
+		res := unwrapResource(e.GetResource())
+		if res == nil {
+			continue
+		}
+		typ, id := resourceTypeAndID(res)
+		if id == "" {
+			return nil, nil, fmt.Errorf("entry %d: %s has no id", i, typ)
+		}
+		key := typ + "/" + id
+		if _, ok := resources[key]; ok {
+			return nil, nil, fmt.Errorf("entry %d: duplicate resource %s", i, key)
+		}
+		resources[key] = res
+		keys = append(keys, key)
+	}
+	return resources, keys, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func collection(patients ...*r4patientpb.Patient) *r4pb.Bundle {
+	b := &r4pb.Bundle{Type: &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_COLLECTION}}
+	for _, p := range patients {
+		b.Entry = append(b.Entry, &r4pb.Bundle_Entry{
+			Resource: &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: p}},
+		})
+	}
+	return b
+}
+
+func TestDiffBundles(t *testing.T) {
+	unchanged := patientVersion("1", 0, true)
+	unchanged.Id = &d4pb.Id{Value: "same"}
+	// A new version with the same content differs only in meta.
+	touched := proto.Clone(unchanged).(*r4patientpb.Patient)
+	touched.Meta.VersionId = &d4pb.Id{Value: "2"}
+	gone := patientVersion("1", 0, true)
+	gone.Id = &d4pb.Id{Value: "gone"}
+	before := patientVersion("1", 0, false)
+	after := patientVersion("2", 5, true)
+	created := patientVersion("1", 5, true)
+	created.Id = &d4pb.Id{Value: "new"}
+
+	added, changed, removed, err := DiffBundles(collection(unchanged, gone, before), collection(touched, after, created))
+	if err != nil {
+		t.Fatalf("DiffBundles() failed: %v", err)
+	}
+	if diff := cmp.Diff([]proto.Message{created}, added, protocmp.Transform()); diff != "" {
+		t.Errorf("DiffBundles() added returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]proto.Message{after}, changed, protocmp.Transform()); diff != "" {
+		t.Errorf("DiffBundles() changed returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]proto.Message{gone}, removed, protocmp.Transform()); diff != "" {
+		t.Errorf("DiffBundles() removed returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiffBundles_Errors(t *testing.T) {
+	noID := &r4patientpb.Patient{}
+	tests := []struct {
+		name     string
+		old, new proto.Message
+	}{
+		{
+			name: "not a bundle",
+			old:  &r4patientpb.Patient{},
+			new:  collection(),
+		},
+		{
+			name: "resource without id",
+			old:  collection(),
+			new:  collection(noID),
+		},
+		{
+			name: "duplicate resource",
+			old:  collection(patientVersion("1", 0, true), patientVersion("2", 5, true)),
+			new:  collection(),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, _, err := DiffBundles(test.old, test.new); err == nil {
+				t.Errorf("DiffBundles() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/fhir/go/meta"
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// fullURLOptions configure AssignFullURLs.
+type fullURLOptions struct {
+	overwrite bool
+}
+
+// A FullURLOption configures AssignFullURLs.
+type FullURLOption func(*fullURLOptions)
+
+// OverwriteFullURLs makes AssignFullURLs replace fullUrls entries already
+// have, which are kept by default.
+func OverwriteFullURLs() FullURLOption {
+	return func(opts *fullURLOptions) {
+		opts.overwrite = true
+	}
+}
+
+// AssignFullURLs sets the fullUrl of each entry of an R4 Bundle that has a
+// resource. Resources with an id get "base/ResourceType/id", and those
+// without a new random "urn:uuid:" URL. base must be an absolute URL, such as
+// "https://example.com/fhir"; a trailing slash is ignored. Entries without a
+// resource, such as DELETE requests, are left alone.
+func AssignFullURLs(bundle proto.Message, base string, opts ...FullURLOption) error {
+	var o fullURLOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	b, err := asBundle(bundle)
+	if err != nil {
+		return err
+	}
+	if u, err := url.Parse(base); err != nil || !u.IsAbs() {
+		return fmt.Errorf("base %q is not an absolute URL", base)
+	}
+	base = strings.TrimSuffix(base, "/")
+	for i, e := range b.GetEntry() {
+		r := unwrapResource(e.GetResource())
+		if r == nil || (e.GetFullUrl().GetValue() != "" && !o.overwrite) {
+			continue
+		}
+		fullURL := base
+		if typ, id := resourceTypeAndID(r); id != "" {
+			fullURL += "/" + typ + "/" + id
+		} else if fullURL, err = newUUIDURL(); err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+		e.FullUrl = &d4pb.Uri{Value: fullURL}
+	}
+	return nil
+}
+
+// newUUIDURL returns a "urn:uuid:" URL holding a random version 4 UUID.
+func newUUIDURL() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
+
+// CheckFullURLUniqueness checks that no two entries of an R4 Bundle share a
+// fullUrl, which would make references to it ambiguous. As the bdl-7
+// invariant allows, entries of history bundles may share a fullUrl if their
+// resources have different meta.versionIds. It returns an error for each
+// duplicated fullUrl, listing the entries that share it.
+func CheckFullURLUniqueness(bundle proto.Message) []error {
+	b, err := asBundle(bundle)
+	if err != nil {
+		return []error{err}
+	}
+	history := b.GetType().GetValue() == c4pb.BundleTypeCode_HISTORY
+	type key struct{ fullURL, versionID string }
+	var keys []key
+	entries := map[key][]int{}
+	for i, e := range b.GetEntry() {
+		k := key{fullURL: e.GetFullUrl().GetValue()}
+		if k.fullURL == "" {
+			continue
+		}
+		if r := unwrapResource(e.GetResource()); history && r != nil {
+			k.versionID, _ = meta.VersionID(r)
+		}
+		if entries[k] == nil {
+			keys = append(keys, k)
+		}
+		entries[k] = append(entries[k], i)
+	}
+	var errs []error
+	for _, k := range keys {
+		if indices := entries[k]; len(indices) > 1 {
+			errs = append(errs, fmt.Errorf("fullUrl %q is shared by entries %s", k.fullURL, joinInts(indices)))
+		}
+	}
+	return errs
+}
+
+// joinInts formats ns as a comma-separated list.
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,186 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+var uuidURLPattern = regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func fullURLBundle(t *testing.T) *r4pb.Bundle {
+	t.Helper()
+	existing := postEntry(t, &r4patientpb.Patient{Id: &d4pb.Id{Value: "p2"}}, "Patient")
+	existing.FullUrl = &d4pb.Uri{Value: "http://other.example.com/Patient/p2"}
+	return bundleOfType(c4pb.BundleTypeCode_COLLECTION,
+		postEntry(t, &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}}, "Patient"),
+		postEntry(t, &r4observationpb.Observation{}, "Observation"),
+		postEntry(t, &r4observationpb.Observation{}, "Observation"),
+		existing,
+		&r4pb.Bundle_Entry{
+			Request: &r4pb.Bundle_Entry_Request{
+				Method: &r4pb.Bundle_Entry_Request_MethodCode{Value: c4pb.HTTPVerbCode_DELETE},
+				Url:    &d4pb.Uri{Value: "Patient/p3"},
+			},
+		},
+	)
+}
+
+func TestAssignFullURLs(t *testing.T) {
+	b := fullURLBundle(t)
+	if err := AssignFullURLs(b, "https://example.com/fhir/"); err != nil {
+		t.Fatalf("AssignFullURLs() failed: %v", err)
+	}
+	e := b.GetEntry()
+	if got, want := e[0].GetFullUrl().GetValue(), "https://example.com/fhir/Patient/p1"; got != want {
+		t.Errorf("entry 0 fullUrl = %q, want %q", got, want)
+	}
+	for _, i := range []int{1, 2} {
+		if got := e[i].GetFullUrl().GetValue(); !uuidURLPattern.MatchString(got) {
+			t.Errorf("entry %d fullUrl = %q, want a urn:uuid URL", i, got)
+		}
+	}
+	if e[1].GetFullUrl().GetValue() == e[2].GetFullUrl().GetValue() {
+		t.Errorf("entries 1 and 2 share fullUrl %q, want distinct URLs", e[1].GetFullUrl().GetValue())
+	}
+	if got, want := e[3].GetFullUrl().GetValue(), "http://other.example.com/Patient/p2"; got != want {
+		t.Errorf("entry 3 fullUrl = %q, want existing %q kept", got, want)
+	}
+	if e[4].GetFullUrl() != nil {
+		t.Errorf("entry 4 fullUrl = %q, want none for an entry without a resource", e[4].GetFullUrl().GetValue())
+	}
+
+	// Assigning again keeps the URLs already assigned.
+	again := proto.Clone(b).(*r4pb.Bundle)
+	if err := AssignFullURLs(again, "https://example.com/fhir"); err != nil {
+		t.Fatalf("AssignFullURLs() failed: %v", err)
+	}
+	if !proto.Equal(again, b) {
+		t.Errorf("AssignFullURLs() changed fullUrls already assigned")
+	}
+}
+
+func TestAssignFullURLs_Overwrite(t *testing.T) {
+	b := fullURLBundle(t)
+	if err := AssignFullURLs(b, "https://example.com/fhir", OverwriteFullURLs()); err != nil {
+		t.Fatalf("AssignFullURLs() failed: %v", err)
+	}
+	if got, want := b.GetEntry()[3].GetFullUrl().GetValue(), "https://example.com/fhir/Patient/p2"; got != want {
+		t.Errorf("entry 3 fullUrl = %q, want %q", got, want)
+	}
+}
+
+func TestAssignFullURLs_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		bundle proto.Message
+		base   string
+	}{
+		{
+			name:   "relative base",
+			bundle: fullURLBundle(t),
+			base:   "fhir",
+		},
+		{
+			name:   "not a bundle",
+			bundle: &r4patientpb.Patient{},
+			base:   "https://example.com/fhir",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := AssignFullURLs(test.bundle, test.base); err == nil {
+				t.Errorf("AssignFullURLs() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestCheckFullURLUniqueness(t *testing.T) {
+	withURL := func(e *r4pb.Bundle_Entry, fullURL string) *r4pb.Bundle_Entry {
+		e.FullUrl = &d4pb.Uri{Value: fullURL}
+		return e
+	}
+	version := func(id, versionID string) *r4patientpb.Patient {
+		return &r4patientpb.Patient{Id: &d4pb.Id{Value: id}, Meta: &d4pb.Meta{VersionId: &d4pb.Id{Value: versionID}}}
+	}
+	tests := []struct {
+		name   string
+		bundle *r4pb.Bundle
+		want   []string
+	}{
+		{
+			name: "shared fullUrl",
+			bundle: bundleOfType(c4pb.BundleTypeCode_COLLECTION,
+				withURL(postEntry(t, &r4patientpb.Patient{}, "Patient"), "urn:uuid:1"),
+				withURL(postEntry(t, &r4patientpb.Patient{}, "Patient"), "urn:uuid:2"),
+				withURL(postEntry(t, &r4patientpb.Patient{}, "Patient"), "urn:uuid:1"),
+			),
+			want: []string{`fullUrl "urn:uuid:1" is shared by entries 0, 2`},
+		},
+		{
+			name: "unique and missing fullUrls",
+			bundle: bundleOfType(c4pb.BundleTypeCode_COLLECTION,
+				withURL(postEntry(t, &r4patientpb.Patient{}, "Patient"), "urn:uuid:1"),
+				postEntry(t, &r4patientpb.Patient{}, "Patient"),
+				postEntry(t, &r4patientpb.Patient{}, "Patient"),
+			),
+		},
+		{
+			name: "history versions",
+			bundle: bundleOfType(c4pb.BundleTypeCode_HISTORY,
+				withURL(postEntry(t, version("p1", "1"), "Patient"), "http://example.com/Patient/p1"),
+				withURL(postEntry(t, version("p1", "2"), "Patient"), "http://example.com/Patient/p1"),
+			),
+		},
+		{
+			name: "history repeating a version",
+			bundle: bundleOfType(c4pb.BundleTypeCode_HISTORY,
+				withURL(postEntry(t, version("p1", "1"), "Patient"), "http://example.com/Patient/p1"),
+				withURL(postEntry(t, version("p1", "1"), "Patient"), "http://example.com/Patient/p1"),
+			),
+			want: []string{`fullUrl "http://example.com/Patient/p1" is shared by entries 0, 1`},
+		},
+		{
+			name: "versions outside history",
+			bundle: bundleOfType(c4pb.BundleTypeCode_TRANSACTION,
+				withURL(postEntry(t, version("p1", "1"), "Patient"), "http://example.com/Patient/p1"),
+				withURL(postEntry(t, version("p1", "2"), "Patient"), "http://example.com/Patient/p1"),
+			),
+			want: []string{`fullUrl "http://example.com/Patient/p1" is shared by entries 0, 1`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, err := range CheckFullURLUniqueness(test.bundle) {
+				got = append(got, err.Error())
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("CheckFullURLUniqueness() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/fhir/go/fhirpath"
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4graphpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/graph_definition_go_proto"
+)
+
+// A Resolver fetches the resources linked to by a GraphDefinition, typically
+// from a FHIR server or store.
+type Resolver interface {
+	// Resolve returns the resource ref refers to, or nil if there is none.
+	Resolve(ref *d4pb.Reference) (proto.Message, error)
+	// Search returns the resources of type resourceType matching params, a
+	// FHIR search query string such as "subject=Patient/p1".
+	Search(resourceType, params string) ([]proto.Message, error)
+}
+
+// ApplyGraphDefinition returns the resources in the graph gd, an R4
+// GraphDefinition or a ContainedResource holding one, starting from root,
+// whose type must be the start of the graph. Links with a path follow the
+// references found by evaluating the path as FHIRPath on the source resource,
+// and are resolved with resolve.Resolve; links without one search for each
+// target with resolve.Search, replacing "{ref}" in the target's params with a
+// reference to the source, e.g. "Patient/p1". Only resources of a type
+// listed among a link's targets are collected, and the links of the matching
+// target are followed from them in turn. An error is returned if the number
+// of resources found for a link from a source is outside the link's min and
+// max. root comes first in the result, followed by the other resources in the
+// order they are found, each once.
+func ApplyGraphDefinition(root proto.Message, gd proto.Message, resolve Resolver) ([]proto.Message, error) {
+	g, err := asGraphDefinition(gd)
+	if err != nil {
+		return nil, err
+	}
+	if cr, ok := root.(*r4pb.ContainedResource); ok {
+		root = unwrapResource(cr)
+		if root == nil {
+			return nil, fmt.Errorf("empty root ContainedResource")
+		}
+	}
+	if start := g.GetStart().GetValue(); !isResourceType(start, root) {
+		rt, _ := resourceTypeAndID(root)
+		return nil, fmt.Errorf("root is a %s, want the graph start %s", rt, resourceTypeName(start))
+	}
+	w := &graphWalker{resolve: resolve, seen: map[string]bool{}, seenWithoutID: map[proto.Message]bool{}}
+	w.add(root)
+	if err := w.follow(root, g.GetLink()); err != nil {
+		return nil, err
+	}
+	return w.out, nil
+}
+
+// graphWalker collects the resources found while following the links of a
+// GraphDefinition.
+type graphWalker struct {
+	resolve Resolver
+	// seen holds the "Type/id" keys of the collected resources, and
+	// seenWithoutID those that have no id, which are told apart by identity.
+	seen          map[string]bool
+	seenWithoutID map[proto.Message]bool
+	out           []proto.Message
+}
+
+// add records r, returning false if it was already collected.
+func (w *graphWalker) add(r proto.Message) bool {
+	rt, id := resourceTypeAndID(r)
+	if id == "" {
+		if w.seenWithoutID[r] {
+			return false
+		}
+		w.seenWithoutID[r] = true
+	} else {
+		key := rt + "/" + id
+		if w.seen[key] {
+			return false
+		}
+		w.seen[key] = true
+	}
+	w.out = append(w.out, r)
+	return true
+}
+
+// follow collects the resources linked from src by links, and those linked
+// from them in turn.
+func (w *graphWalker) follow(src proto.Message, links []*r4graphpb.GraphDefinition_Link) error {
+	srcType, srcID := resourceTypeAndID(src)
+	for i, link := range links {
+		found, err := w.linked(src, link)
+		if err != nil {
+			return fmt.Errorf("link %d from %s/%s: %w", i, srcType, srcID, err)
+		}
+		if err := checkCardinality(link, len(found)); err != nil {
+			return fmt.Errorf("link %d from %s/%s: %w", i, srcType, srcID, err)
+		}
+		for _, f := range found {
+			if !w.add(f.resource) {
+				continue
+			}
+			if err := w.follow(f.resource, f.target.GetLink()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// linkedResource is a resource found by following a link, with the link
+// target it matched.
+type linkedResource struct {
+	resource proto.Message
+	target   *r4graphpb.GraphDefinition_Link_Target
+}
+
+// linked returns the resources link leads to from src that match one of its
+// targets.
+func (w *graphWalker) linked(src proto.Message, link *r4graphpb.GraphDefinition_Link) ([]linkedResource, error) {
+	var out []linkedResource
+	if path := link.GetPath().GetValue(); path != "" {
+		res, err := fhirpath.Evaluate(src, path)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range res {
+			ref, ok := v.(*d4pb.Reference)
+			if !ok {
+				return nil, fmt.Errorf("path %q yields %T, want Reference", path, v)
+			}
+			r, err := w.resolve.Resolve(ref)
+			if err != nil {
+				return nil, err
+			}
+			if cr, ok := r.(*r4pb.ContainedResource); ok {
+				r = unwrapResource(cr)
+			}
+			if r == nil {
+				continue
+			}
+			if t := matchingTarget(link, r); t != nil {
+				out = append(out, linkedResource{resource: r, target: t})
+			}
+		}
+		return out, nil
+	}
+	srcType, srcID := resourceTypeAndID(src)
+	for _, t := range link.GetTarget() {
+		params := strings.ReplaceAll(t.GetParams().GetValue(), "{ref}", srcType+"/"+srcID)
+		rs, err := w.resolve.Search(resourceTypeName(t.GetType().GetValue()), params)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs {
+			if cr, ok := r.(*r4pb.ContainedResource); ok {
+				r = unwrapResource(cr)
+			}
+			if r != nil && isResourceType(t.GetType().GetValue(), r) {
+				out = append(out, linkedResource{resource: r, target: t})
+			}
+		}
+	}
+	return out, nil
+}
+
+// matchingTarget returns the target of link whose type is that of r, or nil.
+func matchingTarget(link *r4graphpb.GraphDefinition_Link, r proto.Message) *r4graphpb.GraphDefinition_Link_Target {
+	for _, t := range link.GetTarget() {
+		if isResourceType(t.GetType().GetValue(), r) {
+			return t
+		}
+	}
+	return nil
+}
+
+// checkCardinality returns an error if n resources are outside the min and
+// max of link.
+func checkCardinality(link *r4graphpb.GraphDefinition_Link, n int) error {
+	if min := int(link.GetMin().GetValue()); n < min {
+		return fmt.Errorf("found %d resources, want at least %d", n, min)
+	}
+	max := link.GetMax().GetValue()
+	if max == "" || max == "*" {
+		return nil
+	}
+	m, err := strconv.Atoi(max)
+	if err != nil {
+		return fmt.Errorf("invalid max %q", max)
+	}
+	if n > m {
+		return fmt.Errorf("found %d resources, want at most %d", n, m)
+	}
+	return nil
+}
+
+// isResourceType reports whether r has the resource type code.
+func isResourceType(code c4pb.ResourceTypeCode_Value, r proto.Message) bool {
+	return resourceTypeName(code) == string(r.ProtoReflect().Descriptor().Name())
+}
+
+// resourceTypeName returns the FHIR name of the resource type code, e.g.
+// "MedicationRequest" for MEDICATION_REQUEST.
+func resourceTypeName(code c4pb.ResourceTypeCode_Value) string {
+	name := strings.ReplaceAll(code.String(), "_", "")
+	fields := (&r4pb.ContainedResource{}).ProtoReflect().Descriptor().Oneofs().ByName("oneof_resource").Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if n := string(fields.Get(i).Message().Name()); strings.EqualFold(n, name) {
+			return n
+		}
+	}
+	return name
+}
+
+// asGraphDefinition returns msg as an R4 GraphDefinition, unwrapping a
+// ContainedResource if necessary.
+func asGraphDefinition(msg proto.Message) (*r4graphpb.GraphDefinition, error) {
+	switch g := msg.(type) {
+	case *r4graphpb.GraphDefinition:
+		return g, nil
+	case *r4pb.ContainedResource:
+		if gd := g.GetGraphDefinition(); gd != nil {
+			return gd, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported message %T, want an R4 GraphDefinition", msg)
+}
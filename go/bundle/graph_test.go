@@ -0,0 +1,236 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4graphpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/graph_definition_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	r4practitionerpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/practitioner_go_proto"
+)
+
+// fakeResolver serves patients and practitioners by id and search results by
+// resource type and params.
+type fakeResolver struct {
+	patients      map[string]proto.Message
+	practitioners map[string]proto.Message
+	searches      map[string][]proto.Message
+}
+
+func (f *fakeResolver) Resolve(ref *d4pb.Reference) (proto.Message, error) {
+	if id := ref.GetPatientId().GetValue(); id != "" {
+		return f.patients[id], nil
+	}
+	id := ref.GetPractitionerId().GetValue()
+	if id == "" {
+		return nil, fmt.Errorf("unsupported reference %v", ref)
+	}
+	return f.practitioners[id], nil
+}
+
+func (f *fakeResolver) Search(resourceType, params string) ([]proto.Message, error) {
+	return f.searches[resourceType+"?"+params], nil
+}
+
+func graphObservation(id, performer string) *r4observationpb.Observation {
+	obs := &r4observationpb.Observation{
+		Id: &d4pb.Id{Value: id},
+		Subject: &d4pb.Reference{
+			Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}},
+		},
+	}
+	if performer != "" {
+		obs.Performer = []*d4pb.Reference{{
+			Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: performer}},
+		}}
+	}
+	return obs
+}
+
+// patientObservationsGraph links a Patient to its Observations, at least
+// min of them, and each Observation to its performing Practitioner.
+func patientObservationsGraph(min int32) *r4graphpb.GraphDefinition {
+	return &r4graphpb.GraphDefinition{
+		Start: &r4graphpb.GraphDefinition_StartCode{Value: c4pb.ResourceTypeCode_PATIENT},
+		Link: []*r4graphpb.GraphDefinition_Link{{
+			Min: &d4pb.Integer{Value: min},
+			Max: &d4pb.String{Value: "*"},
+			Target: []*r4graphpb.GraphDefinition_Link_Target{{
+				Type:   &r4graphpb.GraphDefinition_Link_Target_TypeCode{Value: c4pb.ResourceTypeCode_OBSERVATION},
+				Params: &d4pb.String{Value: "subject={ref}"},
+				Link: []*r4graphpb.GraphDefinition_Link{{
+					Path: &d4pb.String{Value: "Observation.performer"},
+					Max:  &d4pb.String{Value: "1"},
+					Target: []*r4graphpb.GraphDefinition_Link_Target{{
+						Type: &r4graphpb.GraphDefinition_Link_Target_TypeCode{Value: c4pb.ResourceTypeCode_PRACTITIONER},
+					}},
+				}},
+			}},
+		}},
+	}
+}
+
+func TestApplyGraphDefinition(t *testing.T) {
+	patient := &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}}
+	resolver := &fakeResolver{
+		practitioners: map[string]proto.Message{
+			"dr1": &r4practitionerpb.Practitioner{Id: &d4pb.Id{Value: "dr1"}},
+		},
+		searches: map[string][]proto.Message{
+			"Observation?subject=Patient/p1": {
+				graphObservation("o1", "dr1"),
+				graphObservation("o2", "dr1"),
+				graphObservation("o3", ""),
+				// Not a target type of the link.
+				&r4patientpb.Patient{Id: &d4pb.Id{Value: "p2"}},
+			},
+		},
+	}
+
+	got, err := ApplyGraphDefinition(patient, patientObservationsGraph(1), resolver)
+	if err != nil {
+		t.Fatalf("ApplyGraphDefinition() failed: %v", err)
+	}
+	var ids []string
+	for _, r := range got {
+		rt, id := resourceTypeAndID(r)
+		ids = append(ids, rt+"/"+id)
+	}
+	want := []string{"Patient/p1", "Observation/o1", "Practitioner/dr1", "Observation/o2", "Observation/o3"}
+	if diff := cmp.Diff(want, ids); diff != "" {
+		t.Errorf("ApplyGraphDefinition() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestApplyGraphDefinition_EmptyResolution(t *testing.T) {
+	patient := &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}}
+	resolver := &fakeResolver{
+		practitioners: map[string]proto.Message{"dr1": &r4pb.ContainedResource{}},
+		searches: map[string][]proto.Message{
+			"Observation?subject=Patient/p1": {graphObservation("o1", "dr1")},
+		},
+	}
+
+	got, err := ApplyGraphDefinition(patient, patientObservationsGraph(1), resolver)
+	if err != nil {
+		t.Fatalf("ApplyGraphDefinition() failed: %v", err)
+	}
+	if len(got) != 2 || got[0] != proto.Message(patient) {
+		t.Errorf("ApplyGraphDefinition() = %v, want the patient and its observation", got)
+	}
+}
+
+func TestApplyGraphDefinition_CyclicWithoutIDs(t *testing.T) {
+	// Resources without ids, linked by a graph whose Observation target links
+	// back to the Patient start, as recursive GraphDefinitions do.
+	patient := &r4patientpb.Patient{}
+	obs := graphObservation("", "")
+	toObservations := &r4graphpb.GraphDefinition_Link{
+		Target: []*r4graphpb.GraphDefinition_Link_Target{{
+			Type:   &r4graphpb.GraphDefinition_Link_Target_TypeCode{Value: c4pb.ResourceTypeCode_OBSERVATION},
+			Params: &d4pb.String{Value: "subject={ref}"},
+		}},
+	}
+	toSubject := &r4graphpb.GraphDefinition_Link{
+		Path: &d4pb.String{Value: "Observation.subject"},
+		Target: []*r4graphpb.GraphDefinition_Link_Target{{
+			Type: &r4graphpb.GraphDefinition_Link_Target_TypeCode{Value: c4pb.ResourceTypeCode_PATIENT},
+			Link: []*r4graphpb.GraphDefinition_Link{toObservations},
+		}},
+	}
+	toObservations.Target[0].Link = []*r4graphpb.GraphDefinition_Link{toSubject}
+	gd := &r4graphpb.GraphDefinition{
+		Start: &r4graphpb.GraphDefinition_StartCode{Value: c4pb.ResourceTypeCode_PATIENT},
+		Link:  []*r4graphpb.GraphDefinition_Link{toObservations},
+	}
+	resolver := &fakeResolver{
+		patients: map[string]proto.Message{"p1": patient},
+		searches: map[string][]proto.Message{
+			"Observation?subject=Patient/": {obs},
+		},
+	}
+
+	got, err := ApplyGraphDefinition(patient, gd, resolver)
+	if err != nil {
+		t.Fatalf("ApplyGraphDefinition() failed: %v", err)
+	}
+	if len(got) != 2 || got[0] != proto.Message(patient) || got[1] != proto.Message(obs) {
+		t.Errorf("ApplyGraphDefinition() = %v, want the patient and its observation once each", got)
+	}
+}
+
+func TestApplyGraphDefinition_Errors(t *testing.T) {
+	patient := &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}}
+	twoPerformers := graphObservation("o1", "dr1")
+	twoPerformers.Performer = append(twoPerformers.Performer, &d4pb.Reference{
+		Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "dr2"}},
+	})
+	practitioners := map[string]proto.Message{
+		"dr1": &r4practitionerpb.Practitioner{Id: &d4pb.Id{Value: "dr1"}},
+		"dr2": &r4practitionerpb.Practitioner{Id: &d4pb.Id{Value: "dr2"}},
+	}
+	tests := []struct {
+		name     string
+		root     proto.Message
+		gd       proto.Message
+		resolver *fakeResolver
+	}{
+		{
+			name:     "not a GraphDefinition",
+			root:     patient,
+			gd:       patient,
+			resolver: &fakeResolver{},
+		},
+		{
+			name:     "root is not the start type",
+			root:     graphObservation("o1", ""),
+			gd:       patientObservationsGraph(0),
+			resolver: &fakeResolver{},
+		},
+		{
+			name:     "fewer than min",
+			root:     patient,
+			gd:       patientObservationsGraph(1),
+			resolver: &fakeResolver{},
+		},
+		{
+			name: "more than max",
+			root: patient,
+			gd:   patientObservationsGraph(0),
+			resolver: &fakeResolver{
+				practitioners: practitioners,
+				searches: map[string][]proto.Message{
+					"Observation?subject=Patient/p1": {twoPerformers},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := ApplyGraphDefinition(test.root, test.gd, test.resolver); err == nil {
+				t.Errorf("ApplyGraphDefinition() = %v, want error", got)
+			}
+		})
+	}
+}
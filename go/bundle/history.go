@@ -0,0 +1,106 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"fmt"
+
+	"github.com/google/fhir/go/meta"
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+)
+
+// deletedVersion marks a resource version as a deletion in AssembleHistory.
+type deletedVersion struct {
+	proto.Message
+}
+
+// Deleted marks r as the version recording the deletion of a resource, for
+// use with AssembleHistory. r needs at least its id, and typically carries the
+// meta.versionId and meta.lastUpdated of the deletion.
+func Deleted(r proto.Message) proto.Message {
+	return deletedVersion{r}
+}
+
+// AssembleHistory builds an R4 history Bundle from resource versions given
+// oldest first. Entries are ordered newest first, as the FHIR history
+// interaction requires. The first version of each resource is recorded as a
+// POST, later versions as a PUT, and versions wrapped with Deleted as a
+// DELETE without a resource. Each entry's response.etag and
+// response.lastModified are taken from the version's meta. Entries have no
+// fullUrl, which must be absolute; use AssignFullURLs with the server's base
+// URL to set them.
+func AssembleHistory(versions []proto.Message) (proto.Message, error) {
+	seen := map[string]bool{}
+	entries := make([]*r4pb.Bundle_Entry, len(versions))
+	for i, v := range versions {
+		deleted, isDeleted := v.(deletedVersion)
+		if isDeleted {
+			v = deleted.Message
+		}
+		cr, err := wrapResource(v)
+		if err != nil {
+			return nil, fmt.Errorf("version %d: %w", i, err)
+		}
+		res := unwrapResource(cr)
+		if res == nil {
+			return nil, fmt.Errorf("version %d: empty resource", i)
+		}
+		typ, id := resourceTypeAndID(res)
+		if id == "" {
+			return nil, fmt.Errorf("version %d: %s has no id", i, typ)
+		}
+		key := typ + "/" + id
+		entry := &r4pb.Bundle_Entry{
+			Request:  &r4pb.Bundle_Entry_Request{},
+			Response: &r4pb.Bundle_Entry_Response{},
+		}
+		switch {
+		case isDeleted:
+			entry.Request.Method = &r4pb.Bundle_Entry_Request_MethodCode{Value: c4pb.HTTPVerbCode_DELETE}
+			entry.Request.Url = &d4pb.Uri{Value: key}
+			entry.Response.Status = &d4pb.String{Value: "204 No Content"}
+		case !seen[key]:
+			entry.Request.Method = &r4pb.Bundle_Entry_Request_MethodCode{Value: c4pb.HTTPVerbCode_POST}
+			entry.Request.Url = &d4pb.Uri{Value: typ}
+			entry.Response.Status = &d4pb.String{Value: "201 Created"}
+			entry.Resource = cr
+		default:
+			entry.Request.Method = &r4pb.Bundle_Entry_Request_MethodCode{Value: c4pb.HTTPVerbCode_PUT}
+			entry.Request.Url = &d4pb.Uri{Value: key}
+			entry.Response.Status = &d4pb.String{Value: "200 OK"}
+			entry.Resource = cr
+		}
+		seen[key] = !isDeleted
+		if etag, ok := meta.ETag(res); ok {
+			entry.Response.Etag = &d4pb.String{Value: etag}
+		}
+		if m, ok := meta.Of(res); ok {
+			if lu := m.Interface().(*d4pb.Meta).GetLastUpdated(); lu != nil {
+				entry.Response.LastModified = proto.Clone(lu).(*d4pb.Instant)
+			}
+		}
+		entries[len(versions)-1-i] = entry
+	}
+	return &r4pb.Bundle{
+		Type:  &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_HISTORY},
+		Total: &d4pb.UnsignedInt{Value: uint32(len(entries))},
+		Entry: entries,
+	}, nil
+}
+
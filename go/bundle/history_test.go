@@ -0,0 +1,151 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+var baseTime = time.Date(2023, 3, 4, 10, 0, 0, 0, time.UTC)
+
+func instant(minutes int) *d4pb.Instant {
+	return &d4pb.Instant{
+		ValueUs:   baseTime.Add(time.Duration(minutes) * time.Minute).UnixMicro(),
+		Timezone:  "Z",
+		Precision: d4pb.Instant_SECOND,
+	}
+}
+
+func patientVersion(version string, minutes int, active bool) *r4patientpb.Patient {
+	return &r4patientpb.Patient{
+		Id: &d4pb.Id{Value: "p1"},
+		Meta: &d4pb.Meta{
+			VersionId:   &d4pb.Id{Value: version},
+			LastUpdated: instant(minutes),
+		},
+		Active: &d4pb.Boolean{Value: active},
+	}
+}
+
+func TestAssembleHistory(t *testing.T) {
+	created := patientVersion("1", 0, false)
+	updated := patientVersion("2", 5, true)
+	deleted := &r4patientpb.Patient{
+		Id: &d4pb.Id{Value: "p1"},
+		Meta: &d4pb.Meta{
+			VersionId:   &d4pb.Id{Value: "3"},
+			LastUpdated: instant(10),
+		},
+	}
+
+	got, err := AssembleHistory([]proto.Message{created, updated, Deleted(deleted)})
+	if err != nil {
+		t.Fatalf("AssembleHistory() failed: %v", err)
+	}
+
+	want := &r4pb.Bundle{
+		Type:  &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_HISTORY},
+		Total: &d4pb.UnsignedInt{Value: 3},
+		Entry: []*r4pb.Bundle_Entry{
+			{
+				Request: &r4pb.Bundle_Entry_Request{
+					Method: &r4pb.Bundle_Entry_Request_MethodCode{Value: c4pb.HTTPVerbCode_DELETE},
+					Url:    &d4pb.Uri{Value: "Patient/p1"},
+				},
+				Response: &r4pb.Bundle_Entry_Response{
+					Status:       &d4pb.String{Value: "204 No Content"},
+					Etag:         &d4pb.String{Value: `W/"3"`},
+					LastModified: instant(10),
+				},
+			},
+			{
+				Resource: &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: updated}},
+				Request: &r4pb.Bundle_Entry_Request{
+					Method: &r4pb.Bundle_Entry_Request_MethodCode{Value: c4pb.HTTPVerbCode_PUT},
+					Url:    &d4pb.Uri{Value: "Patient/p1"},
+				},
+				Response: &r4pb.Bundle_Entry_Response{
+					Status:       &d4pb.String{Value: "200 OK"},
+					Etag:         &d4pb.String{Value: `W/"2"`},
+					LastModified: instant(5),
+				},
+			},
+			{
+				Resource: &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: created}},
+				Request: &r4pb.Bundle_Entry_Request{
+					Method: &r4pb.Bundle_Entry_Request_MethodCode{Value: c4pb.HTTPVerbCode_POST},
+					Url:    &d4pb.Uri{Value: "Patient"},
+				},
+				Response: &r4pb.Bundle_Entry_Response{
+					Status:       &d4pb.String{Value: "201 Created"},
+					Etag:         &d4pb.String{Value: `W/"1"`},
+					LastModified: instant(0),
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("AssembleHistory() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestAssembleHistory_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []proto.Message
+	}{
+		{
+			name:     "missing id",
+			versions: []proto.Message{&r4patientpb.Patient{}},
+		},
+		{
+			name:     "empty contained resource",
+			versions: []proto.Message{&r4pb.ContainedResource{}},
+		},
+		{
+			name:     "not a resource",
+			versions: []proto.Message{&d4pb.String{Value: "x"}},
+		},
+		{
+			name:     "nil resource",
+			versions: []proto.Message{nil},
+		},
+		{
+			name:     "typed nil resource",
+			versions: []proto.Message{(*r4patientpb.Patient)(nil)},
+		},
+		{
+			name:     "deleted nil resource",
+			versions: []proto.Message{Deleted(nil)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := AssembleHistory(test.versions); err == nil {
+				t.Errorf("AssembleHistory() succeeded, want error")
+			}
+		})
+	}
+}
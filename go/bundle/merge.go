@@ -0,0 +1,76 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+)
+
+// MergeBundles concatenates the entries of R4 Bundles of the same type into a
+// new Bundle, such as when combining the pages of a search. Entries sharing a
+// fullUrl are deduplicated, keeping the first; entries without a fullUrl are
+// always kept. For searchset bundles, total is recomputed as the number of
+// entries that are not included or outcome entries. The input bundles are not
+// modified.
+func MergeBundles(bundles ...proto.Message) (proto.Message, error) {
+	if len(bundles) == 0 {
+		return nil, errors.New("no bundles to merge")
+	}
+	var typ c4pb.BundleTypeCode_Value
+	merged := &r4pb.Bundle{}
+	seen := map[string]bool{}
+	for i, msg := range bundles {
+		b, err := asBundle(msg)
+		if err != nil {
+			return nil, fmt.Errorf("bundle %d: %w", i, err)
+		}
+		if i == 0 {
+			typ = b.GetType().GetValue()
+			if b.GetType() != nil {
+				merged.Type = proto.Clone(b.GetType()).(*r4pb.Bundle_TypeCode)
+			}
+		} else if t := b.GetType().GetValue(); t != typ {
+			return nil, fmt.Errorf("bundle %d: type %v conflicts with %v", i, t, typ)
+		}
+		for _, e := range b.GetEntry() {
+			if url := e.GetFullUrl().GetValue(); url != "" {
+				if seen[url] {
+					continue
+				}
+				seen[url] = true
+			}
+			merged.Entry = append(merged.Entry, proto.Clone(e).(*r4pb.Bundle_Entry))
+		}
+	}
+	if typ == c4pb.BundleTypeCode_SEARCHSET {
+		var total uint32
+		for _, e := range merged.GetEntry() {
+			switch e.GetSearch().GetMode().GetValue() {
+			case c4pb.SearchEntryModeCode_INCLUDE, c4pb.SearchEntryModeCode_OUTCOME:
+			default:
+				total++
+			}
+		}
+		merged.Total = &d4pb.UnsignedInt{Value: total}
+	}
+	return merged, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func searchEntry(id string, mode c4pb.SearchEntryModeCode_Value) *r4pb.Bundle_Entry {
+	return &r4pb.Bundle_Entry{
+		FullUrl: &d4pb.Uri{Value: "http://example.com/fhir/Patient/" + id},
+		Resource: &r4pb.ContainedResource{
+			OneofResource: &r4pb.ContainedResource_Patient{
+				Patient: &r4patientpb.Patient{Id: &d4pb.Id{Value: id}},
+			},
+		},
+		Search: &r4pb.Bundle_Entry_Search{
+			Mode: &r4pb.Bundle_Entry_Search_ModeCode{Value: mode},
+		},
+	}
+}
+
+func searchset(total uint32, entries ...*r4pb.Bundle_Entry) *r4pb.Bundle {
+	return &r4pb.Bundle{
+		Type:  &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_SEARCHSET},
+		Total: &d4pb.UnsignedInt{Value: total},
+		Entry: entries,
+	}
+}
+
+func TestMergeBundles(t *testing.T) {
+	page1 := searchset(3,
+		searchEntry("a", c4pb.SearchEntryModeCode_MATCH),
+		searchEntry("b", c4pb.SearchEntryModeCode_MATCH),
+	)
+	page2 := searchset(3,
+		searchEntry("b", c4pb.SearchEntryModeCode_MATCH),
+		searchEntry("c", c4pb.SearchEntryModeCode_MATCH),
+		searchEntry("org", c4pb.SearchEntryModeCode_INCLUDE),
+	)
+
+	got, err := MergeBundles(page1, page2)
+	if err != nil {
+		t.Fatalf("MergeBundles() failed: %v", err)
+	}
+	want := searchset(3,
+		searchEntry("a", c4pb.SearchEntryModeCode_MATCH),
+		searchEntry("b", c4pb.SearchEntryModeCode_MATCH),
+		searchEntry("c", c4pb.SearchEntryModeCode_MATCH),
+		searchEntry("org", c4pb.SearchEntryModeCode_INCLUDE),
+	)
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("MergeBundles() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if len(page1.GetEntry()) != 2 || len(page2.GetEntry()) != 3 {
+		t.Errorf("MergeBundles() modified its inputs")
+	}
+}
+
+func TestMergeBundles_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		bundles []proto.Message
+	}{
+		{
+			name: "no bundles",
+		},
+		{
+			name: "conflicting types",
+			bundles: []proto.Message{
+				searchset(0),
+				&r4pb.Bundle{Type: &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_COLLECTION}},
+			},
+		},
+		{
+			name:    "not a bundle",
+			bundles: []proto.Message{&r4patientpb.Patient{}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := MergeBundles(test.bundles...); err == nil {
+				t.Errorf("MergeBundles() succeeded, want error")
+			}
+		})
+	}
+}
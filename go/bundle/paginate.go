@@ -0,0 +1,105 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+)
+
+// Paginate builds one page of an R4 searchset Bundle. matches are the R4
+// resources, or ContainedResources wrapping them, found for the page that
+// starts at offset, and total is the number of matches across all pages.
+// Each match becomes an entry with search mode "match"; fullUrls can be added
+// with AssignFullURLs. The resources are not copied.
+//
+// The page links to itself and, where such pages exist, to the next and
+// previous pages, with URLs formed by setting the _offset and _count
+// parameters of baseURL, the URL of the search, and keeping its other
+// parameters.
+func Paginate(matches []proto.Message, baseURL string, offset, pageSize, total int) (proto.Message, error) {
+	switch {
+	case pageSize <= 0:
+		return nil, fmt.Errorf("page size %d is not positive", pageSize)
+	case offset < 0:
+		return nil, fmt.Errorf("offset %d is negative", offset)
+	case len(matches) > pageSize:
+		return nil, fmt.Errorf("%d matches do not fit in a page of %d", len(matches), pageSize)
+	case offset+len(matches) > total:
+		return nil, fmt.Errorf("total %d is less than the %d matches up to the end of the page", total, offset+len(matches))
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	if !base.IsAbs() {
+		return nil, errors.New("base URL must be absolute")
+	}
+	entries := make([]*r4pb.Bundle_Entry, 0, len(matches))
+	for i, r := range matches {
+		cr, err := wrapResource(r)
+		if err != nil {
+			return nil, fmt.Errorf("match %d: %w", i, err)
+		}
+		if unwrapResource(cr) == nil {
+			return nil, fmt.Errorf("match %d: empty resource", i)
+		}
+		entries = append(entries, &r4pb.Bundle_Entry{
+			Resource: cr,
+			Search: &r4pb.Bundle_Entry_Search{
+				Mode: &r4pb.Bundle_Entry_Search_ModeCode{Value: c4pb.SearchEntryModeCode_MATCH},
+			},
+		})
+	}
+	links := []*r4pb.Bundle_Link{pageLink(base, "self", offset, pageSize)}
+	if offset+pageSize < total {
+		links = append(links, pageLink(base, "next", offset+pageSize, pageSize))
+	}
+	if offset > 0 {
+		prev := offset - pageSize
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, pageLink(base, "previous", prev, pageSize))
+	}
+	return &r4pb.Bundle{
+		Type:  &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_SEARCHSET},
+		Total: &d4pb.UnsignedInt{Value: uint32(total)},
+		Link:  links,
+		Entry: entries,
+	}, nil
+}
+
+// pageLink returns a link with the given relation to the page of pageSize
+// matches starting at offset.
+func pageLink(base *url.URL, relation string, offset, pageSize int) *r4pb.Bundle_Link {
+	u := *base
+	q := u.Query()
+	q.Set("_offset", strconv.Itoa(offset))
+	q.Set("_count", strconv.Itoa(pageSize))
+	u.RawQuery = q.Encode()
+	return &r4pb.Bundle_Link{
+		Relation: &d4pb.String{Value: relation},
+		Url:      &d4pb.Uri{Value: u.String()},
+	}
+}
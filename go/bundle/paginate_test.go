@@ -0,0 +1,213 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func patients(ids ...int) []proto.Message {
+	var out []proto.Message
+	for _, id := range ids {
+		out = append(out, &r4patientpb.Patient{Id: &d4pb.Id{Value: fmt.Sprint(id)}})
+	}
+	return out
+}
+
+func TestPaginate(t *testing.T) {
+	const base = "https://example.com/fhir/Patient?name=smith"
+	tests := []struct {
+		name      string
+		matches   []proto.Message
+		offset    int
+		total     int
+		wantLinks map[string]string
+	}{
+		{
+			name:    "first page",
+			matches: patients(1, 2),
+			total:   5,
+			wantLinks: map[string]string{
+				"self": "https://example.com/fhir/Patient?_count=2&_offset=0&name=smith",
+				"next": "https://example.com/fhir/Patient?_count=2&_offset=2&name=smith",
+			},
+		},
+		{
+			name:    "middle page",
+			matches: patients(3, 4),
+			offset:  2,
+			total:   5,
+			wantLinks: map[string]string{
+				"self":     "https://example.com/fhir/Patient?_count=2&_offset=2&name=smith",
+				"next":     "https://example.com/fhir/Patient?_count=2&_offset=4&name=smith",
+				"previous": "https://example.com/fhir/Patient?_count=2&_offset=0&name=smith",
+			},
+		},
+		{
+			name:    "last page",
+			matches: patients(5),
+			offset:  4,
+			total:   5,
+			wantLinks: map[string]string{
+				"self":     "https://example.com/fhir/Patient?_count=2&_offset=4&name=smith",
+				"previous": "https://example.com/fhir/Patient?_count=2&_offset=2&name=smith",
+			},
+		},
+		{
+			name:    "exactly full last page",
+			matches: patients(3, 4),
+			offset:  2,
+			total:   4,
+			wantLinks: map[string]string{
+				"self":     "https://example.com/fhir/Patient?_count=2&_offset=2&name=smith",
+				"previous": "https://example.com/fhir/Patient?_count=2&_offset=0&name=smith",
+			},
+		},
+		{
+			name:    "unaligned offset",
+			matches: patients(2, 3),
+			offset:  1,
+			total:   5,
+			wantLinks: map[string]string{
+				"self":     "https://example.com/fhir/Patient?_count=2&_offset=1&name=smith",
+				"next":     "https://example.com/fhir/Patient?_count=2&_offset=3&name=smith",
+				"previous": "https://example.com/fhir/Patient?_count=2&_offset=0&name=smith",
+			},
+		},
+		{
+			name: "no matches",
+			wantLinks: map[string]string{
+				"self": "https://example.com/fhir/Patient?_count=2&_offset=0&name=smith",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Paginate(test.matches, base, test.offset, 2, test.total)
+			if err != nil {
+				t.Fatalf("Paginate() failed: %v", err)
+			}
+			if errs := ValidateBundleStructure(got); len(errs) != 0 {
+				t.Errorf("ValidateBundleStructure(Paginate()) = %v, want no errors", errs)
+			}
+			b := got.(*r4pb.Bundle)
+			if b.GetType().GetValue() != c4pb.BundleTypeCode_SEARCHSET {
+				t.Errorf("Paginate() type = %v, want SEARCHSET", b.GetType().GetValue())
+			}
+			if b.GetTotal().GetValue() != uint32(test.total) {
+				t.Errorf("Paginate() total = %d, want %d", b.GetTotal().GetValue(), test.total)
+			}
+			links := map[string]string{}
+			for _, l := range b.GetLink() {
+				links[l.GetRelation().GetValue()] = l.GetUrl().GetValue()
+			}
+			if diff := cmp.Diff(test.wantLinks, links); diff != "" {
+				t.Errorf("Paginate() links returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if len(b.GetEntry()) != len(test.matches) {
+				t.Fatalf("Paginate() has %d entries, want %d", len(b.GetEntry()), len(test.matches))
+			}
+			for i, e := range b.GetEntry() {
+				if !proto.Equal(unwrapResource(e.GetResource()), test.matches[i]) {
+					t.Errorf("entry %d resource = %v, want %v", i, unwrapResource(e.GetResource()), test.matches[i])
+				}
+				if mode := e.GetSearch().GetMode().GetValue(); mode != c4pb.SearchEntryModeCode_MATCH {
+					t.Errorf("entry %d search mode = %v, want MATCH", i, mode)
+				}
+			}
+		})
+	}
+}
+
+func TestPaginate_Errors(t *testing.T) {
+	const base = "https://example.com/fhir/Patient"
+	tests := []struct {
+		name     string
+		matches  []proto.Message
+		baseURL  string
+		offset   int
+		pageSize int
+		total    int
+	}{
+		{
+			name:     "zero page size",
+			baseURL:  base,
+			pageSize: 0,
+		},
+		{
+			name:     "negative offset",
+			baseURL:  base,
+			offset:   -1,
+			pageSize: 2,
+		},
+		{
+			name:     "too many matches",
+			matches:  patients(1, 2, 3),
+			baseURL:  base,
+			pageSize: 2,
+			total:    3,
+		},
+		{
+			name:     "total too small",
+			matches:  patients(3, 4),
+			baseURL:  base,
+			offset:   2,
+			pageSize: 2,
+			total:    3,
+		},
+		{
+			name:     "relative base URL",
+			baseURL:  "Patient?name=smith",
+			pageSize: 2,
+		},
+		{
+			name:     "not a resource",
+			matches:  []proto.Message{&r4pb.Bundle_Entry{}},
+			baseURL:  base,
+			pageSize: 2,
+			total:    1,
+		},
+		{
+			name:     "nil resource",
+			matches:  []proto.Message{nil},
+			baseURL:  base,
+			pageSize: 2,
+			total:    1,
+		},
+		{
+			name:     "typed nil resource",
+			matches:  []proto.Message{(*r4patientpb.Patient)(nil)},
+			baseURL:  base,
+			pageSize: 2,
+			total:    1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Paginate(test.matches, test.baseURL, test.offset, test.pageSize, test.total); err == nil {
+				t.Errorf("Paginate() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+)
+
+// An EntryPair is an entry of a transaction or batch Bundle together with the
+// corresponding entry of the server's response.
+type EntryPair struct {
+	Request  *r4pb.Bundle_Entry
+	Response *r4pb.Bundle_Entry
+	// Location and ETag are the server-assigned response.location, e.g.
+	// "Patient/123/_history/1", and response.etag, or "" if the server did
+	// not return them.
+	Location, ETag string
+}
+
+// responseTypes maps the types of Bundles submitted to a server to the type
+// of their response.
+var responseTypes = map[c4pb.BundleTypeCode_Value]c4pb.BundleTypeCode_Value{
+	c4pb.BundleTypeCode_TRANSACTION: c4pb.BundleTypeCode_TRANSACTION_RESPONSE,
+	c4pb.BundleTypeCode_BATCH:       c4pb.BundleTypeCode_BATCH_RESPONSE,
+}
+
+// CorrelateResponse pairs the entries of an R4 transaction or batch Bundle
+// with those of the server's transaction-response or batch-response Bundle,
+// which FHIR requires to be in the same order. It returns an error if the
+// Bundle types do not match or the Bundles have different numbers of
+// entries.
+func CorrelateResponse(request, response proto.Message) ([]EntryPair, error) {
+	req, err := asBundle(request)
+	if err != nil {
+		return nil, fmt.Errorf("request: %w", err)
+	}
+	resp, err := asBundle(response)
+	if err != nil {
+		return nil, fmt.Errorf("response: %w", err)
+	}
+	reqType, respType := req.GetType().GetValue(), resp.GetType().GetValue()
+	want, ok := responseTypes[reqType]
+	if !ok {
+		return nil, fmt.Errorf("request Bundle has type %v, want TRANSACTION or BATCH", reqType)
+	}
+	if respType != want {
+		return nil, fmt.Errorf("response Bundle has type %v, want %v", respType, want)
+	}
+	if len(req.GetEntry()) != len(resp.GetEntry()) {
+		return nil, fmt.Errorf("request has %d entries but response has %d", len(req.GetEntry()), len(resp.GetEntry()))
+	}
+	pairs := make([]EntryPair, len(req.GetEntry()))
+	for i, e := range req.GetEntry() {
+		r := resp.GetEntry()[i]
+		if r.GetResponse() == nil {
+			return nil, fmt.Errorf("response entry %d has no response", i)
+		}
+		pairs[i] = EntryPair{
+			Request:  e,
+			Response: r,
+			Location: r.GetResponse().GetLocation().GetValue(),
+			ETag:     r.GetResponse().GetEtag().GetValue(),
+		}
+	}
+	return pairs, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func bundleOfType(typ c4pb.BundleTypeCode_Value, entries ...*r4pb.Bundle_Entry) *r4pb.Bundle {
+	return &r4pb.Bundle{Type: &r4pb.Bundle_TypeCode{Value: typ}, Entry: entries}
+}
+
+func postEntry(t *testing.T, r proto.Message, url string) *r4pb.Bundle_Entry {
+	t.Helper()
+	cr, err := wrapResource(r)
+	if err != nil {
+		t.Fatalf("wrapResource() failed: %v", err)
+	}
+	return &r4pb.Bundle_Entry{
+		Resource: cr,
+		Request: &r4pb.Bundle_Entry_Request{
+			Method: &r4pb.Bundle_Entry_Request_MethodCode{Value: c4pb.HTTPVerbCode_POST},
+			Url:    &d4pb.Uri{Value: url},
+		},
+	}
+}
+
+func responseEntry(status, location, etag string) *r4pb.Bundle_Entry {
+	return &r4pb.Bundle_Entry{
+		Response: &r4pb.Bundle_Entry_Response{
+			Status:   &d4pb.String{Value: status},
+			Location: &d4pb.Uri{Value: location},
+			Etag:     &d4pb.String{Value: etag},
+		},
+	}
+}
+
+func TestCorrelateResponse(t *testing.T) {
+	patient := postEntry(t, &r4patientpb.Patient{}, "Patient")
+	obs := postEntry(t, &r4observationpb.Observation{}, "Observation")
+	request := bundleOfType(c4pb.BundleTypeCode_TRANSACTION, patient, obs)
+	response := bundleOfType(c4pb.BundleTypeCode_TRANSACTION_RESPONSE,
+		responseEntry("201 Created", "Patient/p1/_history/1", `W/"1"`),
+		responseEntry("201 Created", "Observation/o1/_history/1", `W/"1"`),
+	)
+
+	got, err := CorrelateResponse(request, &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Bundle{Bundle: response},
+	})
+	if err != nil {
+		t.Fatalf("CorrelateResponse() failed: %v", err)
+	}
+	want := []EntryPair{
+		{Request: patient, Response: response.Entry[0], Location: "Patient/p1/_history/1", ETag: `W/"1"`},
+		{Request: obs, Response: response.Entry[1], Location: "Observation/o1/_history/1", ETag: `W/"1"`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("CorrelateResponse() returned %d pairs, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("CorrelateResponse() pair %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCorrelateResponse_Errors(t *testing.T) {
+	patient := postEntry(t, &r4patientpb.Patient{}, "Patient")
+	created := responseEntry("201 Created", "Patient/p1/_history/1", `W/"1"`)
+	tests := []struct {
+		name              string
+		request, response proto.Message
+	}{
+		{
+			name:     "entry count mismatch",
+			request:  bundleOfType(c4pb.BundleTypeCode_TRANSACTION, patient, patient),
+			response: bundleOfType(c4pb.BundleTypeCode_TRANSACTION_RESPONSE, created),
+		},
+		{
+			name:     "request not a transaction",
+			request:  bundleOfType(c4pb.BundleTypeCode_COLLECTION, patient),
+			response: bundleOfType(c4pb.BundleTypeCode_TRANSACTION_RESPONSE, created),
+		},
+		{
+			name:     "batch response to transaction",
+			request:  bundleOfType(c4pb.BundleTypeCode_TRANSACTION, patient),
+			response: bundleOfType(c4pb.BundleTypeCode_BATCH_RESPONSE, created),
+		},
+		{
+			name:     "entry without response",
+			request:  bundleOfType(c4pb.BundleTypeCode_BATCH, patient),
+			response: bundleOfType(c4pb.BundleTypeCode_BATCH_RESPONSE, &r4pb.Bundle_Entry{}),
+		},
+		{
+			name:     "not a bundle",
+			request:  &r4patientpb.Patient{},
+			response: bundleOfType(c4pb.BundleTypeCode_BATCH_RESPONSE, created),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := CorrelateResponse(test.request, test.response); err == nil {
+				t.Errorf("CorrelateResponse() = %v, want error", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"fmt"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+)
+
+// An EntrySize is the serialized size of a Bundle entry.
+type EntrySize struct {
+	// ResourceType and ID identify the entry's resource. Both are empty for
+	// entries without a resource, such as DELETE requests.
+	ResourceType, ID string
+	// Bytes is the size of the entry, including its request and response, as
+	// compact FHIR JSON.
+	Bytes int
+}
+
+// sizer estimates the serialized size of Bundle elements.
+type sizer struct {
+	m *jsonformat.Marshaller
+}
+
+func newSizer() (*sizer, error) {
+	m, err := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		return nil, err
+	}
+	return &sizer{m: m}, nil
+}
+
+// size returns the size of an element as compact FHIR JSON.
+func (s *sizer) size(elem proto.Message) (int, error) {
+	b, err := s.m.MarshalElement(elem)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// EntrySizes returns the size of each entry of an R4 Bundle when marshalled
+// as compact FHIR JSON, in entry order. The sizes sum to slightly less than
+// the size of the whole Bundle, which also holds its own elements and the
+// punctuation between entries.
+func EntrySizes(bundle proto.Message) ([]EntrySize, error) {
+	b, err := asBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSizer()
+	if err != nil {
+		return nil, err
+	}
+	sizes := make([]EntrySize, len(b.GetEntry()))
+	for i, e := range b.GetEntry() {
+		n, err := s.size(e)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		sizes[i].Bytes = n
+		if r := unwrapResource(e.GetResource()); r != nil {
+			sizes[i].ResourceType, sizes[i].ID = resourceTypeAndID(r)
+		}
+	}
+	return sizes, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func TestEntrySizes(t *testing.T) {
+	b := bundleOfType(c4pb.BundleTypeCode_TRANSACTION,
+		postEntry(t, &r4patientpb.Patient{
+			Id:   &d4pb.Id{Value: "p1"},
+			Name: []*d4pb.HumanName{{Family: &d4pb.String{Value: "Doe"}}},
+		}, "Patient"),
+		postEntry(t, &r4observationpb.Observation{
+			Id:     &d4pb.Id{Value: "o1"},
+			Status: &r4observationpb.Observation_StatusCode{Value: c4pb.ObservationStatusCode_FINAL},
+			Code:   &d4pb.CodeableConcept{Text: &d4pb.String{Value: "a considerably longer observation code text"}},
+		}, "Observation"),
+		&r4pb.Bundle_Entry{
+			Request: &r4pb.Bundle_Entry_Request{
+				Method: &r4pb.Bundle_Entry_Request_MethodCode{Value: c4pb.HTTPVerbCode_DELETE},
+				Url:    &d4pb.Uri{Value: "Patient/p2"},
+			},
+		},
+	)
+
+	got, err := EntrySizes(b)
+	if err != nil {
+		t.Fatalf("EntrySizes() failed: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("EntrySizes() returned %d sizes, want 3", len(got))
+	}
+	for i, want := range []EntrySize{{ResourceType: "Patient", ID: "p1"}, {ResourceType: "Observation", ID: "o1"}, {}} {
+		if got[i].ResourceType != want.ResourceType || got[i].ID != want.ID {
+			t.Errorf("EntrySizes()[%d] = %s/%s, want %s/%s", i, got[i].ResourceType, got[i].ID, want.ResourceType, want.ID)
+		}
+	}
+	if got[1].Bytes <= got[0].Bytes {
+		t.Errorf("EntrySizes() Observation size %d, want more than Patient size %d", got[1].Bytes, got[0].Bytes)
+	}
+
+	m, err := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("jsonformat.NewMarshaller() failed: %v", err)
+	}
+	whole, err := m.MarshalResource(b)
+	if err != nil {
+		t.Fatalf("MarshalResource() failed: %v", err)
+	}
+	sum := 0
+	for _, s := range got {
+		sum += s.Bytes
+	}
+	// The Bundle adds its resourceType, type and the entry array around the
+	// entries.
+	if overhead := len(whole) - sum; overhead < 0 || overhead > 64 {
+		t.Errorf("EntrySizes() sum to %d bytes, want within 64 bytes below the Bundle's %d", sum, len(whole))
+	}
+}
+
+func TestEntrySizes_NotBundle(t *testing.T) {
+	if got, err := EntrySizes(&r4patientpb.Patient{}); err == nil {
+		t.Errorf("EntrySizes() = %v, want error", got)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/fhir/go/fhirpath"
+	"google.golang.org/protobuf/proto"
+
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+)
+
+// SortEntries stable-sorts the entries of an R4 Bundle in place by the first
+// value the FHIRPath expression yields for each entry's resource, e.g.
+// "Observation.effective". Values are ordered with fhirpath.Compare, so they
+// must be dates, numbers or strings of one kind. Entries without a resource
+// or for which the expression yields nothing sort last, whatever the
+// direction.
+func SortEntries(bundle proto.Message, expr string, ascending bool) error {
+	b, err := asBundle(bundle)
+	if err != nil {
+		return err
+	}
+	e, err := fhirpath.Compile(expr)
+	if err != nil {
+		return err
+	}
+	type keyedEntry struct {
+		entry  *r4pb.Bundle_Entry
+		key    any
+		hasKey bool
+		index  int
+	}
+	order := make([]keyedEntry, len(b.GetEntry()))
+	for i, entry := range b.GetEntry() {
+		order[i] = keyedEntry{entry: entry, index: i}
+		if entry.GetResource() == nil {
+			continue
+		}
+		res, err := e.Evaluate(entry.GetResource())
+		if err != nil {
+			return fmt.Errorf("entry[%d]: %w", i, err)
+		}
+		if len(res) > 0 {
+			order[i].key, order[i].hasKey = res[0], true
+		}
+	}
+	var cmpErr error
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if !a.hasKey || !b.hasKey {
+			return a.hasKey && !b.hasKey
+		}
+		c, err := fhirpath.Compare(a.key, b.key)
+		if err != nil {
+			if cmpErr == nil {
+				cmpErr = fmt.Errorf("entries %d and %d: %w", a.index, b.index, err)
+			}
+			return false
+		}
+		if ascending {
+			return c < 0
+		}
+		return c > 0
+	})
+	if cmpErr != nil {
+		return cmpErr
+	}
+	for i, o := range order {
+		b.Entry[i] = o.entry
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+)
+
+// observationEntry returns an entry for an Observation effective on the
+// given day of March 2023, or without an effective time if day is 0.
+func observationEntry(id string, day int) *r4pb.Bundle_Entry {
+	obs := &r4observationpb.Observation{Id: &d4pb.Id{Value: id}}
+	if day != 0 {
+		obs.Effective = &r4observationpb.Observation_EffectiveX{
+			Choice: &r4observationpb.Observation_EffectiveX_DateTime{
+				DateTime: &d4pb.DateTime{
+					ValueUs:   time.Date(2023, 3, day, 0, 0, 0, 0, time.UTC).UnixMicro(),
+					Timezone:  "Z",
+					Precision: d4pb.DateTime_DAY,
+				},
+			},
+		}
+	}
+	return &r4pb.Bundle_Entry{
+		Resource: &r4pb.ContainedResource{
+			OneofResource: &r4pb.ContainedResource_Observation{Observation: obs},
+		},
+	}
+}
+
+func entryIDs(b *r4pb.Bundle) []string {
+	var ids []string
+	for _, e := range b.GetEntry() {
+		ids = append(ids, e.GetResource().GetObservation().GetId().GetValue())
+	}
+	return ids
+}
+
+func TestSortEntries(t *testing.T) {
+	tests := []struct {
+		name      string
+		ascending bool
+		want      []string
+	}{
+		{
+			name:      "ascending",
+			ascending: true,
+			want:      []string{"mar1", "mar5a", "mar5b", "mar9", "none"},
+		},
+		{
+			name:      "descending",
+			ascending: false,
+			want:      []string{"mar9", "mar5a", "mar5b", "mar1", "none"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := &r4pb.Bundle{
+				Type: &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_SEARCHSET},
+				Entry: []*r4pb.Bundle_Entry{
+					observationEntry("mar5a", 5),
+					observationEntry("none", 0),
+					observationEntry("mar9", 9),
+					observationEntry("mar1", 1),
+					observationEntry("mar5b", 5),
+				},
+			}
+			if err := SortEntries(b, "Observation.effectiveDateTime", test.ascending); err != nil {
+				t.Fatalf("SortEntries() failed: %v", err)
+			}
+			if diff := cmp.Diff(test.want, entryIDs(b)); diff != "" {
+				t.Errorf("SortEntries() returned unexpected order (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSortEntries_Errors(t *testing.T) {
+	period := observationEntry("period", 0)
+	period.GetResource().GetObservation().Effective = &r4observationpb.Observation_EffectiveX{
+		Choice: &r4observationpb.Observation_EffectiveX_Period{Period: &d4pb.Period{}},
+	}
+	tests := []struct {
+		name   string
+		bundle *r4pb.Bundle
+		expr   string
+	}{
+		{
+			name:   "invalid expression",
+			bundle: &r4pb.Bundle{Entry: []*r4pb.Bundle_Entry{observationEntry("a", 1)}},
+			expr:   "Observation.(",
+		},
+		{
+			name:   "incomparable values",
+			bundle: &r4pb.Bundle{Entry: []*r4pb.Bundle_Entry{observationEntry("a", 1), period}},
+			expr:   "Observation.effective",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := SortEntries(test.bundle, test.expr, true); err == nil {
+				t.Errorf("SortEntries() succeeded, want error")
+			}
+		})
+	}
+}
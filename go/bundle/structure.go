@@ -0,0 +1,125 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+)
+
+// ValidateBundleStructure checks that the entries of an R4 Bundle carry the
+// elements its type calls for, following the bdl invariants of the FHIR
+// specification:
+//   - request, with a method and url, is required on batch, transaction and
+//     history entries and prohibited otherwise;
+//   - response, with a status, is required on batch-response,
+//     transaction-response and history entries and prohibited otherwise;
+//   - search is only allowed on searchset entries;
+//   - total is only allowed on searchset and history bundles;
+//   - documents and messages start with a Composition and a MessageHeader.
+//
+// It returns an error for each problem found.
+func ValidateBundleStructure(bundle proto.Message) []error {
+	b, err := asBundle(bundle)
+	if err != nil {
+		return []error{err}
+	}
+	typ := b.GetType().GetValue()
+	if typ == c4pb.BundleTypeCode_INVALID_UNINITIALIZED {
+		return []error{errors.New("bundle type is required")}
+	}
+	name := strings.ReplaceAll(strings.ToLower(typ.String()), "_", "-")
+	var requestRequired, responseRequired bool
+	switch typ {
+	case c4pb.BundleTypeCode_BATCH, c4pb.BundleTypeCode_TRANSACTION:
+		requestRequired = true
+	case c4pb.BundleTypeCode_BATCH_RESPONSE, c4pb.BundleTypeCode_TRANSACTION_RESPONSE:
+		responseRequired = true
+	case c4pb.BundleTypeCode_HISTORY:
+		requestRequired, responseRequired = true, true
+	}
+
+	var errs []error
+	if b.GetTotal() != nil && typ != c4pb.BundleTypeCode_SEARCHSET && typ != c4pb.BundleTypeCode_HISTORY {
+		errs = append(errs, fmt.Errorf("total is only allowed on searchset and history bundles, not %s", name))
+	}
+	for i, e := range b.GetEntry() {
+		switch req := e.GetRequest(); {
+		case req == nil && requestRequired:
+			errs = append(errs, fmt.Errorf("entry[%d]: request is required in %s bundles", i, name))
+		case req != nil && !requestRequired:
+			errs = append(errs, fmt.Errorf("entry[%d]: request is not allowed in %s bundles", i, name))
+		case req != nil:
+			if req.GetMethod().GetValue() == c4pb.HTTPVerbCode_INVALID_UNINITIALIZED {
+				errs = append(errs, fmt.Errorf("entry[%d]: request.method is required", i))
+			}
+			if req.GetUrl().GetValue() == "" {
+				errs = append(errs, fmt.Errorf("entry[%d]: request.url is required", i))
+			}
+		}
+		switch resp := e.GetResponse(); {
+		case resp == nil && responseRequired:
+			errs = append(errs, fmt.Errorf("entry[%d]: response is required in %s bundles", i, name))
+		case resp != nil && !responseRequired:
+			errs = append(errs, fmt.Errorf("entry[%d]: response is not allowed in %s bundles", i, name))
+		case resp != nil && resp.GetStatus().GetValue() == "":
+			errs = append(errs, fmt.Errorf("entry[%d]: response.status is required", i))
+		}
+		if e.GetSearch() != nil && typ != c4pb.BundleTypeCode_SEARCHSET {
+			errs = append(errs, fmt.Errorf("entry[%d]: search is only allowed in searchset bundles, not %s", i, name))
+		}
+	}
+	switch typ {
+	case c4pb.BundleTypeCode_DOCUMENT:
+		if len(b.GetEntry()) == 0 || b.GetEntry()[0].GetResource().GetComposition() == nil {
+			errs = append(errs, errors.New("the first entry of a document bundle must be a Composition"))
+		}
+	case c4pb.BundleTypeCode_MESSAGE:
+		if len(b.GetEntry()) == 0 || b.GetEntry()[0].GetResource().GetMessageHeader() == nil {
+			errs = append(errs, errors.New("the first entry of a message bundle must be a MessageHeader"))
+		}
+	}
+	return errs
+}
+
+// CheckBundleTotal checks that the total of an R4 searchset Bundle is not
+// less than the number of its entries with search mode match, as a page of
+// results cannot hold more matches than there are in all. Bundles of other
+// types and searchsets without a total are not checked. It returns an error
+// for each problem found.
+func CheckBundleTotal(bundle proto.Message) []error {
+	b, err := asBundle(bundle)
+	if err != nil {
+		return []error{err}
+	}
+	if b.GetType().GetValue() != c4pb.BundleTypeCode_SEARCHSET || b.GetTotal() == nil {
+		return nil
+	}
+	matches := 0
+	for _, e := range b.GetEntry() {
+		if e.GetSearch().GetMode().GetValue() == c4pb.SearchEntryModeCode_MATCH {
+			matches++
+		}
+	}
+	if total := b.GetTotal().GetValue(); int(total) < matches {
+		return []error{fmt.Errorf("total is %d but the bundle has %d match entries", total, matches)}
+	}
+	return nil
+}
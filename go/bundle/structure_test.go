@@ -0,0 +1,192 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func patientResource(id string) *r4pb.ContainedResource {
+	return &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Patient{
+			Patient: &r4patientpb.Patient{Id: &d4pb.Id{Value: id}},
+		},
+	}
+}
+
+func postRequest(url string) *r4pb.Bundle_Entry_Request {
+	return &r4pb.Bundle_Entry_Request{
+		Method: &r4pb.Bundle_Entry_Request_MethodCode{Value: c4pb.HTTPVerbCode_POST},
+		Url:    &d4pb.Uri{Value: url},
+	}
+}
+
+func TestValidateBundleStructure(t *testing.T) {
+	tests := []struct {
+		name   string
+		bundle *r4pb.Bundle
+		want   []string
+	}{
+		{
+			name: "valid transaction",
+			bundle: &r4pb.Bundle{
+				Type: &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_TRANSACTION},
+				Entry: []*r4pb.Bundle_Entry{
+					{Resource: patientResource("p1"), Request: postRequest("Patient")},
+				},
+			},
+		},
+		{
+			name: "transaction entry without request",
+			bundle: &r4pb.Bundle{
+				Type: &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_TRANSACTION},
+				Entry: []*r4pb.Bundle_Entry{
+					{Resource: patientResource("p1"), Request: postRequest("Patient")},
+					{Resource: patientResource("p2")},
+				},
+			},
+			want: []string{"entry[1]: request is required in transaction bundles"},
+		},
+		{
+			name: "transaction entry without method",
+			bundle: &r4pb.Bundle{
+				Type: &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_TRANSACTION},
+				Entry: []*r4pb.Bundle_Entry{
+					{Resource: patientResource("p1"), Request: &r4pb.Bundle_Entry_Request{Url: &d4pb.Uri{Value: "Patient"}}},
+				},
+			},
+			want: []string{"entry[0]: request.method is required"},
+		},
+		{
+			name: "transaction response without status",
+			bundle: &r4pb.Bundle{
+				Type: &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_TRANSACTION_RESPONSE},
+				Entry: []*r4pb.Bundle_Entry{
+					{Response: &r4pb.Bundle_Entry_Response{}},
+				},
+			},
+			want: []string{"entry[0]: response.status is required"},
+		},
+		{
+			name: "searchset with request and total",
+			bundle: &r4pb.Bundle{
+				Type:  &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_SEARCHSET},
+				Total: &d4pb.UnsignedInt{Value: 1},
+				Entry: []*r4pb.Bundle_Entry{
+					{Resource: patientResource("p1"), Request: postRequest("Patient")},
+				},
+			},
+			want: []string{"entry[0]: request is not allowed in searchset bundles"},
+		},
+		{
+			name: "collection with search and total",
+			bundle: &r4pb.Bundle{
+				Type:  &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_COLLECTION},
+				Total: &d4pb.UnsignedInt{Value: 1},
+				Entry: []*r4pb.Bundle_Entry{
+					{Resource: patientResource("p1"), Search: &r4pb.Bundle_Entry_Search{}},
+				},
+			},
+			want: []string{
+				"total is only allowed on searchset and history bundles, not collection",
+				"entry[0]: search is only allowed in searchset bundles, not collection",
+			},
+		},
+		{
+			name: "document without composition",
+			bundle: &r4pb.Bundle{
+				Type:  &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_DOCUMENT},
+				Entry: []*r4pb.Bundle_Entry{{Resource: patientResource("p1")}},
+			},
+			want: []string{"the first entry of a document bundle must be a Composition"},
+		},
+		{
+			name:   "missing type",
+			bundle: &r4pb.Bundle{},
+			want:   []string{"bundle type is required"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, err := range ValidateBundleStructure(test.bundle) {
+				got = append(got, err.Error())
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ValidateBundleStructure() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckBundleTotal(t *testing.T) {
+	withoutTotal := searchset(0, searchEntry("a", c4pb.SearchEntryModeCode_MATCH))
+	withoutTotal.Total = nil
+	tests := []struct {
+		name   string
+		bundle *r4pb.Bundle
+		want   []string
+	}{
+		{
+			name: "total below matches",
+			bundle: searchset(0,
+				searchEntry("a", c4pb.SearchEntryModeCode_MATCH),
+				searchEntry("b", c4pb.SearchEntryModeCode_MATCH),
+			),
+			want: []string{"total is 0 but the bundle has 2 match entries"},
+		},
+		{
+			name: "includes not counted",
+			bundle: searchset(1,
+				searchEntry("a", c4pb.SearchEntryModeCode_MATCH),
+				searchEntry("org", c4pb.SearchEntryModeCode_INCLUDE),
+			),
+		},
+		{
+			name:   "total above page",
+			bundle: searchset(100, searchEntry("a", c4pb.SearchEntryModeCode_MATCH)),
+		},
+		{
+			name:   "no total",
+			bundle: withoutTotal,
+		},
+		{
+			name: "not a searchset",
+			bundle: &r4pb.Bundle{
+				Type:  &r4pb.Bundle_TypeCode{Value: c4pb.BundleTypeCode_HISTORY},
+				Total: &d4pb.UnsignedInt{Value: 0},
+				Entry: []*r4pb.Bundle_Entry{searchEntry("a", c4pb.SearchEntryModeCode_MATCH)},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, err := range CheckBundleTotal(test.bundle) {
+				got = append(got, err.Error())
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("CheckBundleTotal() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
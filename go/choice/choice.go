@@ -0,0 +1,144 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package choice provides helpers for working with FHIR choice elements,
+// such as Observation.value[x].
+package choice
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+)
+
+// codeRegex is the value constraint of the FHIR code type.
+var codeRegex = regexp.MustCompile(`^[^\s]+(\s[^\s]+)*$`)
+
+// CoerceChoice converts the active value of the choice element baseName of
+// msg, e.g. "value", to the type targetType, e.g. "Code", in place. Only
+// lossless conversions are supported: between string and code, and between
+// integer and decimal, where the decimal must be written without a fraction,
+// as "7" rather than "7.00". The element id and extensions of the value are kept.
+// Converting a value to its current type does nothing.
+func CoerceChoice(msg proto.Message, baseName string, targetType string) error {
+	rm := msg.ProtoReflect()
+	if oneof := rm.Descriptor().Oneofs().ByName("oneof_resource"); oneof != nil {
+		f := rm.WhichOneof(oneof)
+		if f == nil {
+			return fmt.Errorf("empty %v", rm.Descriptor().FullName())
+		}
+		rm = rm.Get(f).Message()
+	}
+	f := choiceField(rm.Descriptor(), baseName)
+	if f == nil {
+		return fmt.Errorf("%v has no choice element %q", rm.Descriptor().Name(), baseName)
+	}
+	if !rm.Has(f) {
+		return fmt.Errorf("choice element %q is not set", baseName)
+	}
+	cm := rm.Mutable(f).Message()
+	oneof := cm.Descriptor().Oneofs().Get(0)
+	active := cm.WhichOneof(oneof)
+	if active == nil {
+		return fmt.Errorf("choice element %q is not set", baseName)
+	}
+	target := typeField(oneof, targetType)
+	if target == nil {
+		return fmt.Errorf("choice element %q does not allow type %q", baseName, targetType)
+	}
+	if target == active {
+		return nil
+	}
+	src := cm.Get(active).Message()
+	dst := cm.NewField(target).Message()
+	if err := convert(src, dst); err != nil {
+		return fmt.Errorf("%s%s to %s%s: %w", baseName, typeName(active), baseName, typeName(target), err)
+	}
+	cm.Set(target, protoreflect.ValueOfMessage(dst))
+	return nil
+}
+
+// choiceField returns the choice-typed field of d with the given JSON name.
+func choiceField(d protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := d.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.Get(i)
+		if f.JSONName() == name && f.Message() != nil && proto.GetExtension(f.Message().Options(), apb.E_IsChoiceType).(bool) {
+			return f
+		}
+	}
+	return nil
+}
+
+// typeField returns the field of the choice oneof for the given FHIR type,
+// matched case-insensitively, e.g. "Code" or "dateTime".
+func typeField(oneof protoreflect.OneofDescriptor, typ string) protoreflect.FieldDescriptor {
+	fields := oneof.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if f := fields.Get(i); strings.EqualFold(f.JSONName(), typ) {
+			return f
+		}
+	}
+	return nil
+}
+
+func typeName(f protoreflect.FieldDescriptor) string {
+	name := f.JSONName()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// convert sets the value of the primitive dst from the primitive src,
+// copying the id and extensions.
+func convert(src, dst protoreflect.Message) error {
+	srcValue := src.Get(src.Descriptor().Fields().ByName("value"))
+	dstField := dst.Descriptor().Fields().ByName("value")
+	var v protoreflect.Value
+	switch pair := string(src.Descriptor().Name()) + "->" + string(dst.Descriptor().Name()); pair {
+	case "String->Code":
+		s := srcValue.String()
+		if !codeRegex.MatchString(s) {
+			return fmt.Errorf("%q is not a valid code", s)
+		}
+		v = protoreflect.ValueOfString(s)
+	case "Code->String":
+		v = protoreflect.ValueOfString(srcValue.String())
+	case "Integer->Decimal":
+		v = protoreflect.ValueOfString(big.NewInt(srcValue.Int()).String())
+	case "Decimal->Integer":
+		// Only decimals written without a fraction or exponent convert, as
+		// "7.00" states a precision an integer cannot keep.
+		n, err := strconv.ParseInt(srcValue.String(), 10, 32)
+		if err != nil {
+			return fmt.Errorf("decimal %s is not a 32-bit integer without a fraction", srcValue.String())
+		}
+		v = protoreflect.ValueOfInt32(int32(n))
+	default:
+		return fmt.Errorf("unsupported conversion %s", pair)
+	}
+	dst.Set(dstField, v)
+	for _, name := range []protoreflect.Name{"id", "extension"} {
+		sf, df := src.Descriptor().Fields().ByName(name), dst.Descriptor().Fields().ByName(name)
+		if sf != nil && df != nil && src.Has(sf) {
+			dst.Set(df, src.Get(sf))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package choice
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+)
+
+func extension(v *d4pb.Extension_ValueX) *d4pb.Extension {
+	return &d4pb.Extension{
+		Url:   &d4pb.Uri{Value: "http://example.com/ext"},
+		Value: v,
+	}
+}
+
+func TestCoerceChoice(t *testing.T) {
+	tests := []struct {
+		name       string
+		msg        proto.Message
+		targetType string
+		want       proto.Message
+	}{
+		{
+			name: "string to code",
+			msg: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_StringValue{
+				StringValue: &d4pb.String{Id: &d4pb.String{Value: "v1"}, Value: "final"},
+			}}),
+			targetType: "Code",
+			want: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Code{
+				Code: &d4pb.Code{Id: &d4pb.String{Value: "v1"}, Value: "final"},
+			}}),
+		},
+		{
+			name: "code to string",
+			msg: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Code{
+				Code: &d4pb.Code{Value: "final"},
+			}}),
+			targetType: "string",
+			want: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_StringValue{
+				StringValue: &d4pb.String{Value: "final"},
+			}}),
+		},
+		{
+			name: "integer to decimal",
+			msg: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Integer{
+				Integer: &d4pb.Integer{Value: -42},
+			}}),
+			targetType: "decimal",
+			want: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Decimal{
+				Decimal: &d4pb.Decimal{Value: "-42"},
+			}}),
+		},
+		{
+			name: "decimal to integer",
+			msg: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Decimal{
+				Decimal: &d4pb.Decimal{Value: "7"},
+			}}),
+			targetType: "integer",
+			want: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Integer{
+				Integer: &d4pb.Integer{Value: 7},
+			}}),
+		},
+		{
+			name: "same type",
+			msg: &r4observationpb.Observation{Value: &r4observationpb.Observation_ValueX{
+				Choice: &r4observationpb.Observation_ValueX_StringValue{StringValue: &d4pb.String{Value: "x"}},
+			}},
+			targetType: "string",
+			want: &r4observationpb.Observation{Value: &r4observationpb.Observation_ValueX{
+				Choice: &r4observationpb.Observation_ValueX_StringValue{StringValue: &d4pb.String{Value: "x"}},
+			}},
+		},
+		{
+			name: "contained resource",
+			msg: &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Observation{
+				Observation: &r4observationpb.Observation{Value: &r4observationpb.Observation_ValueX{
+					Choice: &r4observationpb.Observation_ValueX_Integer{Integer: &d4pb.Integer{Value: 3}},
+				}},
+			}},
+			targetType: "Integer",
+			want: &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Observation{
+				Observation: &r4observationpb.Observation{Value: &r4observationpb.Observation_ValueX{
+					Choice: &r4observationpb.Observation_ValueX_Integer{Integer: &d4pb.Integer{Value: 3}},
+				}},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CoerceChoice(test.msg, "value", test.targetType); err != nil {
+				t.Fatalf("CoerceChoice(%q) failed: %v", test.targetType, err)
+			}
+			if diff := cmp.Diff(test.want, test.msg, protocmp.Transform()); diff != "" {
+				t.Errorf("CoerceChoice(%q) returned unexpected diff (-want +got):\n%s", test.targetType, diff)
+			}
+		})
+	}
+}
+
+func TestCoerceChoice_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		msg        proto.Message
+		baseName   string
+		targetType string
+	}{
+		{
+			// R4 Observation.value[x] does not allow the code type.
+			name: "observation valueString to valueCode",
+			msg: &r4observationpb.Observation{Value: &r4observationpb.Observation_ValueX{
+				Choice: &r4observationpb.Observation_ValueX_StringValue{StringValue: &d4pb.String{Value: "final"}},
+			}},
+			baseName:   "value",
+			targetType: "code",
+		},
+		{
+			name: "string with whitespace to code",
+			msg: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_StringValue{
+				StringValue: &d4pb.String{Value: " final"},
+			}}),
+			baseName:   "value",
+			targetType: "code",
+		},
+		{
+			name: "fractional decimal to integer",
+			msg: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Decimal{
+				Decimal: &d4pb.Decimal{Value: "1.5"},
+			}}),
+			baseName:   "value",
+			targetType: "integer",
+		},
+		{
+			name: "decimal with zero fraction to integer",
+			msg: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Decimal{
+				Decimal: &d4pb.Decimal{Value: "7.00"},
+			}}),
+			baseName:   "value",
+			targetType: "integer",
+		},
+		{
+			name: "decimal with exponent to integer",
+			msg: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Decimal{
+				Decimal: &d4pb.Decimal{Value: "7e0"},
+			}}),
+			baseName:   "value",
+			targetType: "integer",
+		},
+		{
+			name: "decimal out of integer range",
+			msg: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Decimal{
+				Decimal: &d4pb.Decimal{Value: "3000000000"},
+			}}),
+			baseName:   "value",
+			targetType: "integer",
+		},
+		{
+			name: "incompatible types",
+			msg: extension(&d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_StringValue{
+				StringValue: &d4pb.String{Value: "true"},
+			}}),
+			baseName:   "value",
+			targetType: "boolean",
+		},
+		{
+			name:       "unset choice",
+			msg:        &r4observationpb.Observation{},
+			baseName:   "value",
+			targetType: "string",
+		},
+		{
+			name:       "unknown element",
+			msg:        &r4observationpb.Observation{},
+			baseName:   "status",
+			targetType: "string",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := proto.Clone(test.msg)
+			if err := CoerceChoice(test.msg, test.baseName, test.targetType); err == nil {
+				t.Errorf("CoerceChoice(%q, %q) succeeded, want error", test.baseName, test.targetType)
+			}
+			if !proto.Equal(before, test.msg) {
+				t.Errorf("CoerceChoice(%q, %q) modified the message on error", test.baseName, test.targetType)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package codeable provides helpers for working with FHIR R4
+// CodeableConcepts.
+package codeable
+
+import (
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// PreferSystem reduces cc to a single coding in the given code system. It
+// returns the first of cc's codings already in system, if any. Otherwise
+// each coding is passed in turn to mapper, which returns the equivalent
+// coding in system or nil if it has no mapping, and the first mapped coding
+// is returned. PreferSystem returns nil if no coding is in or maps to system;
+// mapper may be nil to only consider existing codings.
+//
+// The returned coding is not a copy: an existing coding is shared with cc.
+func PreferSystem(cc *d4pb.CodeableConcept, system string, mapper func(*d4pb.Coding) *d4pb.Coding) *d4pb.Coding {
+	for _, c := range cc.GetCoding() {
+		if c.GetSystem().GetValue() == system {
+			return c
+		}
+	}
+	if mapper == nil {
+		return nil
+	}
+	for _, c := range cc.GetCoding() {
+		if mapped := mapper(c); mapped != nil {
+			return mapped
+		}
+	}
+	return nil
+}
+
+// DedupCodings removes codings from cc that repeat an earlier coding's
+// system, version and code, keeping the first occurrence, and its display, in
+// place. Codings without a code are not compared and are always kept. cc may
+// be nil.
+func DedupCodings(cc *d4pb.CodeableConcept) {
+	if cc == nil {
+		return
+	}
+	type key struct{ system, version, code string }
+	seen := map[key]bool{}
+	kept := cc.Coding[:0]
+	for _, c := range cc.GetCoding() {
+		if c.GetCode().GetValue() != "" {
+			k := key{c.GetSystem().GetValue(), c.GetVersion().GetValue(), c.GetCode().GetValue()}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+		}
+		kept = append(kept, c)
+	}
+	for i := len(kept); i < len(cc.Coding); i++ {
+		cc.Coding[i] = nil
+	}
+	cc.Coding = kept
+}
+
+// CodingDiff returns the codings of a that have no coding with the same
+// system and code in b, and those of b that have none in a, each in their
+// original order. Codings without a code match nothing and are always
+// returned. Either concept may be nil. The returned codings are shared with a
+// and b, not copied.
+func CodingDiff(a, b *d4pb.CodeableConcept) (onlyA, onlyB []*d4pb.Coding) {
+	return codingsNotIn(a, b), codingsNotIn(b, a)
+}
+
+// codingsNotIn returns the codings of cc whose system and code are not those
+// of any coding in other.
+func codingsNotIn(cc, other *d4pb.CodeableConcept) []*d4pb.Coding {
+	type key struct{ system, code string }
+	in := map[key]bool{}
+	for _, c := range other.GetCoding() {
+		if c.GetCode().GetValue() != "" {
+			in[key{c.GetSystem().GetValue(), c.GetCode().GetValue()}] = true
+		}
+	}
+	var out []*d4pb.Coding
+	for _, c := range cc.GetCoding() {
+		if c.GetCode().GetValue() == "" || !in[key{c.GetSystem().GetValue(), c.GetCode().GetValue()}] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
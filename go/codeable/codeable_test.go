@@ -0,0 +1,199 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codeable
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+const (
+	snomed = "http://snomed.info/sct"
+	icd10  = "http://hl7.org/fhir/sid/icd-10"
+	local  = "http://example.org/codes"
+)
+
+func coding(system, code string) *d4pb.Coding {
+	return &d4pb.Coding{System: &d4pb.Uri{Value: system}, Code: &d4pb.Code{Value: code}}
+}
+
+// icdMapper maps SNOMED CT diabetes to ICD-10 and nothing else.
+func icdMapper(c *d4pb.Coding) *d4pb.Coding {
+	if c.GetSystem().GetValue() == snomed && c.GetCode().GetValue() == "44054006" {
+		return coding(icd10, "E11")
+	}
+	return nil
+}
+
+func TestPreferSystem(t *testing.T) {
+	tests := []struct {
+		name   string
+		cc     *d4pb.CodeableConcept
+		mapper func(*d4pb.Coding) *d4pb.Coding
+		want   *d4pb.Coding
+	}{
+		{
+			name: "existing preferred coding",
+			cc: &d4pb.CodeableConcept{Coding: []*d4pb.Coding{
+				coding(snomed, "44054006"),
+				coding(icd10, "E11.9"),
+			}},
+			mapper: icdMapper,
+			want:   coding(icd10, "E11.9"),
+		},
+		{
+			name: "falls back to mapping",
+			cc: &d4pb.CodeableConcept{Coding: []*d4pb.Coding{
+				coding(local, "dm2"),
+				coding(snomed, "44054006"),
+			}},
+			mapper: icdMapper,
+			want:   coding(icd10, "E11"),
+		},
+		{
+			name:   "no mapping",
+			cc:     &d4pb.CodeableConcept{Coding: []*d4pb.Coding{coding(local, "dm2")}},
+			mapper: icdMapper,
+		},
+		{
+			name: "nil mapper",
+			cc:   &d4pb.CodeableConcept{Coding: []*d4pb.Coding{coding(snomed, "44054006")}},
+		},
+		{
+			name:   "nil concept",
+			mapper: icdMapper,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := PreferSystem(test.cc, icd10, test.mapper)
+			if !proto.Equal(got, test.want) {
+				t.Errorf("PreferSystem() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDedupCodings(t *testing.T) {
+	withDisplay := func(c *d4pb.Coding, display string) *d4pb.Coding {
+		c.Display = &d4pb.String{Value: display}
+		return c
+	}
+	versioned := coding(snomed, "44054006")
+	versioned.Version = &d4pb.String{Value: "2023-03"}
+	cc := &d4pb.CodeableConcept{
+		Coding: []*d4pb.Coding{
+			withDisplay(coding(snomed, "44054006"), "Diabetes mellitus type 2"),
+			coding(icd10, "E11"),
+			withDisplay(coding(snomed, "44054006"), "Type 2 diabetes"),
+			versioned,
+			{Display: &d4pb.String{Value: "uncoded"}},
+			{Display: &d4pb.String{Value: "uncoded"}},
+		},
+	}
+	DedupCodings(cc)
+	want := &d4pb.CodeableConcept{
+		Coding: []*d4pb.Coding{
+			withDisplay(coding(snomed, "44054006"), "Diabetes mellitus type 2"),
+			coding(icd10, "E11"),
+			versioned,
+			{Display: &d4pb.String{Value: "uncoded"}},
+			{Display: &d4pb.String{Value: "uncoded"}},
+		},
+	}
+	if !proto.Equal(cc, want) {
+		t.Errorf("DedupCodings() = %v, want %v", cc, want)
+	}
+}
+
+func TestDedupCodings_Identical(t *testing.T) {
+	cc := &d4pb.CodeableConcept{Coding: []*d4pb.Coding{coding(snomed, "44054006"), coding(snomed, "44054006")}}
+	DedupCodings(cc)
+	if len(cc.GetCoding()) != 1 {
+		t.Errorf("DedupCodings() left %d codings, want 1", len(cc.GetCoding()))
+	}
+	DedupCodings(nil)
+}
+
+func TestCodingDiff(t *testing.T) {
+	uncoded := &d4pb.Coding{Display: &d4pb.String{Value: "uncoded"}}
+	tests := []struct {
+		name         string
+		a, b         *d4pb.CodeableConcept
+		onlyA, onlyB []*d4pb.Coding
+	}{
+		{
+			name: "one shared coding",
+			a: &d4pb.CodeableConcept{Coding: []*d4pb.Coding{
+				coding(snomed, "44054006"),
+				coding(icd10, "E11"),
+			}},
+			b: &d4pb.CodeableConcept{Coding: []*d4pb.Coding{
+				coding(icd10, "E11"),
+				coding(local, "dm2"),
+			}},
+			onlyA: []*d4pb.Coding{coding(snomed, "44054006")},
+			onlyB: []*d4pb.Coding{coding(local, "dm2")},
+		},
+		{
+			name: "display and version ignored",
+			a: &d4pb.CodeableConcept{Coding: []*d4pb.Coding{{
+				System:  &d4pb.Uri{Value: snomed},
+				Version: &d4pb.String{Value: "2023-03"},
+				Code:    &d4pb.Code{Value: "44054006"},
+				Display: &d4pb.String{Value: "Type 2 diabetes"},
+			}}},
+			b: &d4pb.CodeableConcept{Coding: []*d4pb.Coding{coding(snomed, "44054006")}},
+		},
+		{
+			name:  "same code in another system",
+			a:     &d4pb.CodeableConcept{Coding: []*d4pb.Coding{coding(snomed, "E11")}},
+			b:     &d4pb.CodeableConcept{Coding: []*d4pb.Coding{coding(icd10, "E11")}},
+			onlyA: []*d4pb.Coding{coding(snomed, "E11")},
+			onlyB: []*d4pb.Coding{coding(icd10, "E11")},
+		},
+		{
+			name:  "codings without a code never match",
+			a:     &d4pb.CodeableConcept{Coding: []*d4pb.Coding{uncoded}},
+			b:     &d4pb.CodeableConcept{Coding: []*d4pb.Coding{uncoded}},
+			onlyA: []*d4pb.Coding{uncoded},
+			onlyB: []*d4pb.Coding{uncoded},
+		},
+		{
+			name:  "nil concept",
+			a:     &d4pb.CodeableConcept{Coding: []*d4pb.Coding{coding(snomed, "44054006")}},
+			onlyA: []*d4pb.Coding{coding(snomed, "44054006")},
+		},
+		{
+			name: "both nil",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			onlyA, onlyB := CodingDiff(test.a, test.b)
+			if diff := cmp.Diff(test.onlyA, onlyA, protocmp.Transform()); diff != "" {
+				t.Errorf("CodingDiff() onlyA returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.onlyB, onlyB, protocmp.Transform()); diff != "" {
+				t.Errorf("CodingDiff() onlyB returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
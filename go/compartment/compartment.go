@@ -0,0 +1,134 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compartment determines the FHIR R4 compartments, such as a
+// patient's or an encounter's, that resources belong to.
+package compartment
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// Compartments returns the compartments the R4 resource msg, or the resource
+// held by a ContainedResource, belongs to: for each compartment type, such as
+// "Patient" or "Encounter", the ids of the compartments in the order they are
+// referenced. For example, an Observation whose subject is Patient/p1 is in
+// the Patient compartment p1. A resource of a compartment type with an id is
+// in its own compartment. References that are not of the form Type/id, such
+// as absolute URLs, are ignored. Compartment types the resource is not in
+// are omitted.
+func Compartments(msg proto.Message) (map[string][]string, error) {
+	rm, ok := element.ResourceOf(msg)
+	if !ok {
+		return nil, errors.New("nil or empty resource")
+	}
+	if !element.IsResource(rm.Descriptor()) {
+		return nil, fmt.Errorf("%v is not a resource", rm.Descriptor().FullName())
+	}
+	resourceType := string(rm.Descriptor().Name())
+	out := map[string][]string{}
+	add := func(compartmentType, id string) {
+		for _, seen := range out[compartmentType] {
+			if seen == id {
+				return
+			}
+		}
+		out[compartmentType] = append(out[compartmentType], id)
+	}
+	if _, ok := definitions[resourceType]; ok {
+		if id := element.PrimitiveString(rm, "id"); id != "" {
+			add(resourceType, id)
+		}
+	}
+	for compartmentType, resources := range definitions {
+		for _, path := range resources[resourceType] {
+			for _, ref := range references(rm, strings.Split(path, ".")) {
+				if typ, id := target(ref); typ == compartmentType {
+					add(compartmentType, id)
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// Elements returns the element paths, such as "subject" or
+// "participant.actor", whose references link resources of resourceType into
+// compartments of compartmentType, or nil if resources of that type cannot be
+// in such a compartment.
+func Elements(compartmentType, resourceType string) []string {
+	return definitions[compartmentType][resourceType]
+}
+
+// references returns the references found by following path, a sequence of
+// FHIR element names, from m.
+func references(m protoreflect.Message, path []string) []*d4pb.Reference {
+	f := m.Descriptor().Fields().ByJSONName(path[0])
+	if f == nil || f.Message() == nil || !m.Has(f) {
+		return nil
+	}
+	var values []protoreflect.Message
+	if f.IsList() {
+		l := m.Get(f).List()
+		for i := 0; i < l.Len(); i++ {
+			values = append(values, l.Get(i).Message())
+		}
+	} else {
+		values = append(values, m.Get(f).Message())
+	}
+	var refs []*d4pb.Reference
+	for _, v := range values {
+		if len(path) > 1 {
+			refs = append(refs, references(v, path[1:])...)
+			continue
+		}
+		if ref, ok := v.Interface().(*d4pb.Reference); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// target returns the resource type and id ref points to, or empty strings if
+// it is not a Type/id reference.
+func target(ref *d4pb.Reference) (string, string) {
+	norm := proto.Clone(ref).(*d4pb.Reference)
+	if err := jsonformat.NormalizeReference(norm); err != nil {
+		return "", ""
+	}
+	rm := norm.ProtoReflect()
+	f := rm.WhichOneof(rm.Descriptor().Oneofs().ByName("reference"))
+	if f == nil {
+		return "", ""
+	}
+	typ := proto.GetExtension(f.Options(), apb.E_ReferencedFhirType).(string)
+	if typ == "" {
+		return "", ""
+	}
+	id, ok := rm.Get(f).Message().Interface().(*d4pb.ReferenceId)
+	if !ok {
+		return "", ""
+	}
+	return typ, id.GetValue()
+}
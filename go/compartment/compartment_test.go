@@ -0,0 +1,178 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compartment
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4encounterpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/encounter_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func TestCompartments(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+		want map[string][]string
+	}{
+		{
+			name: "observation subject",
+			msg: &r4observationpb.Observation{
+				Subject: &d4pb.Reference{Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}}},
+			},
+			want: map[string][]string{"Patient": {"p1"}},
+		},
+		{
+			name: "observation links",
+			msg: &r4observationpb.Observation{
+				Subject:   &d4pb.Reference{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: "Patient/p1"}}},
+				Encounter: &d4pb.Reference{Reference: &d4pb.Reference_EncounterId{EncounterId: &d4pb.ReferenceId{Value: "e1"}}},
+				Performer: []*d4pb.Reference{
+					{Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "dr1"}}},
+					{Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}}},
+					{Reference: &d4pb.Reference_OrganizationId{OrganizationId: &d4pb.ReferenceId{Value: "o1"}}},
+					{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: "http://example.com/fhir/Practitioner/dr2"}}},
+				},
+			},
+			want: map[string][]string{
+				"Patient":      {"p1"},
+				"Encounter":    {"e1"},
+				"Practitioner": {"dr1"},
+			},
+		},
+		{
+			name: "own compartment",
+			msg: &r4encounterpb.Encounter{
+				Id:      &d4pb.Id{Value: "e1"},
+				Subject: &d4pb.Reference{Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}}},
+				Participant: []*r4encounterpb.Encounter_Participant{{
+					Individual: &d4pb.Reference{Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "dr1"}}},
+				}},
+			},
+			want: map[string][]string{
+				"Patient":      {"p1"},
+				"Encounter":    {"e1"},
+				"Practitioner": {"dr1"},
+			},
+		},
+		{
+			name: "contained resource",
+			msg: &r4pb.ContainedResource{
+				OneofResource: &r4pb.ContainedResource_Patient{Patient: &r4patientpb.Patient{
+					Id: &d4pb.Id{Value: "p1"},
+					Link: []*r4patientpb.Patient_Link{{
+						Other: &d4pb.Reference{Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p2"}}},
+					}},
+					GeneralPractitioner: []*d4pb.Reference{
+						{Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "dr1"}}},
+					},
+				}},
+			},
+			want: map[string][]string{
+				"Patient":      {"p1", "p2"},
+				"Practitioner": {"dr1"},
+			},
+		},
+		{
+			name: "no compartments",
+			msg:  &r4observationpb.Observation{},
+			want: map[string][]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Compartments(test.msg)
+			if err != nil {
+				t.Fatalf("Compartments() failed: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Compartments() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCompartments_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "empty contained resource",
+			msg:  &r4pb.ContainedResource{},
+		},
+		{
+			name: "data type",
+			msg:  &d4pb.HumanName{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := Compartments(test.msg); err == nil {
+				t.Errorf("Compartments() = %v, want error", got)
+			}
+		})
+	}
+}
+
+// TestDefinitions checks that every compartment element path names
+// references in the R4 protos.
+func TestDefinitions(t *testing.T) {
+	resources := map[string]protoreflect.MessageDescriptor{}
+	oneof := (&r4pb.ContainedResource{}).ProtoReflect().Descriptor().Oneofs().ByName("oneof_resource")
+	for i := 0; i < oneof.Fields().Len(); i++ {
+		d := oneof.Fields().Get(i).Message()
+		resources[string(d.Name())] = d
+	}
+	reference := (&d4pb.Reference{}).ProtoReflect().Descriptor().FullName()
+	for compartmentType, defs := range definitions {
+		if _, ok := resources[compartmentType]; !ok {
+			t.Errorf("compartment type %s is not a resource", compartmentType)
+		}
+		for resourceType, paths := range defs {
+			d, ok := resources[resourceType]
+			if !ok {
+				t.Errorf("%s compartment: %s is not a resource", compartmentType, resourceType)
+				continue
+			}
+			for _, path := range paths {
+				md := d
+				for _, name := range strings.Split(path, ".") {
+					f := md.Fields().ByJSONName(name)
+					if f == nil || f.Message() == nil {
+						t.Errorf("%s compartment: %s.%s does not exist", compartmentType, resourceType, path)
+						md = nil
+						break
+					}
+					md = f.Message()
+				}
+				if md != nil && md.FullName() != reference {
+					t.Errorf("%s compartment: %s.%s is a %s, want a Reference", compartmentType, resourceType, path, md.FullName())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compartment
+
+// definitions lists, per compartment type and then per resource type, the
+// element paths whose references link a resource into a compartment,
+// following the R4 CompartmentDefinitions
+// (http://hl7.org/fhir/R4/compartmentdefinition.html). Paths are sequences of
+// FHIR element names separated by dots. Within a resource type, the element
+// that identifies the compartment most directly comes first.
+var definitions = map[string]map[string][]string{
+	"Patient": {
+		"Account":                     {"subject"},
+		"AdverseEvent":                {"subject"},
+		"AllergyIntolerance":          {"patient", "recorder", "asserter"},
+		"Appointment":                 {"participant.actor"},
+		"AppointmentResponse":         {"actor"},
+		"Basic":                       {"subject", "author"},
+		"BodyStructure":               {"patient"},
+		"CarePlan":                    {"subject", "activity.detail.performer"},
+		"CareTeam":                    {"subject", "participant.member"},
+		"ChargeItem":                  {"subject"},
+		"Claim":                       {"patient", "payee.party"},
+		"ClaimResponse":               {"patient"},
+		"ClinicalImpression":          {"subject"},
+		"Communication":               {"subject", "sender", "recipient"},
+		"CommunicationRequest":        {"subject", "sender", "recipient", "requester"},
+		"Composition":                 {"subject", "author", "attester.party"},
+		"Condition":                   {"subject", "asserter"},
+		"Consent":                     {"patient"},
+		"Coverage":                    {"beneficiary", "subscriber", "policyHolder", "payor"},
+		"CoverageEligibilityRequest":  {"patient"},
+		"CoverageEligibilityResponse": {"patient"},
+		"DetectedIssue":               {"patient"},
+		"DeviceRequest":               {"subject", "performer"},
+		"DeviceUseStatement":          {"subject"},
+		"DiagnosticReport":            {"subject"},
+		"DocumentManifest":            {"subject", "author", "recipient"},
+		"DocumentReference":           {"subject", "author"},
+		"Encounter":                   {"subject"},
+		"EnrollmentRequest":           {"candidate"},
+		"EpisodeOfCare":               {"patient"},
+		"ExplanationOfBenefit":        {"patient", "payee.party"},
+		"FamilyMemberHistory":         {"patient"},
+		"Flag":                        {"subject"},
+		"Goal":                        {"subject"},
+		"Group":                       {"member.entity"},
+		"ImagingStudy":                {"subject"},
+		"Immunization":                {"patient"},
+		"ImmunizationEvaluation":      {"patient"},
+		"ImmunizationRecommendation":  {"patient"},
+		"Invoice":                     {"subject", "recipient"},
+		"List":                        {"subject", "source"},
+		"MeasureReport":               {"subject"},
+		"Media":                       {"subject"},
+		"MedicationAdministration":    {"subject", "performer.actor"},
+		"MedicationDispense":          {"subject", "receiver"},
+		"MedicationRequest":           {"subject"},
+		"MedicationStatement":         {"subject"},
+		"MolecularSequence":           {"patient"},
+		"NutritionOrder":              {"patient"},
+		"Observation":                 {"subject", "performer"},
+		"Patient":                     {"link.other"},
+		"Person":                      {"link.target"},
+		"Procedure":                   {"subject", "performer.actor"},
+		"QuestionnaireResponse":       {"subject", "author"},
+		"RelatedPerson":               {"patient"},
+		"RequestGroup":                {"subject", "action.participant"},
+		"ResearchSubject":             {"individual"},
+		"RiskAssessment":              {"subject"},
+		"Schedule":                    {"actor"},
+		"ServiceRequest":              {"subject", "performer"},
+		"Specimen":                    {"subject"},
+		"SupplyDelivery":              {"patient"},
+		"SupplyRequest":               {"requester"},
+		"VisionPrescription":          {"patient"},
+	},
+	"Encounter": {
+		"CarePlan":                 {"encounter"},
+		"CareTeam":                 {"encounter"},
+		"ChargeItem":               {"context"},
+		"Claim":                    {"item.encounter"},
+		"ClinicalImpression":       {"encounter"},
+		"Communication":            {"encounter"},
+		"CommunicationRequest":     {"encounter"},
+		"Composition":              {"encounter"},
+		"Condition":                {"encounter"},
+		"DeviceRequest":            {"encounter"},
+		"DiagnosticReport":         {"encounter"},
+		"DocumentManifest":         {"related.ref"},
+		"DocumentReference":        {"context.encounter"},
+		"ExplanationOfBenefit":     {"item.encounter"},
+		"Flag":                     {"encounter"},
+		"ImagingStudy":             {"encounter"},
+		"List":                     {"encounter"},
+		"Media":                    {"encounter"},
+		"MedicationAdministration": {"context"},
+		"MedicationRequest":        {"encounter"},
+		"NutritionOrder":           {"encounter"},
+		"Observation":              {"encounter"},
+		"Procedure":                {"encounter"},
+		"QuestionnaireResponse":    {"encounter"},
+		"RequestGroup":             {"encounter"},
+		"RiskAssessment":           {"encounter"},
+		"ServiceRequest":           {"encounter"},
+		"VisionPrescription":       {"encounter"},
+	},
+	"Practitioner": {
+		"Account":                  {"subject"},
+		"AdverseEvent":             {"recorder"},
+		"AllergyIntolerance":       {"recorder", "asserter"},
+		"Appointment":              {"participant.actor"},
+		"AppointmentResponse":      {"actor"},
+		"AuditEvent":               {"agent.who"},
+		"Basic":                    {"author"},
+		"CarePlan":                 {"activity.detail.performer"},
+		"CareTeam":                 {"participant.member"},
+		"ChargeItem":               {"enterer", "performer.actor"},
+		"Claim":                    {"enterer", "provider", "payee.party", "careTeam.provider"},
+		"ClaimResponse":            {"requestor"},
+		"Communication":            {"sender", "recipient"},
+		"CommunicationRequest":     {"sender", "recipient", "requester"},
+		"Composition":              {"subject", "author", "attester.party"},
+		"Condition":                {"asserter"},
+		"DetectedIssue":            {"author"},
+		"DeviceRequest":            {"requester", "performer"},
+		"DiagnosticReport":         {"performer"},
+		"DocumentManifest":         {"subject", "author", "recipient"},
+		"DocumentReference":        {"subject", "author", "authenticator"},
+		"Encounter":                {"participant.individual"},
+		"EpisodeOfCare":            {"careManager"},
+		"ExplanationOfBenefit":     {"enterer", "provider", "payee.party", "careTeam.provider"},
+		"Flag":                     {"author"},
+		"Group":                    {"member.entity"},
+		"Immunization":             {"performer.actor"},
+		"List":                     {"source"},
+		"MedicationAdministration": {"performer.actor"},
+		"MedicationDispense":       {"performer.actor", "receiver"},
+		"MedicationRequest":        {"requester"},
+		"MedicationStatement":      {"informationSource"},
+		"Observation":              {"performer"},
+		"Patient":                  {"generalPractitioner"},
+		"PractitionerRole":         {"practitioner"},
+		"Procedure":                {"performer.actor"},
+		"QuestionnaireResponse":    {"author", "source"},
+		"RequestGroup":             {"action.participant", "author"},
+		"ResearchStudy":            {"principalInvestigator"},
+		"RiskAssessment":           {"performer"},
+		"Schedule":                 {"actor"},
+		"ServiceRequest":           {"performer", "requester"},
+		"Specimen":                 {"collection.collector"},
+		"SupplyDelivery":           {"supplier", "receiver"},
+		"SupplyRequest":            {"requester"},
+		"VisionPrescription":       {"prescriber"},
+	},
+	"RelatedPerson": {
+		"AdverseEvent":             {"recorder"},
+		"AllergyIntolerance":       {"asserter"},
+		"Appointment":              {"participant.actor"},
+		"AppointmentResponse":      {"actor"},
+		"Basic":                    {"author"},
+		"CarePlan":                 {"activity.detail.performer"},
+		"CareTeam":                 {"participant.member"},
+		"ChargeItem":               {"enterer", "performer.actor"},
+		"Claim":                    {"payee.party"},
+		"Communication":            {"sender", "recipient"},
+		"CommunicationRequest":     {"sender", "recipient", "requester"},
+		"Composition":              {"author"},
+		"Condition":                {"asserter"},
+		"Coverage":                 {"policyHolder", "subscriber", "payor"},
+		"DocumentManifest":         {"author", "recipient"},
+		"DocumentReference":        {"author"},
+		"Encounter":                {"participant.individual"},
+		"ExplanationOfBenefit":     {"payee.party"},
+		"Invoice":                  {"recipient"},
+		"MedicationAdministration": {"performer.actor"},
+		"MedicationStatement":      {"informationSource"},
+		"Observation":              {"performer"},
+		"Patient":                  {"link.other"},
+		"Person":                   {"link.target"},
+		"Procedure":                {"performer.actor"},
+		"QuestionnaireResponse":    {"author", "source"},
+		"RequestGroup":             {"action.participant"},
+		"Schedule":                 {"actor"},
+		"ServiceRequest":           {"performer"},
+		"SupplyRequest":            {"requester"},
+	},
+	"Device": {
+		"Account":                  {"subject"},
+		"Appointment":              {"participant.actor"},
+		"AppointmentResponse":      {"actor"},
+		"AuditEvent":               {"agent.who"},
+		"ChargeItem":               {"enterer", "performer.actor"},
+		"Communication":            {"sender", "recipient"},
+		"CommunicationRequest":     {"sender", "recipient"},
+		"Composition":              {"author"},
+		"DetectedIssue":            {"author"},
+		"DeviceRequest":            {"subject", "performer"},
+		"DeviceUseStatement":       {"device"},
+		"DiagnosticReport":         {"subject"},
+		"DocumentManifest":         {"subject", "author"},
+		"DocumentReference":        {"subject", "author"},
+		"Flag":                     {"author"},
+		"Group":                    {"member.entity"},
+		"Invoice":                  {"participant.actor"},
+		"List":                     {"subject", "source"},
+		"Media":                    {"subject"},
+		"MedicationAdministration": {"device"},
+		"MessageHeader":            {"destination.target"},
+		"Observation":              {"subject", "device"},
+		"QuestionnaireResponse":    {"author"},
+		"RiskAssessment":           {"performer"},
+		"Schedule":                 {"actor"},
+		"ServiceRequest":           {"performer", "requester"},
+		"Specimen":                 {"subject"},
+	},
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conceptmap applies FHIR R4 ConceptMap resources to translate codes
+// between code systems.
+package conceptmap
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4conceptmappb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/concept_map_go_proto"
+)
+
+// A TranslatedCoding is a target of a ConceptMap translation.
+type TranslatedCoding struct {
+	// Coding is the target code, in the target system of its group.
+	Coding *d4pb.Coding
+	// Equivalence is how the target relates to the source code, e.g.
+	// EQUIVALENT or WIDER.
+	Equivalence c4pb.ConceptMapEquivalenceCode_Value
+}
+
+// Translate returns the targets that cm, an R4 ConceptMap or a
+// ContainedResource holding one, maps the given code in system to, in the
+// order the map lists them. Groups without a source system apply to codes
+// from any system. Targets marked unmatched are omitted, dependsOn and
+// product conditions are not evaluated and group.unmapped is not applied.
+// A code the map does not cover gives an empty result.
+func Translate(cm proto.Message, system, code string) ([]TranslatedCoding, error) {
+	m, err := asConceptMap(cm)
+	if err != nil {
+		return nil, err
+	}
+	var out []TranslatedCoding
+	for _, g := range m.GetGroup() {
+		if src := g.GetSource().GetValue(); src != "" && src != system {
+			continue
+		}
+		for _, e := range g.GetElement() {
+			if e.GetCode().GetValue() != code {
+				continue
+			}
+			for _, t := range e.GetTarget() {
+				eq := t.GetEquivalence().GetValue()
+				if eq == c4pb.ConceptMapEquivalenceCode_UNMATCHED || t.GetCode() == nil {
+					continue
+				}
+				c := &d4pb.Coding{Code: proto.Clone(t.GetCode()).(*d4pb.Code)}
+				if g.GetTarget() != nil {
+					c.System = proto.Clone(g.GetTarget()).(*d4pb.Uri)
+				}
+				if g.GetTargetVersion() != nil {
+					c.Version = proto.Clone(g.GetTargetVersion()).(*d4pb.String)
+				}
+				if t.GetDisplay() != nil {
+					c.Display = proto.Clone(t.GetDisplay()).(*d4pb.String)
+				}
+				out = append(out, TranslatedCoding{Coding: c, Equivalence: eq})
+			}
+		}
+	}
+	return out, nil
+}
+
+// asConceptMap returns msg as an R4 ConceptMap, unwrapping a
+// ContainedResource if necessary.
+func asConceptMap(msg proto.Message) (*r4conceptmappb.ConceptMap, error) {
+	switch cm := msg.(type) {
+	case *r4conceptmappb.ConceptMap:
+		return cm, nil
+	case *r4pb.ContainedResource:
+		if m := cm.GetConceptMap(); m != nil {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported message %T, want an R4 ConceptMap", msg)
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conceptmap
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4conceptmappb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/concept_map_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+const (
+	localSystem = "http://example.org/gender"
+	fhirGender  = "http://hl7.org/fhir/administrative-gender"
+)
+
+func target(code string, eq c4pb.ConceptMapEquivalenceCode_Value) *r4conceptmappb.ConceptMap_Group_SourceElement_TargetElement {
+	return &r4conceptmappb.ConceptMap_Group_SourceElement_TargetElement{
+		Code:        &d4pb.Code{Value: code},
+		Equivalence: &r4conceptmappb.ConceptMap_Group_SourceElement_TargetElement_EquivalenceCode{Value: eq},
+	}
+}
+
+func element(code string, targets ...*r4conceptmappb.ConceptMap_Group_SourceElement_TargetElement) *r4conceptmappb.ConceptMap_Group_SourceElement {
+	return &r4conceptmappb.ConceptMap_Group_SourceElement{Code: &d4pb.Code{Value: code}, Target: targets}
+}
+
+var genderMap = &r4conceptmappb.ConceptMap{
+	Group: []*r4conceptmappb.ConceptMap_Group{{
+		Source: &d4pb.Uri{Value: localSystem},
+		Target: &d4pb.Uri{Value: fhirGender},
+		Element: []*r4conceptmappb.ConceptMap_Group_SourceElement{
+			element("M", target("male", c4pb.ConceptMapEquivalenceCode_EQUIVALENT)),
+			element("F", target("female", c4pb.ConceptMapEquivalenceCode_EQUIVALENT)),
+			element("X", target("other", c4pb.ConceptMapEquivalenceCode_WIDER), target("unknown", c4pb.ConceptMapEquivalenceCode_INEXACT)),
+			element("?", target("", c4pb.ConceptMapEquivalenceCode_UNMATCHED)),
+		},
+	}},
+}
+
+func TestTranslate(t *testing.T) {
+	coding := func(code string) *d4pb.Coding {
+		return &d4pb.Coding{System: &d4pb.Uri{Value: fhirGender}, Code: &d4pb.Code{Value: code}}
+	}
+	tests := []struct {
+		name         string
+		system, code string
+		want         []TranslatedCoding
+	}{
+		{
+			name:   "one to one",
+			system: localSystem,
+			code:   "F",
+			want:   []TranslatedCoding{{Coding: coding("female"), Equivalence: c4pb.ConceptMapEquivalenceCode_EQUIVALENT}},
+		},
+		{
+			name:   "one to many",
+			system: localSystem,
+			code:   "X",
+			want: []TranslatedCoding{
+				{Coding: coding("other"), Equivalence: c4pb.ConceptMapEquivalenceCode_WIDER},
+				{Coding: coding("unknown"), Equivalence: c4pb.ConceptMapEquivalenceCode_INEXACT},
+			},
+		},
+		{
+			name:   "unmatched",
+			system: localSystem,
+			code:   "?",
+		},
+		{
+			name:   "unmapped code",
+			system: localSystem,
+			code:   "Z",
+		},
+		{
+			name:   "other system",
+			system: "http://example.org/other",
+			code:   "F",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Translate(genderMap, test.system, test.code)
+			if err != nil {
+				t.Fatalf("Translate() failed: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Translate(%q, %q) returned unexpected diff (-want +got):\n%s", test.system, test.code, diff)
+			}
+		})
+	}
+}
+
+func TestTranslate_ContainedResource(t *testing.T) {
+	cr := &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_ConceptMap{ConceptMap: genderMap}}
+	got, err := Translate(cr, localSystem, "M")
+	if err != nil {
+		t.Fatalf("Translate() failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Coding.GetCode().GetValue() != "male" {
+		t.Errorf("Translate() = %v, want a single male coding", got)
+	}
+}
+
+func TestTranslate_NotConceptMap(t *testing.T) {
+	if got, err := Translate(&r4patientpb.Patient{}, localSystem, "M"); err == nil {
+		t.Errorf("Translate() = %v, want error", got)
+	}
+}
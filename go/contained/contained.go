@@ -0,0 +1,102 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package contained provides helpers for working with the contained
+// resources of FHIR resources.
+package contained
+
+import (
+	"fmt"
+
+	"github.com/google/fhir/go/internal/element"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+// Resources returns the contained resources of msg, unpacking the Any or
+// ContainedResource wrappers they are stored in. msg may itself be wrapped
+// in a ContainedResource.
+func Resources(msg proto.Message) ([]proto.Message, error) {
+	rm, err := unwrap(msg.ProtoReflect())
+	if err != nil || rm == nil {
+		return nil, err
+	}
+	f := rm.Descriptor().Fields().ByName("contained")
+	if f == nil || !f.IsList() || f.Message() == nil {
+		return nil, nil
+	}
+	l := rm.Get(f).List()
+	out := make([]proto.Message, 0, l.Len())
+	for i := 0; i < l.Len(); i++ {
+		r, err := unwrap(l.Get(i).Message())
+		if err != nil {
+			return nil, fmt.Errorf("contained[%d]: %w", i, err)
+		}
+		if r != nil {
+			out = append(out, r.Interface())
+		}
+	}
+	return out, nil
+}
+
+// unwrap returns the resource held by an Any or ContainedResource, or m
+// itself for any other message. It returns nil for an empty
+// ContainedResource.
+func unwrap(m protoreflect.Message) (protoreflect.Message, error) {
+	if a, ok := m.Interface().(*anypb.Any); ok {
+		inner, err := a.UnmarshalNew()
+		if err != nil {
+			return nil, fmt.Errorf("unpacking %s: %w", a.GetTypeUrl(), err)
+		}
+		m = inner.ProtoReflect()
+	}
+	if oneof := m.Descriptor().Oneofs().ByName("oneof_resource"); oneof != nil {
+		f := m.WhichOneof(oneof)
+		if f == nil {
+			return nil, nil
+		}
+		return m.Get(f).Message(), nil
+	}
+	return m, nil
+}
+
+// fragmentReferences returns the ids of the local "#id" references made
+// anywhere within m, in the order they appear.
+func fragmentReferences(m protoreflect.Message) []string {
+	var ids []string
+	d := m.Descriptor()
+	if proto.HasExtension(d.Options(), apb.E_FhirReferenceType) {
+		if id, ok := element.FragmentID(m); ok {
+			ids = append(ids, id)
+		}
+	}
+	m.Range(func(f protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if f.Message() == nil {
+			return true
+		}
+		if f.IsList() {
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				ids = append(ids, fragmentReferences(l.Get(i).Message())...)
+			}
+			return true
+		}
+		ids = append(ids, fragmentReferences(v.Message())...)
+		return true
+	})
+	return ids
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contained
+
+import (
+	"github.com/google/fhir/go/internal/element"
+	"google.golang.org/protobuf/proto"
+)
+
+// DetectContainedCycles builds the graph of fragment references among the
+// contained resources of msg and returns the first cycle found as a path of
+// contained resource ids that starts and ends with the same id, e.g.
+// ["a", "b", "a"]. It returns nil if the references are acyclic. References
+// to the container itself ("#") and to unknown ids are ignored.
+func DetectContainedCycles(msg proto.Message) ([]string, error) {
+	resources, err := Resources(msg)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	edges := map[string][]string{}
+	for _, r := range resources {
+		rm := r.ProtoReflect()
+		id := element.PrimitiveString(rm, "id")
+		if id == "" {
+			continue
+		}
+		if _, ok := edges[id]; !ok {
+			ids = append(ids, id)
+		}
+		edges[id] = append(edges[id], fragmentReferences(rm)...)
+	}
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := map[string]int{}
+	var stack []string
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = inProgress
+		stack = append(stack, id)
+		for _, next := range edges[id] {
+			if _, ok := edges[next]; !ok {
+				continue
+			}
+			switch state[next] {
+			case inProgress:
+				for i, s := range stack {
+					if s == next {
+						return append(append([]string{}, stack[i:]...), next)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+		return nil
+	}
+	for _, id := range ids {
+		if state[id] == unvisited {
+			if cycle := visit(id); cycle != nil {
+				return cycle, nil
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contained
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4organizationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/organization_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+// organization returns a contained Organization that is partOf the
+// organization with id parent, given as a "#id" URI.
+func organization(t *testing.T, id, parent string) *anypb.Any {
+	t.Helper()
+	org := &r4organizationpb.Organization{Id: &d4pb.Id{Value: id}}
+	if parent != "" {
+		org.PartOf = &d4pb.Reference{
+			Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: "#" + parent}},
+		}
+	}
+	a, err := anypb.New(&r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Organization{Organization: org},
+	})
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	return a
+}
+
+func TestDetectContainedCycles(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+		want []string
+	}{
+		{
+			name: "two resource cycle",
+			msg: &r4patientpb.Patient{
+				Contained: []*anypb.Any{organization(t, "a", "b"), organization(t, "b", "a")},
+			},
+			want: []string{"a", "b", "a"},
+		},
+		{
+			name: "cycle after acyclic prefix",
+			msg: &r4patientpb.Patient{
+				Contained: []*anypb.Any{
+					organization(t, "x", "a"),
+					organization(t, "a", "b"),
+					organization(t, "b", "c"),
+					organization(t, "c", "a"),
+				},
+			},
+			want: []string{"a", "b", "c", "a"},
+		},
+		{
+			name: "self reference",
+			msg: &r4patientpb.Patient{
+				Contained: []*anypb.Any{organization(t, "a", "a")},
+			},
+			want: []string{"a", "a"},
+		},
+		{
+			name: "acyclic chain",
+			msg: &r4patientpb.Patient{
+				Contained: []*anypb.Any{organization(t, "a", "b"), organization(t, "b", "missing"), organization(t, "c", "")},
+			},
+		},
+		{
+			name: "contained resource wrapper",
+			msg: &r4pb.ContainedResource{
+				OneofResource: &r4pb.ContainedResource_Patient{
+					Patient: &r4patientpb.Patient{
+						Contained: []*anypb.Any{organization(t, "a", "b"), organization(t, "b", "a")},
+					},
+				},
+			},
+			want: []string{"a", "b", "a"},
+		},
+		{
+			name: "no contained resources",
+			msg:  &r4patientpb.Patient{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := DetectContainedCycles(test.msg)
+			if err != nil {
+				t.Fatalf("DetectContainedCycles() failed: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("DetectContainedCycles() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDetectContainedCycles_InvalidContained(t *testing.T) {
+	p := &r4patientpb.Patient{
+		Contained: []*anypb.Any{{TypeUrl: "type.googleapis.com/unknown.Type"}},
+	}
+	if _, err := DetectContainedCycles(p); err == nil {
+		t.Errorf("DetectContainedCycles() succeeded, want error")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contained
+
+import (
+	"fmt"
+
+	"github.com/google/fhir/go/fhirversion"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r5pb "github.com/google/fhir/go/proto/google/fhir/proto/r5/core/resources/bundle_and_contained_resource_go_proto"
+	r3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+// Pack wraps the resource r the way the contained field of the target FHIR
+// version stores it: in a ContainedResource for STU3, and in an Any holding a
+// ContainedResource for R4 and R5. r must be a resource of the target
+// version; Pack does not convert between versions.
+func Pack(r proto.Message, target fhirversion.Version) (proto.Message, error) {
+	var cr proto.Message
+	switch target {
+	case fhirversion.STU3:
+		cr = &r3pb.ContainedResource{}
+	case fhirversion.R4:
+		cr = &r4pb.ContainedResource{}
+	case fhirversion.R5:
+		cr = &r5pb.ContainedResource{}
+	default:
+		return nil, fmt.Errorf("unsupported FHIR version %v", target)
+	}
+	rcr := cr.ProtoReflect()
+	name := r.ProtoReflect().Descriptor().FullName()
+	fields := rcr.Descriptor().Oneofs().ByName("oneof_resource").Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if f := fields.Get(i); f.Message().FullName() == name {
+			rcr.Set(f, protoreflect.ValueOfMessage(r.ProtoReflect()))
+			if target == fhirversion.STU3 {
+				return cr, nil
+			}
+			return anypb.New(cr)
+		}
+	}
+	return nil, fmt.Errorf("%v is not a %v resource", name, target)
+}
+
+// Unpack returns the resource held by container, an Any or a
+// ContainedResource of any FHIR version, as produced by Pack.
+func Unpack(container proto.Message) (proto.Message, error) {
+	rm, err := unwrap(container.ProtoReflect())
+	if err != nil {
+		return nil, err
+	}
+	if rm == nil {
+		return nil, fmt.Errorf("empty %v", container.ProtoReflect().Descriptor().FullName())
+	}
+	if rm.Descriptor().FullName() == container.ProtoReflect().Descriptor().FullName() {
+		return nil, fmt.Errorf("%v is not a resource container", rm.Descriptor().FullName())
+	}
+	return rm.Interface(), nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contained
+
+import (
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	d5pb "github.com/google/fhir/go/proto/google/fhir/proto/r5/core/datatypes_go_proto"
+	r5patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r5/core/resources/patient_go_proto"
+	d3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	r3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestPackUnpack(t *testing.T) {
+	r5patient := &r5patientpb.Patient{Id: &d5pb.Id{Value: "p5"}}
+	packed, err := Pack(r5patient, fhirversion.R5)
+	if err != nil {
+		t.Fatalf("Pack(R5) failed: %v", err)
+	}
+	a, ok := packed.(*anypb.Any)
+	if !ok {
+		t.Fatalf("Pack(R5) = %T, want *anypb.Any", packed)
+	}
+	// The packed resource fits the contained field of an R5 resource.
+	container := &r5patientpb.Patient{Contained: []*anypb.Any{a}}
+	got, err := Unpack(container.GetContained()[0])
+	if err != nil {
+		t.Fatalf("Unpack() failed: %v", err)
+	}
+	if diff := cmp.Diff(r5patient, got, protocmp.Transform()); diff != "" {
+		t.Errorf("Unpack() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	r3patient := &r3pb.Patient{Id: &d3pb.Id{Value: "p3"}}
+	packed, err = Pack(r3patient, fhirversion.STU3)
+	if err != nil {
+		t.Fatalf("Pack(STU3) failed: %v", err)
+	}
+	if _, ok := packed.(*r3pb.ContainedResource); !ok {
+		t.Fatalf("Pack(STU3) = %T, want *ContainedResource", packed)
+	}
+	got, err = Unpack(packed)
+	if err != nil {
+		t.Fatalf("Unpack() failed: %v", err)
+	}
+	if diff := cmp.Diff(r3patient, got, protocmp.Transform()); diff != "" {
+		t.Errorf("Unpack() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestPack_Errors(t *testing.T) {
+	if _, err := Pack(&r4patientpb.Patient{}, fhirversion.R5); err == nil {
+		t.Errorf("Pack() of an R4 resource for R5 succeeded, want error")
+	}
+	if _, err := Pack(&r4patientpb.Patient{}, fhirversion.Version("DSTU2")); err == nil {
+		t.Errorf("Pack() for an unsupported version succeeded, want error")
+	}
+}
+
+func TestUnpack_Errors(t *testing.T) {
+	for _, msg := range []proto.Message{&r4pb.ContainedResource{}, &r4patientpb.Patient{}} {
+		if _, err := Unpack(msg); err == nil {
+			t.Errorf("Unpack(%T) succeeded, want error", msg)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package convert provides support for converting FHIR resources between
+// versions of the standard.
+package convert
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/internal/element"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r5pb "github.com/google/fhir/go/proto/google/fhir/proto/r5/core/resources/bundle_and_contained_resource_go_proto"
+	r3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+// containedResources holds the ContainedResource message of each supported
+// version, which lists the resource types of the version.
+var containedResources = map[fhirversion.Version]proto.Message{
+	fhirversion.STU3: &r3pb.ContainedResource{},
+	fhirversion.R4:   &r4pb.ContainedResource{},
+	fhirversion.R5:   &r5pb.ContainedResource{},
+}
+
+// A Report lists the elements of a resource that would be lost converting it
+// to another FHIR version.
+type Report struct {
+	Source, Target fhirversion.Version
+	// Unmapped holds the populated elements of the resource that have no
+	// equivalent in the target version.
+	Unmapped []UnmappedElement
+}
+
+// An UnmappedElement is a populated element without a target equivalent.
+type UnmappedElement struct {
+	// Path locates the element, e.g. "Encounter.hospitalization".
+	Path string
+	// Reason describes why the element cannot be converted.
+	Reason string
+}
+
+// ConversionReport inspects msg, a resource or a ContainedResource wrapping
+// one, and reports the populated elements that have no equivalent in the
+// target FHIR version, without converting it. Elements are matched by name,
+// and their types must agree: a primitive must map to the same primitive, a
+// code to a known code of the target value set, and a choice value to a type
+// the target choice allows. Contained resources are inspected too.
+func ConversionReport(msg proto.Message, target fhirversion.Version) (*Report, error) {
+	source, err := versionOf(msg.ProtoReflect().Descriptor())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := containedResources[target]; !ok {
+		return nil, fmt.Errorf("unsupported target version %v", target)
+	}
+	rm, err := unwrap(msg.ProtoReflect())
+	if err != nil {
+		return nil, err
+	}
+	if rm == nil {
+		return nil, fmt.Errorf("empty %v", msg.ProtoReflect().Descriptor().FullName())
+	}
+	r := &Report{Source: source, Target: target}
+	if err := r.checkResource(string(rm.Descriptor().Name()), rm); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Report) add(path, format string, args ...any) {
+	r.Unmapped = append(r.Unmapped, UnmappedElement{Path: path, Reason: fmt.Sprintf(format, args...)})
+}
+
+// checkResource checks the resource rm at path against the resource of the
+// same type in the target version.
+func (r *Report) checkResource(path string, rm protoreflect.Message) error {
+	name := rm.Descriptor().Name()
+	fields := containedResources[r.Target].ProtoReflect().Descriptor().Oneofs().Get(0).Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if td := fields.Get(i).Message(); td.Name() == name {
+			return r.checkMessage(path, rm, td)
+		}
+	}
+	r.add(path, "resource type %s does not exist in %v", name, r.Target)
+	return nil
+}
+
+// checkMessage checks the populated fields of m against the message type td.
+func (r *Report) checkMessage(path string, m protoreflect.Message, td protoreflect.MessageDescriptor) error {
+	var err error
+	m.Range(func(f protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		err = r.checkField(path+"."+f.JSONName(), f, v, td)
+		return err == nil
+	})
+	return err
+}
+
+// checkField checks the value v of field f against the field of the same name
+// in td.
+func (r *Report) checkField(path string, f protoreflect.FieldDescriptor, v protoreflect.Value, td protoreflect.MessageDescriptor) error {
+	tf := td.Fields().ByJSONName(f.JSONName())
+	if tf == nil {
+		r.add(path, "no %v equivalent", r.Target)
+		return nil
+	}
+	if f.Message() == nil || tf.Message() == nil {
+		// Plain proto fields, such as the value of a primitive, are checked
+		// with their primitive.
+		return nil
+	}
+	if !f.IsList() {
+		return r.checkValue(path, v.Message(), tf)
+	}
+	l := v.List()
+	if l.Len() > 1 && !tf.IsList() {
+		r.add(path, "%d values, but the %v element is singular", l.Len(), r.Target)
+	}
+	for i := 0; i < l.Len(); i++ {
+		if err := r.checkValue(fmt.Sprintf("%s[%d]", path, i), l.Get(i).Message(), tf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkValue checks the message value m against the type of the target field
+// tf.
+func (r *Report) checkValue(path string, m protoreflect.Message, tf protoreflect.FieldDescriptor) error {
+	d, td := m.Descriptor(), tf.Message()
+	switch {
+	case d.FullName() == "google.protobuf.Any":
+		cr, err := m.Interface().(*anypb.Any).UnmarshalNew()
+		if err != nil {
+			return fmt.Errorf("%s: unpacking contained resource: %w", path, err)
+		}
+		rm, err := unwrap(cr.ProtoReflect())
+		if err != nil || rm == nil {
+			return err
+		}
+		return r.checkResource(path, rm)
+	case element.IsChoice(d):
+		if !element.IsChoice(td) {
+			r.add(path, "choice element is not a choice in %v", r.Target)
+			return nil
+		}
+		active := m.WhichOneof(d.Oneofs().Get(0))
+		if active == nil {
+			return nil
+		}
+		choicePath := path + strings.ToUpper(active.JSONName()[:1]) + active.JSONName()[1:]
+		ttf := td.Fields().ByJSONName(active.JSONName())
+		if ttf == nil {
+			r.add(choicePath, "type %s is not allowed in %v", active.JSONName(), r.Target)
+			return nil
+		}
+		return r.checkValue(choicePath, m.Get(active).Message(), ttf)
+	case d.Name() == "ContainedResource":
+		rm, err := unwrap(m)
+		if err != nil || rm == nil {
+			return err
+		}
+		return r.checkResource(path, rm)
+	case element.IsPrimitive(d):
+		return r.checkPrimitive(path, m, td)
+	}
+	if element.IsPrimitive(td) || !sameComplexType(d, td) {
+		r.add(path, "type %s becomes %s in %v", d.Name(), td.Name(), r.Target)
+		return nil
+	}
+	return r.checkMessage(path, m, td)
+}
+
+// checkPrimitive checks the primitive m against the target type td.
+func (r *Report) checkPrimitive(path string, m protoreflect.Message, td protoreflect.MessageDescriptor) error {
+	d := m.Descriptor()
+	if !element.IsPrimitive(td) || primitiveType(d) != primitiveType(td) {
+		r.add(path, "type %s becomes %s in %v", primitiveType(d), typeName(td), r.Target)
+		return nil
+	}
+	vf, tvf := d.Fields().ByName("value"), td.Fields().ByName("value")
+	if vf != nil && tvf != nil && vf.Kind() == protoreflect.EnumKind && tvf.Kind() == protoreflect.EnumKind {
+		ev := vf.Enum().Values().ByNumber(m.Get(vf).Enum())
+		if ev != nil && ev.Number() != 0 && tvf.Enum().Values().ByName(ev.Name()) == nil {
+			r.add(path, "code %s is not in the %v value set", ev.Name(), r.Target)
+		}
+	}
+	// The id and extensions of the primitive are checked like other fields.
+	return r.checkMessage(path, m, td)
+}
+
+// versionOf returns the FHIR version of the proto message type d.
+func versionOf(d protoreflect.MessageDescriptor) (fhirversion.Version, error) {
+	switch pkg := d.ParentFile().Package(); {
+	case strings.HasPrefix(string(pkg), "google.fhir.stu3."):
+		return fhirversion.STU3, nil
+	case strings.HasPrefix(string(pkg), "google.fhir.r4."):
+		return fhirversion.R4, nil
+	case strings.HasPrefix(string(pkg), "google.fhir.r5."):
+		return fhirversion.R5, nil
+	}
+	return "", fmt.Errorf("%v is not a FHIR resource", d.FullName())
+}
+
+// unwrap returns the resource held by a ContainedResource, or m itself if it
+// is not one. It returns nil for an empty ContainedResource.
+func unwrap(m protoreflect.Message) (protoreflect.Message, error) {
+	d := m.Descriptor()
+	if d.Name() == "ContainedResource" {
+		active := m.WhichOneof(d.Oneofs().Get(0))
+		if active == nil {
+			return nil, nil
+		}
+		return m.Get(active).Message(), nil
+	}
+	if !element.IsResource(d) {
+		return nil, fmt.Errorf("%v is not a FHIR resource", d.FullName())
+	}
+	return m, nil
+}
+
+// sameComplexType reports whether values of the complex type d convert to
+// td. Data types must have the same name, treating the profiles of Quantity
+// as Quantity; element types nested in resources only need matching fields.
+func sameComplexType(d, td protoreflect.MessageDescriptor) bool {
+	if d.Parent() != d.ParentFile() || td.Parent() != td.ParentFile() {
+		return d.Parent() != d.ParentFile() && td.Parent() != td.ParentFile()
+	}
+	return baseType(d) == baseType(td)
+}
+
+func baseType(d protoreflect.MessageDescriptor) protoreflect.Name {
+	if element.QuantityTypes[d.Name()] {
+		return "Quantity"
+	}
+	return d.Name()
+}
+
+// primitiveType returns the FHIR primitive type of d, treating specialized
+// codes as code.
+func primitiveType(d protoreflect.MessageDescriptor) string {
+	if proto.HasExtension(d.Options(), apb.E_FhirValuesetUrl) {
+		return "Code"
+	}
+	return string(d.Name())
+}
+
+func typeName(d protoreflect.MessageDescriptor) string {
+	if element.IsPrimitive(d) {
+		return primitiveType(d)
+	}
+	return string(d.Name())
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4encounterpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/encounter_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func TestConversionReport(t *testing.T) {
+	encounter := &r4encounterpb.Encounter{
+		Id:     &d4pb.Id{Value: "e1"},
+		Status: &r4encounterpb.Encounter_StatusCode{Value: c4pb.EncounterStatusCode_ARRIVED},
+		ClassValue: &d4pb.Coding{
+			System: &d4pb.Uri{Value: "http://terminology.hl7.org/CodeSystem/v3-ActCode"},
+			Code:   &d4pb.Code{Value: "AMB"},
+		},
+		ClassHistory: []*r4encounterpb.Encounter_ClassHistory{{
+			ClassValue: &d4pb.Coding{Code: &d4pb.Code{Value: "EMER"}},
+		}},
+		Subject: &d4pb.Reference{
+			Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}},
+		},
+		Hospitalization: &r4encounterpb.Encounter_Hospitalization{
+			ReAdmission: &d4pb.CodeableConcept{Text: &d4pb.String{Value: "R"}},
+		},
+	}
+	got, err := ConversionReport(encounter, fhirversion.R5)
+	if err != nil {
+		t.Fatalf("ConversionReport() failed: %v", err)
+	}
+	if got.Source != fhirversion.R4 || got.Target != fhirversion.R5 {
+		t.Errorf("ConversionReport() versions = %v -> %v, want R4 -> R5", got.Source, got.Target)
+	}
+	wantPaths := []string{
+		"Encounter.status",
+		"Encounter.class",
+		"Encounter.classHistory",
+		"Encounter.hospitalization",
+	}
+	var gotPaths []string
+	for _, u := range got.Unmapped {
+		gotPaths = append(gotPaths, u.Path)
+	}
+	if diff := cmp.Diff(wantPaths, gotPaths, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("ConversionReport() unmapped paths returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestConversionReport_NothingLost(t *testing.T) {
+	patient := &r4patientpb.Patient{
+		Id:        &d4pb.Id{Value: "p1"},
+		Active:    &d4pb.Boolean{Value: true},
+		Name:      []*d4pb.HumanName{{Family: &d4pb.String{Value: "Doe"}}},
+		BirthDate: &d4pb.Date{ValueUs: 0, Timezone: "UTC", Precision: d4pb.Date_DAY},
+	}
+	for _, msg := range []proto.Message{
+		patient,
+		&r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: patient}},
+	} {
+		got, err := ConversionReport(msg, fhirversion.R5)
+		if err != nil {
+			t.Fatalf("ConversionReport() failed: %v", err)
+		}
+		if len(got.Unmapped) != 0 {
+			t.Errorf("ConversionReport() = %+v, want nothing unmapped", got.Unmapped)
+		}
+	}
+}
+
+func TestConversionReport_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    proto.Message
+		target fhirversion.Version
+	}{
+		{
+			name:   "not a resource",
+			msg:    &d4pb.String{Value: "x"},
+			target: fhirversion.R5,
+		},
+		{
+			name:   "empty contained resource",
+			msg:    &r4pb.ContainedResource{},
+			target: fhirversion.R5,
+		},
+		{
+			name:   "unsupported target",
+			msg:    &r4patientpb.Patient{},
+			target: fhirversion.Version("DSTU2"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ConversionReport(test.msg, test.target); err == nil {
+				t.Errorf("ConversionReport() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package deid provides helpers for de-identifying FHIR resources, such as
+// when generating test data from real records.
+package deid
+
+import (
+	"errors"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MaskIdentifiers replaces the value of every Identifier in msg with the
+// result of masker, which is given the identifier's system and value. The
+// system is kept as is. Identifiers anywhere in the resource are masked,
+// including those of references and of contained resources. Identifiers
+// without a value are left untouched.
+func MaskIdentifiers(msg proto.Message, masker func(system, value string) string) error {
+	if masker == nil {
+		return errors.New("nil masker")
+	}
+	return walk.Walk(msg, func(_ string, m protoreflect.Message) error {
+		if m.Descriptor().Name() != "Identifier" {
+			return nil
+		}
+		fields := m.Descriptor().Fields()
+		valueField := fields.ByName("value")
+		if valueField == nil || !m.Has(valueField) {
+			return nil
+		}
+		value := m.Mutable(valueField).Message()
+		v := value.Descriptor().Fields().ByName("value")
+		value.Set(v, protoreflect.ValueOfString(masker(element.PrimitiveString(m, "system"), value.Get(v).String())))
+		return nil
+	})
+}
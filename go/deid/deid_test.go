@@ -0,0 +1,81 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+const mrnSystem = "http://hospital.example.org/mrn"
+
+func identifier(system, value string) *d4pb.Identifier {
+	return &d4pb.Identifier{
+		System: &d4pb.Uri{Value: system},
+		Value:  &d4pb.String{Value: value},
+	}
+}
+
+// maskDigits replaces every digit with 9, keeping the value's shape.
+func maskDigits(_, value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return '9'
+		}
+		return r
+	}, value)
+}
+
+func TestMaskIdentifiers(t *testing.T) {
+	patient := &r4patientpb.Patient{
+		Identifier: []*d4pb.Identifier{identifier(mrnSystem, "MRN-123456")},
+		GeneralPractitioner: []*d4pb.Reference{{
+			Identifier: identifier("http://hl7.org/fhir/sid/us-npi", "1234567893"),
+		}},
+	}
+	var systems []string
+	err := MaskIdentifiers(patient, func(system, value string) string {
+		systems = append(systems, system)
+		return maskDigits(system, value)
+	})
+	if err != nil {
+		t.Fatalf("MaskIdentifiers() failed: %v", err)
+	}
+	want := &r4patientpb.Patient{
+		Identifier: []*d4pb.Identifier{identifier(mrnSystem, "MRN-999999")},
+		GeneralPractitioner: []*d4pb.Reference{{
+			Identifier: identifier("http://hl7.org/fhir/sid/us-npi", "9999999999"),
+		}},
+	}
+	if diff := cmp.Diff(want, patient, protocmp.Transform()); diff != "" {
+		t.Errorf("MaskIdentifiers() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	wantSystems := []string{mrnSystem, "http://hl7.org/fhir/sid/us-npi"}
+	if diff := cmp.Diff(wantSystems, systems); diff != "" {
+		t.Errorf("MaskIdentifiers() passed unexpected systems (-want +got):\n%s", diff)
+	}
+}
+
+func TestMaskIdentifiers_NilMasker(t *testing.T) {
+	if err := MaskIdentifiers(&r4patientpb.Patient{}, nil); err == nil {
+		t.Errorf("MaskIdentifiers() with nil masker succeeded, want error")
+	}
+}
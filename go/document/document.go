@@ -0,0 +1,70 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package document builds FHIR R4 DocumentReference resources describing
+// clinical documents.
+package document
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4binarypb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/binary_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4documentreferencepb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/document_reference_go_proto"
+)
+
+// ForBinary returns a current R4 DocumentReference for the document held by
+// binary, an R4 Binary or a ContainedResource holding one. Its single content
+// attachment refers to the Binary as "Binary/id" and has the Binary's
+// contentType. typeCode and subject become the type and subject of the
+// DocumentReference and may be nil; they are not copied. It is an error for
+// binary to have no id or no contentType.
+func ForBinary(binary proto.Message, typeCode *d4pb.CodeableConcept, subject *d4pb.Reference) (proto.Message, error) {
+	var b *r4binarypb.Binary
+	switch m := binary.(type) {
+	case *r4binarypb.Binary:
+		b = m
+	case *r4pb.ContainedResource:
+		b = m.GetBinary()
+	}
+	if b == nil {
+		return nil, fmt.Errorf("unsupported message %T, want an R4 Binary", binary)
+	}
+	id := b.GetId().GetValue()
+	if id == "" {
+		return nil, errors.New("binary has no id")
+	}
+	contentType := b.GetContentType().GetValue()
+	if contentType == "" {
+		return nil, errors.New("binary has no contentType")
+	}
+	return &r4documentreferencepb.DocumentReference{
+		Status: &r4documentreferencepb.DocumentReference_StatusCode{
+			Value: c4pb.DocumentReferenceStatusCode_CURRENT,
+		},
+		Type:    typeCode,
+		Subject: subject,
+		Content: []*r4documentreferencepb.DocumentReference_Content{{
+			Attachment: &d4pb.Attachment{
+				ContentType: &d4pb.Attachment_ContentTypeCode{Value: contentType},
+				Url:         &d4pb.Url{Value: "Binary/" + id},
+			},
+		}},
+	}, nil
+}
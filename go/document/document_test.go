@@ -0,0 +1,120 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package document
+
+import (
+	"testing"
+
+	"github.com/google/fhir/go/jsonformat/fhirvalidate"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4binarypb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/binary_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4documentreferencepb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/document_reference_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func pdfBinary() *r4binarypb.Binary {
+	return &r4binarypb.Binary{
+		Id:          &d4pb.Id{Value: "b1"},
+		ContentType: &r4binarypb.Binary_ContentTypeCode{Value: "application/pdf"},
+		Data:        &d4pb.Base64Binary{Value: []byte("%PDF-1.4")},
+	}
+}
+
+func TestForBinary(t *testing.T) {
+	typeCode := &d4pb.CodeableConcept{Coding: []*d4pb.Coding{{
+		System: &d4pb.Uri{Value: "http://loinc.org"},
+		Code:   &d4pb.Code{Value: "34133-9"},
+	}}}
+	subject := &d4pb.Reference{Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}}}
+	want := &r4documentreferencepb.DocumentReference{
+		Status: &r4documentreferencepb.DocumentReference_StatusCode{
+			Value: c4pb.DocumentReferenceStatusCode_CURRENT,
+		},
+		Type:    typeCode,
+		Subject: subject,
+		Content: []*r4documentreferencepb.DocumentReference_Content{{
+			Attachment: &d4pb.Attachment{
+				ContentType: &d4pb.Attachment_ContentTypeCode{Value: "application/pdf"},
+				Url:         &d4pb.Url{Value: "Binary/b1"},
+			},
+		}},
+	}
+	for _, binary := range []proto.Message{
+		pdfBinary(),
+		&r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Binary{Binary: pdfBinary()}},
+	} {
+		got, err := ForBinary(binary, typeCode, subject)
+		if err != nil {
+			t.Fatalf("ForBinary(%T) failed: %v", binary, err)
+		}
+		if err := fhirvalidate.Validate(got); err != nil {
+			t.Errorf("Validate() of the DocumentReference failed: %v", err)
+		}
+		if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+			t.Errorf("ForBinary(%T) returned unexpected diff (-want +got):\n%s", binary, diff)
+		}
+	}
+}
+
+func TestForBinary_NoTypeOrSubject(t *testing.T) {
+	got, err := ForBinary(pdfBinary(), nil, nil)
+	if err != nil {
+		t.Fatalf("ForBinary() failed: %v", err)
+	}
+	if err := fhirvalidate.Validate(got); err != nil {
+		t.Errorf("Validate() of the DocumentReference failed: %v", err)
+	}
+}
+
+func TestForBinary_Errors(t *testing.T) {
+	noID := pdfBinary()
+	noID.Id = nil
+	noContentType := pdfBinary()
+	noContentType.ContentType = nil
+	tests := []struct {
+		name   string
+		binary proto.Message
+	}{
+		{
+			name:   "no id",
+			binary: noID,
+		},
+		{
+			name:   "no content type",
+			binary: noContentType,
+		},
+		{
+			name:   "not a binary",
+			binary: &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}},
+		},
+		{
+			name:   "empty contained resource",
+			binary: &r4pb.ContainedResource{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ForBinary(test.binary, nil, nil); err == nil {
+				t.Errorf("ForBinary() succeeded, want error")
+			}
+		})
+	}
+}
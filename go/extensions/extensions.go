@@ -0,0 +1,135 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package extensions provides helpers for locating and copying FHIR
+// extensions within resources.
+package extensions
+
+import (
+	"fmt"
+
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// An ExtensionLocation is an extension found within a resource.
+type ExtensionLocation struct {
+	// Path is the FHIR element path of the extension, e.g.
+	// "Patient.extension[0]" or "Patient.birthDate.extension[1]".
+	Path string
+	// URL is the extension's url.
+	URL string
+	// Extension is the extension element. Extensions of contained resources
+	// are copies, so modifying them does not affect msg.
+	Extension proto.Message
+}
+
+// FindExtensionsByURL returns the extensions and modifier extensions anywhere
+// in msg, including on primitives, nested within other extensions and in
+// contained resources, whose url is in urls. They are returned in proto field
+// order, with an extension before those nested within it.
+func FindExtensionsByURL(msg proto.Message, urls map[string]bool) []ExtensionLocation {
+	var locs []ExtensionLocation
+	walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		if m.Descriptor().Name() != "Extension" {
+			return nil
+		}
+		if url := extensionURL(m); urls[url] {
+			locs = append(locs, ExtensionLocation{Path: path, URL: url, Extension: m.Interface()})
+		}
+		return nil
+	})
+	return locs
+}
+
+// extensionURL returns the url of the Extension m.
+func extensionURL(m protoreflect.Message) string {
+	f := m.Descriptor().Fields().ByName("url")
+	if f == nil || f.Message() == nil || !m.Has(f) {
+		return ""
+	}
+	um := m.Get(f).Message()
+	return um.Get(um.Descriptor().Fields().ByName("value")).String()
+}
+
+// CopyExtensions copies the top-level extensions and modifier extensions of
+// src whose url is in urls to dst, which may be a resource of another type or
+// a ContainedResource wrapping one. Copies are appended after the existing
+// extensions of dst, except that extensions of dst with the url of a copied
+// extension are removed first, so copying replaces them. Extensions with the
+// listed urls that src lacks are left on dst.
+func CopyExtensions(dst, src proto.Message, urls []string) error {
+	dm, err := unwrapResource(dst.ProtoReflect())
+	if err != nil {
+		return err
+	}
+	sm, err := unwrapResource(src.ProtoReflect())
+	if err != nil {
+		return err
+	}
+	wanted := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		wanted[url] = true
+	}
+	for _, name := range []protoreflect.Name{"extension", "modifier_extension"} {
+		df, sf := dm.Descriptor().Fields().ByName(name), sm.Descriptor().Fields().ByName(name)
+		if df == nil || sf == nil {
+			return fmt.Errorf("%v or %v has no %s", dm.Descriptor().FullName(), sm.Descriptor().FullName(), name)
+		}
+		if df.Message().FullName() != sf.Message().FullName() {
+			return fmt.Errorf("cannot copy %v to %v", sf.Message().FullName(), df.Message().FullName())
+		}
+		var copies []protoreflect.Value
+		replaced := map[string]bool{}
+		sl := sm.Get(sf).List()
+		for i := 0; i < sl.Len(); i++ {
+			e := sl.Get(i).Message()
+			if url := extensionURL(e); wanted[url] {
+				copies = append(copies, protoreflect.ValueOfMessage(proto.Clone(e.Interface()).ProtoReflect()))
+				replaced[url] = true
+			}
+		}
+		if len(copies) == 0 {
+			continue
+		}
+		dl := dm.Mutable(df).List()
+		kept := 0
+		for i := 0; i < dl.Len(); i++ {
+			if e := dl.Get(i); !replaced[extensionURL(e.Message())] {
+				dl.Set(kept, e)
+				kept++
+			}
+		}
+		dl.Truncate(kept)
+		for _, c := range copies {
+			dl.Append(c)
+		}
+	}
+	return nil
+}
+
+// unwrapResource returns the resource held by a ContainedResource, or m
+// itself if it is not one.
+func unwrapResource(m protoreflect.Message) (protoreflect.Message, error) {
+	oneof := m.Descriptor().Oneofs().ByName("oneof_resource")
+	if oneof == nil {
+		return m, nil
+	}
+	f := m.WhichOneof(oneof)
+	if f == nil {
+		return nil, fmt.Errorf("empty %v", m.Descriptor().FullName())
+	}
+	return m.Mutable(f).Message(), nil
+}
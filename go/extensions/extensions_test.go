@@ -0,0 +1,146 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extensions
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	raceURL      = "http://hl7.org/fhir/us/core/StructureDefinition/us-core-race"
+	ethnicityURL = "http://hl7.org/fhir/us/core/StructureDefinition/us-core-ethnicity"
+	otherURL     = "http://example.org/fhir/StructureDefinition/favourite-colour"
+)
+
+func extension(url string, text string) *d4pb.Extension {
+	return &d4pb.Extension{
+		Url: &d4pb.Uri{Value: url},
+		Extension: []*d4pb.Extension{{
+			Url: &d4pb.Uri{Value: "text"},
+			Value: &d4pb.Extension_ValueX{
+				Choice: &d4pb.Extension_ValueX_StringValue{StringValue: &d4pb.String{Value: text}},
+			},
+		}},
+	}
+}
+
+func TestFindExtensionsByURL(t *testing.T) {
+	race := extension(raceURL, "White")
+	ethnicity := extension(ethnicityURL, "Not Hispanic or Latino")
+	containedRace := extension(raceURL, "Asian")
+	contained, err := anypb.New(&r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Patient{Patient: &r4patientpb.Patient{
+			Extension: []*d4pb.Extension{containedRace},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	patient := &r4patientpb.Patient{
+		Extension: []*d4pb.Extension{extension(otherURL, "blue"), race},
+		BirthDate: &d4pb.Date{
+			Extension: []*d4pb.Extension{ethnicity},
+		},
+		Contained: []*anypb.Any{contained},
+	}
+	urls := map[string]bool{raceURL: true, ethnicityURL: true}
+
+	got := FindExtensionsByURL(patient, urls)
+	want := []ExtensionLocation{
+		{Path: "Patient.contained[0].extension[0]", URL: raceURL, Extension: containedRace},
+		{Path: "Patient.extension[1]", URL: raceURL, Extension: race},
+		{Path: "Patient.birthDate.extension[0]", URL: ethnicityURL, Extension: ethnicity},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("FindExtensionsByURL() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestFindExtensionsByURL_NoMatches(t *testing.T) {
+	patient := &r4patientpb.Patient{Extension: []*d4pb.Extension{extension(otherURL, "blue")}}
+	if got := FindExtensionsByURL(patient, map[string]bool{raceURL: true}); len(got) != 0 {
+		t.Errorf("FindExtensionsByURL() = %v, want no matches", got)
+	}
+}
+
+func TestCopyExtensions(t *testing.T) {
+	src := &r4patientpb.Patient{
+		Extension: []*d4pb.Extension{extension(otherURL, "blue"), extension(raceURL, "White")},
+	}
+	dst := &r4patientpb.Patient{
+		Extension: []*d4pb.Extension{extension(raceURL, "Asian"), extension(ethnicityURL, "Hispanic or Latino")},
+	}
+	if err := CopyExtensions(dst, src, []string{raceURL}); err != nil {
+		t.Fatalf("CopyExtensions() failed: %v", err)
+	}
+	want := &r4patientpb.Patient{
+		Extension: []*d4pb.Extension{extension(ethnicityURL, "Hispanic or Latino"), extension(raceURL, "White")},
+	}
+	if diff := cmp.Diff(want, dst, protocmp.Transform()); diff != "" {
+		t.Errorf("CopyExtensions() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	// The copies are independent of src.
+	src.Extension[1].GetExtension()[0].GetValue().GetStringValue().Value = "changed"
+	if got := dst.Extension[1].GetExtension()[0].GetValue().GetStringValue().GetValue(); got != "White" {
+		t.Errorf("copied extension value = %q after changing src, want White", got)
+	}
+}
+
+func TestCopyExtensions_ContainedResource(t *testing.T) {
+	src := &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: &r4patientpb.Patient{
+		ModifierExtension: []*d4pb.Extension{extension(otherURL, "blue")},
+	}}}
+	dst := &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: &r4patientpb.Patient{
+		Extension: []*d4pb.Extension{extension(otherURL, "red")},
+	}}}
+	if err := CopyExtensions(dst, src, []string{otherURL, raceURL}); err != nil {
+		t.Fatalf("CopyExtensions() failed: %v", err)
+	}
+	want := &r4patientpb.Patient{
+		Extension:         []*d4pb.Extension{extension(otherURL, "red")},
+		ModifierExtension: []*d4pb.Extension{extension(otherURL, "blue")},
+	}
+	if diff := cmp.Diff(want, dst.GetPatient(), protocmp.Transform()); diff != "" {
+		t.Errorf("CopyExtensions() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCopyExtensions_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		dst, src proto.Message
+	}{
+		{"empty destination", &r4pb.ContainedResource{}, &r4patientpb.Patient{}},
+		{"empty source", &r4patientpb.Patient{}, &r4pb.ContainedResource{}},
+		{"not a resource", &r4patientpb.Patient{}, &d4pb.String{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CopyExtensions(test.dst, test.src, []string{raceURL}); err == nil {
+				t.Errorf("CopyExtensions() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"fmt"
+	"math/big"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Precedences of the arithmetic operators. Both bind more tightly than the
+// type operators, and multiplication more tightly than addition.
+const (
+	additivePrecedence       = 9
+	multiplicativePrecedence = 10
+)
+
+func init() {
+	for op, fn := range map[string]func(left, right Collection) (Collection, error){
+		"+": evalAdd,
+		"-": evalSubtract,
+		"&": evalConcatenate,
+	} {
+		binaryOperators[op] = binaryOperator{precedence: additivePrecedence, fn: fn}
+	}
+	for op, fn := range map[string]func(left, right Collection) (Collection, error){
+		"*":   evalMultiply,
+		"/":   evalDivide,
+		"div": evalDiv,
+		"mod": evalMod,
+	} {
+		binaryOperators[op] = binaryOperator{precedence: multiplicativePrecedence, fn: fn}
+	}
+}
+
+func evalAdd(left, right Collection) (Collection, error) {
+	l, r, ok, err := arithmeticOperands(left, right)
+	if !ok {
+		return nil, err
+	}
+	if ls, ok := l.(string); ok {
+		if rs, ok := r.(string); ok {
+			return Collection{ls + rs}, nil
+		}
+	}
+	return arithmetic("add", l, r, (*big.Int).Add, (*big.Rat).Add)
+}
+
+func evalSubtract(left, right Collection) (Collection, error) {
+	l, r, ok, err := arithmeticOperands(left, right)
+	if !ok {
+		return nil, err
+	}
+	return arithmetic("subtract", l, r, (*big.Int).Sub, (*big.Rat).Sub)
+}
+
+func evalMultiply(left, right Collection) (Collection, error) {
+	l, r, ok, err := arithmeticOperands(left, right)
+	if !ok {
+		return nil, err
+	}
+	return arithmetic("multiply", l, r, (*big.Int).Mul, (*big.Rat).Mul)
+}
+
+// evalDivide implements "/", which always produces a decimal. Division by
+// zero produces an empty result.
+func evalDivide(left, right Collection) (Collection, error) {
+	l, r, ok, err := arithmeticOperands(left, right)
+	if !ok {
+		return nil, err
+	}
+	lr, rr, ok := numericPair(l, r)
+	if !ok {
+		return nil, mismatch("divide", l, r)
+	}
+	if rr.Sign() == 0 {
+		return nil, nil
+	}
+	return Collection{new(big.Rat).Quo(lr, rr)}, nil
+}
+
+// evalDiv implements truncated division, which always produces an integer.
+// Division by zero produces an empty result.
+func evalDiv(left, right Collection) (Collection, error) {
+	l, r, ok, err := arithmeticOperands(left, right)
+	if !ok {
+		return nil, err
+	}
+	lr, rr, ok := numericPair(l, r)
+	if !ok {
+		return nil, mismatch("divide", l, r)
+	}
+	if rr.Sign() == 0 {
+		return nil, nil
+	}
+	q := truncate(new(big.Rat).Quo(lr, rr))
+	if !q.IsInt64() {
+		return nil, fmt.Errorf("integer overflow")
+	}
+	return Collection{q.Int64()}, nil
+}
+
+// evalMod implements the remainder of truncated division, which has the sign
+// of the dividend. Division by zero produces an empty result.
+func evalMod(left, right Collection) (Collection, error) {
+	l, r, ok, err := arithmeticOperands(left, right)
+	if !ok {
+		return nil, err
+	}
+	lr, rr, ok := numericPair(l, r)
+	if !ok {
+		return nil, mismatch("divide", l, r)
+	}
+	if rr.Sign() == 0 {
+		return nil, nil
+	}
+	q := new(big.Rat).SetInt(truncate(new(big.Rat).Quo(lr, rr)))
+	rem := new(big.Rat).Sub(lr, q.Mul(q, rr))
+	if _, ok := l.(int64); ok {
+		if _, ok := r.(int64); ok {
+			return Collection{rem.Num().Int64()}, nil
+		}
+	}
+	return Collection{rem}, nil
+}
+
+// evalConcatenate implements "&", which concatenates strings treating empty
+// operands as the empty string.
+func evalConcatenate(left, right Collection) (Collection, error) {
+	l, err := concatenationOperand(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := concatenationOperand(right)
+	if err != nil {
+		return nil, err
+	}
+	return Collection{l + r}, nil
+}
+
+func concatenationOperand(c Collection) (string, error) {
+	switch len(c) {
+	case 0:
+		return "", nil
+	case 1:
+		v, err := toSystem(c[0])
+		if err != nil {
+			return "", err
+		}
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("cannot concatenate %s, expected a String", systemTypeName(v))
+		}
+		return s, nil
+	default:
+		return "", fmt.Errorf("expected a single value, got %d", len(c))
+	}
+}
+
+// arithmeticOperands returns the System values of the singleton operands of
+// an arithmetic operator. The third return value is false when the result is
+// empty, either because an operand is empty or because of an error.
+func arithmeticOperands(left, right Collection) (any, any, bool, error) {
+	if len(left) == 0 || len(right) == 0 {
+		return nil, nil, false, nil
+	}
+	if len(left) > 1 || len(right) > 1 {
+		return nil, nil, false, fmt.Errorf("expected single operands, got %d and %d items", len(left), len(right))
+	}
+	l, err := toSystem(left[0])
+	if err != nil {
+		return nil, nil, false, err
+	}
+	r, err := toSystem(right[0])
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return l, r, true, nil
+}
+
+// arithmetic applies intOp when both operands are integers and ratOp when
+// both are numeric and at least one is a decimal.
+func arithmetic(verb string, l, r any, intOp func(z, x, y *big.Int) *big.Int, ratOp func(z, x, y *big.Rat) *big.Rat) (Collection, error) {
+	li, lok := l.(int64)
+	ri, rok := r.(int64)
+	if lok && rok {
+		z := intOp(new(big.Int), big.NewInt(li), big.NewInt(ri))
+		if !z.IsInt64() {
+			return nil, fmt.Errorf("integer overflow")
+		}
+		return Collection{z.Int64()}, nil
+	}
+	lr, rr, ok := numericPair(l, r)
+	if !ok {
+		return nil, mismatch(verb, l, r)
+	}
+	return Collection{ratOp(new(big.Rat), lr, rr)}, nil
+}
+
+func mismatch(verb string, l, r any) error {
+	return fmt.Errorf("cannot %s %s and %s", verb, systemTypeName(l), systemTypeName(r))
+}
+
+// truncate returns the integer part of r, rounding towards zero.
+func truncate(r *big.Rat) *big.Int {
+	return new(big.Int).Quo(r.Num(), r.Denom())
+}
+
+// systemTypeName returns the type name of a System value or FHIR element
+// for use in error messages.
+func systemTypeName(v any) string {
+	switch x := v.(type) {
+	case bool:
+		return "Boolean"
+	case int64:
+		return "Integer"
+	case *big.Rat:
+		return "Decimal"
+	case string:
+		return "String"
+	case dateTimeValue:
+		return "DateTime"
+	case proto.Message:
+		return fhirTypeName(x.ProtoReflect().Descriptor())
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+)
+
+func TestEvaluate_Arithmetic(t *testing.T) {
+	obs := observation("1", c4pb.ObservationStatusCode_FINAL)
+	obs.Value = &r4observationpb.Observation_ValueX{
+		Choice: &r4observationpb.Observation_ValueX_Quantity{Quantity: &d4pb.Quantity{
+			Value: &d4pb.Decimal{Value: "72.5"},
+			Unit:  &d4pb.String{Value: "kg"},
+		}},
+	}
+	tests := []struct {
+		expr string
+		want Collection
+	}{
+		{
+			expr: "1 + 2",
+			want: Collection{int64(3)},
+		},
+		{
+			expr: "1 + 2.5",
+			want: Collection{big.NewRat(7, 2)},
+		},
+		{
+			expr: "Observation.value.value + Observation.code.coding.count()",
+			want: Collection{big.NewRat(149, 2)},
+		},
+		{
+			expr: "2 + 3 * 4 - 1",
+			want: Collection{int64(13)},
+		},
+		{
+			expr: "(2 + 3) * 4",
+			want: Collection{int64(20)},
+		},
+		{
+			expr: "7 / 2",
+			want: Collection{big.NewRat(7, 2)},
+		},
+		{
+			expr: "7 div 2",
+			want: Collection{int64(3)},
+		},
+		{
+			expr: "(0 - 7) mod 2",
+			want: Collection{int64(-1)},
+		},
+		{
+			expr: "7 mod 2",
+			want: Collection{int64(1)},
+		},
+		{
+			expr: "5.5 mod 0.7",
+			want: Collection{big.NewRat(3, 5)},
+		},
+		{
+			expr: "1 / 0",
+		},
+		{
+			expr: "Observation.note.text + 1",
+		},
+		{
+			expr: "1 + 2 = 3",
+			want: Collection{true},
+		},
+		{
+			expr: "'abc' + 'def'",
+			want: Collection{"abcdef"},
+		},
+		{
+			expr: "Observation.status & '-' & Observation.note.text",
+			want: Collection{"final-"},
+		},
+	}
+	ratEqual := cmp.Comparer(func(a, b *big.Rat) bool { return a.Cmp(b) == 0 })
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			got, err := Evaluate(obs, test.expr)
+			if err != nil {
+				t.Fatalf("Evaluate(%q) failed: %v", test.expr, err)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform(), ratEqual); diff != "" {
+				t.Errorf("Evaluate(%q) returned unexpected diff (-want +got):\n%s", test.expr, diff)
+			}
+		})
+	}
+}
+
+func TestEvaluate_ArithmeticErrors(t *testing.T) {
+	obs := observation("1", c4pb.ObservationStatusCode_FINAL)
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{
+			name: "string plus integer",
+			expr: "Observation.status + 1",
+		},
+		{
+			name: "boolean times integer",
+			expr: "true * 2",
+		},
+		{
+			name: "multiple items",
+			expr: "Observation.code.coding.code + 'x'",
+		},
+		{
+			name: "concatenating an integer",
+			expr: "'a' & 1",
+		},
+		{
+			name: "integer overflow",
+			expr: "9223372036854775807 + 1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := Evaluate(obs, test.expr); err == nil {
+				t.Errorf("Evaluate(%q) = %v, want error", test.expr, got)
+			}
+		})
+	}
+}
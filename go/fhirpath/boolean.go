@@ -0,0 +1,104 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+// Precedences of the boolean operators, which bind less tightly than all
+// others: "and" before "or" and "xor", and those before "implies".
+const (
+	impliesPrecedence = 1
+	orPrecedence      = 2
+	andPrecedence     = 3
+)
+
+func init() {
+	binaryOperators["and"] = binaryOperator{precedence: andPrecedence, fn: evalAnd}
+	binaryOperators["or"] = binaryOperator{precedence: orPrecedence, fn: evalOr}
+	binaryOperators["xor"] = binaryOperator{precedence: orPrecedence, fn: evalXor}
+	binaryOperators["implies"] = binaryOperator{precedence: impliesPrecedence, fn: evalImplies}
+}
+
+// The boolean operators follow FHIRPath's three-valued logic, in which an
+// empty operand is unknown: the result is empty unless the known operands
+// determine it.
+
+func evalAnd(left, right Collection) (Collection, error) {
+	l, lok, r, rok, err := booleanOperands(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case lok && !l, rok && !r:
+		return Collection{false}, nil
+	case lok && rok:
+		return Collection{true}, nil
+	}
+	return nil, nil
+}
+
+func evalOr(left, right Collection) (Collection, error) {
+	l, lok, r, rok, err := booleanOperands(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case lok && l, rok && r:
+		return Collection{true}, nil
+	case lok && rok:
+		return Collection{false}, nil
+	}
+	return nil, nil
+}
+
+func evalXor(left, right Collection) (Collection, error) {
+	l, lok, r, rok, err := booleanOperands(left, right)
+	if err != nil || !lok || !rok {
+		return nil, err
+	}
+	return Collection{l != r}, nil
+}
+
+func evalImplies(left, right Collection) (Collection, error) {
+	l, lok, r, rok, err := booleanOperands(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case lok && !l, rok && r:
+		return Collection{true}, nil
+	case lok && rok:
+		return Collection{false}, nil
+	}
+	return nil, nil
+}
+
+func fnNot(input Collection, _ []node) (Collection, error) {
+	b, ok, err := singletonBoolean(input)
+	if !ok {
+		return nil, err
+	}
+	return Collection{!b}, nil
+}
+
+// booleanOperands converts both operands with singletonBoolean, reporting
+// for each whether it is known.
+func booleanOperands(left, right Collection) (l, lok, r, rok bool, err error) {
+	if l, lok, err = singletonBoolean(left); err != nil {
+		return false, false, false, false, err
+	}
+	if r, rok, err = singletonBoolean(right); err != nil {
+		return false, false, false, false, err
+	}
+	return l, lok, r, rok, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+)
+
+func component(code, value string) *r4observationpb.Observation_Component {
+	return &r4observationpb.Observation_Component{
+		Code: &d4pb.CodeableConcept{Coding: []*d4pb.Coding{{Code: &d4pb.Code{Value: code}}}},
+		Value: &r4observationpb.Observation_Component_ValueX{
+			Choice: &r4observationpb.Observation_Component_ValueX_Quantity{Quantity: &d4pb.Quantity{
+				Value: &d4pb.Decimal{Value: value},
+			}},
+		},
+	}
+}
+
+func TestEvaluate_BooleanLogic(t *testing.T) {
+	obs := observation("1", c4pb.ObservationStatusCode_FINAL)
+	obs.Component = []*r4observationpb.Observation_Component{
+		component("x", "7.5"),
+		component("x", "3"),
+		component("y", "10"),
+		component("x", "5"),
+	}
+	tests := []struct {
+		expr string
+		want Collection
+	}{
+		{
+			expr: "Observation.component.where(code.coding.code = 'x' and value.value > 5)",
+			want: Collection{obs.Component[0]},
+		},
+		{
+			expr: "Observation.component.where(code.coding.code = 'y' or value.value <= 3).value.value",
+			want: Collection{&d4pb.Decimal{Value: "3"}, &d4pb.Decimal{Value: "10"}},
+		},
+		{
+			expr: "Observation.component.where((code.coding.code != 'x' or value.value >= 5) and (value.value < 10)).count()",
+			want: Collection{int64(2)},
+		},
+		{
+			expr: "Observation.component.where((code.coding.code = 'x').not()).count()",
+			want: Collection{int64(1)},
+		},
+		{
+			expr: "true or false and false",
+			want: Collection{true},
+		},
+		{
+			expr: "(true or false) and false",
+			want: Collection{false},
+		},
+		{
+			expr: "1 + 1 > 1 and 2 = 2",
+			want: Collection{true},
+		},
+		{
+			expr: "'apple' < 'banana' xor 2 >= 2",
+			want: Collection{false},
+		},
+		{
+			expr: "false implies Observation.note.exists()",
+			want: Collection{true},
+		},
+		{
+			expr: "Observation.note.text = 'x' and false",
+			want: Collection{false},
+		},
+		{
+			expr: "Observation.note.text = 'x' or true",
+			want: Collection{true},
+		},
+		{
+			expr: "Observation.note.text = 'x' and true",
+		},
+		{
+			expr: "Observation.note.text < 'x'",
+		},
+		{
+			expr: "Observation.status != 'final'",
+			want: Collection{false},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			got, err := Evaluate(obs, test.expr)
+			if err != nil {
+				t.Fatalf("Evaluate(%q) failed: %v", test.expr, err)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Evaluate(%q) returned unexpected diff (-want +got):\n%s", test.expr, diff)
+			}
+		})
+	}
+}
+
+func TestEvaluate_BooleanLogicErrors(t *testing.T) {
+	obs := observation("1", c4pb.ObservationStatusCode_FINAL)
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{
+			name: "comparing string and integer",
+			expr: "Observation.status > 1",
+		},
+		{
+			name: "comparing multiple items",
+			expr: "Observation.code.coding.code < 'x'",
+		},
+		{
+			name: "not with an argument",
+			expr: "Observation.component.where(not(code.coding.code = 'x'))",
+		},
+		{
+			name: "and of multiple items",
+			expr: "Observation.code.coding.code and true",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := Evaluate(obs, test.expr); err == nil {
+				t.Errorf("Evaluate(%q) = %v, want error", test.expr, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Precedences of the equality and comparison operators. Comparisons bind
+// more tightly than equality, and both less tightly than the type operators.
+const (
+	equalityPrecedence   = 5
+	comparisonPrecedence = 6
+)
+
+func init() {
+	binaryOperators["!="] = binaryOperator{precedence: equalityPrecedence, fn: evalNotEquals}
+	for op, holds := range map[string]func(int) bool{
+		"<":  func(c int) bool { return c < 0 },
+		"<=": func(c int) bool { return c <= 0 },
+		">":  func(c int) bool { return c > 0 },
+		">=": func(c int) bool { return c >= 0 },
+	} {
+		binaryOperators[op] = binaryOperator{precedence: comparisonPrecedence, fn: comparison(holds)}
+	}
+}
+
+// Compare orders two Collection items, returning -1, 0 or 1. Numbers, strings
+// and dates, including FHIR primitives holding them, can be compared with
+// items of the same kind; other combinations return an error. Unlike the
+// FHIRPath comparison operators, dates of different precisions are ordered
+// rather than left uncertain: by instant first, then coarser precision first.
+func Compare(a, b any) (int, error) {
+	av, err := toSystem(a)
+	if err != nil {
+		return 0, err
+	}
+	bv, err := toSystem(b)
+	if err != nil {
+		return 0, err
+	}
+	if ar, br, ok := numericPair(av, bv); ok {
+		return ar.Cmp(br), nil
+	}
+	switch x := av.(type) {
+	case string:
+		if y, ok := bv.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	case dateTimeValue:
+		if y, ok := bv.(dateTimeValue); ok {
+			switch {
+			case x.t.Before(y.t):
+				return -1, nil
+			case x.t.After(y.t):
+				return 1, nil
+			case x.precision < y.precision:
+				return -1, nil
+			case x.precision > y.precision:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %T with %T", av, bv)
+}
+
+func evalNotEquals(left, right Collection) (Collection, error) {
+	eq, err := evalEquals(left, right)
+	if err != nil || len(eq) == 0 {
+		return nil, err
+	}
+	return Collection{!eq[0].(bool)}, nil
+}
+
+// comparison returns a comparison operator which holds when holds does for
+// the result of comparing its operands with Compare. The result is empty if
+// either operand is, or if they are dates of different precisions.
+func comparison(holds func(int) bool) func(left, right Collection) (Collection, error) {
+	return func(left, right Collection) (Collection, error) {
+		if len(left) == 0 || len(right) == 0 {
+			return nil, nil
+		}
+		if len(left) != 1 || len(right) != 1 {
+			return nil, fmt.Errorf("expected single operands, got %d and %d items", len(left), len(right))
+		}
+		l, err := toSystem(left[0])
+		if err != nil {
+			return nil, err
+		}
+		r, err := toSystem(right[0])
+		if err != nil {
+			return nil, err
+		}
+		if ld, ok := l.(dateTimeValue); ok {
+			if rd, ok := r.(dateTimeValue); ok && ld.precision != rd.precision {
+				return nil, nil
+			}
+		}
+		c, err := Compare(l, r)
+		if err != nil {
+			return nil, err
+		}
+		return Collection{holds(c)}, nil
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"testing"
+	"time"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+func dateTime(t time.Time, precision d4pb.DateTime_Precision) *d4pb.DateTime {
+	return &d4pb.DateTime{ValueUs: t.UnixMicro(), Timezone: "Z", Precision: precision}
+}
+
+func TestCompare(t *testing.T) {
+	jan := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		a, b any
+		want int
+	}{
+		{"integer and decimal", &d4pb.Integer{Value: 2}, &d4pb.Decimal{Value: "1.5"}, 1},
+		{"equal numbers", int64(3), &d4pb.Decimal{Value: "3.0"}, 0},
+		{"strings", &d4pb.String{Value: "apple"}, "banana", -1},
+		{"dates", dateTime(jan, d4pb.DateTime_DAY), dateTime(feb, d4pb.DateTime_DAY), -1},
+		{"date precisions", dateTime(jan, d4pb.DateTime_SECOND), dateTime(jan, d4pb.DateTime_DAY), 1},
+		{"equal dates", dateTime(jan, d4pb.DateTime_DAY), dateTime(jan, d4pb.DateTime_DAY), 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Compare(test.a, test.b)
+			if err != nil {
+				t.Fatalf("Compare() failed: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("Compare() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompare_Errors(t *testing.T) {
+	if _, err := Compare("a", int64(1)); err == nil {
+		t.Errorf("Compare() of string and integer succeeded, want error")
+	}
+	if _, err := Compare(&d4pb.Coding{}, &d4pb.Coding{}); err == nil {
+		t.Errorf("Compare() of complex elements succeeded, want error")
+	}
+}
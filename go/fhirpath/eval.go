@@ -0,0 +1,348 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+// node is an element of a parsed FHIRPath expression tree.
+type node interface {
+	eval(focus Collection) (Collection, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+func (n *literalNode) eval(Collection) (Collection, error) {
+	return Collection{n.value}, nil
+}
+
+type thisNode struct{}
+
+func (n *thisNode) eval(focus Collection) (Collection, error) {
+	return focus, nil
+}
+
+// memberNode selects the child elements with the given name, or filters the
+// input by resource type when name is a resource type such as "Patient".
+type memberNode struct {
+	target node
+	name   string
+}
+
+func (n *memberNode) eval(focus Collection) (Collection, error) {
+	input, err := evalTarget(n.target, focus)
+	if err != nil {
+		return nil, err
+	}
+	var out Collection
+	for _, v := range input {
+		m, ok := v.(proto.Message)
+		if !ok {
+			continue
+		}
+		rm := m.ProtoReflect()
+		if element.IsResource(rm.Descriptor()) && string(rm.Descriptor().Name()) == n.name {
+			out = append(out, m)
+			continue
+		}
+		children, err := childElements(rm, n.name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, children...)
+	}
+	return out, nil
+}
+
+type indexNode struct {
+	target node
+	index  node
+}
+
+func (n *indexNode) eval(focus Collection) (Collection, error) {
+	input, err := n.target.eval(focus)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := n.index.eval(focus)
+	if err != nil {
+		return nil, err
+	}
+	i, ok, err := singletonInteger(idx)
+	if err != nil {
+		return nil, fmt.Errorf("indexer: %w", err)
+	}
+	if !ok || i < 0 || i >= int64(len(input)) {
+		return nil, nil
+	}
+	return Collection{input[i]}, nil
+}
+
+type functionNode struct {
+	target node
+	name   string
+	fn     function
+	args   []node
+}
+
+func (n *functionNode) eval(focus Collection) (Collection, error) {
+	input, err := evalTarget(n.target, focus)
+	if err != nil {
+		return nil, err
+	}
+	out, err := n.fn.eval(input, n.args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", n.name, err)
+	}
+	return out, nil
+}
+
+type binaryNode struct {
+	op          string
+	fn          func(left, right Collection) (Collection, error)
+	left, right node
+}
+
+func (n *binaryNode) eval(focus Collection) (Collection, error) {
+	left, err := n.left.eval(focus)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(focus)
+	if err != nil {
+		return nil, err
+	}
+	out, err := n.fn(left, right)
+	if err != nil {
+		return nil, fmt.Errorf("operator %s: %w", n.op, err)
+	}
+	return out, nil
+}
+
+func evalTarget(target node, focus Collection) (Collection, error) {
+	if target == nil {
+		return focus, nil
+	}
+	return target.eval(focus)
+}
+
+func isReference(d protoreflect.MessageDescriptor) bool {
+	return proto.HasExtension(d.Options(), apb.E_FhirReferenceType)
+}
+
+// childElements returns the children of m named by the FHIR element name
+// name. Choice elements may be addressed either by their base name
+// ("value") or with a type suffix ("valueQuantity").
+func childElements(m protoreflect.Message, name string) (Collection, error) {
+	d := m.Descriptor()
+	if isReference(d) && name == "reference" {
+		return referenceString(m)
+	}
+	fields := d.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.Get(i)
+		if f.Message() == nil {
+			continue
+		}
+		if f.JSONName() == name {
+			return fieldValues(m, f)
+		}
+		if element.IsChoice(f.Message()) && strings.HasPrefix(name, f.JSONName()) {
+			if c, ok := choiceValue(m, f, name[len(f.JSONName()):]); ok {
+				return c, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// choiceValue returns the value of the choice field f when its active type
+// matches the given type suffix, e.g. "Quantity".
+func choiceValue(m protoreflect.Message, f protoreflect.FieldDescriptor, suffix string) (Collection, bool) {
+	if suffix == "" || !m.Has(f) {
+		return nil, false
+	}
+	cm := m.Get(f).Message()
+	active := cm.WhichOneof(cm.Descriptor().Oneofs().Get(0))
+	if active == nil || !strings.EqualFold(active.JSONName(), suffix) {
+		return nil, false
+	}
+	return Collection{cm.Get(active).Message().Interface()}, true
+}
+
+func fieldValues(m protoreflect.Message, f protoreflect.FieldDescriptor) (Collection, error) {
+	if !m.Has(f) {
+		return nil, nil
+	}
+	if f.IsList() {
+		l := m.Get(f).List()
+		var out Collection
+		for i := 0; i < l.Len(); i++ {
+			v, err := elementValue(l.Get(i).Message())
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				out = append(out, v)
+			}
+		}
+		return out, nil
+	}
+	v, err := elementValue(m.Get(f).Message())
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return Collection{v}, nil
+}
+
+// elementValue unwraps choice types, contained resources and inlined Any
+// resources so that navigation sees the underlying FHIR element.
+func elementValue(m protoreflect.Message) (proto.Message, error) {
+	d := m.Descriptor()
+	switch {
+	case element.IsChoice(d):
+		active := m.WhichOneof(d.Oneofs().Get(0))
+		if active == nil {
+			return nil, nil
+		}
+		return m.Get(active).Message().Interface(), nil
+	case d.Name() == "ContainedResource":
+		active := m.WhichOneof(d.Oneofs().Get(0))
+		if active == nil {
+			return nil, nil
+		}
+		return m.Get(active).Message().Interface(), nil
+	case d.FullName() == "google.protobuf.Any":
+		cr, err := m.Interface().(*anypb.Any).UnmarshalNew()
+		if err != nil {
+			return nil, fmt.Errorf("unpacking contained resource: %w", err)
+		}
+		return elementValue(cr.ProtoReflect())
+	}
+	return m.Interface(), nil
+}
+
+// referenceString returns the literal reference of a normalized Reference
+// proto as a System string, e.g. "Patient/123".
+func referenceString(m protoreflect.Message) (Collection, error) {
+	ref, err := jsonformat.NewDenormalizedReference(m.Interface())
+	if err != nil {
+		return nil, err
+	}
+	rm := ref.ProtoReflect()
+	uri := rm.Descriptor().Fields().ByName("uri")
+	if uri == nil || !rm.Has(uri) {
+		return nil, nil
+	}
+	return Collection{rm.Get(uri).Message().Get(uri.Message().Fields().ByName("value")).String()}, nil
+}
+
+func singletonInteger(c Collection) (int64, bool, error) {
+	if len(c) == 0 {
+		return 0, false, nil
+	}
+	if len(c) > 1 {
+		return 0, false, fmt.Errorf("expected a single value, got %d", len(c))
+	}
+	v, err := toSystem(c[0])
+	if err != nil {
+		return 0, false, err
+	}
+	i, ok := v.(int64)
+	if !ok {
+		return 0, false, fmt.Errorf("expected an integer, got %T", v)
+	}
+	return i, true, nil
+}
+
+func evalEquals(left, right Collection) (Collection, error) {
+	if len(left) == 0 || len(right) == 0 {
+		return nil, nil
+	}
+	if len(left) != len(right) {
+		return Collection{false}, nil
+	}
+	for i := range left {
+		eq, err := equal(left[i], right[i])
+		if err != nil {
+			return nil, err
+		}
+		if !eq {
+			return Collection{false}, nil
+		}
+	}
+	return Collection{true}, nil
+}
+
+// equal reports whether two collection items are equal, converting FHIR
+// primitives to System values first.
+func equal(a, b any) (bool, error) {
+	av, err := toSystem(a)
+	if err != nil {
+		return false, err
+	}
+	bv, err := toSystem(b)
+	if err != nil {
+		return false, err
+	}
+	switch x := av.(type) {
+	case proto.Message:
+		y, ok := bv.(proto.Message)
+		return ok && proto.Equal(x, y), nil
+	case dateTimeValue:
+		y, ok := bv.(dateTimeValue)
+		return ok && x.precision == y.precision && x.t.Equal(y.t), nil
+	}
+	if ar, br, ok := numericPair(av, bv); ok {
+		return ar.Cmp(br) == 0, nil
+	}
+	return av == bv, nil
+}
+
+// numericPair returns both values as rationals when both are numeric.
+func numericPair(a, b any) (*big.Rat, *big.Rat, bool) {
+	ar, ok := toRat(a)
+	if !ok {
+		return nil, nil, false
+	}
+	br, ok := toRat(b)
+	if !ok {
+		return nil, nil, false
+	}
+	return ar, br, true
+}
+
+func toRat(v any) (*big.Rat, bool) {
+	switch x := v.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(x), true
+	case *big.Rat:
+		return x, true
+	}
+	return nil, false
+}
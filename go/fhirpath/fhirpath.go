@@ -0,0 +1,112 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fhirpath evaluates a subset of FHIRPath expressions against FHIR
+// protos.
+//
+// Supported are path navigation (including choice elements addressed as
+// either "value" or "valueQuantity"), indexers, string, number and boolean
+// literals, the "=", "!=", "<", "<=", ">", ">=", "is" and "as" operators, the
+// boolean operators "and", "or", "xor" and "implies", the arithmetic
+// operators "+", "-", "*", "/", "div", "mod" and "&", the where(), exists(),
+// empty(), first(), count(), not(), ofType(), is() and as() functions and the
+// string functions substring(), startsWith(), endsWith(), contains(),
+// indexOf(), length(), upper(), lower() and replace().
+package fhirpath
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// A Collection is the result of evaluating a FHIRPath expression. Its items
+// are either FHIR elements, as proto messages, or System values of type
+// bool, int64, *big.Rat or string.
+type Collection []any
+
+// An Expression is a compiled FHIRPath expression which can be evaluated
+// repeatedly.
+type Expression struct {
+	src  string
+	root node
+}
+
+// Compile parses a FHIRPath expression.
+func Compile(expr string) (*Expression, error) {
+	root, err := parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing FHIRPath %q: %w", expr, err)
+	}
+	return &Expression{src: expr, root: root}, nil
+}
+
+// String returns the source text of the expression.
+func (e *Expression) String() string {
+	return e.src
+}
+
+// Evaluate evaluates the expression with msg as its context. msg may be a
+// resource, a ContainedResource or any other FHIR element.
+func (e *Expression) Evaluate(msg proto.Message) (Collection, error) {
+	focus, err := elementValue(msg.ProtoReflect())
+	if err != nil {
+		return nil, err
+	}
+	if focus == nil {
+		return nil, nil
+	}
+	res, err := e.root.eval(Collection{focus})
+	if err != nil {
+		return nil, fmt.Errorf("evaluating FHIRPath %q: %w", e.src, err)
+	}
+	return res, nil
+}
+
+// Evaluate compiles and evaluates a FHIRPath expression with msg as its
+// context.
+func Evaluate(msg proto.Message, expr string) (Collection, error) {
+	e, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return e.Evaluate(msg)
+}
+
+// Filter returns the resources for which any result of evaluating expr has
+// the string form equals, e.g. the code "final" for "Observation.status".
+func Filter(resources []proto.Message, expr string, equals string) ([]proto.Message, error) {
+	e, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	var out []proto.Message
+	for _, r := range resources {
+		res, err := e.Evaluate(r)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range res {
+			s, ok, err := toString(v)
+			if err != nil {
+				return nil, err
+			}
+			if ok && s == equals {
+				out = append(out, r)
+				break
+			}
+		}
+	}
+	return out, nil
+}
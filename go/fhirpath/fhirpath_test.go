@@ -0,0 +1,216 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+)
+
+func observation(id string, status c4pb.ObservationStatusCode_Value) *r4observationpb.Observation {
+	return &r4observationpb.Observation{
+		Id:     &d4pb.Id{Value: id},
+		Status: &r4observationpb.Observation_StatusCode{Value: status},
+		Code: &d4pb.CodeableConcept{
+			Coding: []*d4pb.Coding{
+				{System: &d4pb.Uri{Value: "http://loinc.org"}, Code: &d4pb.Code{Value: "8867-4"}},
+				{System: &d4pb.Uri{Value: "http://snomed.info/sct"}, Code: &d4pb.Code{Value: "364075005"}},
+			},
+		},
+		Subject: &d4pb.Reference{
+			Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}},
+		},
+		Value: &r4observationpb.Observation_ValueX{
+			Choice: &r4observationpb.Observation_ValueX_StringValue{StringValue: &d4pb.String{Value: "high"}},
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	obs := observation("1", c4pb.ObservationStatusCode_FINAL)
+	tests := []struct {
+		name string
+		msg  proto.Message
+		expr string
+		want Collection
+	}{
+		{
+			name: "primitive field",
+			msg:  obs,
+			expr: "Observation.id",
+			want: Collection{&d4pb.Id{Value: "1"}},
+		},
+		{
+			name: "without resource type",
+			msg:  obs,
+			expr: "code.coding.code",
+			want: Collection{&d4pb.Code{Value: "8867-4"}, &d4pb.Code{Value: "364075005"}},
+		},
+		{
+			name: "indexer",
+			msg:  obs,
+			expr: "Observation.code.coding[1].code",
+			want: Collection{&d4pb.Code{Value: "364075005"}},
+		},
+		{
+			name: "choice base name",
+			msg:  obs,
+			expr: "Observation.value",
+			want: Collection{&d4pb.String{Value: "high"}},
+		},
+		{
+			name: "choice with type suffix",
+			msg:  obs,
+			expr: "Observation.valueString",
+			want: Collection{&d4pb.String{Value: "high"}},
+		},
+		{
+			name: "choice with other type suffix",
+			msg:  obs,
+			expr: "Observation.valueQuantity",
+		},
+		{
+			name: "reference",
+			msg:  obs,
+			expr: "Observation.subject.reference",
+			want: Collection{"Patient/p1"},
+		},
+		{
+			name: "where and equality",
+			msg:  obs,
+			expr: "Observation.code.coding.where(system = 'http://loinc.org').code",
+			want: Collection{&d4pb.Code{Value: "8867-4"}},
+		},
+		{
+			name: "code equality",
+			msg:  obs,
+			expr: "Observation.status = 'final'",
+			want: Collection{true},
+		},
+		{
+			name: "exists",
+			msg:  obs,
+			expr: "Observation.code.coding.exists(code = '8867-4')",
+			want: Collection{true},
+		},
+		{
+			name: "empty",
+			msg:  obs,
+			expr: "Observation.note.empty()",
+			want: Collection{true},
+		},
+		{
+			name: "count",
+			msg:  obs,
+			expr: "Observation.code.coding.count()",
+			want: Collection{int64(2)},
+		},
+		{
+			name: "contained resource",
+			msg: &r4pb.ContainedResource{
+				OneofResource: &r4pb.ContainedResource_Observation{Observation: obs},
+			},
+			expr: "Observation.id",
+			want: Collection{&d4pb.Id{Value: "1"}},
+		},
+		{
+			name: "other resource type",
+			msg:  obs,
+			expr: "Patient.id",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Evaluate(test.msg, test.expr)
+			if err != nil {
+				t.Fatalf("Evaluate(%q) failed: %v", test.expr, err)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Evaluate(%q) returned unexpected diff (-want +got):\n%s", test.expr, diff)
+			}
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []string{
+		"Observation.",
+		"Observation.status = ",
+		"Observation.code.coding.where(",
+		"Observation.unknownFunction()",
+		"Observation.first(1)",
+		"'unterminated",
+		"Observation # status",
+	}
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := Compile(expr); err == nil {
+				t.Errorf("Compile(%q) succeeded, want error", expr)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	final1 := observation("1", c4pb.ObservationStatusCode_FINAL)
+	prelim := observation("2", c4pb.ObservationStatusCode_PRELIMINARY)
+	final2 := observation("3", c4pb.ObservationStatusCode_FINAL)
+	amended := observation("4", c4pb.ObservationStatusCode_AMENDED)
+	resources := []proto.Message{final1, prelim, final2, amended}
+
+	tests := []struct {
+		name   string
+		expr   string
+		equals string
+		want   []proto.Message
+	}{
+		{
+			name:   "status",
+			expr:   "Observation.status",
+			equals: "final",
+			want:   []proto.Message{final1, final2},
+		},
+		{
+			name:   "no matches",
+			expr:   "Observation.status",
+			equals: "cancelled",
+		},
+		{
+			name:   "any of many results",
+			expr:   "Observation.code.coding.code",
+			equals: "364075005",
+			want:   resources,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Filter(resources, test.expr, test.equals)
+			if err != nil {
+				t.Fatalf("Filter(%q, %q) failed: %v", test.expr, test.equals, err)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Filter(%q, %q) returned unexpected diff (-want +got):\n%s", test.expr, test.equals, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"fmt"
+)
+
+// function is a FHIRPath function. Arguments are passed unevaluated so that
+// functions such as where() can evaluate them against each input item.
+// Functions taking a type, such as ofType(), receive it as a typeNode.
+type function struct {
+	minArgs, maxArgs int
+	typeArg          bool
+	eval             func(input Collection, args []node) (Collection, error)
+}
+
+// functions holds the supported FHIRPath functions keyed by name.
+var functions map[string]function
+
+func init() {
+	functions = map[string]function{
+		"where":  {minArgs: 1, maxArgs: 1, eval: fnWhere},
+		"exists": {minArgs: 0, maxArgs: 1, eval: fnExists},
+		"empty":  {minArgs: 0, maxArgs: 0, eval: fnEmpty},
+		"first":  {minArgs: 0, maxArgs: 0, eval: fnFirst},
+		"count":  {minArgs: 0, maxArgs: 0, eval: fnCount},
+		"not":    {minArgs: 0, maxArgs: 0, eval: fnNot},
+		"ofType": {minArgs: 1, maxArgs: 1, typeArg: true, eval: fnOfType},
+		"is":     {minArgs: 1, maxArgs: 1, typeArg: true, eval: fnIs},
+		"as":     {minArgs: 1, maxArgs: 1, typeArg: true, eval: fnAs},
+	}
+}
+
+func fnWhere(input Collection, args []node) (Collection, error) {
+	var out Collection
+	for _, v := range input {
+		ok, err := criteriaHolds(args[0], v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func fnExists(input Collection, args []node) (Collection, error) {
+	if len(args) == 0 {
+		return Collection{len(input) > 0}, nil
+	}
+	matches, err := fnWhere(input, args)
+	if err != nil {
+		return nil, err
+	}
+	return Collection{len(matches) > 0}, nil
+}
+
+func fnEmpty(input Collection, _ []node) (Collection, error) {
+	return Collection{len(input) == 0}, nil
+}
+
+func fnFirst(input Collection, _ []node) (Collection, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	return input[:1], nil
+}
+
+func fnCount(input Collection, _ []node) (Collection, error) {
+	return Collection{int64(len(input))}, nil
+}
+
+// criteriaHolds evaluates criteria with v as its focus and reports whether
+// the result is true.
+func criteriaHolds(criteria node, v any) (bool, error) {
+	res, err := criteria.eval(Collection{v})
+	if err != nil {
+		return false, err
+	}
+	b, ok, err := singletonBoolean(res)
+	return ok && b, err
+}
+
+// singletonBoolean applies the FHIRPath singleton evaluation of collections
+// rules to obtain a boolean. The second return value is false when the
+// collection is empty.
+func singletonBoolean(c Collection) (bool, bool, error) {
+	switch len(c) {
+	case 0:
+		return false, false, nil
+	case 1:
+		v, err := toSystem(c[0])
+		if err != nil {
+			return false, false, err
+		}
+		if b, ok := v.(bool); ok {
+			return b, true, nil
+		}
+		// A single non-boolean item evaluates to true.
+		return true, true, nil
+	default:
+		return false, false, fmt.Errorf("expected a single boolean, got %d items", len(c))
+	}
+}
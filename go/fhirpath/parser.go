@@ -0,0 +1,351 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// symbols are the punctuation tokens of the grammar, longest first so that
+// two-character operators win over their one-character prefixes.
+var symbols = []string{"<=", ">=", "!=", "!~", ".", "(", ")", "[", "]", ",", "=", "~", "<", ">", "+", "-", "*", "/", "&", "|"}
+
+func tokenize(expr string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'':
+			s, n, err := readString(expr[i:])
+			if err != nil {
+				return nil, fmt.Errorf("at position %d: %w", i, err)
+			}
+			toks = append(toks, token{kind: tokString, text: s, pos: i})
+			i += n
+		case c == '`':
+			end := strings.IndexByte(expr[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("at position %d: unterminated delimited identifier", i)
+			}
+			toks = append(toks, token{kind: tokIdent, text: expr[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(expr) && expr[j] >= '0' && expr[j] <= '9' {
+				j++
+			}
+			if j+1 < len(expr) && expr[j] == '.' && expr[j+1] >= '0' && expr[j+1] <= '9' {
+				j++
+				for j < len(expr) && expr[j] >= '0' && expr[j] <= '9' {
+					j++
+				}
+			}
+			toks = append(toks, token{kind: tokNumber, text: expr[i:j], pos: i})
+			i = j
+		case c == '_' || c == '$' || c == '%' || unicode.IsLetter(c):
+			j := i + 1
+			for j < len(expr) && (expr[j] == '_' || unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j]))) {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: expr[i:j], pos: i})
+			i = j
+		default:
+			matched := false
+			for _, s := range symbols {
+				if strings.HasPrefix(expr[i:], s) {
+					toks = append(toks, token{kind: tokSymbol, text: s, pos: i})
+					i += len(s)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("at position %d: unexpected character %q", i, c)
+			}
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(expr)}), nil
+}
+
+// readString reads a single-quoted string literal from the start of s and
+// returns its unescaped value and the number of bytes consumed.
+func readString(s string) (string, int, error) {
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			return sb.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(s) {
+				return "", 0, fmt.Errorf("unterminated string literal")
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'f':
+				sb.WriteByte('\f')
+			default:
+				sb.WriteByte(s[i])
+			}
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string literal")
+}
+
+// binaryOperator describes an infix operator. Operators with a higher
+// precedence bind more tightly.
+type binaryOperator struct {
+	precedence int
+	fn         func(left, right Collection) (Collection, error)
+}
+
+// binaryOperators holds the supported infix operators keyed by their token.
+var binaryOperators = map[string]binaryOperator{
+	"=": {precedence: equalityPrecedence, fn: evalEquals},
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isSymbol(s string) bool {
+	t := p.peek()
+	return t.kind == tokSymbol && t.text == s
+}
+
+func (p *parser) expect(s string) error {
+	if t := p.next(); t.kind != tokSymbol || t.text != s {
+		return fmt.Errorf("at position %d: expected %q, found %q", t.pos, s, t.text)
+	}
+	return nil
+}
+
+func parse(expr string) (node, error) {
+	toks, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	n, err := p.parseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("at position %d: unexpected %q", t.pos, t.text)
+	}
+	return n, nil
+}
+
+func (p *parser) parseExpression(minPrecedence int) (node, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokSymbol && t.kind != tokIdent {
+			return left, nil
+		}
+		if t.kind == tokIdent && (t.text == "is" || t.text == "as") {
+			if typeOperatorPrecedence < minPrecedence {
+				return left, nil
+			}
+			p.next()
+			spec, err := p.parseTypeSpecifier()
+			if err != nil {
+				return nil, err
+			}
+			left = &typeOperatorNode{op: t.text, operand: left, spec: spec}
+			continue
+		}
+		op, ok := binaryOperators[t.text]
+		if !ok {
+			if t.kind == tokSymbol && t.text != ")" && t.text != "]" && t.text != "," {
+				return nil, fmt.Errorf("at position %d: unsupported operator %q", t.pos, t.text)
+			}
+			return left, nil
+		}
+		if op.precedence < minPrecedence {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseExpression(op.precedence + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: t.text, fn: op.fn, left: left, right: right}
+	}
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.isSymbol("."):
+			p.next()
+			t := p.next()
+			if t.kind != tokIdent {
+				return nil, fmt.Errorf("at position %d: expected identifier after '.', found %q", t.pos, t.text)
+			}
+			if n, err = p.parseInvocation(n, t); err != nil {
+				return nil, err
+			}
+		case p.isSymbol("["):
+			p.next()
+			idx, err := p.parseExpression(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexNode{target: n, index: idx}
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) parseTerm() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return &literalNode{value: t.text}, nil
+	case tokNumber:
+		return parseNumber(t)
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return &literalNode{value: t.text == "true"}, nil
+		case "$this":
+			return &thisNode{}, nil
+		}
+		return p.parseInvocation(nil, t)
+	case tokSymbol:
+		if t.text == "(" {
+			n, err := p.parseExpression(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+	}
+	if t.kind == tokEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("at position %d: unexpected %q", t.pos, t.text)
+}
+
+// parseInvocation parses a member access or function call on target, which
+// is nil when the invocation applies to the current focus.
+func (p *parser) parseInvocation(target node, name token) (node, error) {
+	if !p.isSymbol("(") {
+		return &memberNode{target: target, name: name.text}, nil
+	}
+	p.next()
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("at position %d: unsupported function %q", name.pos, name.text)
+	}
+	var args []node
+	for !p.isSymbol(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		if fn.typeArg {
+			spec, err := p.parseTypeSpecifier()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, &typeNode{spec: spec})
+			continue
+		}
+		arg, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next()
+	if len(args) < fn.minArgs || len(args) > fn.maxArgs {
+		return nil, fmt.Errorf("at position %d: wrong number of arguments to %s(): %d", name.pos, name.text, len(args))
+	}
+	return &functionNode{target: target, name: name.text, fn: fn, args: args}, nil
+}
+
+func parseNumber(t token) (node, error) {
+	if !strings.Contains(t.text, ".") {
+		i, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("at position %d: invalid integer %q", t.pos, t.text)
+		}
+		return &literalNode{value: i}, nil
+	}
+	r, ok := new(big.Rat).SetString(t.text)
+	if !ok {
+		return nil, fmt.Errorf("at position %d: invalid decimal %q", t.pos, t.text)
+	}
+	return &literalNode{value: r}, nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+func init() {
+	for name, fn := range map[string]function{
+		"substring":  {minArgs: 1, maxArgs: 2, eval: fnSubstring},
+		"startsWith": {minArgs: 1, maxArgs: 1, eval: stringPredicate(strings.HasPrefix)},
+		"endsWith":   {minArgs: 1, maxArgs: 1, eval: stringPredicate(strings.HasSuffix)},
+		"contains":   {minArgs: 1, maxArgs: 1, eval: stringPredicate(strings.Contains)},
+		"indexOf":    {minArgs: 1, maxArgs: 1, eval: fnIndexOf},
+		"length":     {minArgs: 0, maxArgs: 0, eval: fnLength},
+		"upper":      {minArgs: 0, maxArgs: 0, eval: stringMapping(strings.ToUpper)},
+		"lower":      {minArgs: 0, maxArgs: 0, eval: stringMapping(strings.ToLower)},
+		"replace":    {minArgs: 2, maxArgs: 2, eval: fnReplace},
+	} {
+		functions[name] = fn
+	}
+}
+
+// fnSubstring returns the part of the input string starting at the
+// zero-based character index start, optionally limited to length characters.
+// A start index outside the string produces an empty result.
+func fnSubstring(input Collection, args []node) (Collection, error) {
+	s, ok, err := singletonString(input)
+	if !ok {
+		return nil, err
+	}
+	start, ok, err := integerArg(input, args[0])
+	if !ok {
+		return nil, err
+	}
+	runes := []rune(s)
+	if start < 0 || start >= int64(len(runes)) {
+		return nil, nil
+	}
+	end := int64(len(runes))
+	if len(args) > 1 {
+		length, ok, err := integerArg(input, args[1])
+		if err != nil {
+			return nil, err
+		}
+		if ok && length < 0 {
+			length = 0
+		}
+		if ok && start+length < end {
+			end = start + length
+		}
+	}
+	return Collection{string(runes[start:end])}, nil
+}
+
+func fnIndexOf(input Collection, args []node) (Collection, error) {
+	s, ok, err := singletonString(input)
+	if !ok {
+		return nil, err
+	}
+	sub, ok, err := stringArg(input, args[0])
+	if !ok {
+		return nil, err
+	}
+	i := strings.Index(s, sub)
+	if i < 0 {
+		return Collection{int64(-1)}, nil
+	}
+	return Collection{int64(utf8.RuneCountInString(s[:i]))}, nil
+}
+
+func fnLength(input Collection, _ []node) (Collection, error) {
+	s, ok, err := singletonString(input)
+	if !ok {
+		return nil, err
+	}
+	return Collection{int64(utf8.RuneCountInString(s))}, nil
+}
+
+func fnReplace(input Collection, args []node) (Collection, error) {
+	s, ok, err := singletonString(input)
+	if !ok {
+		return nil, err
+	}
+	pattern, ok, err := stringArg(input, args[0])
+	if !ok {
+		return nil, err
+	}
+	substitution, ok, err := stringArg(input, args[1])
+	if !ok {
+		return nil, err
+	}
+	return Collection{strings.ReplaceAll(s, pattern, substitution)}, nil
+}
+
+// stringPredicate adapts a function testing a string against a string
+// argument, such as strings.HasPrefix, to a FHIRPath function.
+func stringPredicate(pred func(s, arg string) bool) func(Collection, []node) (Collection, error) {
+	return func(input Collection, args []node) (Collection, error) {
+		s, ok, err := singletonString(input)
+		if !ok {
+			return nil, err
+		}
+		arg, ok, err := stringArg(input, args[0])
+		if !ok {
+			return nil, err
+		}
+		return Collection{pred(s, arg)}, nil
+	}
+}
+
+// stringMapping adapts a string conversion, such as strings.ToUpper, to a
+// FHIRPath function.
+func stringMapping(fn func(string) string) func(Collection, []node) (Collection, error) {
+	return func(input Collection, _ []node) (Collection, error) {
+		s, ok, err := singletonString(input)
+		if !ok {
+			return nil, err
+		}
+		return Collection{fn(s)}, nil
+	}
+}
+
+// singletonString returns the string value of a single item collection. The
+// second return value is false when the collection is empty or on error.
+func singletonString(c Collection) (string, bool, error) {
+	if len(c) == 0 {
+		return "", false, nil
+	}
+	if len(c) > 1 {
+		return "", false, fmt.Errorf("expected a single value, got %d", len(c))
+	}
+	v, err := toSystem(c[0])
+	if err != nil {
+		return "", false, err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", false, fmt.Errorf("expected a String, got %s", systemTypeName(v))
+	}
+	return s, true, nil
+}
+
+// stringArg evaluates a function argument against the function input and
+// returns it as a string.
+func stringArg(input Collection, arg node) (string, bool, error) {
+	res, err := arg.eval(input)
+	if err != nil {
+		return "", false, err
+	}
+	return singletonString(res)
+}
+
+// integerArg evaluates a function argument against the function input and
+// returns it as an integer.
+func integerArg(input Collection, arg node) (int64, bool, error) {
+	res, err := arg.eval(input)
+	if err != nil {
+		return 0, false, err
+	}
+	i, ok, err := singletonInteger(res)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	return i, true, nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func TestEvaluate_Strings(t *testing.T) {
+	patient := &r4patientpb.Patient{
+		Id: &d4pb.Id{Value: "p1"},
+		Name: []*d4pb.HumanName{{
+			Family: &d4pb.String{Value: "Chalmers"},
+			Given:  []*d4pb.String{{Value: "Peter"}, {Value: "James"}},
+		}},
+	}
+	tests := []struct {
+		expr string
+		want Collection
+	}{
+		{
+			expr: "Patient.name.family.substring(0,3)",
+			want: Collection{"Cha"},
+		},
+		{
+			expr: "Patient.name.family.substring(4)",
+			want: Collection{"mers"},
+		},
+		{
+			expr: "Patient.name.family.substring(5, 10)",
+			want: Collection{"ers"},
+		},
+		{
+			expr: "Patient.name.family.substring(8)",
+		},
+		{
+			expr: "Patient.name.family.startsWith('Chal')",
+			want: Collection{true},
+		},
+		{
+			expr: "Patient.name.family.endsWith('Chal')",
+			want: Collection{false},
+		},
+		{
+			expr: "Patient.name.family.contains('alm')",
+			want: Collection{true},
+		},
+		{
+			expr: "Patient.name.family.indexOf('m')",
+			want: Collection{int64(4)},
+		},
+		{
+			expr: "Patient.name.family.length()",
+			want: Collection{int64(8)},
+		},
+		{
+			expr: "Patient.name.family.upper()",
+			want: Collection{"CHALMERS"},
+		},
+		{
+			expr: "Patient.name.family.lower().replace('chal', 'pal')",
+			want: Collection{"palmers"},
+		},
+		{
+			expr: "Patient.name.given.first() & ' ' & Patient.name.family",
+			want: Collection{"Peter Chalmers"},
+		},
+		{
+			expr: "Patient.name.given.where(length() = 5)",
+			want: Collection{&d4pb.String{Value: "Peter"}, &d4pb.String{Value: "James"}},
+		},
+		{
+			expr: "Patient.name.prefix.length()",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			got, err := Evaluate(patient, test.expr)
+			if err != nil {
+				t.Fatalf("Evaluate(%q) failed: %v", test.expr, err)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Evaluate(%q) returned unexpected diff (-want +got):\n%s", test.expr, diff)
+			}
+		})
+	}
+}
+
+func TestEvaluate_StringsErrors(t *testing.T) {
+	patient := &r4patientpb.Patient{
+		Name: []*d4pb.HumanName{{
+			Given: []*d4pb.String{{Value: "Peter"}, {Value: "James"}},
+		}},
+		MultipleBirth: &r4patientpb.Patient_MultipleBirthX{
+			Choice: &r4patientpb.Patient_MultipleBirthX_Integer{Integer: &d4pb.Integer{Value: 2}},
+		},
+	}
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{
+			name: "multiple items",
+			expr: "Patient.name.given.length()",
+		},
+		{
+			name: "integer input",
+			expr: "Patient.multipleBirth.startsWith('2')",
+		},
+		{
+			name: "string start index",
+			expr: "Patient.name.given.first().substring('1')",
+		},
+		{
+			name: "integer argument",
+			expr: "Patient.name.given.first().contains(1)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := Evaluate(patient, test.expr); err == nil {
+				t.Errorf("Evaluate(%q) = %v, want error", test.expr, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/fhirtime"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// dateTimePrecision orders the precisions of date and time values from the
+// coarsest to the finest.
+type dateTimePrecision int
+
+const (
+	precisionYear dateTimePrecision = iota + 1
+	precisionMonth
+	precisionDay
+	precisionSecond
+	precisionMillisecond
+	precisionMicrosecond
+)
+
+var precisionByName = map[protoreflect.Name]dateTimePrecision{
+	"YEAR":        precisionYear,
+	"MONTH":       precisionMonth,
+	"DAY":         precisionDay,
+	"SECOND":      precisionSecond,
+	"MILLISECOND": precisionMillisecond,
+	"MICROSECOND": precisionMicrosecond,
+}
+
+var layoutByPrecision = map[dateTimePrecision]string{
+	precisionYear:        "2006",
+	precisionMonth:       "2006-01",
+	precisionDay:         "2006-01-02",
+	precisionSecond:      "2006-01-02T15:04:05Z07:00",
+	precisionMillisecond: "2006-01-02T15:04:05.000Z07:00",
+	precisionMicrosecond: "2006-01-02T15:04:05.000000Z07:00",
+}
+
+// dateTimeValue is the System representation of FHIR date, dateTime and
+// instant values.
+type dateTimeValue struct {
+	t         time.Time
+	precision dateTimePrecision
+}
+
+func (v dateTimeValue) String() string {
+	return v.t.Format(layoutByPrecision[v.precision])
+}
+
+// toSystem converts FHIR primitive elements to their System value: bool,
+// int64, *big.Rat, string or dateTimeValue. Complex elements and values which
+// are already System values are returned unchanged.
+func toSystem(v any) (any, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return v, nil
+	}
+	rm := m.ProtoReflect()
+	d := rm.Descriptor()
+	if !element.IsPrimitive(d) {
+		return v, nil
+	}
+	value := d.Fields().ByName("value")
+	switch d.Name() {
+	case "Boolean":
+		return rm.Get(value).Bool(), nil
+	case "Integer", "Integer64":
+		return rm.Get(value).Int(), nil
+	case "PositiveInt", "UnsignedInt":
+		return int64(rm.Get(value).Uint()), nil
+	case "Decimal":
+		r, ok := new(big.Rat).SetString(rm.Get(value).String())
+		if !ok {
+			return nil, fmt.Errorf("invalid decimal %q", rm.Get(value).String())
+		}
+		return r, nil
+	case "Date", "DateTime", "Instant":
+		return dateTimeFromProto(rm)
+	case "Time":
+		return timeFromProto(rm)
+	case "Base64Binary":
+		return v, nil
+	}
+	if value == nil {
+		return nil, fmt.Errorf("primitive %v has no value field", d.FullName())
+	}
+	switch value.Kind() {
+	case protoreflect.StringKind:
+		return rm.Get(value).String(), nil
+	case protoreflect.EnumKind:
+		return element.EnumCode(value.Enum().Values().ByNumber(rm.Get(value).Enum())), nil
+	}
+	return nil, fmt.Errorf("unsupported primitive %v", d.FullName())
+}
+
+func dateTimeFromProto(rm protoreflect.Message) (dateTimeValue, error) {
+	fields := rm.Descriptor().Fields()
+	prec := fields.ByName("precision")
+	p, ok := precisionByName[prec.Enum().Values().ByNumber(rm.Get(prec).Enum()).Name()]
+	if !ok {
+		return dateTimeValue{}, fmt.Errorf("invalid %s precision", rm.Descriptor().Name())
+	}
+	loc, err := fhirtime.Location(rm.Get(fields.ByName("timezone")).String())
+	if err != nil {
+		return dateTimeValue{}, err
+	}
+	us := rm.Get(fields.ByName("value_us")).Int()
+	return dateTimeValue{t: time.UnixMicro(us).In(loc), precision: p}, nil
+}
+
+func timeFromProto(rm protoreflect.Message) (string, error) {
+	fields := rm.Descriptor().Fields()
+	prec := fields.ByName("precision")
+	us := rm.Get(fields.ByName("value_us")).Int()
+	t := time.UnixMicro(us).UTC()
+	switch prec.Enum().Values().ByNumber(rm.Get(prec).Enum()).Name() {
+	case "MILLISECOND":
+		return t.Format("15:04:05.000"), nil
+	case "MICROSECOND":
+		return t.Format("15:04:05.000000"), nil
+	default:
+		return t.Format("15:04:05"), nil
+	}
+}
+
+// toString returns the string form of a collection item, or false if it has
+// no string representation.
+func toString(v any) (string, bool, error) {
+	if m, ok := v.(proto.Message); ok && m.ProtoReflect().Descriptor().Name() == "Decimal" {
+		// Keep the original lexical form of decimals, e.g. "1.50".
+		rm := m.ProtoReflect()
+		return rm.Get(rm.Descriptor().Fields().ByName("value")).String(), true, nil
+	}
+	sv, err := toSystem(v)
+	if err != nil {
+		return "", false, err
+	}
+	switch x := sv.(type) {
+	case string:
+		return x, true, nil
+	case bool:
+		return strconv.FormatBool(x), true, nil
+	case int64:
+		return strconv.FormatInt(x, 10), true, nil
+	case *big.Rat:
+		return ratString(x), true, nil
+	case dateTimeValue:
+		return x.String(), true, nil
+	}
+	return "", false, nil
+}
+
+// ratString formats a rational as a decimal without trailing zeros.
+func ratString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.RatString()
+	}
+	s := r.FloatString(16)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
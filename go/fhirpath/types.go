@@ -0,0 +1,241 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/fhir/go/internal/element"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	d3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	r3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+// typeOperatorPrecedence is the precedence of the is and as operators, which
+// bind more tightly than comparisons and equality.
+const typeOperatorPrecedence = 8
+
+// systemTypes are the types of the System namespace.
+var systemTypes = map[string]bool{
+	"Boolean":  true,
+	"Integer":  true,
+	"Decimal":  true,
+	"String":   true,
+	"Date":     true,
+	"DateTime": true,
+	"Time":     true,
+	"Quantity": true,
+}
+
+// quantitySubtypes are the FHIR types derived from Quantity.
+var quantitySubtypes = map[string]bool{
+	"Age":            true,
+	"Count":          true,
+	"Distance":       true,
+	"Duration":       true,
+	"MoneyQuantity":  true,
+	"SimpleQuantity": true,
+}
+
+// fhirTypes are the types of the FHIR namespace: the data types and resources
+// of the supported FHIR versions.
+var fhirTypes = map[string]bool{}
+
+func init() {
+	for _, file := range []protoreflect.FileDescriptor{
+		(&d3pb.String{}).ProtoReflect().Descriptor().ParentFile(),
+		(&d4pb.String{}).ProtoReflect().Descriptor().ParentFile(),
+	} {
+		msgs := file.Messages()
+		for i := 0; i < msgs.Len(); i++ {
+			d := msgs.Get(i)
+			kind := proto.GetExtension(d.Options(), apb.E_StructureDefinitionKind).(apb.StructureDefinitionKindValue)
+			if kind != apb.StructureDefinitionKindValue_KIND_UNKNOWN {
+				fhirTypes[fhirTypeName(d)] = true
+			}
+		}
+	}
+	for _, cr := range []proto.Message{&r3pb.ContainedResource{}, &r4pb.ContainedResource{}} {
+		fields := cr.ProtoReflect().Descriptor().Oneofs().Get(0).Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fhirTypes[string(fields.Get(i).Message().Name())] = true
+		}
+	}
+	fhirTypes["code"] = true
+}
+
+// fhirTypeName returns the FHIR type name of the proto message type d, e.g.
+// "dateTime" for the DateTime primitive or "code" for specialized codes.
+func fhirTypeName(d protoreflect.MessageDescriptor) string {
+	name := string(d.Name())
+	if !element.IsPrimitive(d) {
+		return name
+	}
+	if proto.HasExtension(d.Options(), apb.E_FhirValuesetUrl) {
+		return "code"
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// A typeSpecifier names a FHIR or System type.
+type typeSpecifier struct {
+	namespace string
+	name      string
+}
+
+func (t typeSpecifier) String() string {
+	return t.namespace + "." + t.name
+}
+
+// parseTypeSpecifier parses a type name, optionally qualified with its
+// namespace, e.g. "Quantity" or "System.String". Unqualified names are
+// resolved in the FHIR namespace first. Unknown types are an error.
+func (p *parser) parseTypeSpecifier() (typeSpecifier, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return typeSpecifier{}, fmt.Errorf("at position %d: expected type name, found %q", t.pos, t.text)
+	}
+	name := t.text
+	if (name == "FHIR" || name == "System") && p.isSymbol(".") {
+		p.next()
+		qualified := p.next()
+		if qualified.kind != tokIdent {
+			return typeSpecifier{}, fmt.Errorf("at position %d: expected type name, found %q", qualified.pos, qualified.text)
+		}
+		spec := typeSpecifier{namespace: name, name: qualified.text}
+		if (name == "FHIR" && !fhirTypes[spec.name]) || (name == "System" && !systemTypes[spec.name]) {
+			return typeSpecifier{}, fmt.Errorf("at position %d: unknown type %s", t.pos, spec)
+		}
+		return spec, nil
+	}
+	switch {
+	case fhirTypes[name]:
+		return typeSpecifier{namespace: "FHIR", name: name}, nil
+	case systemTypes[name]:
+		return typeSpecifier{namespace: "System", name: name}, nil
+	}
+	return typeSpecifier{}, fmt.Errorf("at position %d: unknown type %q", t.pos, name)
+}
+
+// matches reports whether the collection item v is of type t or one of its
+// subtypes.
+func (t typeSpecifier) matches(v any) bool {
+	if m, ok := v.(proto.Message); ok {
+		if t.namespace != "FHIR" {
+			return false
+		}
+		name := fhirTypeName(m.ProtoReflect().Descriptor())
+		return name == t.name || t.name == "Quantity" && quantitySubtypes[name]
+	}
+	if t.namespace != "System" {
+		return false
+	}
+	switch v.(type) {
+	case bool:
+		return t.name == "Boolean"
+	case int64:
+		return t.name == "Integer"
+	case *big.Rat:
+		return t.name == "Decimal"
+	case string:
+		return t.name == "String"
+	case dateTimeValue:
+		return t.name == "DateTime"
+	}
+	return false
+}
+
+// typeNode is the type specifier argument of ofType(), is() and as(). It is
+// not evaluated; the functions read its type directly.
+type typeNode struct {
+	spec typeSpecifier
+}
+
+func (n *typeNode) eval(Collection) (Collection, error) {
+	return nil, fmt.Errorf("type specifier %s cannot be evaluated", n.spec)
+}
+
+// typeOperatorNode applies the is or as operator to its operand.
+type typeOperatorNode struct {
+	op      string
+	operand node
+	spec    typeSpecifier
+}
+
+func (n *typeOperatorNode) eval(focus Collection) (Collection, error) {
+	input, err := n.operand.eval(focus)
+	if err != nil {
+		return nil, err
+	}
+	var out Collection
+	if n.op == "is" {
+		out, err = evalIs(input, n.spec)
+	} else {
+		out, err = evalAs(input, n.spec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("operator %s: %w", n.op, err)
+	}
+	return out, nil
+}
+
+func evalIs(input Collection, spec typeSpecifier) (Collection, error) {
+	switch len(input) {
+	case 0:
+		return nil, nil
+	case 1:
+		return Collection{spec.matches(input[0])}, nil
+	}
+	return nil, fmt.Errorf("expected a single item, got %d", len(input))
+}
+
+func evalAs(input Collection, spec typeSpecifier) (Collection, error) {
+	switch len(input) {
+	case 0:
+		return nil, nil
+	case 1:
+		if spec.matches(input[0]) {
+			return input, nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("expected a single item, got %d", len(input))
+}
+
+func fnOfType(input Collection, args []node) (Collection, error) {
+	spec := args[0].(*typeNode).spec
+	var out Collection
+	for _, v := range input {
+		if spec.matches(v) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func fnIs(input Collection, args []node) (Collection, error) {
+	return evalIs(input, args[0].(*typeNode).spec)
+}
+
+func fnAs(input Collection, args []node) (Collection, error) {
+	return evalAs(input, args[0].(*typeNode).spec)
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirpath
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+)
+
+func TestEvaluate_Types(t *testing.T) {
+	obs := observation("1", c4pb.ObservationStatusCode_FINAL)
+	obs.Value = &r4observationpb.Observation_ValueX{
+		Choice: &r4observationpb.Observation_ValueX_Quantity{Quantity: &d4pb.Quantity{
+			Value: &d4pb.Decimal{Value: "72.5"},
+			Unit:  &d4pb.String{Value: "kg"},
+		}},
+	}
+	tests := []struct {
+		expr string
+		want Collection
+	}{
+		{
+			expr: "Observation.value.ofType(Quantity).value",
+			want: Collection{&d4pb.Decimal{Value: "72.5"}},
+		},
+		{
+			expr: "Observation.value.ofType(FHIR.string)",
+		},
+		{
+			expr: "Observation.value is Quantity",
+			want: Collection{true},
+		},
+		{
+			expr: "Observation.value.is(string)",
+			want: Collection{false},
+		},
+		{
+			expr: "(Observation.value as Quantity).unit",
+			want: Collection{&d4pb.String{Value: "kg"}},
+		},
+		{
+			expr: "Observation.value.as(Period)",
+		},
+		{
+			expr: "Observation.status.is(code)",
+			want: Collection{true},
+		},
+		{
+			expr: "Observation.value.value is decimal",
+			want: Collection{true},
+		},
+		{
+			expr: "Observation.subject.reference is System.String",
+			want: Collection{true},
+		},
+		{
+			expr: "Observation.code.coding.count() is Integer",
+			want: Collection{true},
+		},
+		{
+			expr: "Observation.note.first() is Annotation",
+		},
+		{
+			expr: "Observation.value is Quantity = true",
+			want: Collection{true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			got, err := Evaluate(obs, test.expr)
+			if err != nil {
+				t.Fatalf("Evaluate(%q) failed: %v", test.expr, err)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Evaluate(%q) returned unexpected diff (-want +got):\n%s", test.expr, diff)
+			}
+		})
+	}
+}
+
+func TestEvaluate_TypesErrors(t *testing.T) {
+	obs := observation("1", c4pb.ObservationStatusCode_FINAL)
+	compileErrors := []string{
+		"Observation.value.ofType(Bogus)",
+		"Observation.value is Unknown",
+		"Observation.value.ofType(System.Bogus)",
+		"Observation.value.ofType('Quantity')",
+	}
+	for _, expr := range compileErrors {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("Compile(%q) succeeded, want error", expr)
+		}
+	}
+	if _, err := Evaluate(obs, "Observation.code.coding is Coding"); err == nil {
+		t.Errorf("Evaluate() of is on multiple items succeeded, want error")
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fhirschema provides helpers for inspecting FHIR R4
+// StructureDefinition resources, such as implementation guide profiles.
+package fhirschema
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4sdpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/structure_definition_go_proto"
+)
+
+// MustSupportPaths returns the paths of the elements in structureDef's
+// snapshot that are flagged mustSupport, in snapshot order. A path that is
+// constrained by several slices, such as Patient.extension, is returned once.
+//
+// structureDef must be an R4 StructureDefinition, or a ContainedResource
+// holding one, with a snapshot; profiles that only carry a differential must
+// have their snapshot generated first.
+func MustSupportPaths(structureDef proto.Message) ([]string, error) {
+	sd, err := asStructureDefinition(structureDef)
+	if err != nil {
+		return nil, err
+	}
+	if sd.GetSnapshot() == nil {
+		return nil, fmt.Errorf("StructureDefinition %q has no snapshot", sd.GetUrl().GetValue())
+	}
+	var paths []string
+	seen := map[string]bool{}
+	for i, e := range sd.GetSnapshot().GetElement() {
+		if !e.GetMustSupport().GetValue() {
+			continue
+		}
+		path := e.GetPath().GetValue()
+		if path == "" {
+			return nil, fmt.Errorf("snapshot element %d of StructureDefinition %q has no path", i, sd.GetUrl().GetValue())
+		}
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// asStructureDefinition returns msg as an R4 StructureDefinition, unwrapping a
+// ContainedResource if necessary.
+func asStructureDefinition(msg proto.Message) (*r4sdpb.StructureDefinition, error) {
+	switch sd := msg.(type) {
+	case *r4sdpb.StructureDefinition:
+		return sd, nil
+	case *r4pb.ContainedResource:
+		if s := sd.GetStructureDefinition(); s != nil {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported message %T, want an R4 StructureDefinition", msg)
+}
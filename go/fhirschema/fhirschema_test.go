@@ -0,0 +1,106 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirschema
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	r4sdpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/structure_definition_go_proto"
+)
+
+func loadProfile(t *testing.T, path string) *r4pb.ContainedResource {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) failed: %v", path, err)
+	}
+	u, err := jsonformat.NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("jsonformat.NewUnmarshaller() failed: %v", err)
+	}
+	msg, err := u.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal(%q) failed: %v", path, err)
+	}
+	return msg.(*r4pb.ContainedResource)
+}
+
+func TestMustSupportPaths(t *testing.T) {
+	profile := loadProfile(t, "testdata/patient-profile.json")
+	want := []string{
+		"Patient.extension",
+		"Patient.identifier",
+		"Patient.identifier.system",
+		"Patient.identifier.value",
+		"Patient.name",
+		"Patient.gender",
+		"Patient.birthDate",
+	}
+	for _, msg := range []proto.Message{profile, profile.GetStructureDefinition()} {
+		got, err := MustSupportPaths(msg)
+		if err != nil {
+			t.Fatalf("MustSupportPaths(%T) failed: %v", msg, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("MustSupportPaths(%T) returned unexpected diff (-want +got):\n%s", msg, diff)
+		}
+	}
+}
+
+func TestMustSupportPaths_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+	}{
+		{
+			name: "no snapshot",
+			msg: &r4sdpb.StructureDefinition{
+				Url: &d4pb.Uri{Value: "http://example.org/fhir/StructureDefinition/differential-only"},
+			},
+		},
+		{
+			name: "element without path",
+			msg: &r4sdpb.StructureDefinition{
+				Snapshot: &r4sdpb.StructureDefinition_Snapshot{
+					Element: []*d4pb.ElementDefinition{{MustSupport: &d4pb.Boolean{Value: true}}},
+				},
+			},
+		},
+		{
+			name: "not a StructureDefinition",
+			msg:  &r4patientpb.Patient{},
+		},
+		{
+			name: "empty ContainedResource",
+			msg:  &r4pb.ContainedResource{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := MustSupportPaths(test.msg); err == nil {
+				t.Errorf("MustSupportPaths() = %v, want error", got)
+			}
+		})
+	}
+}
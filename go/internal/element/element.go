@@ -0,0 +1,134 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package element reads FHIR elements of any version generically through proto
+// reflection.
+package element
+
+import (
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+)
+
+// DecimalPattern is the lexical form of a FHIR decimal.
+var DecimalPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// QuantityTypes are the FHIR Quantity type and its specializations.
+var QuantityTypes = map[protoreflect.Name]bool{
+	"Quantity":       true,
+	"SimpleQuantity": true,
+	"MoneyQuantity":  true,
+	"Age":            true,
+	"Count":          true,
+	"Distance":       true,
+	"Duration":       true,
+}
+
+// IsChoice reports whether d is the message of a FHIR choice type element.
+func IsChoice(d protoreflect.MessageDescriptor) bool {
+	return proto.GetExtension(d.Options(), apb.E_IsChoiceType).(bool)
+}
+
+// EnumCode returns the FHIR code of a specialized code enum value: its
+// original code if it has one, otherwise its name in lower case with
+// underscores replaced by hyphens. It returns "" for a nil or uninitialized
+// value.
+func EnumCode(ev protoreflect.EnumValueDescriptor) string {
+	if ev == nil || ev.Number() == 0 {
+		return ""
+	}
+	if orig := proto.GetExtension(ev.Options(), apb.E_FhirOriginalCode).(string); orig != "" {
+		return orig
+	}
+	return strings.ReplaceAll(strings.ToLower(string(ev.Name())), "_", "-")
+}
+
+// IsPrimitive reports whether d is the message of a FHIR primitive type.
+func IsPrimitive(d protoreflect.MessageDescriptor) bool {
+	kind := proto.GetExtension(d.Options(), apb.E_StructureDefinitionKind).(apb.StructureDefinitionKindValue)
+	return kind == apb.StructureDefinitionKindValue_KIND_PRIMITIVE_TYPE
+}
+
+// IsResource reports whether d is the message of a FHIR resource.
+func IsResource(d protoreflect.MessageDescriptor) bool {
+	kind := proto.GetExtension(d.Options(), apb.E_StructureDefinitionKind).(apb.StructureDefinitionKindValue)
+	return kind == apb.StructureDefinitionKindValue_KIND_RESOURCE
+}
+
+// ResourceOf returns the message held by msg, unwrapping a ContainedResource
+// if necessary. It returns false if msg is nil or an empty ContainedResource.
+func ResourceOf(msg proto.Message) (protoreflect.Message, bool) {
+	if msg == nil || !msg.ProtoReflect().IsValid() {
+		return nil, false
+	}
+	rm := UnwrapContained(msg.ProtoReflect())
+	return rm, rm != nil
+}
+
+// UnwrapContained returns the resource held by a ContainedResource, nil if it
+// is empty, or m itself for any other message.
+func UnwrapContained(m protoreflect.Message) protoreflect.Message {
+	oneof := m.Descriptor().Oneofs().ByName("oneof_resource")
+	if oneof == nil {
+		return m
+	}
+	f := m.WhichOneof(oneof)
+	if f == nil {
+		return nil
+	}
+	return m.Get(f).Message()
+}
+
+// PrimitiveString returns the value of the string-valued primitive field name
+// of m, or "" if it is not set.
+func PrimitiveString(m protoreflect.Message, name protoreflect.Name) string {
+	f := m.Descriptor().Fields().ByName(name)
+	if f == nil || f.Message() == nil || !m.Has(f) {
+		return ""
+	}
+	pm := m.Get(f).Message()
+	vf := pm.Descriptor().Fields().ByName("value")
+	if vf == nil || vf.Kind() != protoreflect.StringKind {
+		return ""
+	}
+	return pm.Get(vf).String()
+}
+
+// FragmentID returns the id named by the local reference ref, set either as a
+// normalized fragment or as a "#id" URI. A bare "#" refers to the container
+// and is not reported.
+func FragmentID(ref protoreflect.Message) (string, bool) {
+	oneof := ref.Descriptor().Oneofs().ByName("reference")
+	if oneof == nil {
+		return "", false
+	}
+	f := ref.WhichOneof(oneof)
+	if f == nil {
+		return "", false
+	}
+	value := PrimitiveString(ref, f.Name())
+	switch f.Name() {
+	case "fragment":
+		return value, value != ""
+	case "uri":
+		if id := strings.TrimPrefix(value, "#"); id != value && id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
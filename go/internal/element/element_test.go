@@ -0,0 +1,187 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package element
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func TestIsChoice(t *testing.T) {
+	tests := []struct {
+		msg  proto.Message
+		want bool
+	}{
+		{&r4patientpb.Patient_DeceasedX{}, true},
+		{&d4pb.Extension_ValueX{}, true},
+		{&r4patientpb.Patient{}, false},
+		{&d4pb.Quantity{}, false},
+	}
+	for _, test := range tests {
+		d := test.msg.ProtoReflect().Descriptor()
+		if got := IsChoice(d); got != test.want {
+			t.Errorf("IsChoice(%v) = %v, want %v", d.FullName(), got, test.want)
+		}
+	}
+}
+
+func TestEnumCode(t *testing.T) {
+	values := c4pb.AdministrativeGenderCode_Value(0).Descriptor().Values()
+	tests := []struct {
+		value protoreflect.EnumValueDescriptor
+		want  string
+	}{
+		{values.ByName("FEMALE"), "female"},
+		{values.ByName("INVALID_UNINITIALIZED"), ""},
+		{nil, ""},
+		{c4pb.BindingStrengthCode_Value(0).Descriptor().Values().ByName("EXTENSIBLE"), "extensible"},
+		{c4pb.QuantityComparatorCode_Value(0).Descriptor().Values().ByName("LESS_THAN"), "<"},
+	}
+	for _, test := range tests {
+		if got := EnumCode(test.value); got != test.want {
+			t.Errorf("EnumCode(%v) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestIsPrimitive(t *testing.T) {
+	tests := []struct {
+		msg  proto.Message
+		want bool
+	}{
+		{&d4pb.String{}, true},
+		{&d4pb.DateTime{}, true},
+		{&r4patientpb.Patient_GenderCode{}, true},
+		{&d4pb.Quantity{}, false},
+		{&r4patientpb.Patient{}, false},
+	}
+	for _, test := range tests {
+		d := test.msg.ProtoReflect().Descriptor()
+		if got := IsPrimitive(d); got != test.want {
+			t.Errorf("IsPrimitive(%v) = %v, want %v", d.FullName(), got, test.want)
+		}
+	}
+}
+
+func TestIsResource(t *testing.T) {
+	tests := []struct {
+		msg  proto.Message
+		want bool
+	}{
+		{&r4patientpb.Patient{}, true},
+		{&r4pb.ContainedResource{}, false},
+		{&d4pb.Quantity{}, false},
+		{&d4pb.String{}, false},
+	}
+	for _, test := range tests {
+		d := test.msg.ProtoReflect().Descriptor()
+		if got := IsResource(d); got != test.want {
+			t.Errorf("IsResource(%v) = %v, want %v", d.FullName(), got, test.want)
+		}
+	}
+}
+
+func TestResourceOf(t *testing.T) {
+	patient := &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}}
+	tests := []struct {
+		name string
+		msg  proto.Message
+		want proto.Message
+	}{
+		{"resource", patient, patient},
+		{"contained resource", &r4pb.ContainedResource{
+			OneofResource: &r4pb.ContainedResource_Patient{Patient: patient},
+		}, patient},
+		{"nil", nil, nil},
+		{"typed nil", (*r4patientpb.Patient)(nil), nil},
+		{"empty contained resource", &r4pb.ContainedResource{}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := ResourceOf(test.msg)
+			if ok != (test.want != nil) {
+				t.Fatalf("ResourceOf(%v) returned ok %v, want %v", test.msg, ok, test.want != nil)
+			}
+			if ok && got.Interface() != test.want {
+				t.Errorf("ResourceOf(%v) = %v, want %v", test.msg, got.Interface(), test.want)
+			}
+		})
+	}
+}
+
+func TestUnwrapContained(t *testing.T) {
+	patient := &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}}
+	contained := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Patient{Patient: patient},
+	}
+	if got := UnwrapContained(contained.ProtoReflect()); got.Interface() != patient {
+		t.Errorf("UnwrapContained(%v) = %v, want %v", contained, got.Interface(), patient)
+	}
+	if got := UnwrapContained(patient.ProtoReflect()); got.Interface() != patient {
+		t.Errorf("UnwrapContained(%v) = %v, want the patient itself", patient, got.Interface())
+	}
+	if got := UnwrapContained((&r4pb.ContainedResource{}).ProtoReflect()); got != nil {
+		t.Errorf("UnwrapContained(empty) = %v, want nil", got.Interface())
+	}
+}
+
+func TestPrimitiveString(t *testing.T) {
+	q := (&d4pb.Quantity{
+		Unit:  &d4pb.String{Value: "mg"},
+		Value: &d4pb.Decimal{Value: "1.5"},
+	}).ProtoReflect()
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"unit", "mg"},
+		{"value", "1.5"},
+		{"code", ""},
+		{"comparator", ""},
+		{"no_such_field", ""},
+	}
+	for _, test := range tests {
+		if got := PrimitiveString(q, protoreflect.Name(test.name)); got != test.want {
+			t.Errorf("PrimitiveString(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFragmentID(t *testing.T) {
+	tests := []struct {
+		ref    *d4pb.Reference
+		want   string
+		wantOK bool
+	}{
+		{&d4pb.Reference{Reference: &d4pb.Reference_Fragment{Fragment: &d4pb.String{Value: "p1"}}}, "p1", true},
+		{&d4pb.Reference{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: "#p1"}}}, "p1", true},
+		{&d4pb.Reference{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: "#"}}}, "", false},
+		{&d4pb.Reference{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: "Patient/p1"}}}, "", false},
+		{&d4pb.Reference{Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}}}, "", false},
+		{&d4pb.Reference{}, "", false},
+	}
+	for _, test := range tests {
+		got, ok := FragmentID(test.ref.ProtoReflect())
+		if got != test.want || ok != test.wantOK {
+			t.Errorf("FragmentID(%v) = %q, %v, want %q, %v", test.ref, got, ok, test.want, test.wantOK)
+		}
+	}
+}
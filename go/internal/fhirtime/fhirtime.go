@@ -0,0 +1,69 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fhirtime converts the timezones and precisions of FHIR date and
+// time protos.
+package fhirtime
+
+import (
+	"fmt"
+	"time"
+)
+
+// Location returns the time.Location for a FHIR proto timezone, which is
+// either "Z", a fixed "+hh:mm" offset or an IANA zone name. An empty timezone
+// is UTC.
+func Location(tz string) (*time.Location, error) {
+	if tz == "" || tz == "Z" || tz == "UTC" {
+		return time.UTC, nil
+	}
+	if (tz[0] == '+' || tz[0] == '-') && len(tz) == 6 {
+		t, err := time.Parse("-07:00", tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone offset %q", tz)
+		}
+		_, offset := t.Zone()
+		return time.FixedZone(tz, offset), nil
+	}
+	return time.LoadLocation(tz)
+}
+
+// Timezone returns the FHIR proto timezone of t: "Z" for UTC, or its fixed
+// "+hh:mm" offset.
+func Timezone(t time.Time) string {
+	if _, offset := t.Zone(); offset == 0 {
+		return "Z"
+	}
+	return t.Format("-07:00")
+}
+
+// End returns the end of the period that starts at t and is given to
+// precision, the name of a FHIR date or time precision such as "DAY" or
+// "MILLISECOND": the first instant after the period. Any other precision,
+// including "MICROSECOND", covers a single microsecond.
+func End(t time.Time, precision string) time.Time {
+	switch precision {
+	case "YEAR":
+		return t.AddDate(1, 0, 0)
+	case "MONTH":
+		return t.AddDate(0, 1, 0)
+	case "DAY":
+		return t.AddDate(0, 0, 1)
+	case "SECOND":
+		return t.Add(time.Second)
+	case "MILLISECOND":
+		return t.Add(time.Millisecond)
+	}
+	return t.Add(time.Microsecond)
+}
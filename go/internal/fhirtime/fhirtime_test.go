@@ -0,0 +1,92 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fhirtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocation(t *testing.T) {
+	tests := []struct {
+		tz         string
+		wantOffset int
+	}{
+		{"", 0},
+		{"Z", 0},
+		{"UTC", 0},
+		{"+05:30", 5*60*60 + 30*60},
+		{"-07:00", -7 * 60 * 60},
+		{"America/New_York", -5 * 60 * 60},
+	}
+	// A winter date, so that named zones are on standard time.
+	when := time.Date(2023, time.January, 15, 12, 0, 0, 0, time.UTC)
+	for _, test := range tests {
+		loc, err := Location(test.tz)
+		if err != nil {
+			t.Errorf("Location(%q) failed: %v", test.tz, err)
+			continue
+		}
+		if _, offset := when.In(loc).Zone(); offset != test.wantOffset {
+			t.Errorf("Location(%q) has offset %d, want %d", test.tz, offset, test.wantOffset)
+		}
+	}
+}
+
+func TestLocation_Errors(t *testing.T) {
+	for _, tz := range []string{"+5:30:", "-ab:cd", "Not/AZone"} {
+		if _, err := Location(tz); err == nil {
+			t.Errorf("Location(%q) succeeded, want error", tz)
+		}
+	}
+}
+
+func TestTimezone(t *testing.T) {
+	when := time.Date(2023, time.January, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		loc  *time.Location
+		want string
+	}{
+		{time.UTC, "Z"},
+		{time.FixedZone("", 5*60*60+30*60), "+05:30"},
+		{time.FixedZone("", -7*60*60), "-07:00"},
+	}
+	for _, test := range tests {
+		if got := Timezone(when.In(test.loc)); got != test.want {
+			t.Errorf("Timezone(%v) = %q, want %q", when.In(test.loc), got, test.want)
+		}
+	}
+}
+
+func TestEnd(t *testing.T) {
+	start := time.Date(2023, time.January, 15, 10, 20, 30, 0, time.UTC)
+	tests := []struct {
+		precision string
+		want      time.Time
+	}{
+		{"YEAR", time.Date(2024, time.January, 15, 10, 20, 30, 0, time.UTC)},
+		{"MONTH", time.Date(2023, time.February, 15, 10, 20, 30, 0, time.UTC)},
+		{"DAY", time.Date(2023, time.January, 16, 10, 20, 30, 0, time.UTC)},
+		{"SECOND", start.Add(time.Second)},
+		{"MILLISECOND", start.Add(time.Millisecond)},
+		{"MICROSECOND", start.Add(time.Microsecond)},
+		{"", start.Add(time.Microsecond)},
+	}
+	for _, test := range tests {
+		if got := End(start, test.precision); !got.Equal(test.want) {
+			t.Errorf("End(%v, %q) = %v, want %v", start, test.precision, got, test.want)
+		}
+	}
+}
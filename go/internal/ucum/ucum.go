@@ -0,0 +1,433 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ucum parses UCUM unit expressions (https://ucum.org/ucum) and
+// computes the factors that convert between them.
+package ucum
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxExponent bounds the exponents of units, which keeps the factors of
+// expressions such as "10*999999" from growing without limit.
+const maxExponent = 99
+
+// A Unit is a UCUM unit as a multiple of a product of powers of base units.
+type Unit struct {
+	// Factor is the magnitude of the unit in its base units.
+	Factor *big.Rat
+	dims   map[string]int
+}
+
+// Commensurable reports whether u and o measure the same dimension, so that
+// a value in u can be converted to o by multiplying it by
+// u.Factor / o.Factor.
+func (u Unit) Commensurable(o Unit) bool {
+	if len(u.dims) != len(o.dims) {
+		return false
+	}
+	for d, n := range u.dims {
+		if o.dims[d] != n {
+			return false
+		}
+	}
+	return true
+}
+
+func one() Unit {
+	return Unit{Factor: big.NewRat(1, 1), dims: map[string]int{}}
+}
+
+// mul multiplies u by o raised to the power n.
+func (u Unit) mul(o Unit, n int) {
+	e := big.NewInt(int64(n))
+	num := new(big.Int).Exp(o.Factor.Num(), new(big.Int).Abs(e), nil)
+	den := new(big.Int).Exp(o.Factor.Denom(), new(big.Int).Abs(e), nil)
+	if n < 0 {
+		num, den = den, num
+	}
+	u.Factor.Mul(u.Factor, new(big.Rat).SetFrac(num, den))
+	for d, k := range o.dims {
+		u.dims[d] += k * n
+		if u.dims[d] == 0 {
+			delete(u.dims, d)
+		}
+	}
+}
+
+// An atom is a UCUM unit symbol defined as value times the unit expression
+// unit. Atoms with an empty unit are base units.
+type atom struct {
+	value  string
+	unit   string
+	metric bool
+}
+
+// The UCUM atoms and prefixes recognized by Parse. This is the subset of the
+// UCUM tables in common clinical use. Units on special, non-ratio scales,
+// arbitrary units and units whose definition involves pi are base units of
+// their own, and so only convert among themselves; so are the equivalent and
+// the osmole, whose conversion to moles depends on the substance.
+var (
+	prefixes = []struct {
+		symbol string
+		factor string
+	}{
+		{"Y", "1e24"}, {"Z", "1e21"}, {"E", "1e18"}, {"P", "1e15"}, {"T", "1e12"}, {"G", "1e9"},
+		{"M", "1e6"}, {"k", "1e3"}, {"h", "1e2"}, {"da", "1e1"}, {"d", "1e-1"}, {"c", "1e-2"},
+		{"m", "1e-3"}, {"u", "1e-6"}, {"n", "1e-9"}, {"p", "1e-12"}, {"f", "1e-15"}, {"a", "1e-18"},
+		{"z", "1e-21"}, {"y", "1e-24"},
+		{"Ki", "1024"}, {"Mi", "1048576"}, {"Gi", "1073741824"}, {"Ti", "1099511627776"},
+	}
+	atoms = map[string]atom{
+		// Metric units, which may be combined with a prefix.
+		"m":      {"1", "", true},
+		"s":      {"1", "", true},
+		"g":      {"1", "", true},
+		"rad":    {"1", "", true},
+		"K":      {"1", "", true},
+		"C":      {"1", "", true},
+		"cd":     {"1", "", true},
+		"mol":    {"1", "", true},
+		"eq":     {"1", "", true},
+		"osm":    {"1", "", true},
+		"Cel":    {"1", "", true},
+		"B":      {"1", "", true},
+		"Gb":     {"1", "", true},
+		"[iU]":   {"1", "", true},
+		"[CFU]":  {"1", "", true},
+		"[pfu]":  {"1", "", true},
+		"sr":     {"1", "rad2", true},
+		"Hz":     {"1", "s-1", true},
+		"N":      {"1", "kg.m/s2", true},
+		"Pa":     {"1", "N/m2", true},
+		"J":      {"1", "N.m", true},
+		"W":      {"1", "J/s", true},
+		"A":      {"1", "C/s", true},
+		"V":      {"1", "J/C", true},
+		"F":      {"1", "C/V", true},
+		"Ohm":    {"1", "V/A", true},
+		"S":      {"1", "Ohm-1", true},
+		"Wb":     {"1", "V.s", true},
+		"T":      {"1", "Wb/m2", true},
+		"H":      {"1", "Wb/A", true},
+		"lm":     {"1", "cd.sr", true},
+		"lx":     {"1", "lm/m2", true},
+		"Bq":     {"1", "s-1", true},
+		"Gy":     {"1", "J/kg", true},
+		"Sv":     {"1", "J/kg", true},
+		"L":      {"1", "dm3", true},
+		"l":      {"1", "dm3", true},
+		"t":      {"1000", "kg", true},
+		"bar":    {"100000", "Pa", true},
+		"u":      {"1.6605402e-24", "g", true},
+		"eV":     {"1.60217733e-19", "J", true},
+		"cal":    {"4.184", "J", true},
+		"kat":    {"1", "mol/s", true},
+		"U":      {"1", "umol/min", true},
+		"Ci":     {"37000000000", "Bq", true},
+		"R":      {"0.000258", "C/kg", true},
+		"P":      {"1", "dyn.s/cm2", true},
+		"St":     {"1", "cm2/s", true},
+		"m[Hg]":  {"133.322", "kPa", true},
+		"m[H2O]": {"9.80665", "kPa", true},
+		"[IU]":   {"1", "[iU]", true},
+		"bit":    {"1", "1", true},
+		"By":     {"8", "bit", true},
+		"Bd":     {"1", "s-1", true},
+		"dyn":    {"1", "g.cm/s2", true},
+		"erg":    {"1", "dyn.cm", true},
+		"G":      {"0.0001", "T", true},
+
+		// Other units, which may not.
+		"10*":         {"10", "1", false},
+		"10^":         {"10", "1", false},
+		"%":           {"1/100", "1", false},
+		"[ppth]":      {"1e-3", "1", false},
+		"[ppm]":       {"1e-6", "1", false},
+		"[ppb]":       {"1e-9", "1", false},
+		"[pptr]":      {"1e-12", "1", false},
+		"[pi]":        {"1", "", false},
+		"min":         {"60", "s", false},
+		"h":           {"60", "min", false},
+		"d":           {"24", "h", false},
+		"wk":          {"7", "d", false},
+		"a":           {"365.25", "d", false},
+		"mo":          {"1/12", "a", false},
+		"deg":         {"1", "", false},
+		"'":           {"1/60", "deg", false},
+		"''":          {"1/60", "'", false},
+		"[in_i]":      {"2.54", "cm", false},
+		"[ft_i]":      {"12", "[in_i]", false},
+		"[yd_i]":      {"3", "[ft_i]", false},
+		"[mi_i]":      {"5280", "[ft_i]", false},
+		"[lne]":       {"1/12", "[in_i]", false},
+		"[smoot]":     {"67", "[in_i]", false},
+		"[mesh_i]":    {"1", "/[in_i]", false},
+		"[Ch]":        {"1/3", "mm", false},
+		"[sin_i]":     {"1", "[in_i]2", false},
+		"[sft_i]":     {"1", "[ft_i]2", false},
+		"[cin_i]":     {"1", "[in_i]3", false},
+		"[cft_i]":     {"1", "[ft_i]3", false},
+		"ar":          {"100", "m2", false},
+		"[gal_us]":    {"231", "[cin_i]", false},
+		"[qt_us]":     {"1/4", "[gal_us]", false},
+		"[pt_us]":     {"1/2", "[qt_us]", false},
+		"[foz_us]":    {"1/16", "[pt_us]", false},
+		"[tbs_us]":    {"1/2", "[foz_us]", false},
+		"[tsp_us]":    {"1/3", "[tbs_us]", false},
+		"[cup_us]":    {"16", "[tbs_us]", false},
+		"[drp]":       {"1/20", "mL", false},
+		"[gr]":        {"64.79891", "mg", false},
+		"[lb_av]":     {"7000", "[gr]", false},
+		"[oz_av]":     {"1/16", "[lb_av]", false},
+		"[car_m]":     {"0.2", "g", false},
+		"[car_Au]":    {"1/24", "1", false},
+		"[lbf_av]":    {"9.80665", "[lb_av].m/s2", false},
+		"[psi]":       {"1", "[lbf_av]/[sin_i]", false},
+		"[mmHg]":      {"1", "mm[Hg]", false},
+		"atm":         {"101325", "Pa", false},
+		"[HP]":        {"550", "[ft_i].[lbf_av]/s", false},
+		"[S]":         {"1e-13", "s", false},
+		"[degR]":      {"5/9", "K", false},
+		"[degF]":      {"1", "", false},
+		"[pH]":        {"1", "", false},
+		"[HPF]":       {"1", "1", false},
+		"[LPF]":       {"100", "1", false},
+		"[hp'_X]":     {"1", "", false},
+		"[hp'_C]":     {"1", "", false},
+		"[kp_X]":      {"1", "", false},
+		"[kp_C]":      {"1", "", false},
+		"[arb'U]":     {"1", "", false},
+		"[USP'U]":     {"1", "", false},
+		"[beth'U]":    {"1", "", false},
+		"[mclg'U]":    {"1", "", false},
+		"[tb'U]":      {"1", "", false},
+		"[anti'Xa'U]": {"1", "", false},
+		"[todd'U]":    {"1", "", false},
+		"[dye'U]":     {"1", "", false},
+		"[knk'U]":     {"1", "", false},
+		"[mtp'U]":     {"1", "", false},
+		"[EID_50]":    {"1", "", false},
+		"[TCID_50]":   {"1", "", false},
+		"[PFU]":       {"1", "", false},
+		"[FFU]":       {"1", "", false},
+		"[BAU]":       {"1", "", false},
+		"[AU]":        {"1", "", false},
+		"[ELU]":       {"1", "", false},
+	}
+)
+
+// Parse parses the UCUM unit expression s, following the grammar of the UCUM
+// specification, and returns its unit. Every unit in s must be known.
+func Parse(s string) (Unit, error) {
+	if s == "" {
+		return Unit{}, fmt.Errorf("empty unit")
+	}
+	p := &parser{s: s}
+	n := 1
+	if p.peek() == '/' {
+		p.pos++
+		n = -1
+	}
+	u, err := p.term(n)
+	if err != nil {
+		return Unit{}, err
+	}
+	if p.pos < len(p.s) {
+		return Unit{}, p.errorf("unexpected %q", p.s[p.pos])
+	}
+	return u, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// term parses a sequence of components joined by "." or "/", the first of
+// which is raised to the power n.
+func (p *parser) term(n int) (Unit, error) {
+	u := one()
+	for {
+		c, err := p.component()
+		if err != nil {
+			return Unit{}, err
+		}
+		u.mul(c, n)
+		switch p.peek() {
+		case '.':
+			n = 1
+		case '/':
+			n = -1
+		default:
+			return u, nil
+		}
+		p.pos++
+	}
+}
+
+// component parses a factor, an annotation, a parenthesized term or a unit
+// with an optional exponent and annotation.
+func (p *parser) component() (Unit, error) {
+	switch c := p.peek(); {
+	case c == 0 || c == '.' || c == '/' || c == ')':
+		return Unit{}, p.errorf("missing unit")
+	case c == '{':
+		return one(), p.annotation()
+	case c == '(':
+		p.pos++
+		u, err := p.term(1)
+		if err != nil {
+			return Unit{}, err
+		}
+		if p.peek() != ')' {
+			return Unit{}, p.errorf("missing ')'")
+		}
+		p.pos++
+		return u, nil
+	case isDigit(c) && !strings.HasPrefix(p.s[p.pos:], "10*") && !strings.HasPrefix(p.s[p.pos:], "10^"):
+		start := p.pos
+		for isDigit(p.peek()) {
+			p.pos++
+		}
+		f, _ := new(big.Rat).SetString(p.s[start:p.pos])
+		return Unit{Factor: f, dims: map[string]int{}}, nil
+	}
+	a, err := p.simpleUnit()
+	if err != nil {
+		return Unit{}, err
+	}
+	start := p.pos
+	if c := p.peek(); c == '+' || c == '-' {
+		p.pos++
+		if !isDigit(p.peek()) {
+			return Unit{}, p.errorf("missing exponent")
+		}
+	}
+	for isDigit(p.peek()) {
+		p.pos++
+	}
+	exp := 1
+	if p.pos > start {
+		if exp, err = strconv.Atoi(p.s[start:p.pos]); err != nil || exp > maxExponent || exp < -maxExponent {
+			return Unit{}, p.errorf("invalid exponent %q", p.s[start:p.pos])
+		}
+	}
+	u := one()
+	u.mul(a, exp)
+	if p.peek() == '{' {
+		return u, p.annotation()
+	}
+	return u, nil
+}
+
+// simpleUnit parses a unit symbol, optionally prefixed.
+func (p *parser) simpleUnit() (Unit, error) {
+	start := p.pos
+	if strings.HasPrefix(p.s[p.pos:], "10*") || strings.HasPrefix(p.s[p.pos:], "10^") {
+		p.pos += 3
+	}
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if c == '[' {
+			end := strings.IndexByte(p.s[p.pos:], ']')
+			if end < 0 {
+				return Unit{}, p.errorf("missing ']'")
+			}
+			p.pos += end + 1
+			continue
+		}
+		if strings.IndexByte("./(){}+-", c) >= 0 || isDigit(c) || c <= ' ' || c > '~' {
+			break
+		}
+		p.pos++
+	}
+	sym := p.s[start:p.pos]
+	if sym == "" {
+		return Unit{}, p.errorf("unexpected %q", p.peek())
+	}
+	return lookup(sym)
+}
+
+// annotation parses a curly-braced annotation.
+func (p *parser) annotation() error {
+	p.pos++
+	for p.pos < len(p.s) && p.s[p.pos] != '}' {
+		if c := p.s[p.pos]; c < '!' || c > '~' || c == '{' {
+			return p.errorf("invalid character %q in annotation", c)
+		}
+		p.pos++
+	}
+	if p.pos == len(p.s) {
+		return p.errorf("missing '}'")
+	}
+	p.pos++
+	return nil
+}
+
+// lookup returns the unit of the possibly prefixed symbol sym.
+func lookup(sym string) (Unit, error) {
+	if a, ok := atoms[sym]; ok {
+		return a.resolve(sym), nil
+	}
+	for _, prefix := range prefixes {
+		if a, ok := atoms[strings.TrimPrefix(sym, prefix.symbol)]; ok && a.metric && strings.HasPrefix(sym, prefix.symbol) {
+			u := a.resolve(sym[len(prefix.symbol):])
+			f, _ := new(big.Rat).SetString(prefix.factor)
+			u.Factor.Mul(u.Factor, f)
+			return u, nil
+		}
+	}
+	return Unit{}, fmt.Errorf("unknown unit %q", sym)
+}
+
+// resolve returns the unit of a, whose symbol is sym, in base units.
+func (a atom) resolve(sym string) Unit {
+	u := one()
+	if a.unit == "" {
+		u.dims[sym] = 1
+	} else {
+		def, err := Parse(a.unit)
+		if err != nil {
+			panic(fmt.Sprintf("invalid definition of %q: %v", sym, err))
+		}
+		u = def
+	}
+	f, ok := new(big.Rat).SetString(a.value)
+	if !ok {
+		panic(fmt.Sprintf("invalid value of %q: %q", sym, a.value))
+	}
+	u.Factor.Mul(u.Factor, f)
+	return u
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ucum
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		"mg", "mg/dL", "mmol/L", "10*3/uL", "10*9/L", "/min", "kg/m2", "m2", "cm3", "s-1", "%",
+		"[in_i]", "[lb_av]", "mm[Hg]", "Cel", "[degF]", "1", "{cells}/uL", "mL{total}",
+		"mL/min/{1.73_m2}", "kg.m/s2", "(kg.m)/s2", "[IU]/L", "ug/(kg.h)", "dB", "Ki[IU]", "daL",
+	}
+	for _, u := range valid {
+		if _, err := Parse(u); err != nil {
+			t.Errorf("Parse(%q) failed: %v", u, err)
+		}
+	}
+	invalid := []string{
+		"", "mg//dL", "mg/", "/", "mg dL", "(mg/dL", "kg)", "xyz", "mg{x", "[in_i", "m-", "kmin", "10*3/uL/",
+		"10*999",
+	}
+	for _, u := range invalid {
+		if _, err := Parse(u); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", u)
+		}
+	}
+}
+
+func TestParse_Factors(t *testing.T) {
+	tests := []struct {
+		from, to string
+		factor   string
+	}{
+		{"g", "mg", "1000"},
+		{"mg/dL", "g/L", "1/100"},
+		{"m2", "cm2", "10000"},
+		{"L", "cm3", "1000"},
+		{"h", "min", "60"},
+		{"/min", "/h", "60"},
+		{"ug/(kg.h)", "ug/kg/h", "1"},
+		{"[lb_av]", "g", "45359237/100000"},
+		{"[gal_us]", "[foz_us]", "128"},
+		{"10*3/uL", "10*9/L", "1"},
+		{"kPa", "mm[Hg]", "1000000/133322"},
+		{"[IU]/L", "[iU]/mL", "1/1000"},
+		{"{beats}/min", "/s", "1/60"},
+		{"%", "1", "1/100"},
+		{"N", "kg.m/s2", "1"},
+	}
+	for _, test := range tests {
+		from, err := Parse(test.from)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", test.from, err)
+		}
+		to, err := Parse(test.to)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", test.to, err)
+		}
+		if !from.Commensurable(to) {
+			t.Errorf("%q and %q are not commensurable", test.from, test.to)
+			continue
+		}
+		want, _ := new(big.Rat).SetString(test.factor)
+		if got := new(big.Rat).Quo(from.Factor, to.Factor); got.Cmp(want) != 0 {
+			t.Errorf("%q in %q = %v, want %v", test.from, test.to, got, want)
+		}
+	}
+}
+
+func TestParse_NotCommensurable(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"g", "mL"},
+		{"mg/dL", "mmol/L"},
+		{"Cel", "K"},
+		{"Cel", "[degF]"},
+		{"[iU]", "[arb'U]"},
+		{"meq", "mmol"},
+	}
+	for _, test := range tests {
+		a, err := Parse(test.a)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", test.a, err)
+		}
+		b, err := Parse(test.b)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", test.b, err)
+		}
+		if a.Commensurable(b) {
+			t.Errorf("%q and %q are commensurable, want not", test.a, test.b)
+		}
+	}
+}
+
+func TestAtoms(t *testing.T) {
+	for sym := range atoms {
+		if _, err := lookup(sym); err != nil {
+			t.Errorf("lookup(%q) failed: %v", sym, err)
+		}
+	}
+}
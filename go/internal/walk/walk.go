@@ -0,0 +1,119 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package walk traverses the elements of FHIR protos, tracking the FHIR
+// element path of each.
+package walk
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/fhir/go/internal/element"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+// Func is called for each element visited by Walk with its FHIR element
+// path, e.g. "Patient.identifier[0].value". Returning an error stops the
+// walk.
+type Func func(path string, m protoreflect.Message) error
+
+// Walk visits msg and every element nested within it, depth first and parents
+// before children. The path of the root is its resource type, or its message
+// name for other elements. Choice elements are visited as their active value
+// under the type-suffixed name, e.g. "Observation.valueQuantity", and
+// ContainedResource wrappers are visited as the resource they hold.
+//
+// Resources contained in an Any are unpacked and visited too; if fn modifies
+// such a resource, it is packed back into its Any. fn may modify the fields
+// of the element it is given but must not clear the element itself.
+func Walk(msg proto.Message, fn Func) error {
+	rm := element.UnwrapContained(msg.ProtoReflect())
+	if rm == nil {
+		return nil
+	}
+	return walk(string(rm.Descriptor().Name()), rm, fn)
+}
+
+func walk(path string, m protoreflect.Message, fn Func) error {
+	if err := fn(path, m); err != nil {
+		return err
+	}
+	var err error
+	m.Range(func(f protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if f.Message() == nil {
+			return true
+		}
+		name := f.JSONName()
+		if f.IsList() {
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				if err = walkValue(fmt.Sprintf("%s.%s[%d]", path, name, i), l.Get(i).Message(), fn); err != nil {
+					return false
+				}
+			}
+			return true
+		}
+		err = walkValue(path+"."+name, v.Message(), fn)
+		return err == nil
+	})
+	return err
+}
+
+// walkValue visits a field value, unwrapping choice types, contained
+// resources and Any.
+func walkValue(path string, m protoreflect.Message, fn Func) error {
+	d := m.Descriptor()
+	if proto.GetExtension(d.Options(), apb.E_IsChoiceType).(bool) {
+		active := m.WhichOneof(d.Oneofs().Get(0))
+		if active == nil {
+			return nil
+		}
+		name := active.JSONName()
+		return walk(path+strings.ToUpper(name[:1])+name[1:], m.Get(active).Message(), fn)
+	}
+	if a, ok := m.Interface().(*anypb.Any); ok {
+		return walkAny(path, a, fn)
+	}
+	if inner := element.UnwrapContained(m); inner != m {
+		if inner == nil {
+			return nil
+		}
+		return walk(path, inner, fn)
+	}
+	return walk(path, m, fn)
+}
+
+// walkAny visits the resource packed in a, repacking it if fn modified it.
+func walkAny(path string, a *anypb.Any, fn Func) error {
+	packed, err := a.UnmarshalNew()
+	if err != nil {
+		return fmt.Errorf("%s: unpacking %s: %w", path, a.GetTypeUrl(), err)
+	}
+	before := proto.Clone(packed)
+	if err := walkValue(path, packed.ProtoReflect(), fn); err != nil {
+		return err
+	}
+	if proto.Equal(before, packed) {
+		return nil
+	}
+	if err := a.MarshalFrom(packed); err != nil {
+		return fmt.Errorf("%s: repacking %s: %w", path, a.GetTypeUrl(), err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package walk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4organizationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/organization_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+func containedOrganization(t *testing.T, name string) *anypb.Any {
+	t.Helper()
+	a, err := anypb.New(&r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Organization{
+			Organization: &r4organizationpb.Organization{Name: &d4pb.String{Value: name}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	return a
+}
+
+func TestWalk_Paths(t *testing.T) {
+	obs := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Observation{
+			Observation: &r4observationpb.Observation{
+				Contained: []*anypb.Any{containedOrganization(t, "Acme")},
+				Value: &r4observationpb.Observation_ValueX{
+					Choice: &r4observationpb.Observation_ValueX_StringValue{
+						StringValue: &d4pb.String{Value: "high"},
+					},
+				},
+				Note: []*d4pb.Annotation{
+					{Text: &d4pb.Markdown{Value: "first"}},
+					{Text: &d4pb.Markdown{Value: "second"}},
+				},
+			},
+		},
+	}
+	var got []string
+	err := Walk(obs, func(path string, _ protoreflect.Message) error {
+		got = append(got, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() failed: %v", err)
+	}
+	want := []string{
+		"Observation",
+		"Observation.contained[0]",
+		"Observation.contained[0].name",
+		"Observation.valueString",
+		"Observation.note[0]",
+		"Observation.note[0].text",
+		"Observation.note[1]",
+		"Observation.note[1].text",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Walk() visited unexpected paths (-want +got):\n%s", diff)
+	}
+}
+
+func TestWalk_RepacksModifiedContained(t *testing.T) {
+	obs := &r4observationpb.Observation{
+		Contained: []*anypb.Any{containedOrganization(t, "Acme")},
+	}
+	err := Walk(obs, func(path string, m protoreflect.Message) error {
+		if s, ok := m.Interface().(*d4pb.String); ok {
+			s.Value = "Redacted"
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() failed: %v", err)
+	}
+	cr := &r4pb.ContainedResource{}
+	if err := obs.GetContained()[0].UnmarshalTo(cr); err != nil {
+		t.Fatalf("UnmarshalTo() failed: %v", err)
+	}
+	if got := cr.GetOrganization().GetName().GetValue(); got != "Redacted" {
+		t.Errorf("contained organization name = %q, want %q", got, "Redacted")
+	}
+}
+
+func TestWalk_StopsOnError(t *testing.T) {
+	errStop := errors.New("stop")
+	visited := 0
+	err := Walk(&r4observationpb.Observation{Note: []*d4pb.Annotation{{}, {}}}, func(string, protoreflect.Message) error {
+		visited++
+		if visited == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) || visited != 2 {
+		t.Errorf("Walk() = %v after %d visits, want %v after 2", err, visited, errStop)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"runtime"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// UnmarshalConcurrent unmarshals each of inputs with u, as by Unmarshal, on a
+// pool of workers goroutines, or runtime.GOMAXPROCS(0) of them if workers is
+// zero or less. The results are in input order: msgs[i] and errs[i] are the
+// result of unmarshalling inputs[i], and errs[i] is nil on success. An
+// Unmarshaller is not modified by unmarshalling, so u is shared by the
+// workers.
+func UnmarshalConcurrent(u *Unmarshaller, inputs [][]byte, workers int) (msgs []proto.Message, errs []error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(inputs) {
+		workers = len(inputs)
+	}
+	msgs = make([]proto.Message, len(inputs))
+	errs = make([]error, len(inputs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				msgs[i], errs[i] = u.Unmarshal(inputs[i])
+			}
+		}()
+	}
+	for i := range inputs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return msgs, errs
+}
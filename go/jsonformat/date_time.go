@@ -18,6 +18,7 @@ package jsonformat
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/google/fhir/go/jsonformat/internal/accessor"
@@ -440,3 +441,65 @@ func SerializeInstant(instant proto.Message) (string, error) {
 		return "", fmt.Errorf("invalid instant precision: %v", precision)
 	}
 }
+
+// clockOverflowRegex matches a time of day of 24:00:00 or one with a leap
+// second, either on its own or in a dateTime or instant. The submatches hold
+// the fraction of an end-of-day time, or the hour, minute and fraction of a
+// leap second.
+var clockOverflowRegex = regexp.MustCompile(`(?:^|T)(?:24:00:00(\.0+)?|([01][0-9]|2[0-3]):([0-5][0-9]):60(\.[0-9]+)?)`)
+
+// normalizeClockOverflow rewrites a time, dateTime or instant in rm that
+// uses 24:00:00 or a leap second into one the parsers accept, returning it
+// with the duration to add once it is parsed: 24:00:00 becomes 00:00:00 of
+// the same day, to which a day is added, and a leap second becomes second 59,
+// to which a second is added. Values without either are returned unchanged.
+func normalizeClockOverflow(rm json.RawMessage) (json.RawMessage, time.Duration, error) {
+	var s string
+	if err := jsonpbhelper.JSP.Unmarshal(rm, &s); err != nil {
+		// Leave the error to the parser.
+		return rm, 0, nil
+	}
+	m := clockOverflowRegex.FindStringSubmatchIndex(s)
+	if m == nil || (m[1] < len(s) && (s[m[1]] == '.' || s[m[1]] >= '0' && s[m[1]] <= '9')) {
+		// A time such as 24:00:00.1 is past the end of the day.
+		return rm, 0, nil
+	}
+	group := func(i int) string {
+		if m[2*i] < 0 {
+			return ""
+		}
+		return s[m[2*i]:m[2*i+1]]
+	}
+	// Keep the "T" of a dateTime or instant.
+	start := m[0]
+	if s[start] == 'T' {
+		start++
+	}
+	var clock string
+	var extra time.Duration
+	if group(2) == "" {
+		clock, extra = "00:00:00"+group(1), 24*time.Hour
+	} else {
+		clock, extra = group(2)+":"+group(3)+":59"+group(4), time.Second
+	}
+	out, err := jsonpbhelper.JSP.Marshal(s[:start] + clock + s[m[1]:])
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, extra, nil
+}
+
+// addToValueUs adds d to the value_us of a Time, DateTime or Instant m. For
+// a Time, the result wraps around to the start of the day.
+func addToValueUs(m proto.Message, d time.Duration, wrapDay bool) error {
+	rm := m.ProtoReflect()
+	us, err := accessor.GetInt64(rm, "value_us")
+	if err != nil {
+		return err
+	}
+	us += d.Microseconds()
+	if wrapDay {
+		us %= (24 * time.Hour).Microseconds()
+	}
+	return accessor.SetValue(rm, us, "value_us")
+}
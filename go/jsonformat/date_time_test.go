@@ -20,6 +20,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/fhir/go/fhirversion"
 	"github.com/google/fhir/go/jsonformat/internal/accessor"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/proto"
@@ -607,3 +608,78 @@ func TestSerializeInstant_Invalid(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeClockOverflow(t *testing.T) {
+	march16 := time.Date(2023, 3, 16, 0, 0, 0, 0, time.UTC).UnixMicro()
+	tests := []struct {
+		name string
+		json string
+		want proto.Message
+	}{
+		{
+			name: "end of day time",
+			json: `"24:00:00"`,
+			want: &d4pb.Time{ValueUs: 0, Precision: d4pb.Time_SECOND},
+		},
+		{
+			name: "leap second time",
+			json: `"23:59:60.5"`,
+			want: &d4pb.Time{ValueUs: 500000, Precision: d4pb.Time_MILLISECOND},
+		},
+		{
+			name: "end of day dateTime",
+			json: `"2023-03-15T24:00:00Z"`,
+			want: &d4pb.DateTime{ValueUs: march16, Timezone: "Z", Precision: d4pb.DateTime_SECOND},
+		},
+		{
+			name: "end of day dateTime with offset",
+			json: `"2023-03-15T24:00:00.000+01:00"`,
+			want: &d4pb.DateTime{ValueUs: march16 - time.Hour.Microseconds(), Timezone: "+01:00", Precision: d4pb.DateTime_MILLISECOND},
+		},
+		{
+			name: "leap second instant",
+			json: `"2023-03-15T23:59:60Z"`,
+			want: &d4pb.Instant{ValueUs: march16, Timezone: "Z", Precision: d4pb.Instant_SECOND},
+		},
+	}
+	strict, err := NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("NewUnmarshaller() failed: %v", err)
+	}
+	lenient, err := NewUnmarshaller("UTC", fhirversion.R4, NormalizeClockOverflow(true))
+	if err != nil {
+		t.Fatalf("NewUnmarshaller() failed: %v", err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := test.want.ProtoReflect()
+			if _, err := strict.parsePrimitiveType("value", in, json.RawMessage(test.json)); err == nil {
+				t.Errorf("parsePrimitiveType(%s) succeeded by default, want error", test.json)
+			}
+			got, err := lenient.parsePrimitiveType("value", in, json.RawMessage(test.json))
+			if err != nil {
+				t.Fatalf("parsePrimitiveType(%s) failed: %v", test.json, err)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("parsePrimitiveType(%s) returned unexpected diff (-want +got):\n%s", test.json, diff)
+			}
+		})
+	}
+}
+
+func TestNormalizeClockOverflow_Invalid(t *testing.T) {
+	u, err := NewUnmarshaller("UTC", fhirversion.R4, NormalizeClockOverflow(true))
+	if err != nil {
+		t.Fatalf("NewUnmarshaller() failed: %v", err)
+	}
+	for _, test := range []string{`"24:00:01"`, `"24:30:00"`, `"23:60:00"`, `"24:00:00.1"`} {
+		if _, err := u.parsePrimitiveType("value", (&d4pb.Time{}).ProtoReflect(), json.RawMessage(test)); err == nil {
+			t.Errorf("parsePrimitiveType(%s) succeeded, want error", test)
+		}
+	}
+	for _, test := range []string{`"2023-03-15T24:00:00.1Z"`, `"2023-03-15T23:59:61Z"`} {
+		if _, err := u.parsePrimitiveType("value", (&d4pb.DateTime{}).ProtoReflect(), json.RawMessage(test)); err == nil {
+			t.Errorf("parsePrimitiveType(%s) succeeded, want error", test)
+		}
+	}
+}
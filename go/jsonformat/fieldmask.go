@@ -0,0 +1,172 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/fhir/go/jsonformat/internal/jsonpbhelper"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+)
+
+// maskTree holds the paths of a field mask by proto field name. A nil subtree
+// keeps the whole field.
+type maskTree map[protoreflect.Name]maskTree
+
+func (t maskTree) add(path []string) {
+	for i, name := range path {
+		n := protoreflect.Name(name)
+		if i == len(path)-1 {
+			t[n] = nil
+			return
+		}
+		child, ok := t[n]
+		if ok && child == nil {
+			// The whole field is already kept.
+			return
+		}
+		if !ok {
+			child = maskTree{}
+			t[n] = child
+		}
+		t = child
+	}
+}
+
+// validate checks that every path in t names a field of messages of type d.
+func (t maskTree) validate(d protoreflect.MessageDescriptor) error {
+	for name, sub := range t {
+		f := d.Fields().ByName(name)
+		if f == nil {
+			return fmt.Errorf("no field %q in %v", name, d.FullName())
+		}
+		if sub == nil {
+			continue
+		}
+		if f.Message() == nil {
+			return fmt.Errorf("field %q of %v has no subfields", name, d.FullName())
+		}
+		if err := sub.validate(f.Message()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addElement adds the dotted FHIR element path, e.g. "name.family", to t,
+// resolving each element by its JSON name within messages of type d.
+func (t maskTree) addElement(d protoreflect.MessageDescriptor, path string) error {
+	var names []string
+	for _, elem := range strings.Split(path, ".") {
+		if d == nil {
+			return fmt.Errorf("element %q has no child elements", strings.Join(names, "."))
+		}
+		f := d.Fields().ByJSONName(elem)
+		if f == nil {
+			return fmt.Errorf("no element %q in %v", elem, d.FullName())
+		}
+		names = append(names, string(f.Name()))
+		d = f.Message()
+	}
+	t.add(names)
+	return nil
+}
+
+// addRequired adds the fields required by FHIR to t and to each partially
+// kept message within it, so that the filtered output stays valid.
+func (t maskTree) addRequired(d protoreflect.MessageDescriptor) {
+	fields := d.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.Get(i)
+		if _, ok := t[f.Name()]; ok {
+			continue
+		}
+		if proto.GetExtension(f.Options(), apb.E_ValidationRequirement).(apb.Requirement) == apb.Requirement_REQUIRED_BY_FHIR {
+			t[f.Name()] = nil
+		}
+	}
+	for name, sub := range t {
+		if sub != nil {
+			sub.addRequired(fields.ByName(name).Message())
+		}
+	}
+}
+
+// prune clears the fields of m that are not in t.
+func (t maskTree) prune(m protoreflect.Message) {
+	var clear []protoreflect.FieldDescriptor
+	m.Range(func(f protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		sub, ok := t[f.Name()]
+		switch {
+		case !ok:
+			clear = append(clear, f)
+		case sub == nil:
+		case f.IsList():
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				sub.prune(l.Get(i).Message())
+			}
+		default:
+			sub.prune(v.Message())
+		}
+		return true
+	})
+	for _, f := range clear {
+		m.Clear(f)
+	}
+}
+
+// applyFieldMask returns a copy of the resource pb, which may be wrapped in a
+// ContainedResource, holding only the fields in the marshaller's field mask or
+// elements list and the id, or pb itself if neither is set.
+func (m *Marshaller) applyFieldMask(pb proto.Message) (proto.Message, error) {
+	if m.fieldMask == nil && m.elements == nil {
+		return pb, nil
+	}
+	out := proto.Clone(pb)
+	rm := out.ProtoReflect()
+	if od := rm.Descriptor().Oneofs().ByName(jsonpbhelper.OneofName); od != nil {
+		f := rm.WhichOneof(od)
+		if f == nil {
+			// Let marshalling report the empty ContainedResource.
+			return out, nil
+		}
+		rm = rm.Mutable(f).Message()
+	}
+	t := maskTree{}
+	if m.fieldMask != nil {
+		for _, p := range m.fieldMask.GetPaths() {
+			t.add(strings.Split(p, "."))
+		}
+		if err := t.validate(rm.Descriptor()); err != nil {
+			return nil, fmt.Errorf("invalid field mask: %w", err)
+		}
+	}
+	if m.elements != nil {
+		for _, e := range m.elements {
+			if err := t.addElement(rm.Descriptor(), e); err != nil {
+				return nil, fmt.Errorf("invalid elements: %w", err)
+			}
+		}
+		t.addRequired(rm.Descriptor())
+	}
+	t["id"] = nil
+	t.prune(rm)
+	return out, nil
+}
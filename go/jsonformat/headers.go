@@ -0,0 +1,47 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"net/http"
+
+	"github.com/google/fhir/go/meta"
+	"google.golang.org/protobuf/proto"
+)
+
+// MarshalWithHeaders marshals msg, either a resource or a ContainedResource,
+// with m and returns the HTTP headers describing the version it holds: a weak
+// ETag from meta.versionId, e.g. W/"3", and a Last-Modified date from
+// meta.lastUpdated, as given by meta.ETag and meta.LastUpdated. Each header
+// is omitted if its meta element is missing or empty.
+func MarshalWithHeaders(m *Marshaller, msg proto.Message) (body []byte, headers map[string]string, err error) {
+	if msg.ProtoReflect().Descriptor().Name() == containedResourceProtoName(m.cfg) {
+		body, err = m.Marshal(msg)
+	} else {
+		body, err = m.MarshalResource(msg)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	headers = map[string]string{}
+	if etag, ok := meta.ETag(msg); ok {
+		headers["ETag"] = etag
+	}
+	if t, ok := meta.LastUpdated(msg); ok {
+		headers["Last-Modified"] = t.UTC().Format(http.TimeFormat)
+	}
+	return body, headers, nil
+}
+
@@ -1075,8 +1075,10 @@ func findAllReferencedMessageTypes(root protoreflect.MessageDescriptor, onVisit
 }
 
 // UnmarshalCode interprets `rm` as a value of the enum that is the same type
-// as `in`. `in` will not be modified.
-func UnmarshalCode(jsonPath string, in protoreflect.Message, rm json.RawMessage) (proto.Message, error) {
+// as `in`. `in` will not be modified. Codes are matched to enum value names in
+// any case, and to original codes, such as "biologicalAgent", exactly unless
+// ignoreCase is true.
+func UnmarshalCode(jsonPath string, in protoreflect.Message, rm json.RawMessage, ignoreCase bool) (proto.Message, error) {
 	d := in.Descriptor()
 	f := d.Fields().ByName("value")
 	if f == nil {
@@ -1114,7 +1116,7 @@ func UnmarshalCode(jsonPath string, in protoreflect.Message, rm json.RawMessage)
 		for i := 0; i < values.Len(); i++ {
 			ev := values.Get(i)
 			origCode := proto.GetExtension(ev.Options(), apb.E_FhirOriginalCode).(string)
-			if origCode == val {
+			if origCode == val || ignoreCase && origCode != "" && strings.EqualFold(origCode, val) {
 				pb.Set(f, protoreflect.ValueOf(ev.Number()))
 				return pb.Interface().(proto.Message), nil
 			}
@@ -25,6 +25,9 @@ import (
 const (
 	// ContainedField is the JSON field name of inline resources.
 	ContainedField = "contained"
+	// ParameterResourceField is the JSON field name of the resource of a
+	// Parameters parameter, which is held in an Any like contained resources.
+	ParameterResourceField = "resource"
 	// ResourceTypeField constant.
 	ResourceTypeField = "resourceType"
 	// OneofName field constant.
@@ -33,6 +36,12 @@ const (
 	Extension = "extension"
 )
 
+// IsInlineResourceField reports whether an Any field with the given JSON name
+// holds an inline resource.
+func IsInlineResourceField(jsonName string) bool {
+	return jsonName == ContainedField || jsonName == ParameterResourceField
+}
+
 // IsJSON defines JSON related interface.
 type IsJSON interface {
 	// IsJSON method.
@@ -18,9 +18,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/internal/element"
 	"github.com/google/fhir/go/jsonformat/internal/accessor"
 	"github.com/google/fhir/go/jsonformat/internal/jsonpbhelper"
 	"golang.org/x/exp/maps"
@@ -28,6 +31,7 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 
 	anypb "google.golang.org/protobuf/types/known/anypb"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
 	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
 )
 
@@ -76,50 +80,186 @@ type Marshaller struct {
 	// If true, the resourceType field will be populated in the output JSON.
 	// This is enabled for the pure format and contained resources in AnalyticsV2.
 	includeResourceType bool
+	// If true, the contained field of resources is omitted from the output.
+	omitContained bool
+	// If set, called for each primitive element to allow replacing its value.
+	fieldHook FieldHookFunc
+	// If set, generates ids for resources written without one.
+	newID func() string
+	// If true, newID also applies to contained resources.
+	assignContainedIDs bool
+	// If true, decimals are written as JSON strings rather than numbers.
+	decimalAsString bool
+	// If set, only the fields of the top-level resource in the mask are
+	// written.
+	fieldMask *fieldmaskpb.FieldMask
+	// If set, only these elements of the top-level resource, and the
+	// elements FHIR requires alongside them, are written.
+	elements []string
+	// If true, extension and modifierExtension arrays are written sorted by
+	// url.
+	sortExtensionsByURL bool
+	// If set, generates the narrative of the top-level resource.
+	narrativeGenerator NarrativeGeneratorFunc
+	// If true, narrativeGenerator also replaces existing narratives.
+	regenerateNarrative bool
+	// If oldBase is set, references starting with it are written with newBase
+	// in its place.
+	oldBase, newBase string
+	// The FHIR path of the element being marshalled, tracked only when
+	// fieldHook is set.
+	path []string
+}
+
+// A MarshallerOption configures a Marshaller.
+type MarshallerOption func(*Marshaller)
+
+// OmitContained drops the contained field from every resource when omit is
+// true. References into the dropped resources are left untouched.
+func OmitContained(omit bool) MarshallerOption {
+	return func(m *Marshaller) {
+		m.omitContained = omit
+	}
+}
+
+// FieldHookFunc is called with the path and value of each primitive element
+// being marshalled. path is the FHIR element path, with indices for repeated
+// elements, e.g. "Patient.identifier[1].value". value holds the primitive
+// element message, e.g. a String. Returning a replacement message of the same
+// type and true substitutes it in the output.
+type FieldHookFunc func(path string, value protoreflect.Value) (protoreflect.Value, bool)
+
+// FieldHook installs hook to transform primitive values as they are written,
+// for example to redact identifiers. Only the output is affected; the
+// marshalled proto is not modified.
+func FieldHook(hook FieldHookFunc) MarshallerOption {
+	return func(m *Marshaller) {
+		m.fieldHook = hook
+	}
+}
+
+// AssignMissingIDs writes an id generated by newID for a resource that has
+// none, for servers that require ids. Only the top-level resource is given an
+// id, unless AssignMissingContainedIDs is also set. The marshalled proto is not
+// modified.
+func AssignMissingIDs(newID func() string) MarshallerOption {
+	return func(m *Marshaller) {
+		m.newID = newID
+	}
+}
+
+// AssignMissingContainedIDs extends AssignMissingIDs to contained resources
+// when assign is true.
+func AssignMissingContainedIDs(assign bool) MarshallerOption {
+	return func(m *Marshaller) {
+		m.assignContainedIDs = assign
+	}
+}
+
+// DecimalAsString writes decimal primitives as quoted JSON strings, e.g.
+// "1.50", when asString is true, for consumers that would otherwise parse them
+// as lossy floating point numbers. The exact lexical value is kept either way.
+// The Unmarshaller accepts both forms.
+func DecimalAsString(asString bool) MarshallerOption {
+	return func(m *Marshaller) {
+		m.decimalAsString = asString
+	}
+}
+
+// FieldMask writes only the fields of the resource named by mask, for partial
+// representations such as PATCH bodies. Paths are relative to the resource
+// and use proto field names, e.g. "name" or "name.family"; a path through a
+// repeated field applies to each of its elements. The resourceType and id are
+// always written. The marshalled proto is not modified.
+func FieldMask(mask *fieldmaskpb.FieldMask) MarshallerOption {
+	return func(m *Marshaller) {
+		m.fieldMask = mask
+	}
+}
+
+// Elements writes only the given elements of the resource, as for the FHIR
+// _elements search parameter. Elements are named as in JSON and may be dotted
+// paths into complex elements, e.g. "name.family", in which case the parent
+// structure is kept and pruned to the named children; a path through a
+// repeated element applies to each of its values. Elements that FHIR
+// requires, at the top level and within each partially kept element, are
+// always written, as are the resourceType and id. The marshalled proto is not
+// modified.
+func Elements(elements ...string) MarshallerOption {
+	return func(m *Marshaller) {
+		m.elements = append([]string{}, elements...)
+	}
+}
+
+// SortExtensionsByURL writes every extension and modifierExtension array
+// stably sorted by url when sort is true, so that resources differing only in
+// the order of their extensions marshal identically, e.g. for reproducible
+// test output. Extensions without a url sort first. The marshalled proto is
+// not modified.
+func SortExtensionsByURL(sort bool) MarshallerOption {
+	return func(m *Marshaller) {
+		m.sortExtensionsByURL = sort
+	}
+}
+
+// RebaseReferences writes references that start with oldBase with newBase in
+// its place, e.g. to export resources to another server. oldBase is matched
+// as a plain prefix of the reference, so it should normally end with a slash;
+// relative and internal references are left alone. Only the output is
+// affected; the marshalled proto is not modified.
+func RebaseReferences(oldBase, newBase string) MarshallerOption {
+	return func(m *Marshaller) {
+		m.oldBase = oldBase
+		m.newBase = newBase
+	}
 }
 
 // NewMarshaller returns a Marshaller.
-func NewMarshaller(enableIndent bool, prefix, indent string, ver fhirversion.Version) (*Marshaller, error) {
+func NewMarshaller(enableIndent bool, prefix, indent string, ver fhirversion.Version, opts ...MarshallerOption) (*Marshaller, error) {
 	cfg, err := getConfig(ver)
 	if err != nil {
 		return nil, err
 	}
-	return &Marshaller{
+	m := &Marshaller{
 		enableIndent:        enableIndent,
 		prefix:              prefix,
 		jsonFormat:          formatPure,
 		indent:              indent,
 		cfg:                 cfg,
 		includeResourceType: true,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
 }
 
 // NewPrettyMarshaller returns a pretty Marshaller.
-func NewPrettyMarshaller(ver fhirversion.Version) (*Marshaller, error) {
-	return NewMarshaller(true, "", "  ", ver)
+func NewPrettyMarshaller(ver fhirversion.Version, opts ...MarshallerOption) (*Marshaller, error) {
+	return NewMarshaller(true, "", "  ", ver, opts...)
 }
 
 // NewAnalyticsMarshaller returns an Analytics Marshaller with limited support
 // for extensions. A default maxDepth of 2 will be used if the input is 0.
-func NewAnalyticsMarshaller(maxDepth int, ver fhirversion.Version) (*Marshaller, error) {
-	return newAnalyticsMarshaller(maxDepth, ver, formatAnalytic)
+func NewAnalyticsMarshaller(maxDepth int, ver fhirversion.Version, opts ...MarshallerOption) (*Marshaller, error) {
+	return newAnalyticsMarshaller(maxDepth, ver, formatAnalytic, opts...)
 }
 
 // NewAnalyticsMarshallerWithInferredSchema returns an Analytics Marshaller with
 // support for extensions as first class fields. A default maxDepth of 2 will be
 // used if the input is 0.
-func NewAnalyticsMarshallerWithInferredSchema(maxDepth int, ver fhirversion.Version) (*Marshaller, error) {
-	return newAnalyticsMarshaller(maxDepth, ver, formatAnalyticWithInferredSchema)
+func NewAnalyticsMarshallerWithInferredSchema(maxDepth int, ver fhirversion.Version, opts ...MarshallerOption) (*Marshaller, error) {
+	return newAnalyticsMarshaller(maxDepth, ver, formatAnalyticWithInferredSchema, opts...)
 }
 
 // NewAnalyticsV2MarshallerWithInferredSchema returns an Analytics Marshaller with
 // support for extensions as first class fields. A default maxDepth of 2 will be
 // used if the input is 0.
-func NewAnalyticsV2MarshallerWithInferredSchema(maxDepth int, ver fhirversion.Version) (*Marshaller, error) {
-	return newAnalyticsMarshaller(maxDepth, ver, formatAnalyticV2WithInferredSchema)
+func NewAnalyticsV2MarshallerWithInferredSchema(maxDepth int, ver fhirversion.Version, opts ...MarshallerOption) (*Marshaller, error) {
+	return newAnalyticsMarshaller(maxDepth, ver, formatAnalyticV2WithInferredSchema, opts...)
 }
 
-func newAnalyticsMarshaller(maxDepth int, ver fhirversion.Version, format jsonFormat) (*Marshaller, error) {
+func newAnalyticsMarshaller(maxDepth int, ver fhirversion.Version, format jsonFormat, opts ...MarshallerOption) (*Marshaller, error) {
 	if maxDepth == 0 {
 		maxDepth = jsonpbhelper.DefaultAnalyticsRecurExpansionDepth
 	}
@@ -127,13 +267,17 @@ func newAnalyticsMarshaller(maxDepth int, ver fhirversion.Version, format jsonFo
 	if err != nil {
 		return nil, err
 	}
-	return &Marshaller{
+	m := &Marshaller{
 		enableIndent: false,
 		jsonFormat:   format,
 		maxDepth:     maxDepth,
 		depths:       map[string]int{},
 		cfg:          cfg,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
 }
 
 func (m *Marshaller) clone() *Marshaller {
@@ -146,9 +290,80 @@ func (m *Marshaller) clone() *Marshaller {
 		depths:              maps.Clone(m.depths),
 		cfg:                 m.cfg,
 		includeResourceType: m.includeResourceType,
+		omitContained:       m.omitContained,
+		fieldHook:           m.fieldHook,
+		newID:               m.newID,
+		assignContainedIDs:  m.assignContainedIDs,
+		decimalAsString:     m.decimalAsString,
+		fieldMask:           m.fieldMask,
+		elements:            m.elements,
+		sortExtensionsByURL: m.sortExtensionsByURL,
+		narrativeGenerator:  m.narrativeGenerator,
+		regenerateNarrative: m.regenerateNarrative,
+		oldBase:             m.oldBase,
+		newBase:             m.newBase,
+	}
+}
+
+// assignID sets the id of the marshalled resource obj if it has none and ids
+// are being assigned.
+func (m *Marshaller) assignID(obj jsonpbhelper.JSONObject) {
+	if m.newID == nil {
+		return
+	}
+	if id, ok := obj["id"]; !ok || id == jsonpbhelper.JSONString("") {
+		obj["id"] = jsonpbhelper.JSONString(m.newID())
 	}
 }
 
+// containedMarshaller returns the Marshaller for the contained resources of
+// the resource m is marshalling, which are written with their resourceType.
+func (m *Marshaller) containedMarshaller() *Marshaller {
+	cm := m.clone()
+	cm.includeResourceType = true
+	if !m.assignContainedIDs {
+		cm.newID = nil
+	}
+	return cm
+}
+
+// forCall returns the Marshaller to use for a single top-level call. Field
+// hooks need the element path, which is per-call state, so a copy is used
+// when one is set.
+func (m *Marshaller) forCall() *Marshaller {
+	if m.fieldHook == nil {
+		return m
+	}
+	return m.clone()
+}
+
+// pushPath appends elem to the path of the element being marshalled, if it is
+// tracked, and returns a function restoring the previous path.
+func (m *Marshaller) pushPath(elem string) func() {
+	if m.fieldHook == nil {
+		return func() {}
+	}
+	m.path = append(m.path, elem)
+	return func() { m.path = m.path[:len(m.path)-1] }
+}
+
+// applyFieldHook returns the primitive pb, or the replacement for it given by
+// the field hook.
+func (m *Marshaller) applyFieldHook(pb protoreflect.Message) (protoreflect.Message, error) {
+	if m.fieldHook == nil {
+		return pb, nil
+	}
+	path := strings.Join(m.path, ".")
+	v, ok := m.fieldHook(path, protoreflect.ValueOfMessage(pb))
+	if !ok {
+		return pb, nil
+	}
+	if got, want := v.Message().Descriptor().FullName(), pb.Descriptor().FullName(); got != want {
+		return nil, fmt.Errorf("field hook for %s returned %v, want %v", path, got, want)
+	}
+	return v.Message(), nil
+}
+
 // MarshalToString returns serialized JSON object of a ContainedResource protobuf message as string.
 func (m *Marshaller) MarshalToString(pb proto.Message) (string, error) {
 	pbTypeName := pb.ProtoReflect().Descriptor().FullName()
@@ -177,10 +392,19 @@ func (m *Marshaller) Marshal(pb proto.Message) ([]byte, error) {
 	if pbTypeName != expTypeName {
 		return nil, fmt.Errorf("type mismatch, given proto is a message of type: %v, marshaller expects message of type: %v", pbTypeName, expTypeName)
 	}
-	data, err := m.marshal(pb.ProtoReflect())
+	pb, err := m.applyNarrativeGenerator(pb)
+	if err != nil {
+		return nil, err
+	}
+	pb, err = m.applyFieldMask(pb)
+	if err != nil {
+		return nil, err
+	}
+	data, err := m.forCall().marshal(pb.ProtoReflect())
 	if err != nil {
 		return nil, err
 	}
+	m.assignID(data)
 	return m.render(data)
 }
 
@@ -206,10 +430,19 @@ func (m *Marshaller) render(data jsonpbhelper.IsJSON) ([]byte, error) {
 // declaring messages, and does not require knowledge of the specific Resource
 // type.
 func (m *Marshaller) MarshalResource(r proto.Message) ([]byte, error) {
-	data, err := m.marshalResource(r.ProtoReflect())
+	r, err := m.applyNarrativeGenerator(r)
+	if err != nil {
+		return nil, err
+	}
+	r, err = m.applyFieldMask(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := m.forCall().marshalResource(r.ProtoReflect())
 	if err != nil {
 		return nil, err
 	}
+	m.assignID(data)
 	return m.render(data)
 }
 
@@ -234,6 +467,9 @@ func (m *Marshaller) marshal(pb protoreflect.Message) (jsonpbhelper.JSONObject,
 }
 
 func (m *Marshaller) marshalResource(pb protoreflect.Message) (jsonpbhelper.JSONObject, error) {
+	if len(m.path) == 0 {
+		defer m.pushPath(string(pb.Descriptor().Name()))()
+	}
 	decmap, err := m.marshalMessageToMap(pb)
 	if err != nil {
 		return nil, err
@@ -247,12 +483,27 @@ func (m *Marshaller) marshalResource(pb protoreflect.Message) (jsonpbhelper.JSON
 // MarshalToJSONObject returns the resource message as a JSON object, instead of marshalling the JSON data to a []byte.
 // This can be useful if you need to modify the marshalled JSON data without needing to re-decode it.
 func (m *Marshaller) MarshalToJSONObject(pb proto.Message) (jsonpbhelper.JSONObject, error) {
-	return m.marshal(pb.ProtoReflect())
+	pb, err := m.applyNarrativeGenerator(pb)
+	if err != nil {
+		return nil, err
+	}
+	pb, err = m.applyFieldMask(pb)
+	if err != nil {
+		return nil, err
+	}
+	data, err := m.forCall().marshal(pb.ProtoReflect())
+	if err != nil {
+		return nil, err
+	}
+	m.assignID(data)
+	return data, nil
 }
 
 // MarshalElement marshals any FHIR complex value to JSON.
 func (m *Marshaller) MarshalElement(pb proto.Message) ([]byte, error) {
-	obj, err := m.marshalMessageToMap(pb.ProtoReflect())
+	em := m.forCall()
+	defer em.pushPath(string(pb.ProtoReflect().Descriptor().Name()))()
+	obj, err := em.marshalMessageToMap(pb.ProtoReflect())
 	if err != nil {
 		return nil, err
 	}
@@ -261,6 +512,9 @@ func (m *Marshaller) MarshalElement(pb proto.Message) ([]byte, error) {
 
 func (m *Marshaller) marshalRepeatedFieldValue(decmap jsonpbhelper.JSONObject, f protoreflect.FieldDescriptor, pbs []protoreflect.Message) error {
 	fieldName := f.JSONName()
+	if m.sortExtensionsByURL && (fieldName == jsonpbhelper.Extension || fieldName == "modifierExtension") {
+		pbs = sortedByURL(pbs)
+	}
 	if fieldName == jsonpbhelper.Extension {
 		switch m.jsonFormat {
 		case formatAnalyticWithInferredSchema:
@@ -288,7 +542,13 @@ func (m *Marshaller) marshalRepeatedFieldValue(decmap jsonpbhelper.JSONObject, f
 	}
 
 	for i, pb := range pbs {
+		// The path is per-call state, so it need not be restored on error.
+		popPath := m.pushPath(fmt.Sprintf("%s[%d]", fieldName, i))
 		if isPrimitive {
+			pb, err := m.applyFieldHook(pb)
+			if err != nil {
+				return err
+			}
 			rm, err := m.marshalPrimitiveType(pb)
 			if err != nil {
 				return err
@@ -317,6 +577,7 @@ func (m *Marshaller) marshalRepeatedFieldValue(decmap jsonpbhelper.JSONObject, f
 				hasValue = true
 			}
 		}
+		popPath()
 	}
 	if hasValue {
 		decmap[fieldName] = rms
@@ -327,6 +588,29 @@ func (m *Marshaller) marshalRepeatedFieldValue(decmap jsonpbhelper.JSONObject, f
 	return nil
 }
 
+// sortedByURL returns a copy of the extensions exts stably sorted by url,
+// with those without a url first.
+func sortedByURL(exts []protoreflect.Message) []protoreflect.Message {
+	type urlExt struct {
+		url string
+		ext protoreflect.Message
+	}
+	byURL := make([]urlExt, len(exts))
+	for i, e := range exts {
+		// An extension without a url sorts as the empty url.
+		url, _ := jsonpbhelper.ExtensionURL(e)
+		byURL[i] = urlExt{url, e}
+	}
+	sort.SliceStable(byURL, func(i, j int) bool {
+		return byURL[i].url < byURL[j].url
+	})
+	sorted := make([]protoreflect.Message, len(exts))
+	for i, e := range byURL {
+		sorted[i] = e.ext
+	}
+	return sorted
+}
+
 func (m *Marshaller) marshalExtensionsAsFirstClassFields(decmap jsonpbhelper.JSONObject, pbs []protoreflect.Message) error {
 	// Loop through the extenions first to get all the field name occurrence, lowercase field name
 	// is used for counting since duplicate field names are not allowed in BigQuery even if the
@@ -593,7 +877,12 @@ func (m *Marshaller) marshalFieldValue(decmap jsonpbhelper.JSONObject, f protore
 			pb = pb.Get(fd).Message()
 		}
 	}
+	defer m.pushPath(jsonName)()
 	if jsonpbhelper.IsPrimitiveType(f.Message()) {
+		pb, err := m.applyFieldHook(pb)
+		if err != nil {
+			return err
+		}
 		base, err := m.marshalPrimitiveType(pb)
 		if err != nil {
 			return err
@@ -636,9 +925,7 @@ func (m *Marshaller) marshalNonPrimitiveFieldValue(f protoreflect.FieldDescripto
 	}
 	if d.Name() == containedResourceProtoName(m.cfg) {
 		if m.jsonFormat == formatAnalyticV2WithInferredSchema {
-			containedMarshaller := m.clone()
-			containedMarshaller.includeResourceType = true
-			str, err := containedMarshaller.MarshalToString(pb.Interface())
+			str, err := m.containedMarshaller().MarshalToString(pb.Interface())
 			if err != nil {
 				return nil, err
 			}
@@ -647,19 +934,18 @@ func (m *Marshaller) marshalNonPrimitiveFieldValue(f protoreflect.FieldDescripto
 			// Contained resources are dropped for analytics output
 			return nil, nil
 		}
-		return m.marshal(pb)
+		return m.marshalContained(pb)
 	}
-	// Handle inlined resources which are wrapped in Any proto. The JSON field name must be 'contained'.
-	if _, ok := pb.Interface().(*anypb.Any); ok && f.JSONName() == jsonpbhelper.ContainedField {
+	// Handle inlined resources which are wrapped in Any proto. The JSON field name must be 'contained',
+	// or 'resource' for the resource of a Parameters parameter.
+	if _, ok := pb.Interface().(*anypb.Any); ok && jsonpbhelper.IsInlineResourceField(f.JSONName()) {
 		if m.jsonFormat == formatAnalyticV2WithInferredSchema {
 			crpb := m.cfg.newEmptyContainedResource()
 			pbAny := pb.Interface().(*anypb.Any)
 			if err := pbAny.UnmarshalTo(crpb); err != nil {
 				return nil, fmt.Errorf("unmarshalling Any, err: %w", err)
 			}
-			containedMarshaller := m.clone()
-			containedMarshaller.includeResourceType = true
-			str, err := containedMarshaller.MarshalToString(crpb.ProtoReflect().Interface())
+			str, err := m.containedMarshaller().MarshalToString(crpb.ProtoReflect().Interface())
 			if err != nil {
 				return nil, err
 			}
@@ -673,7 +959,7 @@ func (m *Marshaller) marshalNonPrimitiveFieldValue(f protoreflect.FieldDescripto
 		if err := pbAny.UnmarshalTo(crpb); err != nil {
 			return nil, fmt.Errorf("unmarshalling Any, err: %w", err)
 		}
-		return m.marshal(crpb.ProtoReflect())
+		return m.marshalContained(crpb.ProtoReflect())
 	}
 
 	if proto.HasExtension(d.Options(), apb.E_FhirReferenceType) {
@@ -682,11 +968,29 @@ func (m *Marshaller) marshalNonPrimitiveFieldValue(f protoreflect.FieldDescripto
 	return m.marshalMessageToMap(pb)
 }
 
+// marshalContained marshals the ContainedResource pb held in the contained
+// field of a resource.
+func (m *Marshaller) marshalContained(pb protoreflect.Message) (jsonpbhelper.IsJSON, error) {
+	obj, err := m.marshal(pb)
+	if err != nil {
+		return nil, err
+	}
+	if m.assignContainedIDs {
+		m.assignID(obj)
+	}
+	return obj, nil
+}
+
 func (m *Marshaller) marshalReference(rpb protoreflect.Message) (jsonpbhelper.IsJSON, error) {
 	newRef, err := NewDenormalizedReference(rpb.Interface())
 	if err != nil {
 		return nil, err
 	}
+	if m.oldBase != "" {
+		if newRef, err = rebasedReference(newRef, m.oldBase, m.newBase); err != nil {
+			return nil, err
+		}
+	}
 	if m.jsonFormat != formatPure {
 		if err := normalizeRelativeReferenceAndIgnoreHistory(newRef); err != nil {
 			return nil, err
@@ -698,11 +1002,15 @@ func (m *Marshaller) marshalReference(rpb protoreflect.Message) (jsonpbhelper.Is
 func (m *Marshaller) marshalMessageToMap(pb protoreflect.Message) (jsonpbhelper.JSONObject, error) {
 	decmap := jsonpbhelper.JSONObject{}
 	var err error
+	omitContained := m.omitContained && jsonpbhelper.IsResourceType(pb.Descriptor())
 	pb.Range(func(f protoreflect.FieldDescriptor, val protoreflect.Value) bool {
 		if f.Message() == nil {
 			err = fmt.Errorf("field %v has unexpected kind %v", f.Name(), f.Kind())
 			return false
 		}
+		if omitContained && f.JSONName() == jsonpbhelper.ContainedField {
+			return true
+		}
 		if f.IsMap() {
 			err = fmt.Errorf("field %v is map, which is not supported", f.Name())
 		}
@@ -755,9 +1063,27 @@ func (m *Marshaller) marshalPrimitiveType(rpb protoreflect.Message) (jsonpbhelpe
 		return jsonpbhelper.JSONString(binary), nil
 	case "Canonical", "Code", "Markdown", "Oid", "String", "Uri", "Url", "Uuid", "Xhtml", "ReferenceId", "Id":
 		return jsonpbhelper.JSONString(rpb.Get(desc.Fields().ByName("value")).String()), nil
-	case "Boolean", "Integer", "PositiveInt", "UnsignedInt", "Decimal":
+	case "Decimal":
+		val := rpb.Get(desc.Fields().ByName("value")).String()
+		if m.decimalAsString {
+			return jsonpbhelper.JSONString(val), nil
+		}
+		return jsonpbhelper.JSONRawValue(val), nil
+	case "Boolean", "Integer", "PositiveInt", "UnsignedInt":
 		val := rpb.Get(desc.Fields().ByName("value"))
 		return jsonpbhelper.JSONRawValue(fmt.Sprintf("%v", val.Interface())), nil
+	case "Integer64":
+		// integer64 is written as a JSON string so that values beyond 2^53
+		// survive JavaScript number handling.
+		f := desc.Fields().ByName("value")
+		if f.Kind() == protoreflect.StringKind {
+			val := rpb.Get(f).String()
+			if _, err := strconv.ParseInt(val, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid integer64 %q", val)
+			}
+			return jsonpbhelper.JSONString(val), nil
+		}
+		return jsonpbhelper.JSONString(strconv.FormatInt(rpb.Get(f).Int(), 10)), nil
 	case "Date":
 		date, err := serializeDate(pb)
 		if err != nil {
@@ -801,14 +1127,7 @@ func (m *Marshaller) marshalPrimitiveType(rpb protoreflect.Message) (jsonpbhelpe
 				return nil, nil
 			}
 			// Observe the FHIR original codes if set.
-			ed := f.Enum()
-			ev := ed.Values().ByNumber(num)
-			origCode := proto.GetExtension(ev.Options(), apb.E_FhirOriginalCode).(string)
-			if origCode != "" {
-				return jsonpbhelper.JSONString(origCode), nil
-			}
-			enum := string(ev.Name())
-			return jsonpbhelper.JSONString(strings.Replace(strings.ToLower(enum), "_", "-", -1)), nil
+			return jsonpbhelper.JSONString(element.EnumCode(f.Enum().Values().ByNumber(num))), nil
 		default:
 			return nil, fmt.Errorf("unexpected kind %v, want enum", f.Kind())
 		}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"testing"
 
 	"github.com/google/fhir/go/fhirversion"
@@ -26,9 +27,11 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/pkg/errors"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/testing/protocmp"
 
 	anypb "google.golang.org/protobuf/types/known/anypb"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
 	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
 	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
 	r4binarypb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/binary_go_proto"
@@ -36,6 +39,8 @@ import (
 	r4codesystempb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/code_system_go_proto"
 	r4conditionpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/condition_go_proto"
 	r4devicepb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/device_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4paramspb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/parameters_go_proto"
 	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
 	r4researchstudypb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/research_study_go_proto"
 	r4searchparampb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/search_parameter_go_proto"
@@ -633,6 +638,604 @@ func TestMarshalResource(t *testing.T) {
 	}
 }
 
+func TestMarshalResource_OmitContained(t *testing.T) {
+	contained := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Device{
+			Device: &r4devicepb.Device{
+				Id: &d4pb.Id{Value: "d1"},
+			},
+		},
+	}
+	patient := &r4patientpb.Patient{
+		Id:        &d4pb.Id{Value: "p1"},
+		Contained: []*anypb.Any{marshalToAny(t, contained)},
+		Active:    &d4pb.Boolean{Value: true},
+	}
+	tests := []struct {
+		name string
+		omit bool
+		want string
+	}{
+		{
+			name: "option disabled",
+			omit: false,
+			want: `{"active":true,"contained":[{"id":"d1","resourceType":"Device"}],"id":"p1","resourceType":"Patient"}`,
+		},
+		{
+			name: "option enabled",
+			omit: true,
+			want: `{"active":true,"id":"p1","resourceType":"Patient"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			marshaller, err := NewMarshaller(false, "", "", fhirversion.R4, OmitContained(test.omit))
+			if err != nil {
+				t.Fatalf("failed to create marshaler; %v", err)
+			}
+			got, err := marshaller.MarshalResource(patient)
+			if err != nil {
+				t.Fatalf("MarshalResource() got err %v; want nil err", err)
+			}
+			if diff := cmp.Diff(test.want, string(got), compareJSON); diff != "" {
+				t.Errorf("MarshalResource() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMarshalResource_FieldHook(t *testing.T) {
+	const ssnSystem = "http://hl7.org/fhir/sid/us-ssn"
+	patient := &r4patientpb.Patient{
+		Id: &d4pb.Id{Value: "p1"},
+		Identifier: []*d4pb.Identifier{
+			{System: &d4pb.Uri{Value: "http://hospital.example/mrn"}, Value: &d4pb.String{Value: "12345"}},
+			{System: &d4pb.Uri{Value: ssnSystem}, Value: &d4pb.String{Value: "123-45-6789"}},
+		},
+	}
+	orig := proto.Clone(patient)
+
+	redact := map[string]bool{}
+	for i, id := range patient.GetIdentifier() {
+		if id.GetSystem().GetValue() == ssnSystem {
+			redact[fmt.Sprintf("Patient.identifier[%d].value", i)] = true
+		}
+	}
+	var paths []string
+	hook := func(path string, v protoreflect.Value) (protoreflect.Value, bool) {
+		paths = append(paths, path)
+		if !redact[path] {
+			return v, false
+		}
+		return protoreflect.ValueOfMessage((&d4pb.String{Value: "REDACTED"}).ProtoReflect()), true
+	}
+	marshaller, err := NewMarshaller(false, "", "", fhirversion.R4, FieldHook(hook))
+	if err != nil {
+		t.Fatalf("failed to create marshaler; %v", err)
+	}
+	got, err := marshaller.MarshalResource(patient)
+	if err != nil {
+		t.Fatalf("MarshalResource() got err %v; want nil err", err)
+	}
+	want := `{"id":"p1","identifier":[{"system":"http://hospital.example/mrn","value":"12345"},` +
+		`{"system":"http://hl7.org/fhir/sid/us-ssn","value":"REDACTED"}],"resourceType":"Patient"}`
+	if diff := cmp.Diff(want, string(got), compareJSON); diff != "" {
+		t.Errorf("MarshalResource() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if !proto.Equal(orig, patient) {
+		t.Errorf("MarshalResource() with FieldHook modified the input resource")
+	}
+	sort.Strings(paths)
+	wantPaths := []string{
+		"Patient.id",
+		"Patient.identifier[0].system",
+		"Patient.identifier[0].value",
+		"Patient.identifier[1].system",
+		"Patient.identifier[1].value",
+	}
+	if diff := cmp.Diff(wantPaths, paths); diff != "" {
+		t.Errorf("FieldHook paths returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshalResource_AssignMissingIDs(t *testing.T) {
+	contained := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Device{Device: &r4devicepb.Device{}},
+	}
+	tests := []struct {
+		name    string
+		patient *r4patientpb.Patient
+		opts    []MarshallerOption
+		want    string
+	}{
+		{
+			name:    "missing id",
+			patient: &r4patientpb.Patient{Active: &d4pb.Boolean{Value: true}},
+			want:    `{"active":true,"id":"id-1","resourceType":"Patient"}`,
+		},
+		{
+			name:    "existing id",
+			patient: &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}},
+			want:    `{"id":"p1","resourceType":"Patient"}`,
+		},
+		{
+			name:    "contained resources untouched",
+			patient: &r4patientpb.Patient{Contained: []*anypb.Any{marshalToAny(t, contained)}},
+			want:    `{"contained":[{"resourceType":"Device"}],"id":"id-1","resourceType":"Patient"}`,
+		},
+		{
+			name:    "contained resources included",
+			patient: &r4patientpb.Patient{Contained: []*anypb.Any{marshalToAny(t, contained)}},
+			opts:    []MarshallerOption{AssignMissingContainedIDs(true)},
+			want:    `{"contained":[{"id":"id-1","resourceType":"Device"}],"id":"id-2","resourceType":"Patient"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := 0
+			newID := func() string {
+				n++
+				return fmt.Sprintf("id-%d", n)
+			}
+			orig := proto.Clone(test.patient)
+			marshaller, err := NewMarshaller(false, "", "", fhirversion.R4, append(test.opts, AssignMissingIDs(newID))...)
+			if err != nil {
+				t.Fatalf("failed to create marshaler; %v", err)
+			}
+			got, err := marshaller.MarshalResource(test.patient)
+			if err != nil {
+				t.Fatalf("MarshalResource() got err %v; want nil err", err)
+			}
+			if diff := cmp.Diff(test.want, string(got), compareJSON); diff != "" {
+				t.Errorf("MarshalResource() returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if !proto.Equal(orig, test.patient) {
+				t.Errorf("MarshalResource() with AssignMissingIDs modified the input resource")
+			}
+		})
+	}
+}
+
+func TestMarshalResource_DecimalAsString(t *testing.T) {
+	obs := &r4observationpb.Observation{
+		Status: &r4observationpb.Observation_StatusCode{Value: c4pb.ObservationStatusCode_FINAL},
+		Code:   &d4pb.CodeableConcept{Text: &d4pb.String{Value: "x"}},
+		Value: &r4observationpb.Observation_ValueX{
+			Choice: &r4observationpb.Observation_ValueX_Quantity{
+				Quantity: &d4pb.Quantity{Value: &d4pb.Decimal{Value: "1.50"}},
+			},
+		},
+	}
+	tests := []struct {
+		name string
+		opts []MarshallerOption
+		want string
+	}{
+		{
+			name: "default",
+			want: `{"code":{"text":"x"},"resourceType":"Observation","status":"final","valueQuantity":{"value":1.50}}`,
+		},
+		{
+			name: "as string",
+			opts: []MarshallerOption{DecimalAsString(true)},
+			want: `{"code":{"text":"x"},"resourceType":"Observation","status":"final","valueQuantity":{"value":"1.50"}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			marshaller, err := NewMarshaller(false, "", "", fhirversion.R4, test.opts...)
+			if err != nil {
+				t.Fatalf("failed to create marshaler; %v", err)
+			}
+			got, err := marshaller.MarshalResource(obs)
+			if err != nil {
+				t.Fatalf("MarshalResource() got err %v; want nil err", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("MarshalResource() = %s, want %s", got, test.want)
+			}
+
+			u := setupUnmarshaller(t, fhirversion.R4)
+			back, err := u.UnmarshalR4(got)
+			if err != nil {
+				t.Fatalf("UnmarshalR4(%s) failed: %v", got, err)
+			}
+			if diff := cmp.Diff(obs, back.GetObservation(), protocmp.Transform()); diff != "" {
+				t.Errorf("UnmarshalR4(%s) returned unexpected diff (-want +got):\n%s", got, diff)
+			}
+		})
+	}
+}
+
+func TestMarshalResource_SortExtensionsByURL(t *testing.T) {
+	ext := func(url, value string) *d4pb.Extension {
+		e := &d4pb.Extension{
+			Value: &d4pb.Extension_ValueX{
+				Choice: &d4pb.Extension_ValueX_StringValue{StringValue: &d4pb.String{Value: value}},
+			},
+		}
+		if url != "" {
+			e.Url = &d4pb.Uri{Value: url}
+		}
+		return e
+	}
+	a, b, c := ext("http://example.com/a", "1"), ext("http://example.com/b", "2"), ext("http://example.com/c", "3")
+	patient := func(exts, nested []*d4pb.Extension) *r4patientpb.Patient {
+		return &r4patientpb.Patient{
+			Extension:         exts,
+			ModifierExtension: nested,
+			BirthDate: &d4pb.Date{
+				ValueUs: 0, Precision: d4pb.Date_DAY, Timezone: "UTC",
+				Extension: nested,
+			},
+		}
+	}
+	p1 := patient([]*d4pb.Extension{c, a, b}, []*d4pb.Extension{b, a})
+	p2 := patient([]*d4pb.Extension{b, c, a}, []*d4pb.Extension{a, b})
+	orig := proto.Clone(p1)
+
+	marshaller, err := NewMarshaller(false, "", "", fhirversion.R4, SortExtensionsByURL(true))
+	if err != nil {
+		t.Fatalf("failed to create marshaler; %v", err)
+	}
+	got1, err := marshaller.MarshalResource(p1)
+	if err != nil {
+		t.Fatalf("MarshalResource() got err %v; want nil err", err)
+	}
+	got2, err := marshaller.MarshalResource(p2)
+	if err != nil {
+		t.Fatalf("MarshalResource() got err %v; want nil err", err)
+	}
+	if string(got1) != string(got2) {
+		t.Errorf("MarshalResource() of reordered extensions differ:\n%s\n%s", got1, got2)
+	}
+	want := `{"_birthDate":{"extension":[{"url":"http://example.com/a","valueString":"1"},{"url":"http://example.com/b","valueString":"2"}]},` +
+		`"birthDate":"1970-01-01",` +
+		`"extension":[{"url":"http://example.com/a","valueString":"1"},{"url":"http://example.com/b","valueString":"2"},{"url":"http://example.com/c","valueString":"3"}],` +
+		`"modifierExtension":[{"url":"http://example.com/a","valueString":"1"},{"url":"http://example.com/b","valueString":"2"}],` +
+		`"resourceType":"Patient"}`
+	if string(got1) != want {
+		t.Errorf("MarshalResource() = %s, want %s", got1, want)
+	}
+	if !proto.Equal(orig, p1) {
+		t.Errorf("MarshalResource() with SortExtensionsByURL modified the input resource")
+	}
+
+	unsorted, err := NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("failed to create marshaler; %v", err)
+	}
+	got1, err = unsorted.MarshalResource(p1)
+	if err != nil {
+		t.Fatalf("MarshalResource() got err %v; want nil err", err)
+	}
+	if string(got1) == want {
+		t.Errorf("MarshalResource() without SortExtensionsByURL sorted extensions")
+	}
+}
+
+func TestMarshalResource_SortExtensionsByURL_MissingURLFirst(t *testing.T) {
+	noURL := &d4pb.Extension{
+		Extension: []*d4pb.Extension{{
+			Url:   &d4pb.Uri{Value: "inner"},
+			Value: &d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Boolean{Boolean: &d4pb.Boolean{Value: true}}},
+		}},
+	}
+	withURL := &d4pb.Extension{
+		Url:   &d4pb.Uri{Value: "http://example.com/a"},
+		Value: &d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Boolean{Boolean: &d4pb.Boolean{Value: false}}},
+	}
+	marshaller, err := NewMarshaller(false, "", "", fhirversion.R4, SortExtensionsByURL(true))
+	if err != nil {
+		t.Fatalf("failed to create marshaler; %v", err)
+	}
+	got, err := marshaller.MarshalResource(&r4patientpb.Patient{Extension: []*d4pb.Extension{withURL, noURL}})
+	if err != nil {
+		t.Fatalf("MarshalResource() got err %v; want nil err", err)
+	}
+	want := `{"extension":[{"extension":[{"url":"inner","valueBoolean":true}]},{"url":"http://example.com/a","valueBoolean":false}],"resourceType":"Patient"}`
+	if string(got) != want {
+		t.Errorf("MarshalResource() = %s, want %s", got, want)
+	}
+}
+
+func TestMarshalResource_NarrativeGenerator(t *testing.T) {
+	const div = `<div xmlns="http://www.w3.org/1999/xhtml">Generated</div>`
+	generate := func(r proto.Message) (*d4pb.Narrative, error) {
+		if _, ok := r.(*r4patientpb.Patient); !ok {
+			return nil, fmt.Errorf("generator got %T, want a Patient", r)
+		}
+		return &d4pb.Narrative{
+			Status: &d4pb.Narrative_StatusCode{Value: c4pb.NarrativeStatusCode_GENERATED},
+			Div:    &d4pb.Xhtml{Value: div},
+		}, nil
+	}
+	existing := &d4pb.Narrative{
+		Status: &d4pb.Narrative_StatusCode{Value: c4pb.NarrativeStatusCode_ADDITIONAL},
+		Div:    &d4pb.Xhtml{Value: `<div xmlns="http://www.w3.org/1999/xhtml">Existing</div>`},
+	}
+	const (
+		generatedJSON = `{"active":true,"resourceType":"Patient","text":{"div":"<div xmlns=\"http://www.w3.org/1999/xhtml\">Generated</div>","status":"generated"}}`
+		existingJSON  = `{"active":true,"resourceType":"Patient","text":{"div":"<div xmlns=\"http://www.w3.org/1999/xhtml\">Existing</div>","status":"additional"}}`
+	)
+	tests := []struct {
+		name    string
+		opts    []MarshallerOption
+		patient *r4patientpb.Patient
+		want    string
+	}{
+		{
+			name:    "no narrative",
+			opts:    []MarshallerOption{NarrativeGenerator(generate)},
+			patient: &r4patientpb.Patient{Active: &d4pb.Boolean{Value: true}},
+			want:    generatedJSON,
+		},
+		{
+			name:    "existing narrative kept",
+			opts:    []MarshallerOption{NarrativeGenerator(generate)},
+			patient: &r4patientpb.Patient{Active: &d4pb.Boolean{Value: true}, Text: existing},
+			want:    existingJSON,
+		},
+		{
+			name:    "existing narrative regenerated",
+			opts:    []MarshallerOption{NarrativeGenerator(generate), RegenerateNarrative(true)},
+			patient: &r4patientpb.Patient{Active: &d4pb.Boolean{Value: true}, Text: existing},
+			want:    generatedJSON,
+		},
+		{
+			name: "nil narrative",
+			opts: []MarshallerOption{NarrativeGenerator(func(proto.Message) (*d4pb.Narrative, error) {
+				return nil, nil
+			})},
+			patient: &r4patientpb.Patient{Active: &d4pb.Boolean{Value: true}},
+			want:    `{"active":true,"resourceType":"Patient"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			marshaller, err := NewMarshaller(false, "", "", fhirversion.R4, test.opts...)
+			if err != nil {
+				t.Fatalf("failed to create marshaler; %v", err)
+			}
+			orig := proto.Clone(test.patient)
+			got, err := marshaller.MarshalResource(test.patient)
+			if err != nil {
+				t.Fatalf("MarshalResource() got err %v; want nil err", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("MarshalResource() = %s, want %s", got, test.want)
+			}
+			cr := &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: test.patient}}
+			got, err = marshaller.Marshal(cr)
+			if err != nil {
+				t.Fatalf("Marshal() got err %v; want nil err", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("Marshal() = %s, want %s", got, test.want)
+			}
+			if !proto.Equal(orig, test.patient) {
+				t.Errorf("marshalling modified the input: got %v, want %v", test.patient, orig)
+			}
+		})
+	}
+}
+
+func TestMarshalResource_NarrativeGenerator_Errors(t *testing.T) {
+	generate := func(proto.Message) (*d4pb.Narrative, error) {
+		return nil, errors.New("template failed")
+	}
+	r4, err := NewMarshaller(false, "", "", fhirversion.R4, NarrativeGenerator(generate))
+	if err != nil {
+		t.Fatalf("failed to create marshaler; %v", err)
+	}
+	if _, err := r4.MarshalResource(&r4patientpb.Patient{}); err == nil {
+		t.Errorf("MarshalResource() with a failing generator succeeded, want error")
+	}
+	stu3, err := NewMarshaller(false, "", "", fhirversion.STU3, NarrativeGenerator(generate))
+	if err != nil {
+		t.Fatalf("failed to create marshaler; %v", err)
+	}
+	if _, err := stu3.MarshalResource(&r3pb.Patient{}); err == nil {
+		t.Errorf("MarshalResource() of an STU3 resource with a narrative generator succeeded, want error")
+	}
+}
+
+func TestMarshalResource_RebaseReferences(t *testing.T) {
+	uriRef := func(uri string) *d4pb.Reference {
+		return &d4pb.Reference{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: uri}}}
+	}
+	obs := &r4observationpb.Observation{
+		Subject: uriRef("http://old/Patient/1"),
+		Performer: []*d4pb.Reference{
+			{Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "2"}}},
+			uriRef("http://other/Practitioner/3"),
+			{Reference: &d4pb.Reference_Fragment{Fragment: &d4pb.String{Value: "org"}}},
+		},
+	}
+	orig := proto.Clone(obs)
+	marshaller, err := NewMarshaller(false, "", "", fhirversion.R4, RebaseReferences("http://old/", "http://new/"))
+	if err != nil {
+		t.Fatalf("failed to create marshaler; %v", err)
+	}
+	got, err := marshaller.MarshalResource(obs)
+	if err != nil {
+		t.Fatalf("MarshalResource() got err %v; want nil err", err)
+	}
+	want := `{"performer":[{"reference":"Practitioner/2"},{"reference":"http://other/Practitioner/3"},{"reference":"#org"}],"resourceType":"Observation","subject":{"reference":"http://new/Patient/1"}}`
+	if string(got) != want {
+		t.Errorf("MarshalResource() = %s, want %s", got, want)
+	}
+	if !proto.Equal(orig, obs) {
+		t.Errorf("marshalling modified the input: got %v, want %v", obs, orig)
+	}
+}
+
+func TestMarshalResource_ParametersResource(t *testing.T) {
+	params := &r4paramspb.Parameters{
+		Parameter: []*r4paramspb.Parameters_Parameter{{
+			Name: &d4pb.String{Value: "resource"},
+			Resource: marshalToAny(t, &r4pb.ContainedResource{
+				OneofResource: &r4pb.ContainedResource_Patient{Patient: &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}}},
+			}),
+		}},
+	}
+	marshaller, err := NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("failed to create marshaler; %v", err)
+	}
+	got, err := marshaller.MarshalResource(params)
+	if err != nil {
+		t.Fatalf("MarshalResource() got err %v; want nil err", err)
+	}
+	want := `{"parameter":[{"name":"resource","resource":{"id":"p1","resourceType":"Patient"}}],"resourceType":"Parameters"}`
+	if string(got) != want {
+		t.Errorf("MarshalResource() = %s, want %s", got, want)
+	}
+
+	u := setupUnmarshaller(t, fhirversion.R4)
+	back, err := u.UnmarshalR4(got)
+	if err != nil {
+		t.Fatalf("UnmarshalR4(%s) failed: %v", got, err)
+	}
+	if diff := cmp.Diff(params, back.GetParameters(), protocmp.Transform()); diff != "" {
+		t.Errorf("UnmarshalR4(%s) returned unexpected diff (-want +got):\n%s", got, diff)
+	}
+}
+
+func TestMarshalResource_FieldMask(t *testing.T) {
+	patient := &r4patientpb.Patient{
+		Id:     &d4pb.Id{Value: "p1"},
+		Active: &d4pb.Boolean{Value: true},
+		Name: []*d4pb.HumanName{{
+			Family: &d4pb.String{Value: "Doe"},
+			Given:  []*d4pb.String{{Value: "Jane"}},
+		}},
+		Gender:    &r4patientpb.Patient_GenderCode{Value: c4pb.AdministrativeGenderCode_FEMALE},
+		BirthDate: &d4pb.Date{ValueUs: 0, Precision: d4pb.Date_DAY, Timezone: "UTC"},
+	}
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{
+			name:  "top-level fields",
+			paths: []string{"name", "gender"},
+			want:  `{"gender":"female","id":"p1","name":[{"family":"Doe","given":["Jane"]}],"resourceType":"Patient"}`,
+		},
+		{
+			name:  "nested field",
+			paths: []string{"name.family"},
+			want:  `{"id":"p1","name":[{"family":"Doe"}],"resourceType":"Patient"}`,
+		},
+		{
+			name: "empty mask",
+			want: `{"id":"p1","resourceType":"Patient"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			orig := proto.Clone(patient)
+			marshaller, err := NewMarshaller(false, "", "", fhirversion.R4, FieldMask(&fieldmaskpb.FieldMask{Paths: test.paths}))
+			if err != nil {
+				t.Fatalf("failed to create marshaler; %v", err)
+			}
+			got, err := marshaller.MarshalResource(patient)
+			if err != nil {
+				t.Fatalf("MarshalResource() got err %v; want nil err", err)
+			}
+			if diff := cmp.Diff(test.want, string(got), compareJSON); diff != "" {
+				t.Errorf("MarshalResource() returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if !proto.Equal(orig, patient) {
+				t.Errorf("MarshalResource() with FieldMask modified the input resource")
+			}
+		})
+	}
+}
+
+func TestMarshalResource_FieldMaskErrors(t *testing.T) {
+	patient := &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}}
+	for _, path := range []string{"nonsense", "gender.value.more"} {
+		marshaller, err := NewMarshaller(false, "", "", fhirversion.R4, FieldMask(&fieldmaskpb.FieldMask{Paths: []string{path}}))
+		if err != nil {
+			t.Fatalf("failed to create marshaler; %v", err)
+		}
+		if got, err := marshaller.MarshalResource(patient); err == nil {
+			t.Errorf("MarshalResource() with mask %q = %s, want error", path, got)
+		}
+	}
+}
+
+func TestMarshalResource_Elements(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource proto.Message
+		elements []string
+		want     string
+	}{
+		{
+			name: "nested element",
+			resource: &r4patientpb.Patient{
+				Id:     &d4pb.Id{Value: "p1"},
+				Active: &d4pb.Boolean{Value: true},
+				Name: []*d4pb.HumanName{
+					{Family: &d4pb.String{Value: "Doe"}, Given: []*d4pb.String{{Value: "Jane"}}},
+					{Family: &d4pb.String{Value: "Roe"}, Text: &d4pb.String{Value: "Jane Roe"}},
+				},
+			},
+			elements: []string{"name.family"},
+			want:     `{"id":"p1","name":[{"family":"Doe"},{"family":"Roe"}],"resourceType":"Patient"}`,
+		},
+		{
+			name: "mandatory elements retained",
+			resource: &r4observationpb.Observation{
+				Id:     &d4pb.Id{Value: "o1"},
+				Status: &r4observationpb.Observation_StatusCode{Value: c4pb.ObservationStatusCode_FINAL},
+				Code:   &d4pb.CodeableConcept{Text: &d4pb.String{Value: "Heart rate"}},
+				Subject: &d4pb.Reference{
+					Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}},
+				},
+				Issued: &d4pb.Instant{ValueUs: 0, Precision: d4pb.Instant_SECOND, Timezone: "UTC"},
+			},
+			elements: []string{"subject"},
+			want:     `{"code":{"text":"Heart rate"},"id":"o1","resourceType":"Observation","status":"final","subject":{"reference":"Patient/p1"}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			orig := proto.Clone(test.resource)
+			marshaller, err := NewMarshaller(false, "", "", fhirversion.R4, Elements(test.elements...))
+			if err != nil {
+				t.Fatalf("failed to create marshaler; %v", err)
+			}
+			got, err := marshaller.MarshalResource(test.resource)
+			if err != nil {
+				t.Fatalf("MarshalResource() got err %v; want nil err", err)
+			}
+			if diff := cmp.Diff(test.want, string(got), compareJSON); diff != "" {
+				t.Errorf("MarshalResource() returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if !proto.Equal(orig, test.resource) {
+				t.Errorf("MarshalResource() with Elements modified the input resource")
+			}
+		})
+	}
+}
+
+func TestMarshalResource_ElementsErrors(t *testing.T) {
+	patient := &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}}
+	for _, element := range []string{"nonsense", "name.nonsense", "birthDate.value.more"} {
+		marshaller, err := NewMarshaller(false, "", "", fhirversion.R4, Elements(element))
+		if err != nil {
+			t.Fatalf("failed to create marshaler; %v", err)
+		}
+		if got, err := marshaller.MarshalResource(patient); err == nil {
+			t.Errorf("MarshalResource() with element %q = %s, want error", element, got)
+		}
+	}
+}
+
 func TestMarshalMessage(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -3762,3 +4365,70 @@ func TestMarshalPrimitiveType(t *testing.T) {
 		})
 	}
 }
+
+func TestMarshalWithHeaders(t *testing.T) {
+	lastUpdated := &d4pb.Instant{ValueUs: 1678886400123000, Precision: d4pb.Instant_MILLISECOND, Timezone: "Z"}
+	tests := []struct {
+		name        string
+		msg         proto.Message
+		wantBody    string
+		wantHeaders map[string]string
+	}{
+		{
+			name: "versioned resource",
+			msg: &r4patientpb.Patient{
+				Id:   &d4pb.Id{Value: "p1"},
+				Meta: &d4pb.Meta{VersionId: &d4pb.Id{Value: "3"}, LastUpdated: lastUpdated},
+			},
+			wantBody: `{"id":"p1","meta":{"lastUpdated":"2023-03-15T13:20:00.123Z","versionId":"3"},"resourceType":"Patient"}`,
+			wantHeaders: map[string]string{
+				"ETag":          `W/"3"`,
+				"Last-Modified": "Wed, 15 Mar 2023 13:20:00 GMT",
+			},
+		},
+		{
+			name: "contained resource without last updated",
+			msg: &r4pb.ContainedResource{
+				OneofResource: &r4pb.ContainedResource_Patient{Patient: &r4patientpb.Patient{
+					Id:   &d4pb.Id{Value: "p1"},
+					Meta: &d4pb.Meta{VersionId: &d4pb.Id{Value: "3"}},
+				}},
+			},
+			wantBody:    `{"id":"p1","meta":{"versionId":"3"},"resourceType":"Patient"}`,
+			wantHeaders: map[string]string{"ETag": `W/"3"`},
+		},
+		{
+			name: "empty version id",
+			msg: &r4patientpb.Patient{
+				Id:   &d4pb.Id{Value: "p1"},
+				Meta: &d4pb.Meta{VersionId: &d4pb.Id{}, LastUpdated: lastUpdated},
+			},
+			wantBody:    `{"id":"p1","meta":{"lastUpdated":"2023-03-15T13:20:00.123Z","versionId":""},"resourceType":"Patient"}`,
+			wantHeaders: map[string]string{"Last-Modified": "Wed, 15 Mar 2023 13:20:00 GMT"},
+		},
+		{
+			name:        "no meta",
+			msg:         &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}},
+			wantBody:    `{"id":"p1","resourceType":"Patient"}`,
+			wantHeaders: map[string]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := NewMarshaller(false, "", "", fhirversion.R4)
+			if err != nil {
+				t.Fatalf("failed to create marshaler; %v", err)
+			}
+			body, headers, err := MarshalWithHeaders(m, test.msg)
+			if err != nil {
+				t.Fatalf("MarshalWithHeaders() failed: %v", err)
+			}
+			if string(body) != test.wantBody {
+				t.Errorf("MarshalWithHeaders() body = %s, want %s", body, test.wantBody)
+			}
+			if diff := cmp.Diff(test.wantHeaders, headers); diff != "" {
+				t.Errorf("MarshalWithHeaders() returned unexpected headers diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"fmt"
+
+	"github.com/google/fhir/go/jsonformat/internal/jsonpbhelper"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// NarrativeGeneratorFunc returns the narrative to write for resource, or nil
+// to leave its narrative as it is.
+type NarrativeGeneratorFunc func(resource proto.Message) (*d4pb.Narrative, error)
+
+// NarrativeGenerator writes the narrative returned by generate as the text of
+// an R4 resource that has none, e.g. to render a summary of its content on
+// the fly. Only the top-level resource is given a narrative, as contained
+// resources should not have one. It is an error to marshal resources of other
+// FHIR versions with a generator set. The marshalled proto is not modified.
+func NarrativeGenerator(generate NarrativeGeneratorFunc) MarshallerOption {
+	return func(m *Marshaller) {
+		m.narrativeGenerator = generate
+	}
+}
+
+// RegenerateNarrative extends NarrativeGenerator to resources that already
+// have a narrative when regenerate is true, replacing it with the generated
+// one.
+func RegenerateNarrative(regenerate bool) MarshallerOption {
+	return func(m *Marshaller) {
+		m.regenerateNarrative = regenerate
+	}
+}
+
+// applyNarrativeGenerator returns pb, or a copy of it with the narrative
+// given by the narrative generator.
+func (m *Marshaller) applyNarrativeGenerator(pb proto.Message) (proto.Message, error) {
+	if m.narrativeGenerator == nil {
+		return pb, nil
+	}
+	rm, f := narrativeField(pb.ProtoReflect())
+	if rm == nil {
+		// Let marshalling report the empty ContainedResource.
+		return pb, nil
+	}
+	if f == nil || f.Message().FullName() != (&d4pb.Narrative{}).ProtoReflect().Descriptor().FullName() {
+		return nil, fmt.Errorf("narrative generator does not support %v, want an R4 resource", rm.Descriptor().FullName())
+	}
+	if rm.Has(f) && !m.regenerateNarrative {
+		return pb, nil
+	}
+	narrative, err := m.narrativeGenerator(rm.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("generating narrative: %w", err)
+	}
+	if narrative == nil {
+		return pb, nil
+	}
+	out := proto.Clone(pb)
+	rm, f = narrativeField(out.ProtoReflect())
+	rm.Set(f, protoreflect.ValueOfMessage(narrative.ProtoReflect()))
+	return out, nil
+}
+
+// narrativeField returns the resource held by pb, unwrapping a
+// ContainedResource, and its text field, which is nil if it has none. The
+// resource is nil for an empty ContainedResource.
+func narrativeField(pb protoreflect.Message) (protoreflect.Message, protoreflect.FieldDescriptor) {
+	if od := pb.Descriptor().Oneofs().ByName(jsonpbhelper.OneofName); od != nil {
+		f := pb.WhichOneof(od)
+		if f == nil {
+			return nil, nil
+		}
+		pb = pb.Get(f).Message()
+	}
+	f := pb.Descriptor().Fields().ByName("text")
+	if f == nil || f.Message() == nil {
+		return pb, nil
+	}
+	return pb, f
+}
@@ -104,6 +104,15 @@ func protoToExtension(pb, ext proto.Message) error {
 
 // parseDecimal parses a FHIR decimal data object into a Decimal proto message, m.
 func parseDecimal(decimal json.RawMessage, m proto.Message) error {
+	// Decimals are JSON numbers, but some producers quote them to guard their
+	// precision; accept either form.
+	if len(decimal) > 0 && decimal[0] == '"' {
+		var s string
+		if err := json.Unmarshal(decimal, &s); err != nil {
+			return err
+		}
+		decimal = json.RawMessage(s)
+	}
 	mr := m.ProtoReflect()
 	fn := mr.Descriptor().FullName()
 	regex, has := jsonpbhelper.RegexValues[fn]
@@ -16,6 +16,7 @@ package jsonformat
 
 import (
 	"encoding/json"
+	"math"
 	"strconv"
 	"testing"
 
@@ -29,6 +30,7 @@ import (
 
 	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
 	e4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/fhirproto_extensions_go_proto"
+	d5pb "github.com/google/fhir/go/proto/google/fhir/proto/r5/core/datatypes_go_proto"
 	d3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
 	e3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/fhirproto_extensions_go_proto"
 )
@@ -313,7 +315,7 @@ func TestAddInternalExtension(t *testing.T) {
 }
 
 func TestDecimal(t *testing.T) {
-	allVers := []Version{fhirversion.STU3, fhirversion.R4}
+	allVers := []fhirversion.Version{fhirversion.STU3, fhirversion.R4}
 	tests := []struct {
 		value string
 		vers  []fhirversion.Version
@@ -351,7 +353,7 @@ func TestDecimal(t *testing.T) {
 }
 
 func TestDecimal_Invalid(t *testing.T) {
-	allVers := []Version{fhirversion.STU3, fhirversion.R4}
+	allVers := []fhirversion.Version{fhirversion.STU3, fhirversion.R4}
 	tests := []struct {
 		name string
 		json string
@@ -519,3 +521,68 @@ func TestParseBinary_Invalid(t *testing.T) {
 		})
 	}
 }
+
+func TestInteger64(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want int64
+	}{
+		{"near max", `"9223372036854775806"`, math.MaxInt64 - 1},
+		{"min", `"-9223372036854775808"`, math.MinInt64},
+		{"unquoted", `42`, 42},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			u, err := NewUnmarshaller("UTC", fhirversion.R4)
+			if err != nil {
+				t.Fatalf("failed to create unmarshaller: %v", err)
+			}
+			got, err := u.parsePrimitiveType("value", (&d5pb.Integer64{}).ProtoReflect(), json.RawMessage(test.json))
+			if err != nil {
+				t.Fatalf("parsePrimitiveType(%s) failed: %v", test.json, err)
+			}
+			want := &d5pb.Integer64{Value: strconv.FormatInt(test.want, 10)}
+			if !cmp.Equal(want, got, protocmp.Transform()) {
+				t.Errorf("parsePrimitiveType(%s): got %v, want %v", test.json, got, want)
+			}
+			m, err := NewPrettyMarshaller(fhirversion.R4)
+			if err != nil {
+				t.Fatalf("failed to create marshaller: %v", err)
+			}
+			out, err := m.marshalPrimitiveType(got.ProtoReflect())
+			if err != nil {
+				t.Fatalf("marshalPrimitiveType(%v) failed: %v", got, err)
+			}
+			wantJSON := jsonpbhelper.JSONString(strconv.FormatInt(test.want, 10))
+			if diff := cmp.Diff(wantJSON, out); diff != "" {
+				t.Errorf("marshalPrimitiveType(%v) returned unexpected diff (-want +got):\n%s", got, diff)
+			}
+		})
+	}
+}
+
+func TestInteger64_Invalid(t *testing.T) {
+	tests := []string{
+		`"9223372036854775808"`,
+		`"12.5"`,
+		`"abc"`,
+		`true`,
+	}
+	u, err := NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("failed to create unmarshaller: %v", err)
+	}
+	for _, test := range tests {
+		if _, err := u.parsePrimitiveType("value", (&d5pb.Integer64{}).ProtoReflect(), json.RawMessage(test)); err == nil {
+			t.Errorf("parsePrimitiveType(%s) succeeded, want error", test)
+		}
+	}
+	m, err := NewPrettyMarshaller(fhirversion.R4)
+	if err != nil {
+		t.Fatalf("failed to create marshaller: %v", err)
+	}
+	if _, err := m.marshalPrimitiveType((&d5pb.Integer64{Value: "1e3"}).ProtoReflect()); err == nil {
+		t.Errorf("marshalPrimitiveType() of an invalid integer64 succeeded, want error")
+	}
+}
@@ -86,6 +86,31 @@ func normalizeRelativeReferenceAndIgnoreHistory(pb proto.Message) error {
 	return nil
 }
 
+// rebasedReference returns pb, or a copy of it with the prefix oldBase of its
+// URI reference replaced by newBase.
+func rebasedReference(pb proto.Message, oldBase, newBase string) (proto.Message, error) {
+	rpb := pb.ProtoReflect()
+	field, err := jsonpbhelper.GetOneofField(rpb.Descriptor(), jsonpbhelper.RefOneofName, jsonpbhelper.RefRawURI)
+	if err != nil {
+		return nil, err
+	}
+	if !rpb.Has(field) {
+		return pb, nil
+	}
+	uri, err := accessor.GetString(rpb, jsonpbhelper.RefOneofName, jsonpbhelper.RefRawURI, "value")
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(uri, oldBase) {
+		return pb, nil
+	}
+	out := proto.Clone(pb)
+	if err := accessor.SetValue(out.ProtoReflect(), newBase+strings.TrimPrefix(uri, oldBase), jsonpbhelper.RefRawURI, "value"); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NormalizeReference normalizes a relative or internal reference into its specialized field.
 func NormalizeReference(pb proto.Message) error {
 	switch ref := pb.(type) {
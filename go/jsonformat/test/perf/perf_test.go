@@ -19,6 +19,7 @@ import (
 	"io/ioutil"
 	"bytes"
 	"encoding/base64"
+	"fmt"
 	"io"
 	"math/rand"
 	"path"
@@ -31,6 +32,7 @@ import (
 	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
 	r4binarypb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/binary_go_proto"
 	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
 )
 
 const (
@@ -196,3 +198,83 @@ func BenchmarkLargeBinary_Unmarshal(b *testing.B) {
 		}
 	}
 }
+
+const patientJSON = `{"resourceType":"Patient","id":"example","active":true,` +
+	`"identifier":[{"system":"urn:oid:1.2.36.146.595.217.0.1","value":"12345"}],` +
+	`"name":[{"use":"official","family":"Chalmers","given":["Peter","James"]}],` +
+	`"telecom":[{"system":"phone","value":"(03) 5555 6473","use":"work"}],` +
+	`"gender":"male","birthDate":"1974-12-25",` +
+	`"address":[{"use":"home","line":["534 Erewhon St"],"city":"PleasantVille","postalCode":"3999"}]}`
+
+func BenchmarkUnmarshal_Patient(b *testing.B) {
+	um, err := jsonformat.NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		b.Fatalf("Failed to create the unmarshaller due to error: %v", err)
+	}
+	d := []byte(patientJSON)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := um.Unmarshal(d); err != nil {
+			b.Fatalf("Failed to unmarshal data due to error: %v", err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalInto_Patient(b *testing.B) {
+	um, err := jsonformat.NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		b.Fatalf("Failed to create the unmarshaller due to error: %v", err)
+	}
+	d := []byte(patientJSON)
+	dst := &r4patientpb.Patient{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := um.UnmarshalInto(d, dst); err != nil {
+			b.Fatalf("Failed to unmarshal data due to error: %v", err)
+		}
+	}
+}
+
+// patientInputs returns n Patient documents with distinct ids.
+func patientInputs(n int) [][]byte {
+	inputs := make([][]byte, n)
+	for i := range inputs {
+		inputs[i] = []byte(strings.Replace(patientJSON, `"id":"example"`, fmt.Sprintf(`"id":"p%d"`, i), 1))
+	}
+	return inputs
+}
+
+func BenchmarkUnmarshal_PatientsSerial(b *testing.B) {
+	um, err := jsonformat.NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		b.Fatalf("Failed to create the unmarshaller due to error: %v", err)
+	}
+	inputs := patientInputs(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, d := range inputs {
+			if _, err := um.Unmarshal(d); err != nil {
+				b.Fatalf("Failed to unmarshal data due to error: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkUnmarshal_PatientsConcurrent(b *testing.B) {
+	um, err := jsonformat.NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		b.Fatalf("Failed to create the unmarshaller due to error: %v", err)
+	}
+	inputs := patientInputs(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, errs := jsonformat.UnmarshalConcurrent(um, inputs, benchmarkParallelism)
+		for _, err := range errs {
+			if err != nil {
+				b.Fatalf("Failed to unmarshal data due to error: %v", err)
+			}
+		}
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -57,24 +58,144 @@ type Unmarshaller struct {
 	validator       Validator
 	cfg             config
 	ver             fhirversion.Version
+	// If true, cardinality mismatches between the JSON and the proto are
+	// coerced rather than rejected.
+	coerceCardinality bool
+	// If true, primitives given as an empty JSON string are read as absent
+	// rather than rejected.
+	emptyStringAsAbsent bool
+	// If set, only documents whose resourceType is in this set are accepted.
+	allowedResourceTypes map[string]bool
+	// If true, allowedResourceTypes also applies to the resources of Bundle
+	// entries.
+	restrictBundleEntries bool
+	// If true, enum codes are matched case-insensitively.
+	caseInsensitiveCodes bool
+	// If true, times of 24:00:00 and leap seconds are normalized rather than
+	// rejected.
+	normalizeClockOverflow bool
+	// If true, unknown primitive-valued keys are ignored in objects holding a
+	// primitive extension object without its value.
+	repairMisplacedPrimitives bool
+	// If true, booleans and integers given as JSON strings are parsed as if
+	// they were unquoted.
+	coercePrimitiveStrings bool
+}
+
+// An UnmarshallerOption configures an Unmarshaller.
+type UnmarshallerOption func(*Unmarshaller)
+
+// CoerceCardinality accepts JSON from non-conformant servers when coerce is
+// true: a lone value given for a repeated field is read as a one-element
+// array, and a one-element array given for a singular field is read as its
+// element. By default such input is rejected.
+func CoerceCardinality(coerce bool) UnmarshallerOption {
+	return func(u *Unmarshaller) {
+		u.coerceCardinality = coerce
+	}
+}
+
+// EmptyStringAsAbsent accepts JSON from feeds that send "" for primitive
+// values when absent is true: such a value is read as if the field were not
+// present, keeping any extensions given for it. In a repeated field the
+// element is kept without a value so that it stays aligned with its
+// extensions. By default empty strings are rejected, as the FHIR
+// specification requires primitive values to be non-empty.
+func EmptyStringAsAbsent(absent bool) UnmarshallerOption {
+	return func(u *Unmarshaller) {
+		u.emptyStringAsAbsent = absent
+	}
+}
+
+// AllowedResourceTypes rejects documents whose resourceType is not one of
+// types, e.g. for an endpoint that only ingests Patients and Observations.
+// The resourceType is checked before the rest of the document is parsed.
+// Resources nested in the document, such as contained resources, are not
+// checked; see RestrictBundleEntries.
+func AllowedResourceTypes(types []string) UnmarshallerOption {
+	return func(u *Unmarshaller) {
+		u.allowedResourceTypes = map[string]bool{}
+		for _, t := range types {
+			u.allowedResourceTypes[t] = true
+		}
+	}
+}
+
+// RestrictBundleEntries extends AllowedResourceTypes to the resources of
+// Bundle entries when restrict is true, so that a Bundle is only accepted if
+// it and all of its entries have allowed types. It has no effect without
+// AllowedResourceTypes.
+func RestrictBundleEntries(restrict bool) UnmarshallerOption {
+	return func(u *Unmarshaller) {
+		u.restrictBundleEntries = restrict
+	}
+}
+
+// CaseInsensitiveCodes accepts JSON from sources that send codes in the wrong
+// case when ignoreCase is true. Codes such as "active" are already matched in
+// any case, so "ACTIVE" is accepted by default; this option extends that to
+// codes with mixed-case spellings, such as "BIOLOGICALAGENT" for
+// "biologicalAgent". It applies to codes bound to a value set with a fixed
+// list of codes; the marshaller still emits the canonical code.
+func CaseInsensitiveCodes(ignoreCase bool) UnmarshallerOption {
+	return func(u *Unmarshaller) {
+		u.caseInsensitiveCodes = ignoreCase
+	}
+}
+
+// NormalizeClockOverflow accepts times of day of 24:00:00 and leap seconds,
+// such as 23:59:60, in time, dateTime and instant values when normalize is
+// true, reading them as the instant that follows: "2023-03-15T24:00:00Z" is
+// read as "2023-03-16T00:00:00Z" and "23:59:60" as "00:00:00". By default
+// such values are rejected, as the FHIR specification does not allow them.
+func NormalizeClockOverflow(normalize bool) UnmarshallerOption {
+	return func(u *Unmarshaller) {
+		u.normalizeClockOverflow = normalize
+	}
+}
+
+// RepairMisplacedPrimitives accepts JSON from sources that write the value of
+// a primitive under the wrong key, next to its extension object, when repair
+// is true. In an object holding a primitive extension object such as
+// "_gender" without the matching "gender", unknown keys with a string, number
+// or boolean value are ignored rather than rejected, and the primitive is
+// read from its extension object alone, leaving its value empty. A "_gender"
+// without "gender" is valid FHIR and is always accepted; by default the
+// unknown keys are rejected.
+func RepairMisplacedPrimitives(repair bool) UnmarshallerOption {
+	return func(u *Unmarshaller) {
+		u.repairMisplacedPrimitives = repair
+	}
+}
+
+// CoercePrimitiveStrings accepts JSON from feeds that quote booleans and
+// integers, such as "true" or "5", when coerce is true: such strings given
+// for a boolean, integer, positiveInt or unsignedInt are parsed as if they
+// were unquoted. Strings that do not hold a boolean or integer literal, such
+// as "maybe" for a boolean, are still rejected. By default the values must
+// not be quoted.
+func CoercePrimitiveStrings(coerce bool) UnmarshallerOption {
+	return func(u *Unmarshaller) {
+		u.coercePrimitiveStrings = coerce
+	}
 }
 
 // NewUnmarshaller returns an Unmarshaller that performs resource validation.
-func NewUnmarshaller(tz string, ver fhirversion.Version) (*Unmarshaller, error) {
-	return newUnmarshaller(tz, ver, fhirvalidate.ValidateWithErrorReporter)
+func NewUnmarshaller(tz string, ver fhirversion.Version, opts ...UnmarshallerOption) (*Unmarshaller, error) {
+	return newUnmarshaller(tz, ver, fhirvalidate.ValidateWithErrorReporter, opts...)
 }
 
 // NewUnmarshallerWithoutValidation returns an Unmarshaller that doesn't perform resource validation.
-func NewUnmarshallerWithoutValidation(tz string, ver fhirversion.Version) (*Unmarshaller, error) {
-	return newUnmarshaller(tz, ver, fhirvalidate.ValidatePrimitivesWithErrorReporter)
+func NewUnmarshallerWithoutValidation(tz string, ver fhirversion.Version, opts ...UnmarshallerOption) (*Unmarshaller, error) {
+	return newUnmarshaller(tz, ver, fhirvalidate.ValidatePrimitivesWithErrorReporter, opts...)
 }
 
 // NewUnmarshallerWithValidator returns an Unmarshaller that uses a custom Validator.
-func NewUnmarshallerWithValidator(tz string, ver fhirversion.Version, validator Validator) (*Unmarshaller, error) {
-	return newUnmarshaller(tz, ver, validator)
+func NewUnmarshallerWithValidator(tz string, ver fhirversion.Version, validator Validator, opts ...UnmarshallerOption) (*Unmarshaller, error) {
+	return newUnmarshaller(tz, ver, validator, opts...)
 }
 
-func newUnmarshaller(tz string, ver fhirversion.Version, validator Validator) (*Unmarshaller, error) {
+func newUnmarshaller(tz string, ver fhirversion.Version, validator Validator, opts ...UnmarshallerOption) (*Unmarshaller, error) {
 	cfg, err := getConfig(ver)
 	if err != nil {
 		return nil, err
@@ -84,12 +205,16 @@ func newUnmarshaller(tz string, ver fhirversion.Version, validator Validator) (*
 		return nil, err
 	}
 
-	return &Unmarshaller{
+	u := &Unmarshaller{
 		TimeZone:  l,
 		cfg:       cfg,
 		validator: validator,
 		ver:       ver,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u, nil
 }
 
 // A Validator validates a message against the FHIR specification.
@@ -97,23 +222,108 @@ type Validator func(proto.Message, errorreporter.ErrorReporter) error
 
 // Unmarshal a FHIR resource from JSON into a ContainedResource proto. The FHIR
 // version of the proto is determined by the version the Unmarshaller was
-// created with.
+// created with. in may be a json.RawMessage taken from a larger document.
 func (u *Unmarshaller) Unmarshal(in []byte, opts ...fhirvalidate.ValidationOption) (proto.Message, error) {
-	var umErrList jsonpbhelper.UnmarshalErrorList
 	er := errorreporter.NewBasicErrorReporter()
 	res, err := u.UnmarshalWithErrorReporter(in, er, opts...)
 	if err != nil {
 		return res, err
 	}
+	return res, reportedErrors(er)
+}
+
+// reportedErrors returns the errors collected by er as an UnmarshalErrorList,
+// or nil if there are none.
+func reportedErrors(er *errorreporter.BasicErrorReporter) error {
+	var umErrList jsonpbhelper.UnmarshalErrorList
 	for _, error := range er.Errors {
 		if err := jsonpbhelper.AppendUnmarshalError(&umErrList, *error); err != nil {
-			return res, err
+			return err
 		}
 	}
 	if len(umErrList) > 0 {
-		return res, umErrList
+		return umErrList
 	}
-	return res, nil
+	return nil
+}
+
+// UnmarshalInto unmarshals a FHIR resource from JSON into dst, an existing
+// message that is reset first, so that a loop can reuse one message rather
+// than allocating a new resource and ContainedResource for each document.
+// Nested elements are still allocated. dst may be a ContainedResource or a
+// resource of the Unmarshaller's FHIR version; in the latter case the
+// document's resourceType must match it.
+func (u *Unmarshaller) UnmarshalInto(in []byte, dst proto.Message) error {
+	var decoded map[string]json.RawMessage
+	if err := jsp.Unmarshal(in, &decoded); err != nil {
+		return &jsonpbhelper.UnmarshalError{
+			Details:     "invalid JSON",
+			Diagnostics: err.Error(),
+			Cause:       err,
+		}
+	}
+	rt, err := resourceType("", decoded)
+	if err != nil {
+		return err
+	}
+	if err := u.checkResourceTypeAllowed("", rt); err != nil {
+		return err
+	}
+	delete(decoded, jsonpbhelper.ResourceTypeField)
+
+	cr := u.cfg.newEmptyContainedResource()
+	oneofDesc := cr.ProtoReflect().Descriptor().Oneofs().ByName(jsonpbhelper.OneofName)
+	if oneofDesc == nil {
+		return fmt.Errorf("oneof field not found: %v", jsonpbhelper.OneofName)
+	}
+	var f protoreflect.FieldDescriptor
+	for i := 0; i < oneofDesc.Fields().Len(); i++ {
+		if of := oneofDesc.Fields().Get(i); of.Message() != nil && string(of.Message().Name()) == rt {
+			f = of
+			break
+		}
+	}
+	if f == nil {
+		return jsonpbhelper.UnmarshalErrorList{&jsonpbhelper.UnmarshalError{
+			Path:        rt,
+			Details:     "unknown resource type",
+			Diagnostics: strconv.Quote(rt),
+		}}
+	}
+
+	// dst is only reset once it is known to match, so that a mismatched
+	// document leaves it untouched.
+	dm := dst.ProtoReflect()
+	var target protoreflect.Message
+	switch dm.Descriptor().FullName() {
+	case cr.ProtoReflect().Descriptor().FullName():
+		proto.Reset(dst)
+		target = dm.Mutable(f).Message()
+		cr = dst
+	case f.Message().FullName():
+		proto.Reset(dst)
+		target = dm
+		// Validate through a ContainedResource so that error paths match
+		// those of Unmarshal.
+		cr.ProtoReflect().Set(f, protoreflect.ValueOfMessage(dm))
+	default:
+		return fmt.Errorf("resourceType %q does not match destination %v", rt, dm.Descriptor().FullName())
+	}
+	if err := u.mergeMessage(rt, decoded, target); err != nil {
+		var errors jsonpbhelper.UnmarshalErrorList
+		if err := jsonpbhelper.AppendUnmarshalError(&errors, err); err != nil {
+			return err
+		}
+		return errors
+	}
+	if u.validator == nil {
+		return nil
+	}
+	er := errorreporter.NewBasicErrorReporter()
+	if err := u.validator(cr, er); err != nil {
+		return err
+	}
+	return reportedErrors(er)
 }
 
 // UnmarshalWithErrorReporter unmarshals a FHIR resource from JSON []byte data into a
@@ -171,6 +381,37 @@ func (u *Unmarshaller) UnmarshalFromReaderWithErrorReporter(in io.Reader, er err
 	return u.unmarshalJSONObject(decoded, er)
 }
 
+// UnmarshalAll unmarshals a stream of FHIR resources held in consecutive
+// top-level JSON objects, such as those written back to back by some sources,
+// into ContainedResource protos. Unlike NDJSON, the objects need not be
+// separated by newlines; any whitespace between them is ignored. Each
+// resource is validated as by Unmarshal, and the first error, from either
+// parsing or validation, stops the stream.
+func (u *Unmarshaller) UnmarshalAll(r io.Reader) ([]proto.Message, error) {
+	var res []proto.Message
+	d := jsp.NewDecoder(r)
+	for d.More() {
+		var decoded map[string]json.RawMessage
+		if err := d.Decode(&decoded); err != nil {
+			return nil, fmt.Errorf("resource %d: %w", len(res), &jsonpbhelper.UnmarshalError{
+				Details:     "invalid JSON",
+				Diagnostics: err.Error(),
+				Cause:       err,
+			})
+		}
+		er := errorreporter.NewBasicErrorReporter()
+		msg, err := u.unmarshalJSONObject(decoded, er)
+		if err == nil {
+			err = reportedErrors(er)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("resource %d: %w", len(res), err)
+		}
+		res = append(res, msg)
+	}
+	return res, nil
+}
+
 func (u *Unmarshaller) unmarshalJSONObject(decoded map[string]json.RawMessage, er errorreporter.ErrorReporter, opts ...fhirvalidate.ValidationOption) (proto.Message, error) {
 	res, err := u.parseContainedResource("", decoded)
 	if err != nil {
@@ -218,24 +459,59 @@ func lastFieldInPath(jsonPath string) string {
 	return strings.Split(s, "[")[0]
 }
 
-func (u *Unmarshaller) parseContainedResource(jsonPath string, decmap map[string]json.RawMessage) (proto.Message, error) {
-	var errors jsonpbhelper.UnmarshalErrorList
-	// Determine the type of the resource.
+// resourceType returns the resourceType of the resource in decmap.
+func resourceType(jsonPath string, decmap map[string]json.RawMessage) (string, error) {
 	rt, ok := decmap[jsonpbhelper.ResourceTypeField]
 	if !ok {
-		return nil, &jsonpbhelper.UnmarshalError{
+		return "", &jsonpbhelper.UnmarshalError{
 			Path:    jsonPath,
 			Details: fmt.Sprintf("missing required field %q", jsonpbhelper.ResourceTypeField),
 		}
 	}
 	var rtstr string
 	if err := jsp.Unmarshal(rt, &rtstr); err != nil {
-		return nil, &jsonpbhelper.UnmarshalError{
+		return "", &jsonpbhelper.UnmarshalError{
 			Path:        jsonPath,
 			Details:     "invalid resource type",
 			Diagnostics: string(rt),
 		}
 	}
+	return rtstr, nil
+}
+
+// checkResourceTypeAllowed returns an error if the resource of type rt at
+// jsonPath is subject to AllowedResourceTypes and its type is not allowed.
+func (u *Unmarshaller) checkResourceTypeAllowed(jsonPath, rt string) error {
+	if u.allowedResourceTypes == nil || u.allowedResourceTypes[rt] {
+		return nil
+	}
+	if jsonPath != "" && !(u.restrictBundleEntries && isBundleEntryResource(jsonPath)) {
+		return nil
+	}
+	return jsonpbhelper.UnmarshalErrorList{&jsonpbhelper.UnmarshalError{
+		Path:        jsonpbhelper.AddFieldToPath(jsonPath, rt),
+		Details:     "resource type not allowed",
+		Diagnostics: strconv.Quote(rt),
+	}}
+}
+
+// isBundleEntryResource reports whether jsonPath is that of the resource of a
+// Bundle entry, e.g. "Bundle.entry[0].resource".
+func isBundleEntryResource(jsonPath string) bool {
+	sp := strings.Split(jsonPath, ".")
+	n := len(sp)
+	return n >= 3 && sp[n-1] == "resource" && strings.Split(sp[n-2], "[")[0] == "entry" && sp[n-3] == "Bundle"
+}
+
+func (u *Unmarshaller) parseContainedResource(jsonPath string, decmap map[string]json.RawMessage) (proto.Message, error) {
+	var errors jsonpbhelper.UnmarshalErrorList
+	rtstr, err := resourceType(jsonPath, decmap)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkResourceTypeAllowed(jsonPath, rtstr); err != nil {
+		return nil, err
+	}
 	delete(decmap, jsonpbhelper.ResourceTypeField)
 	jsonPath = jsonpbhelper.AddFieldToPath(jsonPath, rtstr)
 
@@ -298,8 +574,8 @@ func (u *Unmarshaller) mergeMessage(jsonPath string, decmap map[string]json.RawM
 		proto.Merge(pb.Interface(), cr)
 		return nil
 	}
-	if pbdesc.Name() == protoName(&anypb.Any{}) && lastFieldInPath(jsonPath) == jsonpbhelper.ContainedField {
-		// Special handling of inlined resources, with 'contained' JSON field name and Any proto type.
+	if pbdesc.Name() == protoName(&anypb.Any{}) && jsonpbhelper.IsInlineResourceField(lastFieldInPath(jsonPath)) {
+		// Special handling of inlined resources, with 'contained' or 'resource' JSON field name and Any proto type.
 		cr, err := u.parseContainedResource(jsonPath, decmap)
 		if err != nil {
 			return err
@@ -329,6 +605,9 @@ func (u *Unmarshaller) mergeMessage(jsonPath string, decmap map[string]json.RawM
 
 		f, ok := fieldMap[normalizedFieldName]
 		if !ok {
+			if u.repairMisplacedPrimitives && isJSONScalar(v) && hasOrphanedPrimitiveExtension(decmap, fieldMap) {
+				continue
+			}
 			errors = append(errors, &jsonpbhelper.UnmarshalError{
 				Path:        jsonPath,
 				Details:     "unknown field",
@@ -356,6 +635,36 @@ func (u *Unmarshaller) mergeMessage(jsonPath string, decmap map[string]json.RawM
 	return nil
 }
 
+// hasOrphanedPrimitiveExtension reports whether decmap holds the extension
+// object of a primitive field, such as "_gender", without its value.
+func hasOrphanedPrimitiveExtension(decmap map[string]json.RawMessage, fieldMap map[string]protoreflect.FieldDescriptor) bool {
+	for k := range decmap {
+		if !strings.HasPrefix(k, "_") {
+			continue
+		}
+		if _, ok := fieldMap["_"+lowerFirst(k[1:])]; !ok {
+			continue
+		}
+		if _, ok := decmap[k[1:]]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSONScalar reports whether v is a JSON string, number or boolean.
+func isJSONScalar(v json.RawMessage) bool {
+	v = bytes.TrimSpace(v)
+	if len(v) == 0 {
+		return false
+	}
+	switch v[0] {
+	case '{', '[', 'n':
+		return false
+	}
+	return true
+}
+
 // returns a copy of the input string with a lower case first character.
 func lowerFirst(s string) string {
 	if len(s) == 0 {
@@ -407,6 +716,15 @@ func (u *Unmarshaller) mergeField(jsonPath string, f protoreflect.FieldDescripto
 	}
 	switch f.Cardinality() {
 	case protoreflect.Optional:
+		if u.coerceCardinality && isJSONArray(v) {
+			var rms []json.RawMessage
+			if err := jsp.Unmarshal(v, &rms); err == nil && len(rms) == 1 {
+				v = rms[0]
+			}
+		}
+		if u.emptyStringAsAbsent && f.Message() != nil && jsonpbhelper.IsPrimitiveType(f.Message()) && isEmptyJSONString(v) {
+			return nil
+		}
 		if pb.Has(f) {
 			if !jsonpbhelper.IsPrimitiveType(f.Message()) {
 				return &jsonpbhelper.UnmarshalError{
@@ -433,7 +751,9 @@ func (u *Unmarshaller) mergeField(jsonPath string, f protoreflect.FieldDescripto
 		}
 	case protoreflect.Repeated:
 		var rms []json.RawMessage
-		if err := jsp.Unmarshal(v, &rms); err != nil {
+		if u.coerceCardinality && !isJSONArray(v) {
+			rms = []json.RawMessage{v}
+		} else if err := jsp.Unmarshal(v, &rms); err != nil {
 			return &jsonpbhelper.UnmarshalError{
 				Path:    jsonPath,
 				Details: "expected array",
@@ -448,6 +768,37 @@ func (u *Unmarshaller) mergeField(jsonPath string, f protoreflect.FieldDescripto
 	return nil
 }
 
+// isEmptyJSONString reports whether the raw JSON value v is the empty string.
+func isEmptyJSONString(v json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(v), []byte(`""`))
+}
+
+// quotedIntegerPattern matches a JSON string holding an integer literal.
+var quotedIntegerPattern = regexp.MustCompile(`^"-?[0-9]+"$`)
+
+// unquotePrimitive returns the literal held by rm if it is a JSON string
+// quoting a valid boolean or integer token for the primitive type name, and
+// rm unchanged otherwise.
+func unquotePrimitive(name protoreflect.Name, rm json.RawMessage) json.RawMessage {
+	switch name {
+	case "Boolean":
+		if s := string(rm); s == `"true"` || s == `"false"` {
+			return rm[1 : len(rm)-1]
+		}
+	case "Integer", "PositiveInt", "UnsignedInt":
+		if quotedIntegerPattern.Match(rm) {
+			return rm[1 : len(rm)-1]
+		}
+	}
+	return rm
+}
+
+// isJSONArray reports whether the raw JSON value v is an array.
+func isJSONArray(v json.RawMessage) bool {
+	v = bytes.TrimSpace(v)
+	return len(v) > 0 && v[0] == '['
+}
+
 func (u *Unmarshaller) mergeRepeatedField(jsonPath string, fd protoreflect.FieldDescriptor, sourceElems []json.RawMessage, targetMsg protoreflect.Message) error {
 	targetList := targetMsg.Mutable(fd).List()
 	if !(targetList.Len() == 0 || targetList.Len() == len(sourceElems)) {
@@ -509,6 +860,23 @@ func (u *Unmarshaller) mergeReference(jsonPath string, rm json.RawMessage, pb pr
 	return nil
 }
 
+// parseClockValue parses a time, dateTime or instant into m with parse,
+// first normalizing 24:00:00 and leap seconds if the Unmarshaller accepts
+// them. wrapDay is true for times of day, which wrap around to midnight.
+func (u *Unmarshaller) parseClockValue(rm json.RawMessage, m proto.Message, parse func(json.RawMessage, proto.Message) error, wrapDay bool) error {
+	if !u.normalizeClockOverflow {
+		return parse(rm, m)
+	}
+	rm, extra, err := normalizeClockOverflow(rm)
+	if err != nil {
+		return err
+	}
+	if err := parse(rm, m); err != nil || extra == 0 {
+		return err
+	}
+	return addToValueUs(m, extra, wrapDay)
+}
+
 func (u *Unmarshaller) mergePrimitiveType(dst, src proto.Message) error {
 	if proto.Size(src) == 0 {
 		// No merging necessary.
@@ -584,7 +952,19 @@ func (u *Unmarshaller) parsePrimitiveType(jsonPath string, in protoreflect.Messa
 		}
 		return pb.Interface(), nil
 	}
+	if isEmptyJSONString(rm) {
+		if u.emptyStringAsAbsent {
+			return in.New().Interface(), nil
+		}
+		return nil, &jsonpbhelper.UnmarshalError{
+			Path:    jsonPath,
+			Details: "empty string is not a valid primitive value",
+		}
+	}
 	d := in.Descriptor()
+	if u.coercePrimitiveStrings {
+		rm = unquotePrimitive(d.Name(), rm)
+	}
 	createAndSetValue := func(val interface{}) (proto.Message, error) {
 		rpb := in.New()
 		if err := accessor.SetValue(rpb, val, "value"); err != nil {
@@ -646,7 +1026,10 @@ func (u *Unmarshaller) parsePrimitiveType(jsonPath string, in protoreflect.Messa
 		return m, nil
 	case "DateTime":
 		m := in.New().Interface()
-		if err := parseDateTimeFromJSON(rm, u.TimeZone, m); err != nil {
+		parse := func(rm json.RawMessage, m proto.Message) error {
+			return parseDateTimeFromJSON(rm, u.TimeZone, m)
+		}
+		if err := u.parseClockValue(rm, m, parse, false); err != nil {
 			return nil, &jsonpbhelper.UnmarshalError{
 				Path:        jsonPath,
 				Details:     "expected datetime",
@@ -676,7 +1059,7 @@ func (u *Unmarshaller) parsePrimitiveType(jsonPath string, in protoreflect.Messa
 		return createAndSetValue(val)
 	case "Instant":
 		m := in.New().Interface()
-		if err := parseInstant(rm, m); err != nil {
+		if err := u.parseClockValue(rm, m, parseInstant, false); err != nil {
 			return nil, &jsonpbhelper.UnmarshalError{
 				Path:        jsonPath,
 				Details:     "expected instant",
@@ -685,14 +1068,42 @@ func (u *Unmarshaller) parsePrimitiveType(jsonPath string, in protoreflect.Messa
 		}
 		return m, nil
 	case "Integer":
-		var val int32
-		if err := jsp.Unmarshal(rm, &val); err != nil {
+		// Numeric primitives are parsed from the raw token text rather than
+		// through float64, which cannot represent every value exactly.
+		val, err := strconv.ParseInt(string(rm), 10, 32)
+		if err != nil {
 			return nil, &jsonpbhelper.UnmarshalError{
 				Path:        jsonPath,
 				Details:     "expected integer",
 				Diagnostics: fmt.Sprintf("found %s", rm),
 			}
 		}
+		return createAndSetValue(int32(val))
+	case "Integer64":
+		// integer64 is represented as a JSON string so that it survives
+		// JavaScript number handling, but a bare number is accepted too.
+		str := string(rm)
+		if len(rm) > 0 && rm[0] == '"' {
+			if err := jsp.Unmarshal(rm, &str); err != nil {
+				return nil, &jsonpbhelper.UnmarshalError{
+					Path:        jsonPath,
+					Details:     "expected integer64",
+					Diagnostics: fmt.Sprintf("found %s", rm),
+				}
+			}
+		}
+		val, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, &jsonpbhelper.UnmarshalError{
+				Path:        jsonPath,
+				Details:     "expected integer64",
+				Diagnostics: fmt.Sprintf("found %s", rm),
+			}
+		}
+		if d.Fields().ByName("value").Kind() == protoreflect.StringKind {
+			// Protos storing the value as a string keep its canonical form.
+			return createAndSetValue(strconv.FormatInt(val, 10))
+		}
 		return createAndSetValue(val)
 	case "Oid":
 		var val string
@@ -714,15 +1125,15 @@ func (u *Unmarshaller) parsePrimitiveType(jsonPath string, in protoreflect.Messa
 				Diagnostics: fmt.Sprintf("found %s", rm),
 			}
 		}
-		var val uint32
-		if err := jsp.Unmarshal(rm, &val); err != nil {
+		val, err := strconv.ParseUint(string(rm), 10, 32)
+		if err != nil {
 			return nil, &jsonpbhelper.UnmarshalError{
 				Path:        jsonPath,
 				Details:     "invalid positive integer",
 				Diagnostics: fmt.Sprintf("found %s", rm),
 			}
 		}
-		return createAndSetValue(val)
+		return createAndSetValue(uint32(val))
 	case "String":
 		var val string
 		if err := jsp.Unmarshal(rm, &val); err != nil {
@@ -735,7 +1146,7 @@ func (u *Unmarshaller) parsePrimitiveType(jsonPath string, in protoreflect.Messa
 		return createAndSetValue(val)
 	case "Time":
 		m := in.New().Interface()
-		if err := parseTime(rm, m); err != nil {
+		if err := u.parseClockValue(rm, m, parseTime, true); err != nil {
 			return nil, &jsonpbhelper.UnmarshalError{
 				Path:        jsonPath,
 				Details:     "invalid time",
@@ -753,14 +1164,14 @@ func (u *Unmarshaller) parsePrimitiveType(jsonPath string, in protoreflect.Messa
 				Diagnostics: fmt.Sprintf("found %s", rm),
 			}
 		}
-		var val uint32
-		if err := jsp.Unmarshal(rm, &val); err != nil {
+		val, err := strconv.ParseUint(string(rm), 10, 32)
+		if err != nil {
 			return nil, &jsonpbhelper.UnmarshalError{
 				Path:    jsonPath,
 				Details: "non-negative integer out of range 0..2,147,483,647",
 			}
 		}
-		return createAndSetValue(val)
+		return createAndSetValue(uint32(val))
 	case "Url", "Uri", "Canonical":
 		valType := strings.ToLower(string(d.Name()))
 		var val string
@@ -802,7 +1213,7 @@ func (u *Unmarshaller) parsePrimitiveType(jsonPath string, in protoreflect.Messa
 
 	// Handles specialized codes.
 	if proto.HasExtension(d.Options(), apb.E_FhirValuesetUrl) {
-		return jsonpbhelper.UnmarshalCode(jsonPath, in, rm)
+		return jsonpbhelper.UnmarshalCode(jsonPath, in, rm, u.caseInsensitiveCodes)
 	}
 	return nil, fmt.Errorf("unsupported FHIR primitive type: %v", d.Name())
 }
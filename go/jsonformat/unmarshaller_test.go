@@ -1052,9 +1052,6 @@ func TestUnmarshal(t *testing.T) {
 						t.Fatalf("unmarshal %v failed: %v", test.name, err)
 					}
 					sortExtensions := cmp.Options{
-						protocmp.SortRepeated(func(e1, e2 *d2pb.Extension) bool {
-							return e1.GetUrl().GetValue() < e2.GetUrl().GetValue()
-						}),
 						protocmp.SortRepeated(func(e1, e2 *d3pb.Extension) bool {
 							return e1.GetUrl().GetValue() < e2.GetUrl().GetValue()
 						}),
@@ -1532,7 +1529,7 @@ func TestUnmarshal_Errors(t *testing.T) {
       "resourceType": "Patient",
 			"gender": ["male", "female"]
     }`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.gender": invalid value \(expected a (AdministrativeGenderCode|GenderCode) object\)`},
 		},
 		{
@@ -1542,7 +1539,7 @@ func TestUnmarshal_Errors(t *testing.T) {
       "resourceType": "Patient",
 			"gender": "f"
     }`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.gender": code type mismatch`},
 		},
 		{
@@ -1552,7 +1549,7 @@ func TestUnmarshal_Errors(t *testing.T) {
       "resourceType": "Patient",
 			"gender": true
     }`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.gender": expected code`},
 		},
 		{
@@ -1561,7 +1558,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 		{
       "resourceType": "Patient",
     }`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`invalid JSON`},
 		},
 		{
@@ -1570,7 +1567,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 		{
       "resourceType": 1
     }`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{"invalid resource type"},
 		},
 		{
@@ -1579,13 +1576,13 @@ func TestUnmarshal_Errors(t *testing.T) {
 		{
       "resourceType": "Patient1"
     }`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient1": unknown resource type`},
 		},
 		{
 			name: "Missing resource type",
 			json: "{}",
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`missing required field "resourceType"`},
 		},
 		{
@@ -1595,7 +1592,7 @@ func TestUnmarshal_Errors(t *testing.T) {
       "resourceType": "Patient",
 			"foo": [1, 2]
     }`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient": unknown field`},
 		},
 		{
@@ -1605,7 +1602,7 @@ func TestUnmarshal_Errors(t *testing.T) {
       "resourceType": "Patient",
 			"fooBar": "1"
     }`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient": unknown field`},
 		},
 		{
@@ -1616,7 +1613,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 			"managingOrganization": {"reference": "Org/1"},
 			"_managingOrganization": {"foo": "bar"}
     }`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient": unknown field`},
 		},
 		{
@@ -1629,7 +1626,7 @@ func TestUnmarshal_Errors(t *testing.T) {
           "_given": {"id": "1"}
       }]
     }`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.name[0]._given": expected array`},
 		},
 		{
@@ -1642,7 +1639,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 					"_given": [{"id": "1"}, {"id": "2"}]
 				}]
 			}`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{
 				`error at "Patient.name[0]._given": array length mismatch, expected 1, found 2`,
 				`error at "Patient.name[0].given": array length mismatch, expected 2, found 1`,
@@ -1655,7 +1652,7 @@ func TestUnmarshal_Errors(t *testing.T) {
       "resourceType": "Patient",
 			"managingOrganization": {"foo": "bar"}
     }`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.managingOrganization": unknown field`},
 		},
 		{
@@ -1667,7 +1664,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 				"given": [1]
 			}]
     }`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.name[0].given[0]": expected string`},
 		},
 		{
@@ -1682,7 +1679,7 @@ func TestUnmarshal_Errors(t *testing.T) {
       }
     }
     `,
-			vers: []Version{fhirversion.STU3},
+			vers: []fhirversion.Version{fhirversion.STU3},
 			errs: []string{`error at "Patient.animal.species.coding": expected array`},
 		},
 		{
@@ -1712,7 +1709,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 			}]
     }
     `,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.name[0].given[0]": string contains invalid characters: U+0008`},
 		},
 		{
@@ -1723,7 +1720,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 			"implicitRules": "http://\u0000"
     }
     `,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.implicitRules": invalid uri`},
 		},
 		{
@@ -1734,7 +1731,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 			"implicitRules": " http://example.com/"
     }
     `,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.implicitRules": invalid uri`},
 		},
 		{
@@ -1745,7 +1742,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 			"effectiveDateTime": "invalid"
     }
     `,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Observation.effectiveDateTime": expected datetime`},
 		},
 		{
@@ -1758,7 +1755,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 				"value": "x"
 			}]
 		}`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.extension[0]": unknown field`},
 		},
 		{
@@ -1769,7 +1766,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 				"name": [{ "text": "` + "\xa0\xa1" + `"}]
 			}
 			`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.name[0].text": expected UTF-8 encoding`},
 		},
 		{
@@ -1780,7 +1777,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 				"language": "` + "\xa0\xa1" + `"
 			}
 			`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.language": expected UTF-8 encoding`},
 		},
 		// TODO(b/161479338): add test for rejecting upper camel case fields once deprecated.
@@ -1791,7 +1788,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 				"resourceType": "Patient",
 				"GENDER": "female"
 			}`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient": unknown field`},
 		},
 		{
@@ -1801,7 +1798,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 				"resourceType": "Patient",
 				"managingorganization": {"reference": "Org/1"}
 			}`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient": unknown field`},
 		},
 		{
@@ -1811,7 +1808,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 				"resourceType": "Patient",
 				"gEnDeR": "female"
 			}`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient": unknown field`},
 		},
 		{
@@ -1821,7 +1818,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 				"ResourceType": "Patient",
 				"gender": "female"
 			}`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`missing required field "resourceType"`},
 		},
 		{
@@ -1848,7 +1845,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 					"value": "female"
 				}
 			}`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.gender.value": invalid field`},
 		},
 		{
@@ -1860,7 +1857,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 					"reference": "DeviceRequest/1"
 				}
 			}`,
-			vers: []fhirversion.Version{fhirversion.DSTU2},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Observation.device": invalid reference`},
 		},
 		{
@@ -1872,13 +1869,13 @@ func TestUnmarshal_Errors(t *testing.T) {
 					"organizationId": "2810efe9-f993-489d-8d07-86ad32e54923"
 				}
 			}`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{`error at "Patient.managingOrganization.organizationId": invalid type: ReferenceId`},
 		},
 		{
 			name: "trailing characters",
 			json: `{"resourceType": "Patient"}{}`,
-			vers: []Version{fhirversion.STU3, fhirversion.R4},
+			vers: []fhirversion.Version{fhirversion.STU3, fhirversion.R4},
 			errs: []string{"invalid JSON"},
 		},
 	}
@@ -1908,7 +1905,7 @@ func TestUnmarshal_Errors(t *testing.T) {
 }
 
 func TestUnmarshal_ExtendedValidation_Errors(t *testing.T) {
-	[]Version{fhirversion.STU3, fhirversion.R4}
+	allVers := []fhirversion.Version{fhirversion.STU3, fhirversion.R4}
 	tests := []struct {
 		name string
 		json string
@@ -2500,6 +2497,20 @@ func TestParsePrimitiveType_Errors(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "PositiveInt leading plus",
+			value: json.RawMessage(`+123`),
+			msgs: []mvr{
+				{
+					ver: fhirversion.STU3,
+					r:   &d3pb.PositiveInt{},
+				},
+				{
+					ver: fhirversion.R4,
+					r:   &d4pb.PositiveInt{},
+				},
+			},
+		},
 		{
 			name:  "UnsignedInt 00",
 			value: json.RawMessage(`00`),
@@ -2787,7 +2798,7 @@ func TestUnmarshal_NestingDepth(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			versions := []Version{fhirversion.STU3, fhirversion.R4}
+			versions := []fhirversion.Version{fhirversion.STU3, fhirversion.R4}
 			for _, v := range versions {
 				t.Run(v.String(), func(t *testing.T) {
 					u := setupUnmarshaller(t, v)
@@ -2805,6 +2816,315 @@ func TestUnmarshal_NestingDepth(t *testing.T) {
 	}
 }
 
+func TestUnmarshal_CoerceCardinality(t *testing.T) {
+	want := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Patient{
+			Patient: &r4patientpb.Patient{
+				Name: []*d4pb.HumanName{{
+					Family: &d4pb.String{Value: "Doe"},
+					Given:  []*d4pb.String{{Value: "Jane"}},
+				}},
+				Gender: &r4patientpb.Patient_GenderCode{Value: c4pb.AdministrativeGenderCode_FEMALE},
+				ManagingOrganization: &d4pb.Reference{
+					Reference: &d4pb.Reference_OrganizationId{OrganizationId: &d4pb.ReferenceId{Value: "1"}},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name string
+		json string
+	}{
+		{
+			name: "object for repeated field",
+			json: `{"resourceType":"Patient","name":{"family":"Doe","given":"Jane"},"gender":"female","managingOrganization":{"reference":"Organization/1"}}`,
+		},
+		{
+			name: "array for singular field",
+			json: `{"resourceType":"Patient","name":[{"family":["Doe"],"given":["Jane"]}],"gender":["female"],"managingOrganization":[{"reference":"Organization/1"}]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			strict := setupUnmarshaller(t, fhirversion.R4)
+			if _, err := strict.Unmarshal([]byte(test.json)); err == nil {
+				t.Errorf("Unmarshal() without CoerceCardinality succeeded, want error")
+			}
+			u, err := NewUnmarshaller("America/Los_Angeles", fhirversion.R4, CoerceCardinality(true))
+			if err != nil {
+				t.Fatalf("failed to create unmarshaller; %v", err)
+			}
+			got, err := u.Unmarshal([]byte(test.json))
+			if err != nil {
+				t.Fatalf("Unmarshal() with CoerceCardinality failed: %v", err)
+			}
+			if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Unmarshal() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_EmptyStringAsAbsent(t *testing.T) {
+	in := `{"resourceType":"Patient","id":"p1","gender":"","name":[{"family":"","given":["Jane",""]}]}`
+
+	strict := setupUnmarshaller(t, fhirversion.R4)
+	if _, err := strict.Unmarshal([]byte(in)); err == nil {
+		t.Errorf("Unmarshal() without EmptyStringAsAbsent succeeded, want error")
+	}
+
+	u, err := NewUnmarshaller("America/Los_Angeles", fhirversion.R4, EmptyStringAsAbsent(true))
+	if err != nil {
+		t.Fatalf("failed to create unmarshaller; %v", err)
+	}
+	got, err := u.Unmarshal([]byte(in))
+	if err != nil {
+		t.Fatalf("Unmarshal() with EmptyStringAsAbsent failed: %v", err)
+	}
+	want := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Patient{
+			Patient: &r4patientpb.Patient{
+				Id: &d4pb.Id{Value: "p1"},
+				Name: []*d4pb.HumanName{{
+					Given: []*d4pb.String{{Value: "Jane"}, {}},
+				}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("Unmarshal() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshal_NumericPrecision(t *testing.T) {
+	const decimal = "123456789012345.678901234567890"
+	in := `{"resourceType":"Observation","status":"final","code":{"text":"x"},` +
+		`"valueQuantity":{"value":` + decimal + `},"component":[{"code":{"text":"y"},"valueInteger":2147483647}]}`
+
+	u := setupUnmarshaller(t, fhirversion.R4)
+	got, err := u.Unmarshal([]byte(in))
+	if err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	obs := got.(*r4pb.ContainedResource).GetObservation()
+	if v := obs.GetValue().GetQuantity().GetValue().GetValue(); v != decimal {
+		t.Errorf("Unmarshal() decimal = %s, want %s", v, decimal)
+	}
+	if v := obs.GetComponent()[0].GetValue().GetInteger().GetValue(); v != math.MaxInt32 {
+		t.Errorf("Unmarshal() integer = %d, want %d", v, int32(math.MaxInt32))
+	}
+
+	m, err := NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("failed to create marshaller; %v", err)
+	}
+	out, err := m.Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	if !bytes.Contains(out, []byte(`"value":`+decimal)) {
+		t.Errorf("Marshal() = %s, want decimal %s preserved exactly", out, decimal)
+	}
+}
+
+func TestUnmarshalInto(t *testing.T) {
+	u := setupUnmarshaller(t, fhirversion.R4)
+
+	patient := &r4patientpb.Patient{}
+	for _, id := range []string{"p1", "p2"} {
+		in := `{"resourceType":"Patient","id":"` + id + `","name":[{"family":"Doe"}]}`
+		if err := u.UnmarshalInto([]byte(in), patient); err != nil {
+			t.Fatalf("UnmarshalInto(%s) failed: %v", in, err)
+		}
+		want := &r4patientpb.Patient{
+			Id:   &d4pb.Id{Value: id},
+			Name: []*d4pb.HumanName{{Family: &d4pb.String{Value: "Doe"}}},
+		}
+		if diff := cmp.Diff(want, patient, protocmp.Transform()); diff != "" {
+			t.Errorf("UnmarshalInto(%s) returned unexpected diff (-want +got):\n%s", in, diff)
+		}
+	}
+
+	cr := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Observation{Observation: &r4observationpb.Observation{}},
+	}
+	if err := u.UnmarshalInto([]byte(`{"resourceType":"Patient","active":true}`), cr); err != nil {
+		t.Fatalf("UnmarshalInto(ContainedResource) failed: %v", err)
+	}
+	want := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Patient{Patient: &r4patientpb.Patient{Active: &d4pb.Boolean{Value: true}}},
+	}
+	if diff := cmp.Diff(want, cr, protocmp.Transform()); diff != "" {
+		t.Errorf("UnmarshalInto(ContainedResource) returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalInto_Errors(t *testing.T) {
+	u := setupUnmarshaller(t, fhirversion.R4)
+	tests := []struct {
+		name string
+		in   string
+		dst  proto.Message
+	}{
+		{
+			name: "resourceType mismatch",
+			in:   `{"resourceType":"Patient"}`,
+			dst:  &r4observationpb.Observation{},
+		},
+		{
+			name: "invalid field",
+			in:   `{"resourceType":"Patient","bogus":1}`,
+			dst:  &r4patientpb.Patient{},
+		},
+		{
+			name: "missing required field",
+			in:   `{"resourceType":"Observation","code":{"text":"x"}}`,
+			dst:  &r4observationpb.Observation{},
+		},
+		{
+			name: "missing resourceType",
+			in:   `{"id":"p1"}`,
+			dst:  &r4patientpb.Patient{},
+		},
+		{
+			name: "wrong FHIR version",
+			in:   `{"resourceType":"Patient"}`,
+			dst:  &r3pb.Patient{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := u.UnmarshalInto([]byte(test.in), test.dst); err == nil {
+				t.Errorf("UnmarshalInto(%s) succeeded, want error", test.in)
+			}
+		})
+	}
+}
+
+func TestUnmarshalInto_MismatchLeavesDestination(t *testing.T) {
+	u := setupUnmarshaller(t, fhirversion.R4)
+	dsts := []proto.Message{
+		&r4observationpb.Observation{Id: &d4pb.Id{Value: "o1"}},
+		&r3pb.Patient{Id: &d3pb.Id{Value: "p0"}},
+	}
+	for _, dst := range dsts {
+		want := proto.Clone(dst)
+		if err := u.UnmarshalInto([]byte(`{"resourceType":"Patient","id":"p1"}`), dst); err == nil {
+			t.Errorf("UnmarshalInto() into %T succeeded, want error", dst)
+			continue
+		}
+		if diff := cmp.Diff(want, dst, protocmp.Transform()); diff != "" {
+			t.Errorf("UnmarshalInto() modified the destination on mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestUnmarshalAll(t *testing.T) {
+	u := setupUnmarshaller(t, fhirversion.R4)
+	patient := func(id string) proto.Message {
+		return &r4pb.ContainedResource{
+			OneofResource: &r4pb.ContainedResource_Patient{Patient: &r4patientpb.Patient{Id: &d4pb.Id{Value: id}}},
+		}
+	}
+	tests := []struct {
+		name string
+		in   string
+		want []proto.Message
+	}{
+		{
+			name: "concatenated",
+			in:   `{"resourceType":"Patient","id":"p1"}{"resourceType":"Patient","id":"p2"}`,
+			want: []proto.Message{patient("p1"), patient("p2")},
+		},
+		{
+			name: "whitespace between",
+			in:   " {\"resourceType\":\"Patient\",\"id\":\"p1\"}\n\n\t{\"resourceType\":\"Patient\",\"id\":\"p2\"} \n",
+			want: []proto.Message{patient("p1"), patient("p2")},
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := u.UnmarshalAll(strings.NewReader(test.in))
+			if err != nil {
+				t.Fatalf("UnmarshalAll(%q) failed: %v", test.in, err)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("UnmarshalAll(%q) returned unexpected diff (-want +got):\n%s", test.in, diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshalAll_Errors(t *testing.T) {
+	u := setupUnmarshaller(t, fhirversion.R4)
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{
+			name: "truncated object",
+			in:   `{"resourceType":"Patient","id":"p1"}{"resourceType":"Patient"`,
+		},
+		{
+			name: "trailing garbage",
+			in:   `{"resourceType":"Patient","id":"p1"}x`,
+		},
+		{
+			name: "invalid resource",
+			in:   `{"resourceType":"Patient","id":"p1"}{"resourceType":"Patient","bogus":1}`,
+		},
+		{
+			name: "not an object",
+			in:   `[{"resourceType":"Patient","id":"p1"}]`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := u.UnmarshalAll(strings.NewReader(test.in)); err == nil {
+				t.Errorf("UnmarshalAll(%q) = %v, want error", test.in, got)
+			}
+		})
+	}
+}
+
+func TestUnmarshalConcurrent(t *testing.T) {
+	u := setupUnmarshaller(t, fhirversion.R4)
+	var inputs [][]byte
+	for i := 0; i < 100; i++ {
+		in := fmt.Sprintf(`{"resourceType":"Patient","id":"p%d"}`, i)
+		if i%10 == 3 {
+			in = `{"resourceType":"Patient","bogus":1}`
+		}
+		inputs = append(inputs, []byte(in))
+	}
+	for _, workers := range []int{0, 1, 4, 200} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			msgs, errs := UnmarshalConcurrent(u, inputs, workers)
+			if len(msgs) != len(inputs) || len(errs) != len(inputs) {
+				t.Fatalf("UnmarshalConcurrent() returned %d messages and %d errors, want %d of each", len(msgs), len(errs), len(inputs))
+			}
+			for i := range inputs {
+				if i%10 == 3 {
+					if errs[i] == nil {
+						t.Errorf("UnmarshalConcurrent() error %d is nil, want error", i)
+					}
+					continue
+				}
+				if errs[i] != nil {
+					t.Errorf("UnmarshalConcurrent() error %d: %v", i, errs[i])
+					continue
+				}
+				if got, want := msgs[i].(*r4pb.ContainedResource).GetPatient().GetId().GetValue(), fmt.Sprintf("p%d", i); got != want {
+					t.Errorf("UnmarshalConcurrent() message %d has id %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
 func TestUnmarshaller_UnmarshalR4Streaming(t *testing.T) {
 	t.Run("streaming unmarshal", func(t *testing.T) {
 		json := `{"resourceType":"Patient", "id": "exampleID1"}
@@ -2887,3 +3207,228 @@ func ExampleUnmarshaller_UnmarshalR4Streaming() {
 	// exampleID1
 	// exampleID2
 }
+
+func TestUnmarshal_AllowedResourceTypes(t *testing.T) {
+	const (
+		medication = `{"resourceType":"Medication","id":"m1"}`
+		patient    = `{"resourceType":"Patient","id":"p1","contained":[{"resourceType":"Medication","id":"m1"}]}`
+		bundle     = `{"resourceType":"Bundle","type":"collection","entry":[{"resource":{"resourceType":"Patient","id":"p1"}},{"resource":{"resourceType":"Medication","id":"m1"}}]}`
+	)
+	tests := []struct {
+		name    string
+		opts    []UnmarshallerOption
+		in      string
+		wantErr bool
+	}{
+		{
+			name:    "disallowed type",
+			opts:    []UnmarshallerOption{AllowedResourceTypes([]string{"Patient", "Observation"})},
+			in:      medication,
+			wantErr: true,
+		},
+		{
+			name: "allowed type with contained resource",
+			opts: []UnmarshallerOption{AllowedResourceTypes([]string{"Patient", "Observation"}), RestrictBundleEntries(true)},
+			in:   patient,
+		},
+		{
+			name: "bundle entries not checked",
+			opts: []UnmarshallerOption{AllowedResourceTypes([]string{"Bundle", "Patient"})},
+			in:   bundle,
+		},
+		{
+			name:    "bundle entries checked",
+			opts:    []UnmarshallerOption{AllowedResourceTypes([]string{"Bundle", "Patient"}), RestrictBundleEntries(true)},
+			in:      bundle,
+			wantErr: true,
+		},
+		{
+			name: "no restriction",
+			in:   medication,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			u, err := NewUnmarshaller("UTC", fhirversion.R4, test.opts...)
+			if err != nil {
+				t.Fatalf("NewUnmarshaller() failed: %v", err)
+			}
+			_, err = u.Unmarshal([]byte(test.in))
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Errorf("Unmarshal(%s) got err %v, want error: %v", test.in, err, test.wantErr)
+			}
+			if err := u.UnmarshalInto([]byte(test.in), &r4pb.ContainedResource{}); (err != nil) != test.wantErr {
+				t.Errorf("UnmarshalInto(%s) got err %v, want error: %v", test.in, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_AllowedResourceTypesError(t *testing.T) {
+	u, err := NewUnmarshaller("UTC", fhirversion.R4, AllowedResourceTypes([]string{"Patient", "Observation"}))
+	if err != nil {
+		t.Fatalf("NewUnmarshaller() failed: %v", err)
+	}
+	_, err = u.Unmarshal([]byte(`{"resourceType":"Medication","code":{"bogus":1}}`))
+	want := jsonpbhelper.UnmarshalErrorList{&jsonpbhelper.UnmarshalError{
+		Path:        "Medication",
+		Details:     "resource type not allowed",
+		Diagnostics: `"Medication"`,
+	}}
+	if diff := cmp.Diff(want, err); diff != "" {
+		t.Errorf("Unmarshal() returned unexpected error diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshal_CaseInsensitiveCodes(t *testing.T) {
+	const in = `{"resourceType":"Device","id":"d1","status":"ACTIVE"}`
+	const mixedCase = `{"resourceType":"BiologicallyDerivedProduct","productCategory":"BIOLOGICALAGENT"}`
+	def, err := NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("NewUnmarshaller() failed: %v", err)
+	}
+	if _, err := def.Unmarshal([]byte(in)); err != nil {
+		t.Errorf("Unmarshal(%s) failed by default: %v", in, err)
+	}
+	if _, err := def.Unmarshal([]byte(mixedCase)); err == nil {
+		t.Errorf("Unmarshal(%s) succeeded by default, want error", mixedCase)
+	}
+
+	u, err := NewUnmarshaller("UTC", fhirversion.R4, CaseInsensitiveCodes(true))
+	if err != nil {
+		t.Fatalf("NewUnmarshaller() failed: %v", err)
+	}
+	got, err := u.Unmarshal([]byte(in))
+	if err != nil {
+		t.Fatalf("Unmarshal(%s) failed: %v", in, err)
+	}
+	want := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Device{Device: &r4devicepb.Device{
+			Id:     &d4pb.Id{Value: "d1"},
+			Status: &r4devicepb.Device_StatusCode{Value: c4pb.FHIRDeviceStatusCode_ACTIVE},
+		}},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("Unmarshal(%s) returned unexpected diff (-want +got):\n%s", in, diff)
+	}
+	if _, err := u.Unmarshal([]byte(mixedCase)); err != nil {
+		t.Errorf("Unmarshal(%s) failed: %v", mixedCase, err)
+	}
+	if _, err := u.Unmarshal([]byte(`{"resourceType":"Device","status":"ACTIVATED"}`)); err == nil {
+		t.Errorf("Unmarshal() of an unknown code succeeded, want error")
+	}
+
+	m, err := NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("NewMarshaller() failed: %v", err)
+	}
+	out, err := m.Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	if wantJSON := `{"id":"d1","resourceType":"Device","status":"active"}`; string(out) != wantJSON {
+		t.Errorf("Marshal() = %s, want %s", out, wantJSON)
+	}
+}
+
+func TestUnmarshal_RepairMisplacedPrimitives(t *testing.T) {
+	const in = `{
+		"resourceType": "Patient",
+		"id": "p1",
+		"_gender": {
+			"extension": [{"url": "http://example.com/source", "valueString": "intake form"}]
+		},
+		"gender_": "female"
+	}`
+	strict, err := NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("NewUnmarshaller() failed: %v", err)
+	}
+	if _, err := strict.Unmarshal([]byte(in)); err == nil {
+		t.Errorf("Unmarshal(%s) succeeded by default, want error", in)
+	}
+
+	u, err := NewUnmarshaller("UTC", fhirversion.R4, RepairMisplacedPrimitives(true))
+	if err != nil {
+		t.Fatalf("NewUnmarshaller() failed: %v", err)
+	}
+	got, err := u.Unmarshal([]byte(in))
+	if err != nil {
+		t.Fatalf("Unmarshal(%s) failed: %v", in, err)
+	}
+	want := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Patient{Patient: &r4patientpb.Patient{
+			Id: &d4pb.Id{Value: "p1"},
+			Gender: &r4patientpb.Patient_GenderCode{
+				Extension: []*d4pb.Extension{{
+					Url: &d4pb.Uri{Value: "http://example.com/source"},
+					Value: &d4pb.Extension_ValueX{
+						Choice: &d4pb.Extension_ValueX_StringValue{StringValue: &d4pb.String{Value: "intake form"}},
+					},
+				}, {
+					Url: &d4pb.Uri{Value: jsonpbhelper.PrimitiveHasNoValueURL},
+					Value: &d4pb.Extension_ValueX{
+						Choice: &d4pb.Extension_ValueX_Boolean{Boolean: &d4pb.Boolean{Value: true}},
+					},
+				}},
+			},
+		}},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("Unmarshal(%s) returned unexpected diff (-want +got):\n%s", in, diff)
+	}
+
+	for _, bad := range []string{
+		// Without an orphaned extension object there is nothing to repair.
+		`{"resourceType":"Patient","gender":"female","gender_":"female"}`,
+		// Only primitive values are taken for misplaced values.
+		`{"resourceType":"Patient","_gender":{"id":"g"},"gender_":{"value":"female"}}`,
+	} {
+		if _, err := u.Unmarshal([]byte(bad)); err == nil {
+			t.Errorf("Unmarshal(%s) with RepairMisplacedPrimitives succeeded, want error", bad)
+		}
+	}
+}
+
+func TestUnmarshal_CoercePrimitiveStrings(t *testing.T) {
+	const in = `{"resourceType":"Patient","id":"p1","active":"true","multipleBirthInteger":"2"}`
+	strict, err := NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("NewUnmarshaller() failed: %v", err)
+	}
+	if _, err := strict.Unmarshal([]byte(in)); err == nil {
+		t.Errorf("Unmarshal(%s) succeeded by default, want error", in)
+	}
+
+	u, err := NewUnmarshaller("UTC", fhirversion.R4, CoercePrimitiveStrings(true))
+	if err != nil {
+		t.Fatalf("NewUnmarshaller() failed: %v", err)
+	}
+	got, err := u.Unmarshal([]byte(in))
+	if err != nil {
+		t.Fatalf("Unmarshal(%s) failed: %v", in, err)
+	}
+	want := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Patient{Patient: &r4patientpb.Patient{
+			Id:     &d4pb.Id{Value: "p1"},
+			Active: &d4pb.Boolean{Value: true},
+			MultipleBirth: &r4patientpb.Patient_MultipleBirthX{
+				Choice: &r4patientpb.Patient_MultipleBirthX_Integer{Integer: &d4pb.Integer{Value: 2}},
+			},
+		}},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("Unmarshal(%s) returned unexpected diff (-want +got):\n%s", in, diff)
+	}
+
+	for _, bad := range []string{
+		`{"resourceType":"Patient","active":"maybe"}`,
+		`{"resourceType":"Patient","active":"TRUE"}`,
+		`{"resourceType":"Patient","multipleBirthInteger":"2.5"}`,
+		`{"resourceType":"Patient","multipleBirthInteger":"99999999999"}`,
+	} {
+		if _, err := u.Unmarshal([]byte(bad)); err == nil {
+			t.Errorf("Unmarshal(%s) with CoercePrimitiveStrings succeeded, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package markdown processes the markdown primitives of FHIR resources.
+package markdown
+
+import (
+	"errors"
+
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// SanitizeMarkdown replaces the value of every markdown primitive in msg, such
+// as Annotation.text or Communication.note.text, with the result of calling
+// sanitize on it, e.g. to strip HTML before rendering. Elements of other
+// string types, such as Communication.payload.contentString, are left alone,
+// as are markdown elements without a value. Resources contained in msg are
+// sanitized too.
+func SanitizeMarkdown(msg proto.Message, sanitize func(string) string) error {
+	if sanitize == nil {
+		return errors.New("nil sanitize function")
+	}
+	return walk.Walk(msg, func(_ string, m protoreflect.Message) error {
+		if m.Descriptor().Name() != "Markdown" {
+			return nil
+		}
+		f := m.Descriptor().Fields().ByName("value")
+		if f == nil || f.Kind() != protoreflect.StringKind || !m.Has(f) {
+			return nil
+		}
+		m.Set(f, protoreflect.ValueOfString(sanitize(m.Get(f).String())))
+		return nil
+	})
+}
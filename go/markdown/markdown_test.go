@@ -0,0 +1,83 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4communicationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/communication_go_proto"
+)
+
+func stripTags(s string) string {
+	return strings.NewReplacer("<script>", "", "</script>", "").Replace(s)
+}
+
+func TestSanitizeMarkdown(t *testing.T) {
+	communication := func(payload, note string) *r4communicationpb.Communication {
+		return &r4communicationpb.Communication{
+			Payload: []*r4communicationpb.Communication_Payload{{
+				Content: &r4communicationpb.Communication_Payload_ContentX{
+					Choice: &r4communicationpb.Communication_Payload_ContentX_StringValue{
+						StringValue: &d4pb.String{Value: payload},
+					},
+				},
+			}},
+			Note: []*d4pb.Annotation{{Text: &d4pb.Markdown{Value: note}}},
+		}
+	}
+	msg := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Communication{
+			Communication: communication("<script>payload</script>", "**Call** <script>alert(1)</script>"),
+		},
+	}
+	if err := SanitizeMarkdown(msg, stripTags); err != nil {
+		t.Fatalf("SanitizeMarkdown() failed: %v", err)
+	}
+	want := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Communication{
+			// Only the markdown note is sanitized, not the string payload.
+			Communication: communication("<script>payload</script>", "**Call** alert(1)"),
+		},
+	}
+	if diff := cmp.Diff(want, msg, protocmp.Transform()); diff != "" {
+		t.Errorf("SanitizeMarkdown() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestSanitizeMarkdown_NoValue(t *testing.T) {
+	msg := &r4communicationpb.Communication{
+		Note: []*d4pb.Annotation{{Text: &d4pb.Markdown{Id: &d4pb.String{Value: "n1"}}}},
+	}
+	want := proto.Clone(msg)
+	if err := SanitizeMarkdown(msg, func(string) string { return "replaced" }); err != nil {
+		t.Fatalf("SanitizeMarkdown() failed: %v", err)
+	}
+	if diff := cmp.Diff(want, msg, protocmp.Transform()); diff != "" {
+		t.Errorf("SanitizeMarkdown() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestSanitizeMarkdown_Errors(t *testing.T) {
+	if err := SanitizeMarkdown(&r4communicationpb.Communication{}, nil); err == nil {
+		t.Errorf("SanitizeMarkdown() with a nil function succeeded, want error")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package meta reads resource metadata, such as the version id and last
+// updated instant, for use in HTTP conditional logic.
+package meta
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/fhirtime"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// VersionID returns the meta.versionId of a resource or ContainedResource,
+// or false if it is not set. It is safe to call with a nil message.
+func VersionID(msg proto.Message) (string, bool) {
+	m, ok := Of(msg)
+	if !ok {
+		return "", false
+	}
+	f := m.Descriptor().Fields().ByName("version_id")
+	if f == nil || !m.Has(f) {
+		return "", false
+	}
+	vm := m.Get(f).Message()
+	v := vm.Get(vm.Descriptor().Fields().ByName("value")).String()
+	return v, v != ""
+}
+
+// LastUpdated returns the meta.lastUpdated of a resource or
+// ContainedResource, or false if it is not set. It is safe to call with a nil
+// message.
+func LastUpdated(msg proto.Message) (time.Time, bool) {
+	m, ok := Of(msg)
+	if !ok {
+		return time.Time{}, false
+	}
+	f := m.Descriptor().Fields().ByName("last_updated")
+	if f == nil || !m.Has(f) {
+		return time.Time{}, false
+	}
+	lm := m.Get(f).Message()
+	fields := lm.Descriptor().Fields()
+	t := time.UnixMicro(lm.Get(fields.ByName("value_us")).Int())
+	if loc, err := fhirtime.Location(lm.Get(fields.ByName("timezone")).String()); err == nil {
+		t = t.In(loc)
+	}
+	return t, true
+}
+
+// ETag returns the weak ETag, W/"versionId", for a resource or
+// ContainedResource, or false if the resource has no version id. It is safe
+// to call with a nil message.
+func ETag(msg proto.Message) (string, bool) {
+	v, ok := VersionID(msg)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("W/%q", v), true
+}
+
+// Of returns the populated meta element of a resource or ContainedResource.
+// It is safe to call with a nil message.
+func Of(msg proto.Message) (protoreflect.Message, bool) {
+	rm, ok := element.ResourceOf(msg)
+	if !ok {
+		return nil, false
+	}
+	f := rm.Descriptor().Fields().ByName("meta")
+	if f == nil || f.Message() == nil || !rm.Has(f) {
+		return nil, false
+	}
+	return rm.Get(f).Message(), true
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+var lastUpdated = time.Date(2023, 3, 4, 10, 30, 0, 0, time.UTC)
+
+func versionedPatient() *r4patientpb.Patient {
+	return &r4patientpb.Patient{
+		Id: &d4pb.Id{Value: "p1"},
+		Meta: &d4pb.Meta{
+			VersionId: &d4pb.Id{Value: "3"},
+			LastUpdated: &d4pb.Instant{
+				ValueUs:   lastUpdated.UnixMicro(),
+				Timezone:  "Z",
+				Precision: d4pb.Instant_SECOND,
+			},
+		},
+	}
+}
+
+func TestETag(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    proto.Message
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "versioned resource",
+			msg:    versionedPatient(),
+			want:   `W/"3"`,
+			wantOK: true,
+		},
+		{
+			name: "contained resource",
+			msg: &r4pb.ContainedResource{
+				OneofResource: &r4pb.ContainedResource_Patient{Patient: versionedPatient()},
+			},
+			want:   `W/"3"`,
+			wantOK: true,
+		},
+		{
+			name: "no meta",
+			msg:  &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}},
+		},
+		{
+			name: "meta without version",
+			msg:  &r4patientpb.Patient{Meta: &d4pb.Meta{Source: &d4pb.Uri{Value: "urn:source"}}},
+		},
+		{
+			name: "nil resource",
+			msg:  (*r4patientpb.Patient)(nil),
+		},
+		{
+			name: "nil message",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := ETag(test.msg)
+			if got != test.want || ok != test.wantOK {
+				t.Errorf("ETag() = %q, %v; want %q, %v", got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestLastUpdated(t *testing.T) {
+	got, ok := LastUpdated(versionedPatient())
+	if !ok || !got.Equal(lastUpdated) {
+		t.Errorf("LastUpdated() = %v, %v; want %v, true", got, ok, lastUpdated)
+	}
+	if got, ok := LastUpdated(&r4patientpb.Patient{}); ok {
+		t.Errorf("LastUpdated() of resource without meta = %v, want not ok", got)
+	}
+	if got, ok := LastUpdated(nil); ok {
+		t.Errorf("LastUpdated(nil) = %v, want not ok", got)
+	}
+}
+
+func TestVersionID(t *testing.T) {
+	if got, ok := VersionID(versionedPatient()); !ok || got != "3" {
+		t.Errorf("VersionID() = %q, %v; want %q, true", got, ok, "3")
+	}
+	if got, ok := VersionID(&r4patientpb.Patient{}); ok {
+		t.Errorf("VersionID() of resource without meta = %q, want not ok", got)
+	}
+}
+
+func TestOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    proto.Message
+		wantOK bool
+	}{
+		{"resource", versionedPatient(), true},
+		{
+			name: "contained resource",
+			msg: &r4pb.ContainedResource{
+				OneofResource: &r4pb.ContainedResource_Patient{Patient: versionedPatient()},
+			},
+			wantOK: true,
+		},
+		{"no meta", &r4patientpb.Patient{}, false},
+		{"empty contained resource", &r4pb.ContainedResource{}, false},
+		{"typed nil", (*r4patientpb.Patient)(nil), false},
+		{"nil", nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, ok := Of(test.msg)
+			if ok != test.wantOK {
+				t.Fatalf("Of() ok = %v, want %v", ok, test.wantOK)
+			}
+			if ok && !proto.Equal(m.Interface(), versionedPatient().GetMeta()) {
+				t.Errorf("Of() = %v, want %v", m.Interface(), versionedPatient().GetMeta())
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package money builds and reads FHIR R4 Money values, validating their
+// amounts and ISO 4217 currency codes.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/google/fhir/go/internal/element"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// New returns a Money with the given decimal value, e.g. "10.00", and ISO
+// 4217 currency code, e.g. "USD". The value keeps its lexical form so that
+// its precision is preserved.
+func New(value, currency string) (*d4pb.Money, error) {
+	if !element.DecimalPattern.MatchString(value) {
+		return nil, fmt.Errorf("invalid decimal value %q", value)
+	}
+	if !currencies[currency] {
+		return nil, fmt.Errorf("invalid ISO 4217 currency code %q", currency)
+	}
+	return &d4pb.Money{
+		Value:    &d4pb.Decimal{Value: value},
+		Currency: &d4pb.Money_CurrencyCode{Value: currency},
+	}, nil
+}
+
+// Amount returns the value and currency code of m. The currency is empty if
+// m does not have one, as FHIR allows; an invalid currency code or a missing
+// or invalid value is an error.
+func Amount(m *d4pb.Money) (*big.Rat, string, error) {
+	if m.GetValue() == nil {
+		return nil, "", errors.New("money has no value")
+	}
+	value := m.GetValue().GetValue()
+	if !element.DecimalPattern.MatchString(value) {
+		return nil, "", fmt.Errorf("invalid decimal value %q", value)
+	}
+	amount, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, "", fmt.Errorf("invalid decimal value %q", value)
+	}
+	currency := m.GetCurrency().GetValue()
+	if currency != "" && !currencies[currency] {
+		return nil, "", fmt.Errorf("invalid ISO 4217 currency code %q", currency)
+	}
+	return amount, currency, nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package money
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+func TestNew(t *testing.T) {
+	got, err := New("10.00", "USD")
+	if err != nil {
+		t.Fatalf("New(10.00, USD) failed: %v", err)
+	}
+	want := &d4pb.Money{
+		Value:    &d4pb.Decimal{Value: "10.00"},
+		Currency: &d4pb.Money_CurrencyCode{Value: "USD"},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("New(10.00, USD) returned unexpected diff (-want +got):\n%s", diff)
+	}
+	amount, currency, err := Amount(got)
+	if err != nil {
+		t.Fatalf("Amount() failed: %v", err)
+	}
+	if amount.Cmp(big.NewRat(10, 1)) != 0 || currency != "USD" {
+		t.Errorf("Amount() = %v %s, want 10 USD", amount.RatString(), currency)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		currency string
+	}{
+		{
+			name:     "unknown currency",
+			value:    "10.00",
+			currency: "ABC",
+		},
+		{
+			name:     "lower case currency",
+			value:    "10.00",
+			currency: "usd",
+		},
+		{
+			name:     "missing currency",
+			value:    "10.00",
+			currency: "",
+		},
+		{
+			name:     "leading zeros",
+			value:    "010.00",
+			currency: "USD",
+		},
+		{
+			name:     "currency symbol",
+			value:    "$10.00",
+			currency: "USD",
+		},
+		{
+			name:     "trailing point",
+			value:    "10.",
+			currency: "USD",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := New(test.value, test.currency); err == nil {
+				t.Errorf("New(%q, %q) = %v, want error", test.value, test.currency, got)
+			}
+		})
+	}
+}
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		name         string
+		money        *d4pb.Money
+		wantAmount   *big.Rat
+		wantCurrency string
+	}{
+		{
+			name:         "negative",
+			money:        &d4pb.Money{Value: &d4pb.Decimal{Value: "-2.50"}, Currency: &d4pb.Money_CurrencyCode{Value: "EUR"}},
+			wantAmount:   big.NewRat(-5, 2),
+			wantCurrency: "EUR",
+		},
+		{
+			name:       "without currency",
+			money:      &d4pb.Money{Value: &d4pb.Decimal{Value: "1e3"}},
+			wantAmount: big.NewRat(1000, 1),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			amount, currency, err := Amount(test.money)
+			if err != nil {
+				t.Fatalf("Amount() failed: %v", err)
+			}
+			if amount.Cmp(test.wantAmount) != 0 || currency != test.wantCurrency {
+				t.Errorf("Amount() = %v %q, want %v %q", amount.RatString(), currency, test.wantAmount.RatString(), test.wantCurrency)
+			}
+		})
+	}
+}
+
+func TestAmount_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		money *d4pb.Money
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name:  "no value",
+			money: &d4pb.Money{Currency: &d4pb.Money_CurrencyCode{Value: "USD"}},
+		},
+		{
+			name:  "invalid value",
+			money: &d4pb.Money{Value: &d4pb.Decimal{Value: "ten"}},
+		},
+		{
+			name:  "invalid currency",
+			money: &d4pb.Money{Value: &d4pb.Decimal{Value: "10"}, Currency: &d4pb.Money_CurrencyCode{Value: "US$"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := Amount(test.money); err == nil {
+				t.Errorf("Amount(%v) succeeded, want error", test.money)
+			}
+		})
+	}
+}
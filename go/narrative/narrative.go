@@ -0,0 +1,89 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package narrative provides helpers for working with the XHTML narratives of
+// FHIR resources.
+package narrative
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ParseNarrative parses the XHTML div of the narrative of msg, a resource of
+// any FHIR version, a ContainedResource holding one, or a Narrative, and
+// returns the div element. The returned tree is detached from any document
+// and may be freely modified by callers transforming the narrative. It is an
+// error for msg to have no narrative div, or for the div not to hold a single
+// div element.
+func ParseNarrative(msg proto.Message) (*html.Node, error) {
+	div, err := narrativeDiv(msg.ProtoReflect())
+	if err != nil {
+		return nil, err
+	}
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(div), body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing narrative div: %w", err)
+	}
+	var root *html.Node
+	for _, n := range nodes {
+		switch {
+		case n.Type == html.TextNode && strings.TrimSpace(n.Data) == "", n.Type == html.CommentNode:
+			continue
+		case root == nil && n.Type == html.ElementNode && n.DataAtom == atom.Div:
+			root = n
+		default:
+			return nil, errors.New("narrative must hold a single div element")
+		}
+	}
+	if root == nil {
+		return nil, errors.New("narrative must hold a single div element")
+	}
+	return root, nil
+}
+
+// narrativeDiv returns the div of the narrative of m.
+func narrativeDiv(m protoreflect.Message) (string, error) {
+	if oneof := m.Descriptor().Oneofs().ByName("oneof_resource"); oneof != nil {
+		f := m.WhichOneof(oneof)
+		if f == nil {
+			return "", errors.New("empty ContainedResource")
+		}
+		m = m.Get(f).Message()
+	}
+	if m.Descriptor().Name() != "Narrative" {
+		f := m.Descriptor().Fields().ByName("text")
+		if f == nil || f.Message() == nil || f.Message().Name() != "Narrative" || !m.Has(f) {
+			return "", fmt.Errorf("%s has no narrative", m.Descriptor().Name())
+		}
+		m = m.Get(f).Message()
+	}
+	f := m.Descriptor().Fields().ByName("div")
+	if f == nil || f.Message() == nil || !m.Has(f) {
+		return "", errors.New("narrative has no div")
+	}
+	dm := m.Get(f).Message()
+	div := dm.Get(dm.Descriptor().Fields().ByName("value")).String()
+	if strings.TrimSpace(div) == "" {
+		return "", errors.New("narrative has no div")
+	}
+	return div, nil
+}
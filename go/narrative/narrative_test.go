@@ -0,0 +1,128 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package narrative
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+const tableDiv = `<div xmlns="http://www.w3.org/1999/xhtml">
+  <p>Results</p>
+  <table>
+    <tr><th>Test</th><th>Value</th></tr>
+    <tr><td>Glucose</td><td>6.3 mmol/L</td></tr>
+  </table>
+</div>`
+
+func patientWithDiv(div string) *r4patientpb.Patient {
+	return &r4patientpb.Patient{Text: &d4pb.Narrative{Div: &d4pb.Xhtml{Value: div}}}
+}
+
+// find returns the first element of the tree rooted at n with atom a.
+func find(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := find(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// text returns the text content of the tree rooted at n.
+func text(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(text(c))
+	}
+	return b.String()
+}
+
+func TestParseNarrative(t *testing.T) {
+	for _, msg := range []proto.Message{
+		patientWithDiv(tableDiv),
+		&r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: patientWithDiv(tableDiv)}},
+		&d4pb.Narrative{Div: &d4pb.Xhtml{Value: tableDiv}},
+	} {
+		root, err := ParseNarrative(msg)
+		if err != nil {
+			t.Fatalf("ParseNarrative(%T) failed: %v", msg, err)
+		}
+		if root.DataAtom != atom.Div {
+			t.Errorf("ParseNarrative(%T) returned <%s>, want <div>", msg, root.Data)
+		}
+		table := find(root, atom.Table)
+		if table == nil {
+			t.Fatalf("ParseNarrative(%T) has no table", msg)
+		}
+		cell := find(table, atom.Td)
+		if got, want := text(cell), "Glucose"; got != want {
+			t.Errorf("ParseNarrative(%T) first cell = %q, want %q", msg, got, want)
+		}
+	}
+}
+
+func TestParseNarrative_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+	}{
+		{
+			name: "no narrative",
+			msg:  &r4patientpb.Patient{},
+		},
+		{
+			name: "empty div",
+			msg:  patientWithDiv(" "),
+		},
+		{
+			name: "not a div",
+			msg:  patientWithDiv("<p>Results</p>"),
+		},
+		{
+			name: "several elements",
+			msg:  patientWithDiv("<div>a</div><div>b</div>"),
+		},
+		{
+			name: "empty contained resource",
+			msg:  &r4pb.ContainedResource{},
+		},
+		{
+			name: "no text element",
+			msg:  &d4pb.HumanName{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseNarrative(test.msg); err == nil {
+				t.Errorf("ParseNarrative() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package outcome builds FHIR R4 OperationOutcome resources for reporting
+// errors from FHIR servers and clients.
+package outcome
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4outcomepb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/operation_outcome_go_proto"
+)
+
+// NewOutcome returns an R4 OperationOutcome with a single issue of the given
+// severity, one of "fatal", "error", "warning" or "information", and code,
+// from the FHIR issue-type value set, e.g. "not-found" or "invalid", with
+// diagnostics as its text. An unknown severity is reported as "error" and an
+// unknown code as "exception", so the outcome is always valid. Empty
+// diagnostics are omitted.
+func NewOutcome(severity, code, diagnostics string) proto.Message {
+	sev, ok := c4pb.IssueSeverityCode_Value_value[codeEnumName(severity)]
+	if !ok || sev == int32(c4pb.IssueSeverityCode_INVALID_UNINITIALIZED) {
+		sev = int32(c4pb.IssueSeverityCode_ERROR)
+	}
+	typ, ok := c4pb.IssueTypeCode_Value_value[codeEnumName(code)]
+	if !ok || typ == int32(c4pb.IssueTypeCode_INVALID_UNINITIALIZED) {
+		typ = int32(c4pb.IssueTypeCode_EXCEPTION)
+	}
+	issue := &r4outcomepb.OperationOutcome_Issue{
+		Severity: &r4outcomepb.OperationOutcome_Issue_SeverityCode{Value: c4pb.IssueSeverityCode_Value(sev)},
+		Code:     &r4outcomepb.OperationOutcome_Issue_CodeType{Value: c4pb.IssueTypeCode_Value(typ)},
+	}
+	if diagnostics != "" {
+		issue.Diagnostics = &d4pb.String{Value: diagnostics}
+	}
+	return &r4outcomepb.OperationOutcome{Issue: []*r4outcomepb.OperationOutcome_Issue{issue}}
+}
+
+// Errorf returns an R4 OperationOutcome with a single error issue with the
+// given issue-type code and diagnostics formatted as by fmt.Sprintf. See
+// NewOutcome.
+func Errorf(code, format string, args ...any) proto.Message {
+	return NewOutcome("error", code, fmt.Sprintf(format, args...))
+}
+
+// codeEnumName returns the name of the proto enum value for the FHIR code c,
+// e.g. "NOT_FOUND" for "not-found".
+func codeEnumName(c string) string {
+	return strings.ToUpper(strings.ReplaceAll(c, "-", "_"))
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outcome
+
+import (
+	"testing"
+
+	"github.com/google/fhir/go/jsonformat/fhirvalidate"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	r4outcomepb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/operation_outcome_go_proto"
+)
+
+func TestNewOutcome(t *testing.T) {
+	tests := []struct {
+		name         string
+		severity     string
+		code         string
+		diagnostics  string
+		wantSeverity c4pb.IssueSeverityCode_Value
+		wantCode     c4pb.IssueTypeCode_Value
+	}{
+		{
+			name:         "not found",
+			severity:     "error",
+			code:         "not-found",
+			diagnostics:  "Patient/p1 does not exist",
+			wantSeverity: c4pb.IssueSeverityCode_ERROR,
+			wantCode:     c4pb.IssueTypeCode_NOT_FOUND,
+		},
+		{
+			name:         "warning",
+			severity:     "warning",
+			code:         "business-rule",
+			diagnostics:  "Encounter has no diagnosis",
+			wantSeverity: c4pb.IssueSeverityCode_WARNING,
+			wantCode:     c4pb.IssueTypeCode_BUSINESS_RULE,
+		},
+		{
+			name:         "unknown severity and code",
+			severity:     "catastrophic",
+			code:         "teapot",
+			diagnostics:  "I'm a teapot",
+			wantSeverity: c4pb.IssueSeverityCode_ERROR,
+			wantCode:     c4pb.IssueTypeCode_EXCEPTION,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg := NewOutcome(test.severity, test.code, test.diagnostics)
+			if err := fhirvalidate.Validate(msg); err != nil {
+				t.Fatalf("Validate() of the OperationOutcome failed: %v", err)
+			}
+			issues := msg.(*r4outcomepb.OperationOutcome).GetIssue()
+			if len(issues) != 1 {
+				t.Fatalf("NewOutcome() has %d issues, want 1", len(issues))
+			}
+			issue := issues[0]
+			if got := issue.GetSeverity().GetValue(); got != test.wantSeverity {
+				t.Errorf("severity = %v, want %v", got, test.wantSeverity)
+			}
+			if got := issue.GetCode().GetValue(); got != test.wantCode {
+				t.Errorf("code = %v, want %v", got, test.wantCode)
+			}
+			if got := issue.GetDiagnostics().GetValue(); got != test.diagnostics {
+				t.Errorf("diagnostics = %q, want %q", got, test.diagnostics)
+			}
+		})
+	}
+}
+
+func TestErrorf(t *testing.T) {
+	msg := Errorf("invalid", "unknown search parameter %q", "foo")
+	if err := fhirvalidate.Validate(msg); err != nil {
+		t.Fatalf("Validate() of the OperationOutcome failed: %v", err)
+	}
+	issue := msg.(*r4outcomepb.OperationOutcome).GetIssue()[0]
+	if got, want := issue.GetSeverity().GetValue(), c4pb.IssueSeverityCode_ERROR; got != want {
+		t.Errorf("severity = %v, want %v", got, want)
+	}
+	if got, want := issue.GetCode().GetValue(), c4pb.IssueTypeCode_INVALID; got != want {
+		t.Errorf("code = %v, want %v", got, want)
+	}
+	if got, want := issue.GetDiagnostics().GetValue(), `unknown search parameter "foo"`; got != want {
+		t.Errorf("diagnostics = %q, want %q", got, want)
+	}
+}
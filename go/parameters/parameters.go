@@ -0,0 +1,101 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package parameters builds FHIR R4 Parameters resources for calling FHIR
+// operations.
+package parameters
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/fhir/go/contained"
+	"github.com/google/fhir/go/fhirversion"
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4paramspb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/parameters_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+// validateModes are the codes of the mode parameter of $validate.
+var validateModes = map[string]bool{
+	"create":  true,
+	"update":  true,
+	"delete":  true,
+	"profile": true,
+}
+
+// ValidateRequest returns the R4 Parameters to send to a server's $validate
+// operation to validate resource, an R4 resource or a ContainedResource
+// holding one. profile, the canonical URL of a StructureDefinition to
+// validate against, and mode, one of "create", "update", "delete" or
+// "profile", are optional and omitted when empty. resource may be nil only in
+// delete mode, which validates deleting the resource the request is sent
+// for.
+func ValidateRequest(resource proto.Message, profile string, mode string) (proto.Message, error) {
+	if mode != "" && !validateModes[mode] {
+		return nil, fmt.Errorf("invalid $validate mode %q", mode)
+	}
+	params := &r4paramspb.Parameters{}
+	if resource != nil {
+		a, err := packResource(resource)
+		if err != nil {
+			return nil, err
+		}
+		params.Parameter = append(params.Parameter, &r4paramspb.Parameters_Parameter{
+			Name:     &d4pb.String{Value: "resource"},
+			Resource: a,
+		})
+	} else if mode != "delete" {
+		return nil, errors.New("resource is required unless mode is delete")
+	}
+	if mode != "" {
+		params.Parameter = append(params.Parameter, &r4paramspb.Parameters_Parameter{
+			Name: &d4pb.String{Value: "mode"},
+			Value: &r4paramspb.Parameters_Parameter_ValueX{
+				Choice: &r4paramspb.Parameters_Parameter_ValueX_Code{Code: &d4pb.Code{Value: mode}},
+			},
+		})
+	}
+	if profile != "" {
+		params.Parameter = append(params.Parameter, &r4paramspb.Parameters_Parameter{
+			Name: &d4pb.String{Value: "profile"},
+			Value: &r4paramspb.Parameters_Parameter_ValueX{
+				Choice: &r4paramspb.Parameters_Parameter_ValueX_Uri{Uri: &d4pb.Uri{Value: profile}},
+			},
+		})
+	}
+	return params, nil
+}
+
+// packResource returns r packed in an Any as an R4 contained resource. r may
+// already be a non-empty ContainedResource, which is packed as is.
+func packResource(r proto.Message) (*anypb.Any, error) {
+	if cr, ok := r.(*r4pb.ContainedResource); ok {
+		if cr.GetOneofResource() == nil {
+			return nil, errors.New("empty ContainedResource")
+		}
+		return anypb.New(cr)
+	}
+	if !r.ProtoReflect().IsValid() {
+		return nil, fmt.Errorf("nil %T", r)
+	}
+	a, err := contained.Pack(r, fhirversion.R4)
+	if err != nil {
+		return nil, err
+	}
+	return a.(*anypb.Any), nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parameters
+
+import (
+	"testing"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4paramspb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/parameters_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+const usCorePatient = "http://hl7.org/fhir/us/core/StructureDefinition/us-core-patient"
+
+func TestValidateRequest(t *testing.T) {
+	patient := &r4patientpb.Patient{
+		Id:     &d4pb.Id{Value: "p1"},
+		Gender: &r4patientpb.Patient_GenderCode{Value: c4pb.AdministrativeGenderCode_FEMALE},
+	}
+	tests := []struct {
+		name     string
+		resource proto.Message
+		profile  string
+		mode     string
+		want     string
+	}{
+		{
+			name:     "resource only",
+			resource: patient,
+			want:     `{"parameter":[{"name":"resource","resource":{"gender":"female","id":"p1","resourceType":"Patient"}}],"resourceType":"Parameters"}`,
+		},
+		{
+			name:     "profile and mode",
+			resource: &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: patient}},
+			profile:  usCorePatient,
+			mode:     "create",
+			want: `{"parameter":[{"name":"resource","resource":{"gender":"female","id":"p1","resourceType":"Patient"}},` +
+				`{"name":"mode","valueCode":"create"},` +
+				`{"name":"profile","valueUri":"` + usCorePatient + `"}],"resourceType":"Parameters"}`,
+		},
+		{
+			name: "delete",
+			mode: "delete",
+			want: `{"parameter":[{"name":"mode","valueCode":"delete"}],"resourceType":"Parameters"}`,
+		},
+	}
+	m, err := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("NewMarshaller() failed: %v", err)
+	}
+	u, err := jsonformat.NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		t.Fatalf("NewUnmarshaller() failed: %v", err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params, err := ValidateRequest(test.resource, test.profile, test.mode)
+			if err != nil {
+				t.Fatalf("ValidateRequest() failed: %v", err)
+			}
+			got, err := m.MarshalResource(params)
+			if err != nil {
+				t.Fatalf("MarshalResource() failed: %v", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("MarshalResource() = %s, want %s", got, test.want)
+			}
+			back, err := u.UnmarshalR4(got)
+			if err != nil {
+				t.Fatalf("UnmarshalR4(%s) failed: %v", got, err)
+			}
+			if diff := cmp.Diff(params, back.GetParameters(), protocmp.Transform()); diff != "" {
+				t.Errorf("UnmarshalR4(%s) returned unexpected diff (-want +got):\n%s", got, diff)
+			}
+		})
+	}
+}
+
+func TestValidateRequest_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource proto.Message
+		mode     string
+	}{
+		{"no resource", nil, ""},
+		{"no resource to create", nil, "create"},
+		{"invalid mode", &r4patientpb.Patient{}, "check"},
+		{"not a resource", &d4pb.String{Value: "x"}, ""},
+		{"empty ContainedResource", &r4pb.ContainedResource{}, ""},
+		{"typed nil resource", (*r4patientpb.Patient)(nil), ""},
+		{"backbone element", &r4paramspb.Parameters_Parameter{}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := ValidateRequest(test.resource, "", test.mode); err == nil {
+				t.Errorf("ValidateRequest() = %v, want error", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/fhir/go/fhirpath"
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/validation"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4paramspb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/parameters_go_proto"
+)
+
+// ConformancePatch returns the FHIRPath Patch operations that bring msg, an
+// R4 resource or a ContainedResource holding one, into conformance with the
+// fixed and pattern rules, as a Parameters resource for ApplyWithAudit or a
+// server's PATCH interaction. Elements that already conform are left alone.
+//
+// A missing element is added with the fixed or pattern value; its parent,
+// the rule's path up to the last element name, must exist. An element that
+// differs from its fixed value is replaced by it. An element that does not
+// match its pattern is replaced by a copy with the pattern's values merged
+// in, keeping its other values and appending missing repeated values such as
+// codings.
+func ConformancePatch(msg proto.Message, rules []validation.FixedPatternRule) (*r4paramspb.Parameters, error) {
+	out := &r4paramspb.Parameters{}
+	for _, rule := range rules {
+		if (rule.Fixed == nil) == (rule.Pattern == nil) {
+			return nil, fmt.Errorf("rule %s: must set exactly one of fixed or pattern", rule.Path)
+		}
+		ops, err := conformanceOperations(msg, rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Path, err)
+		}
+		out.Parameter = append(out.Parameter, ops...)
+	}
+	return out, nil
+}
+
+// conformanceOperations returns the operations that make the elements
+// selected by rule conform to it.
+func conformanceOperations(msg proto.Message, rule validation.FixedPatternRule) ([]*r4paramspb.Parameters_Parameter, error) {
+	want := rule.Fixed
+	if want == nil {
+		want = rule.Pattern
+	}
+	res, err := fhirpath.Evaluate(msg, rule.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		i := strings.LastIndex(rule.Path, ".")
+		if i < 0 || !elementName.MatchString(rule.Path[i+1:]) {
+			return nil, fmt.Errorf("cannot add a missing element for path")
+		}
+		parents, err := fhirpath.Evaluate(msg, rule.Path[:i])
+		if err != nil {
+			return nil, err
+		}
+		if len(parents) != 1 {
+			return nil, fmt.Errorf("parent %q matches %d elements, want 1", rule.Path[:i], len(parents))
+		}
+		op, err := operationParameter("add", rule.Path[:i], rule.Path[i+1:], want)
+		if err != nil {
+			return nil, err
+		}
+		return []*r4paramspb.Parameters_Parameter{op}, nil
+	}
+	var ops []*r4paramspb.Parameters_Parameter
+	for i, v := range res {
+		m, ok := v.(proto.Message)
+		if !ok {
+			return nil, fmt.Errorf("path matches %v, not an element", v)
+		}
+		var replacement proto.Message
+		if rule.Fixed != nil {
+			if !proto.Equal(m, rule.Fixed) {
+				replacement = rule.Fixed
+			}
+		} else if merged := withPattern(m, rule.Pattern); !proto.Equal(m, merged) {
+			replacement = merged
+		}
+		if replacement == nil {
+			continue
+		}
+		path := rule.Path
+		if len(res) > 1 {
+			path = fmt.Sprintf("%s[%d]", rule.Path, i)
+		}
+		op, err := operationParameter("replace", path, "", replacement)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// withPattern returns a copy of m with the values set in pattern merged in.
+func withPattern(m, pattern proto.Message) proto.Message {
+	out := proto.Clone(m)
+	if out.ProtoReflect().Descriptor().FullName() != pattern.ProtoReflect().Descriptor().FullName() {
+		return proto.Clone(pattern)
+	}
+	mergePattern(out.ProtoReflect(), pattern.ProtoReflect())
+	return out
+}
+
+// mergePattern sets the values of pattern in m. Items of a repeated field in
+// pattern are appended unless m already has an item matching them.
+func mergePattern(m, pattern protoreflect.Message) {
+	pattern.Range(func(f protoreflect.FieldDescriptor, pv protoreflect.Value) bool {
+		switch {
+		case f.IsList():
+			l := m.Mutable(f).List()
+			pl := pv.List()
+			for i := 0; i < pl.Len(); i++ {
+				if !listHasMatch(f, l, pl.Get(i)) {
+					l.Append(cloneValue(f, pl.Get(i)))
+				}
+			}
+		case f.Message() != nil && m.Has(f):
+			mergePattern(m.Mutable(f).Message(), pv.Message())
+		default:
+			m.Set(f, cloneValue(f, pv))
+		}
+		return true
+	})
+}
+
+// listHasMatch reports whether an item of l matches the pattern item p.
+func listHasMatch(f protoreflect.FieldDescriptor, l protoreflect.List, p protoreflect.Value) bool {
+	for i := 0; i < l.Len(); i++ {
+		if f.Message() == nil {
+			if l.Get(i).Interface() == p.Interface() {
+				return true
+			}
+			continue
+		}
+		item := l.Get(i).Message().Interface()
+		if proto.Equal(item, withPattern(item, p.Message().Interface())) {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneValue(f protoreflect.FieldDescriptor, v protoreflect.Value) protoreflect.Value {
+	if f.Message() == nil {
+		return v
+	}
+	return protoreflect.ValueOfMessage(proto.Clone(v.Message().Interface()).ProtoReflect())
+}
+
+// operationParameter returns a FHIRPath Patch operation of the given type.
+// name is only used for add operations.
+func operationParameter(typ, path, name string, value proto.Message) (*r4paramspb.Parameters_Parameter, error) {
+	v, err := valueX(value)
+	if err != nil {
+		return nil, err
+	}
+	str := func(s string) *r4paramspb.Parameters_Parameter_ValueX {
+		return &r4paramspb.Parameters_Parameter_ValueX{
+			Choice: &r4paramspb.Parameters_Parameter_ValueX_StringValue{StringValue: &d4pb.String{Value: s}},
+		}
+	}
+	parts := []*r4paramspb.Parameters_Parameter{
+		{
+			Name: &d4pb.String{Value: "type"},
+			Value: &r4paramspb.Parameters_Parameter_ValueX{
+				Choice: &r4paramspb.Parameters_Parameter_ValueX_Code{Code: &d4pb.Code{Value: typ}},
+			},
+		},
+		{Name: &d4pb.String{Value: "path"}, Value: str(path)},
+	}
+	if name != "" {
+		parts = append(parts, &r4paramspb.Parameters_Parameter{Name: &d4pb.String{Value: "name"}, Value: str(name)})
+	}
+	parts = append(parts, &r4paramspb.Parameters_Parameter{Name: &d4pb.String{Value: "value"}, Value: v})
+	return &r4paramspb.Parameters_Parameter{Name: &d4pb.String{Value: "operation"}, Part: parts}, nil
+}
+
+// valueX returns v as the value of a Parameters parameter. Specialized codes,
+// such as Observation.status, are given as plain codes.
+func valueX(v proto.Message) (*r4paramspb.Parameters_Parameter_ValueX, error) {
+	out := &r4paramspb.Parameters_Parameter_ValueX{}
+	rv := v.ProtoReflect()
+	fields := out.ProtoReflect().Descriptor().Oneofs().Get(0).Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if f := fields.Get(i); f.Message().FullName() == rv.Descriptor().FullName() {
+			out.ProtoReflect().Set(f, protoreflect.ValueOfMessage(proto.Clone(v).ProtoReflect()))
+			return out, nil
+		}
+	}
+	if vf := rv.Descriptor().Fields().ByName("value"); vf != nil && vf.Kind() == protoreflect.EnumKind {
+		if ev := vf.Enum().Values().ByNumber(rv.Get(vf).Enum()); ev != nil {
+			out.Choice = &r4paramspb.Parameters_Parameter_ValueX_Code{Code: &d4pb.Code{Value: element.EnumCode(ev)}}
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("%s cannot be used as a patch value", rv.Descriptor().FullName())
+}
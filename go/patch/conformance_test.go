@@ -0,0 +1,145 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"testing"
+
+	"github.com/google/fhir/go/validation"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4paramspb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/parameters_go_proto"
+)
+
+func concept(system, code string) *d4pb.CodeableConcept {
+	return &d4pb.CodeableConcept{Coding: []*d4pb.Coding{{
+		System: &d4pb.Uri{Value: system},
+		Code:   &d4pb.Code{Value: code},
+	}}}
+}
+
+func conceptValue(cc *d4pb.CodeableConcept) *r4paramspb.Parameters_Parameter_ValueX {
+	return &r4paramspb.Parameters_Parameter_ValueX{Choice: &r4paramspb.Parameters_Parameter_ValueX_CodeableConcept{CodeableConcept: cc}}
+}
+
+const (
+	loinc           = "http://loinc.org"
+	categorySystem  = "http://terminology.hl7.org/CodeSystem/observation-category"
+	heartRateCode   = "8867-4"
+	vitalSignsCode  = "vital-signs"
+	localCodeSystem = "http://example.org/codes"
+)
+
+func TestConformancePatch(t *testing.T) {
+	obs := &r4observationpb.Observation{
+		Id:       &d4pb.Id{Value: "o1"},
+		Status:   observationStatus(c4pb.ObservationStatusCode_PRELIMINARY),
+		Category: []*d4pb.CodeableConcept{concept(categorySystem, vitalSignsCode)},
+	}
+	rules := []validation.FixedPatternRule{
+		{Path: "Observation.code", Pattern: concept(loinc, heartRateCode)},
+		{Path: "Observation.status", Fixed: observationStatus(c4pb.ObservationStatusCode_FINAL)},
+		// Already satisfied.
+		{Path: "Observation.category", Pattern: concept(categorySystem, vitalSignsCode)},
+	}
+
+	got, err := ConformancePatch(obs, rules)
+	if err != nil {
+		t.Fatalf("ConformancePatch() failed: %v", err)
+	}
+	want := patch(
+		[]*r4paramspb.Parameters_Parameter{
+			part("type", codeValue("add")),
+			part("path", stringValue("Observation")),
+			part("name", stringValue("code")),
+			part("value", conceptValue(concept(loinc, heartRateCode))),
+		},
+		[]*r4paramspb.Parameters_Parameter{
+			part("type", codeValue("replace")),
+			part("path", stringValue("Observation.status")),
+			part("value", codeValue("final")),
+		},
+	)
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Fatalf("ConformancePatch() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	patched, _, err := ApplyWithAudit(obs, got)
+	if err != nil {
+		t.Fatalf("ApplyWithAudit() of the conformance patch failed: %v", err)
+	}
+	if v := validation.CheckFixedPatterns(patched, rules); len(v) != 0 {
+		t.Errorf("patched resource still violates the rules: %v", v)
+	}
+}
+
+func TestConformancePatch_MergesPattern(t *testing.T) {
+	local := concept(localCodeSystem, "hr")
+	local.Text = &d4pb.String{Value: "Heart rate"}
+	obs := &r4observationpb.Observation{
+		Status: observationStatus(c4pb.ObservationStatusCode_FINAL),
+		Code:   local,
+	}
+	rules := []validation.FixedPatternRule{{Path: "Observation.code", Pattern: concept(loinc, heartRateCode)}}
+
+	got, err := ConformancePatch(obs, rules)
+	if err != nil {
+		t.Fatalf("ConformancePatch() failed: %v", err)
+	}
+	merged := &d4pb.CodeableConcept{
+		Coding: []*d4pb.Coding{local.Coding[0], concept(loinc, heartRateCode).Coding[0]},
+		Text:   &d4pb.String{Value: "Heart rate"},
+	}
+	want := patch([]*r4paramspb.Parameters_Parameter{
+		part("type", codeValue("replace")),
+		part("path", stringValue("Observation.code")),
+		part("value", conceptValue(merged)),
+	})
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("ConformancePatch() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestConformancePatch_Errors(t *testing.T) {
+	obs := &r4observationpb.Observation{}
+	tests := []struct {
+		name string
+		rule validation.FixedPatternRule
+	}{
+		{
+			name: "neither fixed nor pattern",
+			rule: validation.FixedPatternRule{Path: "Observation.code"},
+		},
+		{
+			name: "missing parent",
+			rule: validation.FixedPatternRule{Path: "Observation.code.text", Fixed: &d4pb.String{Value: "x"}},
+		},
+		{
+			name: "path not ending in an element",
+			rule: validation.FixedPatternRule{Path: "Observation.code.where(text = 'x')", Fixed: concept(loinc, heartRateCode)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := ConformancePatch(obs, []validation.FixedPatternRule{test.rule}); err == nil {
+				t.Errorf("ConformancePatch() = %v, want error", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package patch applies FHIRPath Patch documents, expressed as R4 Parameters
+// resources, to FHIR R4 resources, builds such documents, and renders the
+// differences between resources as text.
+//
+// The add, insert, delete, replace and move operations are supported. Paths
+// are evaluated with the fhirpath package; elements of resources contained
+// in another resource cannot be patched.
+package patch
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/fhir/go/fhirpath"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	r4paramspb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/parameters_go_proto"
+)
+
+// A ChangedPath records an element touched by a patch operation.
+type ChangedPath struct {
+	// Operation is the type of the patch operation, e.g. "replace".
+	Operation string
+	// Path is the FHIR element path of the element, e.g.
+	// "Observation.status" or "Patient.identifier[1]".
+	Path string
+	// Before and After are the values of the element before and after the
+	// operation. Before is nil for added elements and After is nil for
+	// deleted ones.
+	Before, After proto.Message
+}
+
+// ApplyWithAudit applies the FHIRPath Patch operations in patch to a copy of
+// resource, which may be an R4 resource or a ContainedResource holding one,
+// and returns the patched copy along with the elements each operation
+// changed, in order. resource itself is not modified.
+//
+// A move is reported as two changes: the element leaving its source path and
+// the element arriving at its destination path.
+func ApplyWithAudit(resource proto.Message, patch *r4paramspb.Parameters) (proto.Message, []ChangedPath, error) {
+	if resource == nil {
+		return nil, nil, fmt.Errorf("nil resource")
+	}
+	out := proto.Clone(resource)
+	var changes []ChangedPath
+	for i, p := range patch.GetParameter() {
+		op, err := parseOperation(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		c, err := op.apply(out)
+		if err != nil {
+			return nil, nil, fmt.Errorf("operation %d (%s %s): %w", i, op.typ, op.path, err)
+		}
+		changes = append(changes, c...)
+	}
+	return out, changes, nil
+}
+
+type operation struct {
+	typ   string
+	path  string
+	name  string
+	value proto.Message
+	// index, source and destination are -1 when not given.
+	index, source, destination int
+}
+
+func parseOperation(p *r4paramspb.Parameters_Parameter) (*operation, error) {
+	if name := p.GetName().GetValue(); name != "operation" {
+		return nil, fmt.Errorf("unexpected parameter %q, want operation", name)
+	}
+	op := &operation{index: -1, source: -1, destination: -1}
+	for _, part := range p.GetPart() {
+		var err error
+		switch name := part.GetName().GetValue(); name {
+		case "type":
+			op.typ, err = partString(part)
+		case "path":
+			op.path, err = partString(part)
+		case "name":
+			op.name, err = partString(part)
+		case "value":
+			op.value, err = partValue(part)
+		case "index":
+			op.index, err = partInteger(part)
+		case "source":
+			op.source, err = partInteger(part)
+		case "destination":
+			op.destination, err = partInteger(part)
+		default:
+			err = fmt.Errorf("unexpected part %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if op.path == "" {
+		return nil, fmt.Errorf("missing path")
+	}
+	var missing string
+	switch op.typ {
+	case "add":
+		switch {
+		case op.name == "":
+			missing = "name"
+		case op.value == nil:
+			missing = "value"
+		}
+	case "insert":
+		switch {
+		case op.value == nil:
+			missing = "value"
+		case op.index < 0:
+			missing = "index"
+		}
+	case "delete":
+	case "replace":
+		if op.value == nil {
+			missing = "value"
+		}
+	case "move":
+		switch {
+		case op.source < 0:
+			missing = "source"
+		case op.destination < 0:
+			missing = "destination"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported operation type %q", op.typ)
+	}
+	if missing != "" {
+		return nil, fmt.Errorf("%s operation missing %s", op.typ, missing)
+	}
+	return op, nil
+}
+
+func partString(p *r4paramspb.Parameters_Parameter) (string, error) {
+	v := p.GetValue()
+	switch {
+	case v.GetStringValue() != nil:
+		return v.GetStringValue().GetValue(), nil
+	case v.GetCode() != nil:
+		return v.GetCode().GetValue(), nil
+	}
+	return "", fmt.Errorf("part %q must be a string", p.GetName().GetValue())
+}
+
+func partInteger(p *r4paramspb.Parameters_Parameter) (int, error) {
+	i := p.GetValue().GetInteger()
+	if i == nil || i.GetValue() < 0 {
+		return 0, fmt.Errorf("part %q must be a non-negative integer", p.GetName().GetValue())
+	}
+	return int(i.GetValue()), nil
+}
+
+// partValue returns the datatype or resource held by a value part.
+func partValue(p *r4paramspb.Parameters_Parameter) (proto.Message, error) {
+	if r := p.GetResource(); r != nil {
+		return r.UnmarshalNew()
+	}
+	if v := p.GetValue(); v != nil {
+		rv := v.ProtoReflect()
+		if f := rv.WhichOneof(rv.Descriptor().Oneofs().Get(0)); f != nil {
+			return rv.Get(f).Message().Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf("part %q has no value", p.GetName().GetValue())
+}
+
+func (op *operation) apply(msg proto.Message) ([]ChangedPath, error) {
+	t := newTree(msg)
+	if t.root == nil {
+		return nil, fmt.Errorf("empty resource")
+	}
+	switch op.typ {
+	case "add":
+		return op.add(t)
+	case "insert":
+		return op.insert(t)
+	case "delete":
+		return op.delete(t)
+	case "replace":
+		return op.replace(t)
+	default:
+		return op.move(t)
+	}
+}
+
+func (op *operation) add(t *tree) ([]ChangedPath, error) {
+	parent, parentPath, err := t.resolveParent(op.path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := childField(parent, op.name)
+	if err != nil {
+		return nil, err
+	}
+	loc := location{path: parentPath + "." + op.name, parent: parent, field: f, index: -1}
+	var elem proto.Message
+	if f.IsList() {
+		l := parent.Mutable(f).List()
+		v := l.NewElement()
+		if elem, err = setValue(v.Message(), op.value); err != nil {
+			return nil, err
+		}
+		l.Append(v)
+		loc.path = fmt.Sprintf("%s[%d]", loc.path, l.Len()-1)
+	} else {
+		if parent.Has(f) {
+			return nil, fmt.Errorf("%s already has a value", loc.path)
+		}
+		v := parent.NewField(f)
+		if elem, err = setValue(v.Message(), op.value); err != nil {
+			return nil, err
+		}
+		parent.Set(f, v)
+	}
+	return []ChangedPath{{Operation: op.typ, Path: loc.elementPath(elem), After: elem}}, nil
+}
+
+func (op *operation) insert(t *tree) ([]ChangedPath, error) {
+	l, loc, err := t.resolveList(op.path)
+	if err != nil {
+		return nil, err
+	}
+	if op.index > l.Len() {
+		return nil, fmt.Errorf("index %d out of range for %d elements", op.index, l.Len())
+	}
+	v := l.NewElement()
+	elem, err := setValue(v.Message(), op.value)
+	if err != nil {
+		return nil, err
+	}
+	insertAt(l, op.index, v)
+	return []ChangedPath{{Operation: op.typ, Path: fmt.Sprintf("%s[%d]", loc.path, op.index), After: elem}}, nil
+}
+
+func (op *operation) delete(t *tree) ([]ChangedPath, error) {
+	res, err := fhirpath.Evaluate(t.msg, op.path)
+	if err != nil {
+		return nil, err
+	}
+	switch len(res) {
+	case 0:
+		// Deleting an element that does not exist is not an error.
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("path matches %d elements, want at most 1", len(res))
+	}
+	elem, loc, err := t.locate(res[0])
+	if err != nil {
+		return nil, err
+	}
+	if loc.index >= 0 {
+		l := loc.parent.Mutable(loc.field).List()
+		removeAt(l, loc.index)
+	} else {
+		loc.parent.Clear(loc.field)
+	}
+	return []ChangedPath{{Operation: op.typ, Path: loc.elementPath(elem), Before: elem}}, nil
+}
+
+func (op *operation) replace(t *tree) ([]ChangedPath, error) {
+	res, err := fhirpath.Evaluate(t.msg, op.path)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) != 1 {
+		return nil, fmt.Errorf("path matches %d elements, want 1", len(res))
+	}
+	before, loc, err := t.locate(res[0])
+	if err != nil {
+		return nil, err
+	}
+	var v protoreflect.Value
+	if loc.index >= 0 {
+		v = loc.parent.Get(loc.field).List().NewElement()
+	} else {
+		v = loc.parent.NewField(loc.field)
+	}
+	after, err := setValue(v.Message(), op.value)
+	if err != nil {
+		return nil, err
+	}
+	if loc.index >= 0 {
+		loc.parent.Mutable(loc.field).List().Set(loc.index, v)
+	} else {
+		loc.parent.Set(loc.field, v)
+	}
+	return []ChangedPath{{Operation: op.typ, Path: loc.elementPath(after), Before: before, After: after}}, nil
+}
+
+func (op *operation) move(t *tree) ([]ChangedPath, error) {
+	l, loc, err := t.resolveList(op.path)
+	if err != nil {
+		return nil, err
+	}
+	if op.source >= l.Len() || op.destination >= l.Len() {
+		return nil, fmt.Errorf("source %d or destination %d out of range for %d elements", op.source, op.destination, l.Len())
+	}
+	v := l.Get(op.source)
+	removeAt(l, op.source)
+	insertAt(l, op.destination, v)
+	elem := v.Message().Interface()
+	return []ChangedPath{
+		{Operation: op.typ, Path: fmt.Sprintf("%s[%d]", loc.path, op.source), Before: elem},
+		{Operation: op.typ, Path: fmt.Sprintf("%s[%d]", loc.path, op.destination), After: elem},
+	}, nil
+}
+
+var elementName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// resolveList resolves a path whose last step names a repeated element, such
+// as "Patient.identifier", returning the list and its location.
+func (t *tree) resolveList(path string) (protoreflect.List, location, error) {
+	i := strings.LastIndex(path, ".")
+	if i < 0 || !elementName.MatchString(path[i+1:]) {
+		return nil, location{}, fmt.Errorf("path must end with the name of a repeated element")
+	}
+	parent, parentPath, err := t.resolveParent(path[:i])
+	if err != nil {
+		return nil, location{}, err
+	}
+	f, err := childField(parent, path[i+1:])
+	if err != nil {
+		return nil, location{}, err
+	}
+	if !f.IsList() {
+		return nil, location{}, fmt.Errorf("%s is not a repeated element", f.JSONName())
+	}
+	loc := location{path: parentPath + "." + f.JSONName(), parent: parent, field: f, index: -1}
+	return parent.Mutable(f).List(), loc, nil
+}
+
+// resolveParent resolves a path that must match exactly one element, to which
+// children will be added.
+func (t *tree) resolveParent(path string) (protoreflect.Message, string, error) {
+	res, err := fhirpath.Evaluate(t.msg, path)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(res) != 1 {
+		return nil, "", fmt.Errorf("path %q matches %d elements, want 1", path, len(res))
+	}
+	m, ok := res[0].(proto.Message)
+	if !ok {
+		return nil, "", fmt.Errorf("path %q does not match an element", path)
+	}
+	if m == t.root.Interface() {
+		return t.root, string(t.root.Descriptor().Name()), nil
+	}
+	_, loc, err := t.locate(m)
+	if err != nil {
+		return nil, "", err
+	}
+	return m.ProtoReflect(), loc.path, nil
+}
+
+// childField returns the field of parent with the given FHIR element name.
+func childField(parent protoreflect.Message, name string) (protoreflect.FieldDescriptor, error) {
+	fields := parent.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if f := fields.Get(i); f.Message() != nil && f.JSONName() == name {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no element %q", parent.Descriptor().Name(), name)
+}
+
+func insertAt(l protoreflect.List, i int, v protoreflect.Value) {
+	l.Append(v)
+	for j := l.Len() - 1; j > i; j-- {
+		l.Set(j, l.Get(j-1))
+	}
+	l.Set(i, v)
+}
+
+func removeAt(l protoreflect.List, i int) {
+	for j := i; j < l.Len()-1; j++ {
+		l.Set(j, l.Get(j+1))
+	}
+	l.Truncate(l.Len() - 1)
+}
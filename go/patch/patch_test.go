@@ -0,0 +1,298 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4paramspb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/parameters_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func part(name string, v *r4paramspb.Parameters_Parameter_ValueX) *r4paramspb.Parameters_Parameter {
+	return &r4paramspb.Parameters_Parameter{Name: &d4pb.String{Value: name}, Value: v}
+}
+
+func codeValue(s string) *r4paramspb.Parameters_Parameter_ValueX {
+	return &r4paramspb.Parameters_Parameter_ValueX{Choice: &r4paramspb.Parameters_Parameter_ValueX_Code{Code: &d4pb.Code{Value: s}}}
+}
+
+func stringValue(s string) *r4paramspb.Parameters_Parameter_ValueX {
+	return &r4paramspb.Parameters_Parameter_ValueX{Choice: &r4paramspb.Parameters_Parameter_ValueX_StringValue{StringValue: &d4pb.String{Value: s}}}
+}
+
+func integerValue(i int32) *r4paramspb.Parameters_Parameter_ValueX {
+	return &r4paramspb.Parameters_Parameter_ValueX{Choice: &r4paramspb.Parameters_Parameter_ValueX_Integer{Integer: &d4pb.Integer{Value: i}}}
+}
+
+func identifierValue(system, value string) *r4paramspb.Parameters_Parameter_ValueX {
+	return &r4paramspb.Parameters_Parameter_ValueX{Choice: &r4paramspb.Parameters_Parameter_ValueX_Identifier{Identifier: identifier(system, value)}}
+}
+
+func identifier(system, value string) *d4pb.Identifier {
+	return &d4pb.Identifier{System: &d4pb.Uri{Value: system}, Value: &d4pb.String{Value: value}}
+}
+
+func patch(ops ...[]*r4paramspb.Parameters_Parameter) *r4paramspb.Parameters {
+	p := &r4paramspb.Parameters{}
+	for _, parts := range ops {
+		p.Parameter = append(p.Parameter, &r4paramspb.Parameters_Parameter{
+			Name: &d4pb.String{Value: "operation"},
+			Part: parts,
+		})
+	}
+	return p
+}
+
+func observationStatus(v c4pb.ObservationStatusCode_Value) *r4observationpb.Observation_StatusCode {
+	return &r4observationpb.Observation_StatusCode{Value: v}
+}
+
+func TestApplyWithAudit_ReplaceStatus(t *testing.T) {
+	obs := &r4observationpb.Observation{
+		Id:     &d4pb.Id{Value: "o1"},
+		Status: observationStatus(c4pb.ObservationStatusCode_PRELIMINARY),
+	}
+	p := patch([]*r4paramspb.Parameters_Parameter{
+		part("type", codeValue("replace")),
+		part("path", stringValue("Observation.status")),
+		part("value", codeValue("final")),
+	})
+
+	got, changes, err := ApplyWithAudit(obs, p)
+	if err != nil {
+		t.Fatalf("ApplyWithAudit() failed: %v", err)
+	}
+	want := &r4observationpb.Observation{
+		Id:     &d4pb.Id{Value: "o1"},
+		Status: observationStatus(c4pb.ObservationStatusCode_FINAL),
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("ApplyWithAudit() returned unexpected resource diff (-want +got):\n%s", diff)
+	}
+	wantChanges := []ChangedPath{{
+		Operation: "replace",
+		Path:      "Observation.status",
+		Before:    observationStatus(c4pb.ObservationStatusCode_PRELIMINARY),
+		After:     observationStatus(c4pb.ObservationStatusCode_FINAL),
+	}}
+	if diff := cmp.Diff(wantChanges, changes, protocmp.Transform()); diff != "" {
+		t.Errorf("ApplyWithAudit() returned unexpected changes diff (-want +got):\n%s", diff)
+	}
+	if s := obs.GetStatus().GetValue(); s != c4pb.ObservationStatusCode_PRELIMINARY {
+		t.Errorf("ApplyWithAudit() modified its input, status = %v", s)
+	}
+}
+
+func TestApplyWithAudit(t *testing.T) {
+	patient := func(ids ...*d4pb.Identifier) *r4patientpb.Patient {
+		return &r4patientpb.Patient{
+			Identifier: ids,
+			Active:     &d4pb.Boolean{Value: true},
+		}
+	}
+	tests := []struct {
+		name        string
+		resource    proto.Message
+		patch       *r4paramspb.Parameters
+		want        proto.Message
+		wantChanges []ChangedPath
+	}{
+		{
+			name:     "add to repeated element",
+			resource: patient(identifier("urn:a", "1")),
+			patch: patch([]*r4paramspb.Parameters_Parameter{
+				part("type", codeValue("add")),
+				part("path", stringValue("Patient")),
+				part("name", stringValue("identifier")),
+				part("value", identifierValue("urn:b", "2")),
+			}),
+			want: patient(identifier("urn:a", "1"), identifier("urn:b", "2")),
+			wantChanges: []ChangedPath{
+				{Operation: "add", Path: "Patient.identifier[1]", After: identifier("urn:b", "2")},
+			},
+		},
+		{
+			name:     "insert",
+			resource: patient(identifier("urn:a", "1"), identifier("urn:b", "2")),
+			patch: patch([]*r4paramspb.Parameters_Parameter{
+				part("type", codeValue("insert")),
+				part("path", stringValue("Patient.identifier")),
+				part("index", integerValue(1)),
+				part("value", identifierValue("urn:c", "3")),
+			}),
+			want: patient(identifier("urn:a", "1"), identifier("urn:c", "3"), identifier("urn:b", "2")),
+			wantChanges: []ChangedPath{
+				{Operation: "insert", Path: "Patient.identifier[1]", After: identifier("urn:c", "3")},
+			},
+		},
+		{
+			name:     "delete and move",
+			resource: patient(identifier("urn:a", "1"), identifier("urn:b", "2"), identifier("urn:c", "3")),
+			patch: patch(
+				[]*r4paramspb.Parameters_Parameter{
+					part("type", codeValue("delete")),
+					part("path", stringValue("Patient.identifier.where(system = 'urn:b')")),
+				},
+				[]*r4paramspb.Parameters_Parameter{
+					part("type", codeValue("move")),
+					part("path", stringValue("Patient.identifier")),
+					part("source", integerValue(1)),
+					part("destination", integerValue(0)),
+				},
+			),
+			want: patient(identifier("urn:c", "3"), identifier("urn:a", "1")),
+			wantChanges: []ChangedPath{
+				{Operation: "delete", Path: "Patient.identifier[1]", Before: identifier("urn:b", "2")},
+				{Operation: "move", Path: "Patient.identifier[1]", Before: identifier("urn:c", "3")},
+				{Operation: "move", Path: "Patient.identifier[0]", After: identifier("urn:c", "3")},
+			},
+		},
+		{
+			name:     "delete missing element",
+			resource: patient(),
+			patch: patch([]*r4paramspb.Parameters_Parameter{
+				part("type", codeValue("delete")),
+				part("path", stringValue("Patient.birthDate")),
+			}),
+			want: patient(),
+		},
+		{
+			name: "replace choice with another type",
+			resource: &r4pb.ContainedResource{
+				OneofResource: &r4pb.ContainedResource_Observation{Observation: &r4observationpb.Observation{
+					Value: &r4observationpb.Observation_ValueX{
+						Choice: &r4observationpb.Observation_ValueX_Quantity{Quantity: &d4pb.Quantity{Value: &d4pb.Decimal{Value: "7"}}},
+					},
+				}},
+			},
+			patch: patch([]*r4paramspb.Parameters_Parameter{
+				part("type", codeValue("replace")),
+				part("path", stringValue("Observation.value")),
+				part("value", stringValue("seven")),
+			}),
+			want: &r4pb.ContainedResource{
+				OneofResource: &r4pb.ContainedResource_Observation{Observation: &r4observationpb.Observation{
+					Value: &r4observationpb.Observation_ValueX{
+						Choice: &r4observationpb.Observation_ValueX_StringValue{StringValue: &d4pb.String{Value: "seven"}},
+					},
+				}},
+			},
+			wantChanges: []ChangedPath{{
+				Operation: "replace",
+				Path:      "Observation.valueString",
+				Before:    &d4pb.Quantity{Value: &d4pb.Decimal{Value: "7"}},
+				After:     &d4pb.String{Value: "seven"},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, changes, err := ApplyWithAudit(test.resource, test.patch)
+			if err != nil {
+				t.Fatalf("ApplyWithAudit() failed: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("ApplyWithAudit() returned unexpected resource diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantChanges, changes, protocmp.Transform()); diff != "" {
+				t.Errorf("ApplyWithAudit() returned unexpected changes diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyWithAudit_Errors(t *testing.T) {
+	obs := &r4observationpb.Observation{
+		Status: observationStatus(c4pb.ObservationStatusCode_PRELIMINARY),
+	}
+	tests := []struct {
+		name  string
+		parts []*r4paramspb.Parameters_Parameter
+	}{
+		{
+			name: "unsupported type",
+			parts: []*r4paramspb.Parameters_Parameter{
+				part("type", codeValue("copy")),
+				part("path", stringValue("Observation.status")),
+			},
+		},
+		{
+			name: "missing value",
+			parts: []*r4paramspb.Parameters_Parameter{
+				part("type", codeValue("replace")),
+				part("path", stringValue("Observation.status")),
+			},
+		},
+		{
+			name: "invalid code",
+			parts: []*r4paramspb.Parameters_Parameter{
+				part("type", codeValue("replace")),
+				part("path", stringValue("Observation.status")),
+				part("value", codeValue("bogus")),
+			},
+		},
+		{
+			name: "replace missing element",
+			parts: []*r4paramspb.Parameters_Parameter{
+				part("type", codeValue("replace")),
+				part("path", stringValue("Observation.issued")),
+				part("value", stringValue("x")),
+			},
+		},
+		{
+			name: "add unknown element",
+			parts: []*r4paramspb.Parameters_Parameter{
+				part("type", codeValue("add")),
+				part("path", stringValue("Observation")),
+				part("name", stringValue("nonsense")),
+				part("value", stringValue("x")),
+			},
+		},
+		{
+			name: "add to element with a value",
+			parts: []*r4paramspb.Parameters_Parameter{
+				part("type", codeValue("add")),
+				part("path", stringValue("Observation")),
+				part("name", stringValue("status")),
+				part("value", codeValue("final")),
+			},
+		},
+		{
+			name: "move out of range",
+			parts: []*r4paramspb.Parameters_Parameter{
+				part("type", codeValue("move")),
+				part("path", stringValue("Observation.category")),
+				part("source", integerValue(0)),
+				part("destination", integerValue(1)),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := ApplyWithAudit(obs, patch(test.parts)); err == nil {
+				t.Errorf("ApplyWithAudit() succeeded, want error")
+			}
+		})
+	}
+}
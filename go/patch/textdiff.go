@@ -0,0 +1,160 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+)
+
+// TextDiff renders the differences between two versions of an R4 resource,
+// or ContainedResources holding them, for people to read. The resources are
+// compared as FHIR JSON, and each primitive value that differs gets one line:
+// `+ Patient.active: true` for an added value, `- Patient.gender: "male"` for
+// a removed one, and `~ Patient.birthDate: "1970-01-01" -> "1971-01-01"` for
+// a changed one. Lines are sorted by element path, with indices ordered
+// numerically, and unchanged values are omitted, so that identical resources
+// yield "". Both resources must be set; nil, including a typed nil, is an
+// error.
+func TextDiff(old, new proto.Message) (string, error) {
+	if old == nil || !old.ProtoReflect().IsValid() {
+		return "", fmt.Errorf("nil old resource")
+	}
+	if new == nil || !new.ProtoReflect().IsValid() {
+		return "", fmt.Errorf("nil new resource")
+	}
+	if o, n := unwrap(old.ProtoReflect()), unwrap(new.ProtoReflect()); o != nil && n != nil && o.Descriptor().FullName() != n.Descriptor().FullName() {
+		return "", fmt.Errorf("cannot diff %s with %s", o.Descriptor().Name(), n.Descriptor().Name())
+	}
+	m, err := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		return "", err
+	}
+	before, err := leafValues(m, old)
+	if err != nil {
+		return "", err
+	}
+	after, err := leafValues(m, new)
+	if err != nil {
+		return "", err
+	}
+
+	paths := make([]string, 0, len(before)+len(after))
+	for p := range before {
+		paths = append(paths, p)
+	}
+	for p := range after {
+		if _, ok := before[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool { return pathLess(paths[i], paths[j]) })
+
+	var sb strings.Builder
+	for _, p := range paths {
+		b, inBefore := before[p]
+		a, inAfter := after[p]
+		switch {
+		case !inBefore:
+			fmt.Fprintf(&sb, "+ %s: %s\n", p, a)
+		case !inAfter:
+			fmt.Fprintf(&sb, "- %s: %s\n", p, b)
+		case a != b:
+			fmt.Fprintf(&sb, "~ %s: %s -> %s\n", p, b, a)
+		}
+	}
+	return sb.String(), nil
+}
+
+// leafValues returns the FHIR JSON of each primitive value of msg, keyed by
+// element path.
+func leafValues(m *jsonformat.Marshaller, msg proto.Message) (map[string]string, error) {
+	values := map[string]string{}
+	res := unwrap(msg.ProtoReflect())
+	if res == nil {
+		return values, nil
+	}
+	b, err := m.MarshalResource(res.Interface())
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var obj map[string]any
+	if err := d.Decode(&obj); err != nil {
+		return nil, err
+	}
+	rt := obj["resourceType"].(string)
+	delete(obj, "resourceType")
+	return values, flatten(rt, obj, values)
+}
+
+// flatten records the leaves of the decoded JSON v, found at path, in
+// values.
+func flatten(path string, v any, values map[string]string) error {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, elem := range x {
+			if err := flatten(path+"."+k, elem, values); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, elem := range x {
+			if err := flatten(fmt.Sprintf("%s[%d]", path, i), elem, values); err != nil {
+				return err
+			}
+		}
+	default:
+		b, err := json.Marshal(x)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		values[path] = string(b)
+	}
+	return nil
+}
+
+// pathLess orders element paths as strings, except that the indices of
+// repeated elements are compared as numbers, so that "name[2]" sorts before
+// "name[10]".
+func pathLess(a, b string) bool {
+	for a != "" && b != "" {
+		ai, bi := strings.IndexByte(a, '['), strings.IndexByte(b, '[')
+		if ai < 0 || bi < 0 || a[:ai] != b[:bi] {
+			break
+		}
+		a, b = a[ai+1:], b[bi+1:]
+		ae, be := strings.IndexByte(a, ']'), strings.IndexByte(b, ']')
+		an, aerr := strconv.Atoi(a[:ae])
+		bn, berr := strconv.Atoi(b[:be])
+		if aerr != nil || berr != nil {
+			break
+		}
+		if an != bn {
+			return an < bn
+		}
+		a, b = a[ae+1:], b[be+1:]
+	}
+	return a < b
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func TestTextDiff_OneChange(t *testing.T) {
+	old := &r4observationpb.Observation{
+		Id:     &d4pb.Id{Value: "o1"},
+		Status: observationStatus(c4pb.ObservationStatusCode_PRELIMINARY),
+		Code:   &d4pb.CodeableConcept{Text: &d4pb.String{Value: "heart rate"}},
+	}
+	new := &r4observationpb.Observation{
+		Id:     &d4pb.Id{Value: "o1"},
+		Status: observationStatus(c4pb.ObservationStatusCode_FINAL),
+		Code:   &d4pb.CodeableConcept{Text: &d4pb.String{Value: "heart rate"}},
+	}
+	got, err := TextDiff(old, new)
+	if err != nil {
+		t.Fatalf("TextDiff() failed: %v", err)
+	}
+	if want := "~ Observation.status: \"preliminary\" -> \"final\"\n"; got != want {
+		t.Errorf("TextDiff() = %q, want %q", got, want)
+	}
+}
+
+func TestTextDiff(t *testing.T) {
+	old := &r4patientpb.Patient{
+		Id:     &d4pb.Id{Value: "p1"},
+		Gender: &r4patientpb.Patient_GenderCode{Value: c4pb.AdministrativeGenderCode_MALE},
+		Name: []*d4pb.HumanName{
+			{Family: &d4pb.String{Value: "Doe"}},
+		},
+	}
+	new := &r4patientpb.Patient{
+		Id:     &d4pb.Id{Value: "p1"},
+		Active: &d4pb.Boolean{Value: true},
+		Name: []*d4pb.HumanName{
+			{Family: &d4pb.String{Value: "Roe"}},
+		},
+	}
+	for i := 0; i < 11; i++ {
+		new.Identifier = append(new.Identifier, identifier("urn:x", string(rune('a'+i))))
+	}
+	got, err := TextDiff(&r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Patient{Patient: old},
+	}, new)
+	if err != nil {
+		t.Fatalf("TextDiff() failed: %v", err)
+	}
+	want := `+ Patient.active: true
+- Patient.gender: "male"
++ Patient.identifier[0].system: "urn:x"
++ Patient.identifier[0].value: "a"
++ Patient.identifier[1].system: "urn:x"
++ Patient.identifier[1].value: "b"
++ Patient.identifier[2].system: "urn:x"
++ Patient.identifier[2].value: "c"
++ Patient.identifier[3].system: "urn:x"
++ Patient.identifier[3].value: "d"
++ Patient.identifier[4].system: "urn:x"
++ Patient.identifier[4].value: "e"
++ Patient.identifier[5].system: "urn:x"
++ Patient.identifier[5].value: "f"
++ Patient.identifier[6].system: "urn:x"
++ Patient.identifier[6].value: "g"
++ Patient.identifier[7].system: "urn:x"
++ Patient.identifier[7].value: "h"
++ Patient.identifier[8].system: "urn:x"
++ Patient.identifier[8].value: "i"
++ Patient.identifier[9].system: "urn:x"
++ Patient.identifier[9].value: "j"
++ Patient.identifier[10].system: "urn:x"
++ Patient.identifier[10].value: "k"
+~ Patient.name[0].family: "Doe" -> "Roe"
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("TextDiff() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	if got, err := TextDiff(new, new); err != nil || got != "" {
+		t.Errorf("TextDiff() of identical resources = %q, %v, want empty", got, err)
+	}
+}
+
+func TestTextDiff_Errors(t *testing.T) {
+	if _, err := TextDiff(&r4patientpb.Patient{}, &r4observationpb.Observation{}); err == nil {
+		t.Errorf("TextDiff() of different resource types succeeded, want error")
+	}
+	if _, err := TextDiff(nil, &r4patientpb.Patient{}); err == nil {
+		t.Errorf("TextDiff() of a nil old resource succeeded, want error")
+	}
+	if _, err := TextDiff(&r4patientpb.Patient{}, (*r4patientpb.Patient)(nil)); err == nil {
+		t.Errorf("TextDiff() of a typed nil new resource succeeded, want error")
+	}
+}
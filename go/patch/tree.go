@@ -0,0 +1,129 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/fhir/go/internal/element"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// A location is where an element is held within a resource.
+type location struct {
+	// path is the FHIR element path of the field holding the element, without
+	// the type suffix of choice elements.
+	path   string
+	parent protoreflect.Message
+	field  protoreflect.FieldDescriptor
+	// index is the element's position in a repeated field, or -1.
+	index int
+}
+
+// elementPath returns the path of elem held at l, adding the type suffix for
+// choice elements, e.g. "Observation.valueQuantity".
+func (l location) elementPath(elem proto.Message) string {
+	if !element.IsChoice(l.field.Message()) {
+		return l.path
+	}
+	fields := l.field.Message().Oneofs().Get(0).Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if f := fields.Get(i); f.Message().FullName() == elem.ProtoReflect().Descriptor().FullName() {
+			name := f.JSONName()
+			return l.path + strings.ToUpper(name[:1]) + name[1:]
+		}
+	}
+	return l.path
+}
+
+// A tree indexes the elements of a resource by their proto message, so that
+// the results of evaluating a FHIRPath expression can be modified in place.
+type tree struct {
+	msg  proto.Message
+	root protoreflect.Message
+	locs map[proto.Message]location
+}
+
+func newTree(msg proto.Message) *tree {
+	t := &tree{msg: msg, root: unwrap(msg.ProtoReflect()), locs: map[proto.Message]location{}}
+	if t.root != nil {
+		t.index(string(t.root.Descriptor().Name()), t.root)
+	}
+	return t
+}
+
+func (t *tree) index(path string, m protoreflect.Message) {
+	m.Range(func(f protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if f.Message() == nil {
+			return true
+		}
+		loc := location{path: path + "." + f.JSONName(), parent: m, field: f, index: -1}
+		if !f.IsList() {
+			t.indexValue(loc, v.Message())
+			return true
+		}
+		l := v.List()
+		for i := 0; i < l.Len(); i++ {
+			loc.path = fmt.Sprintf("%s.%s[%d]", path, f.JSONName(), i)
+			loc.index = i
+			t.indexValue(loc, l.Get(i).Message())
+		}
+		return true
+	})
+}
+
+// indexValue records the element held in a field value, which is the active
+// value for choice types and ContainedResource. Resources contained in an Any
+// are not indexed since they are unpacked into copies when evaluated.
+func (t *tree) indexValue(loc location, v protoreflect.Message) {
+	if v.Descriptor().FullName() == "google.protobuf.Any" {
+		return
+	}
+	elem := unwrap(v)
+	if elem == nil {
+		return
+	}
+	t.locs[elem.Interface()] = loc
+	t.index(loc.elementPath(elem.Interface()), elem)
+}
+
+// locate returns the element and location of a FHIRPath result.
+func (t *tree) locate(v any) (proto.Message, location, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, location{}, fmt.Errorf("path matches %v, not an element", v)
+	}
+	loc, ok := t.locs[m]
+	if !ok {
+		return nil, location{}, fmt.Errorf("path does not match an element of the resource itself")
+	}
+	return m, loc, nil
+}
+
+// unwrap returns the active value of a choice type or ContainedResource, nil
+// if it has none, or m itself for any other message.
+func unwrap(m protoreflect.Message) protoreflect.Message {
+	d := m.Descriptor()
+	if !element.IsChoice(d) && d.Name() != "ContainedResource" {
+		return m
+	}
+	f := m.WhichOneof(d.Oneofs().Get(0))
+	if f == nil {
+		return nil
+	}
+	return m.Get(f).Message()
+}
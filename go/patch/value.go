@@ -0,0 +1,110 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+
+	"github.com/google/fhir/go/internal/element"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+// setValue stores a copy of the patch value v in dst, an empty field value,
+// and returns the element now held by dst.
+//
+// Choice types and ContainedResource are set to whichever of their types
+// matches v. Primitives are converted where this cannot lose information,
+// e.g. a code value is stored in a specialized code field such as
+// Observation.status.
+func setValue(dst protoreflect.Message, v proto.Message) (proto.Message, error) {
+	v = proto.Clone(v)
+	d := dst.Descriptor()
+	if d.FullName() == v.ProtoReflect().Descriptor().FullName() {
+		proto.Merge(dst.Interface(), v)
+		if elem := unwrap(dst); elem != nil {
+			return elem.Interface(), nil
+		}
+		return v, nil
+	}
+	if a, ok := dst.Interface().(*anypb.Any); ok {
+		if err := a.MarshalFrom(v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	if element.IsChoice(d) || d.Name() == "ContainedResource" {
+		fields := d.Oneofs().Get(0).Fields()
+		for i := 0; i < fields.Len(); i++ {
+			if f := fields.Get(i); f.Message().FullName() == v.ProtoReflect().Descriptor().FullName() {
+				dst.Set(f, protoreflect.ValueOfMessage(v.ProtoReflect()))
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("%s is not an allowed type for %s", v.ProtoReflect().Descriptor().Name(), d.Name())
+	}
+	if err := convertPrimitive(v.ProtoReflect(), dst); err != nil {
+		return nil, err
+	}
+	return dst.Interface(), nil
+}
+
+// convertPrimitive copies src into dst, primitives of different types whose
+// values are both strings or a string and a specialized code enum.
+func convertPrimitive(src, dst protoreflect.Message) error {
+	sv := src.Descriptor().Fields().ByName("value")
+	dv := dst.Descriptor().Fields().ByName("value")
+	if sv == nil || dv == nil || sv.Kind() != protoreflect.StringKind {
+		return fmt.Errorf("cannot use %s as %s", src.Descriptor().Name(), dst.Descriptor().Name())
+	}
+	code := src.Get(sv).String()
+	switch dv.Kind() {
+	case protoreflect.StringKind:
+		dst.Set(dv, protoreflect.ValueOfString(code))
+	case protoreflect.EnumKind:
+		ev := enumValue(dv.Enum(), code)
+		if ev == nil {
+			return fmt.Errorf("%q is not a valid %s", code, dst.Descriptor().Name())
+		}
+		dst.Set(dv, protoreflect.ValueOfEnum(ev.Number()))
+	default:
+		return fmt.Errorf("cannot use %s as %s", src.Descriptor().Name(), dst.Descriptor().Name())
+	}
+	for _, name := range []protoreflect.Name{"id", "extension"} {
+		sf, df := src.Descriptor().Fields().ByName(name), dst.Descriptor().Fields().ByName(name)
+		if sf != nil && df != nil && src.Has(sf) {
+			dst.Set(df, src.Get(sf))
+		}
+	}
+	return nil
+}
+
+// enumValue returns the value of a specialized code enum with the given FHIR
+// code, or nil if there is none.
+func enumValue(e protoreflect.EnumDescriptor, code string) protoreflect.EnumValueDescriptor {
+	values := e.Values()
+	for i := 0; i < values.Len(); i++ {
+		ev := values.Get(i)
+		if ev.Number() == 0 {
+			continue
+		}
+		if element.EnumCode(ev) == code {
+			return ev
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package patient provides helpers for clinical logic over FHIR R4 Patient
+// data.
+package patient
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/fhir/go/internal/fhirtime"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// Age returns the age in full years on the calendar date of asOf of someone
+// born on birthDate. Someone born on February 29 has their birthday on March
+// 1 in non-leap years. For a birthDate of year or month precision, Age
+// returns an error unless the age is the same for every day the birthDate
+// covers; use AgeRange to obtain the possible ages instead.
+func Age(birthDate *d4pb.Date, asOf time.Time) (years int, err error) {
+	min, max, err := AgeRange(birthDate, asOf)
+	if err != nil {
+		return 0, err
+	}
+	if min != max {
+		return 0, fmt.Errorf("birth date of %v precision gives an age between %d and %d", birthDate.GetPrecision(), min, max)
+	}
+	return min, nil
+}
+
+// AgeRange returns the youngest and oldest possible ages in full years on the
+// calendar date of asOf of someone born within birthDate. The two are equal
+// for a birthDate of day precision.
+func AgeRange(birthDate *d4pb.Date, asOf time.Time) (min, max int, err error) {
+	if birthDate == nil {
+		return 0, 0, errors.New("missing birth date")
+	}
+	loc, err := fhirtime.Location(birthDate.GetTimezone())
+	if err != nil {
+		return 0, 0, err
+	}
+	earliest := time.UnixMicro(birthDate.GetValueUs()).In(loc)
+	y, m, d := earliest.Date()
+	var latest time.Time
+	switch birthDate.GetPrecision() {
+	case d4pb.Date_YEAR:
+		earliest = time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+		latest = time.Date(y, time.December, 31, 0, 0, 0, 0, time.UTC)
+	case d4pb.Date_MONTH:
+		earliest = time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+		latest = earliest.AddDate(0, 1, -1)
+	case d4pb.Date_DAY:
+		earliest = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		latest = earliest
+	default:
+		return 0, 0, fmt.Errorf("unsupported birth date precision %v", birthDate.GetPrecision())
+	}
+	max = fullYears(earliest, asOf)
+	if max < 0 {
+		return 0, 0, fmt.Errorf("date %s is before the birth date", asOf.Format("2006-01-02"))
+	}
+	min = fullYears(latest, asOf)
+	if min < 0 {
+		min = 0
+	}
+	return min, max, nil
+}
+
+// fullYears returns the number of birthdays since born on the calendar date
+// of asOf, which is negative if asOf precedes born.
+func fullYears(born, asOf time.Time) int {
+	by, bm, bd := born.Date()
+	ay, am, ad := asOf.Date()
+	years := ay - by
+	if am < bm || am == bm && ad < bd {
+		years--
+	}
+	return years
+}
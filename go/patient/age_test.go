@@ -0,0 +1,137 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patient
+
+import (
+	"testing"
+	"time"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+func date(y int, m time.Month, d int, precision d4pb.Date_Precision) *d4pb.Date {
+	return &d4pb.Date{
+		ValueUs:   time.Date(y, m, d, 0, 0, 0, 0, time.UTC).UnixMicro(),
+		Timezone:  "UTC",
+		Precision: precision,
+	}
+}
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+}
+
+func TestAge(t *testing.T) {
+	tests := []struct {
+		name      string
+		birthDate *d4pb.Date
+		asOf      time.Time
+		want      int
+	}{
+		{
+			name:      "day before birthday",
+			birthDate: date(1980, time.June, 15, d4pb.Date_DAY),
+			asOf:      day(2020, time.June, 14),
+			want:      39,
+		},
+		{
+			name:      "on birthday",
+			birthDate: date(1980, time.June, 15, d4pb.Date_DAY),
+			asOf:      day(2020, time.June, 15),
+			want:      40,
+		},
+		{
+			name:      "day of birth",
+			birthDate: date(2020, time.June, 15, d4pb.Date_DAY),
+			asOf:      day(2020, time.June, 15),
+			want:      0,
+		},
+		{
+			name:      "leap day birthday in non-leap year before march",
+			birthDate: date(2000, time.February, 29, d4pb.Date_DAY),
+			asOf:      day(2021, time.February, 28),
+			want:      20,
+		},
+		{
+			name:      "leap day birthday in non-leap year on march 1",
+			birthDate: date(2000, time.February, 29, d4pb.Date_DAY),
+			asOf:      day(2021, time.March, 1),
+			want:      21,
+		},
+		{
+			name:      "leap day birthday in leap year",
+			birthDate: date(2000, time.February, 29, d4pb.Date_DAY),
+			asOf:      day(2024, time.February, 29),
+			want:      24,
+		},
+		{
+			name:      "unambiguous month precision",
+			birthDate: date(1980, time.June, 1, d4pb.Date_MONTH),
+			asOf:      day(2020, time.July, 1),
+			want:      40,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Age(test.birthDate, test.asOf)
+			if err != nil {
+				t.Fatalf("Age() failed: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("Age() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAge_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		birthDate *d4pb.Date
+		asOf      time.Time
+	}{
+		{
+			name:      "ambiguous year precision",
+			birthDate: date(1980, time.January, 1, d4pb.Date_YEAR),
+			asOf:      day(2020, time.June, 14),
+		},
+		{
+			name:      "before birth",
+			birthDate: date(1980, time.June, 15, d4pb.Date_DAY),
+			asOf:      day(1980, time.June, 14),
+		},
+		{
+			name: "missing birth date",
+			asOf: day(2020, time.June, 14),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := Age(test.birthDate, test.asOf); err == nil {
+				t.Errorf("Age() = %d, want error", got)
+			}
+		})
+	}
+}
+
+func TestAgeRange(t *testing.T) {
+	min, max, err := AgeRange(date(1980, time.January, 1, d4pb.Date_YEAR), day(2020, time.June, 14))
+	if err != nil {
+		t.Fatalf("AgeRange() failed: %v", err)
+	}
+	if min != 39 || max != 40 {
+		t.Errorf("AgeRange() = %d, %d; want 39, 40", min, max)
+	}
+}
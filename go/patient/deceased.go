@@ -0,0 +1,62 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patient
+
+import (
+	"time"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/fhirtime"
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// IsDeceased reads the deceased[x] element of the R4 resource msg, or the
+// resource held by a ContainedResource, which is typically a Patient. A
+// deceasedDateTime means the patient is deceased and gives when, in the
+// timezone of the dateTime; hasWhen is false for a deceasedBoolean. A
+// resource without the element, or with no deceased[x] value, is reported as
+// not deceased.
+func IsDeceased(msg proto.Message) (deceased bool, when time.Time, hasWhen bool) {
+	rm, ok := element.ResourceOf(msg)
+	if !ok {
+		return false, time.Time{}, false
+	}
+	f := rm.Descriptor().Fields().ByName("deceased")
+	if f == nil || f.Message() == nil || !rm.Has(f) {
+		return false, time.Time{}, false
+	}
+	choice := rm.Get(f).Message()
+	oneof := choice.Descriptor().Oneofs().ByName("choice")
+	if oneof == nil {
+		return false, time.Time{}, false
+	}
+	cf := choice.WhichOneof(oneof)
+	if cf == nil {
+		return false, time.Time{}, false
+	}
+	switch v := choice.Get(cf).Message().Interface().(type) {
+	case *d4pb.Boolean:
+		return v.GetValue(), time.Time{}, false
+	case *d4pb.DateTime:
+		when = time.UnixMicro(v.GetValueUs())
+		if loc, err := fhirtime.Location(v.GetTimezone()); err == nil {
+			when = when.In(loc)
+		}
+		return true, when, true
+	}
+	return false, time.Time{}, false
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patient
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	r4practitionerpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/practitioner_go_proto"
+)
+
+func TestIsDeceased(t *testing.T) {
+	died := time.Date(2021, time.March, 4, 9, 30, 0, 0, time.FixedZone("+01:00", 60*60))
+	deceasedBoolean := func(v bool) *r4patientpb.Patient_DeceasedX {
+		return &r4patientpb.Patient_DeceasedX{
+			Choice: &r4patientpb.Patient_DeceasedX_Boolean{Boolean: &d4pb.Boolean{Value: v}},
+		}
+	}
+	deceasedDateTime := &r4patientpb.Patient_DeceasedX{
+		Choice: &r4patientpb.Patient_DeceasedX_DateTime{DateTime: &d4pb.DateTime{
+			ValueUs:   died.UnixMicro(),
+			Timezone:  "+01:00",
+			Precision: d4pb.DateTime_SECOND,
+		}},
+	}
+	tests := []struct {
+		name         string
+		msg          proto.Message
+		wantDeceased bool
+		wantWhen     time.Time
+		wantHasWhen  bool
+	}{
+		{
+			name:         "boolean true",
+			msg:          &r4patientpb.Patient{Deceased: deceasedBoolean(true)},
+			wantDeceased: true,
+		},
+		{
+			name: "boolean false",
+			msg:  &r4patientpb.Patient{Deceased: deceasedBoolean(false)},
+		},
+		{
+			name:         "dateTime",
+			msg:          &r4patientpb.Patient{Deceased: deceasedDateTime},
+			wantDeceased: true,
+			wantWhen:     died,
+			wantHasWhen:  true,
+		},
+		{
+			name: "contained resource",
+			msg: &r4pb.ContainedResource{
+				OneofResource: &r4pb.ContainedResource_Patient{Patient: &r4patientpb.Patient{Deceased: deceasedDateTime}},
+			},
+			wantDeceased: true,
+			wantWhen:     died,
+			wantHasWhen:  true,
+		},
+		{
+			name: "not set",
+			msg:  &r4patientpb.Patient{},
+		},
+		{
+			name: "resource without deceased",
+			msg:  &r4practitionerpb.Practitioner{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deceased, when, hasWhen := IsDeceased(test.msg)
+			if deceased != test.wantDeceased || hasWhen != test.wantHasWhen {
+				t.Fatalf("IsDeceased() = %v, _, %v, want %v, _, %v", deceased, hasWhen, test.wantDeceased, test.wantHasWhen)
+			}
+			if !when.Equal(test.wantWhen) {
+				t.Errorf("IsDeceased() when = %v, want %v", when, test.wantWhen)
+			}
+			if hasWhen {
+				if _, offset := when.Zone(); offset != 60*60 {
+					t.Errorf("IsDeceased() when has offset %d, want 3600", offset)
+				}
+			}
+		})
+	}
+}
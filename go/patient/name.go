@@ -0,0 +1,89 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patient
+
+import (
+	"strings"
+
+	"github.com/google/fhir/go/internal/element"
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// FormatName returns the display form of name: its text if set, otherwise
+// its prefixes, given names, family name and suffixes separated by spaces,
+// e.g. "Dr Peter James Chalmers Jr". Empty parts are skipped, and a nil name
+// gives "".
+func FormatName(name *d4pb.HumanName) string {
+	if text := strings.TrimSpace(name.GetText().GetValue()); text != "" {
+		return text
+	}
+	var parts []string
+	add := func(ss ...*d4pb.String) {
+		for _, s := range ss {
+			if v := strings.TrimSpace(s.GetValue()); v != "" {
+				parts = append(parts, v)
+			}
+		}
+	}
+	add(name.GetPrefix()...)
+	add(name.GetGiven()...)
+	add(name.GetFamily())
+	add(name.GetSuffix()...)
+	return strings.Join(parts, " ")
+}
+
+// PreferredName returns the name to display for the R4 resource msg, or the
+// resource held by a ContainedResource, which may be any resource with a
+// list of HumanNames such as a Patient or Practitioner. It picks the first
+// official name, or else the first name, and returns false if there are no
+// names.
+func PreferredName(msg proto.Message) (*d4pb.HumanName, bool) {
+	names := humanNames(msg)
+	for _, name := range names {
+		if name.GetUse().GetValue() == c4pb.NameUseCode_OFFICIAL {
+			return name, true
+		}
+	}
+	if len(names) == 0 {
+		return nil, false
+	}
+	return names[0], true
+}
+
+// humanNames returns the values of the name field of msg, unwrapping a
+// ContainedResource, or nil if it has no list of HumanNames.
+func humanNames(msg proto.Message) []*d4pb.HumanName {
+	rm, ok := element.ResourceOf(msg)
+	if !ok {
+		return nil
+	}
+	f := rm.Descriptor().Fields().ByName("name")
+	if f == nil || !f.IsList() || f.Message() == nil {
+		return nil
+	}
+	var names []*d4pb.HumanName
+	l := rm.Get(f).List()
+	for i := 0; i < l.Len(); i++ {
+		name, ok := l.Get(i).Message().Interface().(*d4pb.HumanName)
+		if !ok {
+			return nil
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patient
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	r4practitionerpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/practitioner_go_proto"
+)
+
+func strs(values ...string) []*d4pb.String {
+	var out []*d4pb.String
+	for _, v := range values {
+		out = append(out, &d4pb.String{Value: v})
+	}
+	return out
+}
+
+func humanName(use c4pb.NameUseCode_Value, family string, given ...string) *d4pb.HumanName {
+	return &d4pb.HumanName{
+		Use:    &d4pb.HumanName_UseCode{Value: use},
+		Family: &d4pb.String{Value: family},
+		Given:  strs(given...),
+	}
+}
+
+func TestFormatName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *d4pb.HumanName
+		want string
+	}{
+		{
+			name: "text",
+			in: &d4pb.HumanName{
+				Text:   &d4pb.String{Value: "Dr. Peter Chalmers"},
+				Family: &d4pb.String{Value: "Chalmers"},
+			},
+			want: "Dr. Peter Chalmers",
+		},
+		{
+			name: "all parts",
+			in: &d4pb.HumanName{
+				Prefix: strs("Dr"),
+				Given:  strs("Peter", "James"),
+				Family: &d4pb.String{Value: "Chalmers"},
+				Suffix: strs("Jr"),
+			},
+			want: "Dr Peter James Chalmers Jr",
+		},
+		{
+			name: "blank text and empty parts",
+			in: &d4pb.HumanName{
+				Text:   &d4pb.String{Value: "  "},
+				Given:  strs("", "Jim"),
+				Family: &d4pb.String{Value: "Chalmers"},
+			},
+			want: "Jim Chalmers",
+		},
+		{
+			name: "family only",
+			in:   &d4pb.HumanName{Family: &d4pb.String{Value: "Chalmers"}},
+			want: "Chalmers",
+		},
+		{
+			name: "nil",
+			want: "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := FormatName(test.in); got != test.want {
+				t.Errorf("FormatName(%v) = %q, want %q", test.in, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPreferredName(t *testing.T) {
+	usual := humanName(c4pb.NameUseCode_USUAL, "Chalmers", "Jim")
+	official := humanName(c4pb.NameUseCode_OFFICIAL, "Chalmers", "Peter", "James")
+	maiden := humanName(c4pb.NameUseCode_MAIDEN, "Windsor", "Peter")
+	tests := []struct {
+		name   string
+		msg    proto.Message
+		want   *d4pb.HumanName
+		wantOK bool
+	}{
+		{
+			name:   "official",
+			msg:    &r4patientpb.Patient{Name: []*d4pb.HumanName{usual, official, maiden}},
+			want:   official,
+			wantOK: true,
+		},
+		{
+			name:   "first without official",
+			msg:    &r4patientpb.Patient{Name: []*d4pb.HumanName{maiden, usual}},
+			want:   maiden,
+			wantOK: true,
+		},
+		{
+			name:   "practitioner",
+			msg:    &r4practitionerpb.Practitioner{Name: []*d4pb.HumanName{usual, official}},
+			want:   official,
+			wantOK: true,
+		},
+		{
+			name: "contained resource",
+			msg: &r4pb.ContainedResource{
+				OneofResource: &r4pb.ContainedResource_Patient{Patient: &r4patientpb.Patient{Name: []*d4pb.HumanName{usual}}},
+			},
+			want:   usual,
+			wantOK: true,
+		},
+		{
+			name: "no names",
+			msg:  &r4patientpb.Patient{},
+		},
+		{
+			name: "resource without names",
+			msg:  &r4pb.ContainedResource{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := PreferredName(test.msg)
+			if ok != test.wantOK {
+				t.Fatalf("PreferredName() ok = %v, want %v", ok, test.wantOK)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("PreferredName() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pipeline provides streaming transformations of FHIR R4 NDJSON.
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+)
+
+// maxLineSize is the size of the largest NDJSON line Transform reads, which
+// is well above that of any single resource in practice.
+const maxLineSize = 64 << 20
+
+// Transform reads FHIR R4 NDJSON from r, calls fn on each resource in turn and
+// writes the resources it returns to w as NDJSON, one line each, holding only
+// one resource in memory at a time. fn is passed a ContainedResource and may
+// return either a ContainedResource or a bare resource. Returning nil drops the
+// resource from the output. Blank lines are skipped. Transform stops at the
+// first line that can't be parsed, transformed or written and returns an error
+// giving its line number; the lines before it have been written to w.
+func Transform(r io.Reader, w io.Writer, fn func(proto.Message) (proto.Message, error)) error {
+	u, err := jsonformat.NewUnmarshaller("UTC", fhirversion.R4)
+	if err != nil {
+		return err
+	}
+	m, err := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxLineSize)
+	line := 0
+	for s.Scan() {
+		line++
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		if err := transformLine(u, m, bw, s.Bytes(), fn); err != nil {
+			if ferr := bw.Flush(); ferr != nil {
+				return ferr
+			}
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		bw.Flush()
+		return fmt.Errorf("line %d: %w", line+1, err)
+	}
+	return bw.Flush()
+}
+
+// transformLine parses the resource in, applies fn and writes the result to w.
+func transformLine(u *jsonformat.Unmarshaller, m *jsonformat.Marshaller, w *bufio.Writer, in []byte, fn func(proto.Message) (proto.Message, error)) error {
+	cr, err := u.UnmarshalR4(in)
+	if err != nil {
+		return err
+	}
+	out, err := fn(cr)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	var b []byte
+	if _, ok := out.(*r4pb.ContainedResource); ok {
+		b, err = m.Marshal(out)
+	} else {
+		b, err = m.MarshalResource(out)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
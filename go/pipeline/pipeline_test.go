@@ -0,0 +1,111 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+)
+
+const input = `{"resourceType":"Patient","id":"p1"}
+{"resourceType":"Observation","id":"o1","status":"final","code":{"text":"x"}}
+
+{"resourceType":"Patient","id":"p2","active":true}
+`
+
+func patientsOnly(msg proto.Message) (proto.Message, error) {
+	p := msg.(*r4pb.ContainedResource).GetPatient()
+	if p == nil {
+		return nil, nil
+	}
+	return p, nil
+}
+
+func TestTransform(t *testing.T) {
+	var out strings.Builder
+	if err := Transform(strings.NewReader(input), &out, patientsOnly); err != nil {
+		t.Fatalf("Transform() failed: %v", err)
+	}
+	want := `{"id":"p1","resourceType":"Patient"}
+{"active":true,"id":"p2","resourceType":"Patient"}
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("Transform() wrote unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestTransform_ModifiesResources(t *testing.T) {
+	var out strings.Builder
+	err := Transform(strings.NewReader(`{"resourceType":"Patient","id":"p1"}`), &out, func(msg proto.Message) (proto.Message, error) {
+		msg.(*r4pb.ContainedResource).GetPatient().Active = &d4pb.Boolean{Value: false}
+		return msg, nil
+	})
+	if err != nil {
+		t.Fatalf("Transform() failed: %v", err)
+	}
+	if want := "{\"active\":false,\"id\":\"p1\",\"resourceType\":\"Patient\"}\n"; out.String() != want {
+		t.Errorf("Transform() wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestTransform_Errors(t *testing.T) {
+	errAbort := errors.New("abort")
+	tests := []struct {
+		name      string
+		input     string
+		fn        func(proto.Message) (proto.Message, error)
+		wantErr   string
+		wantLines int
+	}{
+		{
+			name:  "transform error",
+			input: input,
+			fn: func(msg proto.Message) (proto.Message, error) {
+				if msg.(*r4pb.ContainedResource).GetObservation() != nil {
+					return nil, errAbort
+				}
+				return msg, nil
+			},
+			wantErr:   "line 2: abort",
+			wantLines: 1,
+		},
+		{
+			name:      "invalid resource",
+			input:     "{\"resourceType\":\"Patient\"}\n{\"resourceType\":\"Patient\",\"bogus\":1}\n",
+			fn:        patientsOnly,
+			wantErr:   "line 2:",
+			wantLines: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out strings.Builder
+			err := Transform(strings.NewReader(test.input), &out, test.fn)
+			if err == nil || !strings.HasPrefix(err.Error(), test.wantErr) {
+				t.Fatalf("Transform() got err %v, want %q", err, test.wantErr)
+			}
+			if got := strings.Count(out.String(), "\n"); got != test.wantLines {
+				t.Errorf("Transform() wrote %d lines before failing, want %d", got, test.wantLines)
+			}
+		})
+	}
+}
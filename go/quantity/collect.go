@@ -0,0 +1,61 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quantity
+
+import (
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// QuantityWithPath is a quantity found by AllQuantities.
+type QuantityWithPath struct {
+	// Path is the FHIR element path of the quantity, e.g.
+	// "Observation.component[1].valueQuantity".
+	Path string
+	// Quantity is the Quantity message, or one of its specializations such as
+	// Age or SimpleQuantity.
+	Quantity proto.Message
+	// System is the system of the unit code, or "" if the quantity has none.
+	System string
+	// Unit is the coded unit of the quantity, falling back to its
+	// human-readable unit when it has no code.
+	Unit string
+}
+
+// AllQuantities returns every Quantity, including its specializations, found
+// in msg in depth-first order. Quantities in components, extensions and
+// contained resources are included.
+func AllQuantities(msg proto.Message) []QuantityWithPath {
+	var out []QuantityWithPath
+	walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		if !element.QuantityTypes[m.Descriptor().Name()] {
+			return nil
+		}
+		unit := element.PrimitiveString(m, "code")
+		if unit == "" {
+			unit = element.PrimitiveString(m, "unit")
+		}
+		out = append(out, QuantityWithPath{
+			Path:     path,
+			Quantity: m.Interface(),
+			System:   element.PrimitiveString(m, "system"),
+			Unit:     unit,
+		})
+		return nil
+	})
+	return out
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quantity
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+)
+
+func TestAllQuantities(t *testing.T) {
+	systolic := ucumQuantity("120", "mm[Hg]")
+	diastolic := ucumQuantity("80", "mm[Hg]")
+	low := &d4pb.SimpleQuantity{
+		Value:  &d4pb.Decimal{Value: "60"},
+		System: &d4pb.Uri{Value: ucumSystem},
+		Code:   &d4pb.Code{Value: "mm[Hg]"},
+	}
+	age := &d4pb.Age{
+		Value: &d4pb.Decimal{Value: "42"},
+		Unit:  &d4pb.String{Value: "years"},
+	}
+	obs := &r4observationpb.Observation{
+		Extension: []*d4pb.Extension{{
+			Url:   &d4pb.Uri{Value: "http://example.org/age-at-observation"},
+			Value: &d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Age{Age: age}},
+		}},
+		Component: []*r4observationpb.Observation_Component{
+			{
+				Code: &d4pb.CodeableConcept{Text: &d4pb.String{Value: "Systolic"}},
+				Value: &r4observationpb.Observation_Component_ValueX{
+					Choice: &r4observationpb.Observation_Component_ValueX_Quantity{Quantity: systolic},
+				},
+			},
+			{
+				Code: &d4pb.CodeableConcept{Text: &d4pb.String{Value: "Diastolic"}},
+				Value: &r4observationpb.Observation_Component_ValueX{
+					Choice: &r4observationpb.Observation_Component_ValueX_Quantity{Quantity: diastolic},
+				},
+				ReferenceRange: []*r4observationpb.Observation_ReferenceRange{{Low: low}},
+			},
+		},
+	}
+	want := []QuantityWithPath{
+		{Path: "Observation.extension[0].valueAge", Quantity: age, Unit: "years"},
+		{Path: "Observation.component[0].valueQuantity", Quantity: systolic, System: ucumSystem, Unit: "mm[Hg]"},
+		{Path: "Observation.component[1].valueQuantity", Quantity: diastolic, System: ucumSystem, Unit: "mm[Hg]"},
+		{Path: "Observation.component[1].referenceRange[0].low", Quantity: low, System: ucumSystem, Unit: "mm[Hg]"},
+	}
+	got := AllQuantities(obs)
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("AllQuantities() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestAllQuantities_None(t *testing.T) {
+	obs := &r4observationpb.Observation{
+		Value: &r4observationpb.Observation_ValueX{
+			Choice: &r4observationpb.Observation_ValueX_StringValue{StringValue: &d4pb.String{Value: "negative"}},
+		},
+	}
+	if got := AllQuantities(obs); len(got) != 0 {
+		t.Errorf("AllQuantities() = %v, want none", got)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quantity converts FHIR R4 Quantity values between UCUM units and
+// collects the quantities held in resources.
+package quantity
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/ucum"
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+const ucumSystem = "http://unitsofmeasure.org"
+
+// defaultSignificantDigits bounds the digits of converted values that have no
+// finite decimal expansion, such as grams in ounces.
+const defaultSignificantDigits = 15
+
+type convertOptions struct {
+	significantDigits int
+	maxDecimalPlaces  int
+}
+
+// ConvertOption configures the rounding of converted values.
+type ConvertOption func(*convertOptions)
+
+// SignificantDigits rounds converted values to at most n significant digits.
+// Digits left of the decimal point are kept, as zeros, to write the value
+// without an exponent, so 1234.5 rounded to two digits is "1200".
+func SignificantDigits(n int) ConvertOption {
+	return func(o *convertOptions) {
+		o.significantDigits = n
+	}
+}
+
+// MaxDecimalPlaces rounds converted values to at most n digits after the
+// decimal point.
+func MaxDecimalPlaces(n int) ConvertOption {
+	return func(o *convertOptions) {
+		o.maxDecimalPlaces = n
+	}
+}
+
+// Convert returns a copy of q with its value converted to the UCUM unit code,
+// e.g. "mg" or "mg/dL". q must have a UCUM code of the same dimension. The
+// converted value is exact when it has a finite decimal expansion, and has 15
+// significant digits otherwise; options round it further, halves away from
+// zero. Rounding never adds trailing zeros beyond the exact value.
+func Convert(q *d4pb.Quantity, code string, opts ...ConvertOption) (*d4pb.Quantity, error) {
+	o := convertOptions{maxDecimalPlaces: -1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.significantDigits < 0 {
+		return nil, fmt.Errorf("invalid significant digits %d", o.significantDigits)
+	}
+	if q.GetValue() == nil {
+		return nil, errors.New("quantity has no value")
+	}
+	value := q.GetValue().GetValue()
+	if !element.DecimalPattern.MatchString(value) {
+		return nil, fmt.Errorf("invalid decimal value %q", value)
+	}
+	v, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal value %q", value)
+	}
+	if system := q.GetSystem().GetValue(); system != "" && system != ucumSystem {
+		return nil, fmt.Errorf("unit system %q is not UCUM", system)
+	}
+	from, err := ucum.Parse(q.GetCode().GetValue())
+	if err != nil {
+		return nil, fmt.Errorf("unit %q: %w", q.GetCode().GetValue(), err)
+	}
+	to, err := ucum.Parse(code)
+	if err != nil {
+		return nil, fmt.Errorf("unit %q: %w", code, err)
+	}
+	if !from.Commensurable(to) {
+		return nil, fmt.Errorf("cannot convert %q to %q", q.GetCode().GetValue(), code)
+	}
+	v.Mul(v, from.Factor)
+	v.Quo(v, to.Factor)
+
+	res := proto.Clone(q).(*d4pb.Quantity)
+	res.Value = &d4pb.Decimal{Value: formatDecimal(v, o)}
+	res.Unit = &d4pb.String{Value: code}
+	res.System = &d4pb.Uri{Value: ucumSystem}
+	res.Code = &d4pb.Code{Value: code}
+	return res, nil
+}
+
+// formatDecimal writes v as a FHIR decimal, rounded as o requires.
+func formatDecimal(v *big.Rat, o convertOptions) string {
+	places := exactPlaces(v)
+	if places < 0 {
+		places = defaultSignificantDigits - 1 - exponent(v)
+	}
+	fromDigits := false
+	if o.significantDigits > 0 {
+		if p := o.significantDigits - 1 - exponent(v); p < places {
+			places, fromDigits = p, true
+		}
+	}
+	if o.maxDecimalPlaces >= 0 && o.maxDecimalPlaces < places {
+		places, fromDigits = o.maxDecimalPlaces, false
+	}
+	r := round(v, places)
+	if fromDigits && r.Sign() != 0 && exponent(r) > exponent(v) {
+		// Rounding carried into a new digit, as 9.99 to 10.0, which
+		// leaves one place fewer for the significant digits.
+		places--
+	}
+	return decimalString(r, places)
+}
+
+// exactPlaces returns the number of decimal places of v, or -1 if it has no
+// finite decimal expansion.
+func exactPlaces(v *big.Rat) int {
+	d := new(big.Int).Set(v.Denom())
+	var twos, fives int
+	two, five, zero := big.NewInt(2), big.NewInt(5), new(big.Int)
+	m := new(big.Int)
+	for m.Mod(d, two).Cmp(zero) == 0 {
+		d.Quo(d, two)
+		twos++
+	}
+	for m.Mod(d, five).Cmp(zero) == 0 {
+		d.Quo(d, five)
+		fives++
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return -1
+	}
+	if twos > fives {
+		return twos
+	}
+	return fives
+}
+
+// exponent returns the power of ten of the leading digit of v, or 0 if v is
+// zero.
+func exponent(v *big.Rat) int {
+	a := new(big.Rat).Abs(v)
+	if a.Sign() == 0 {
+		return 0
+	}
+	e := 0
+	for a.Cmp(pow10(e+1)) >= 0 {
+		e++
+	}
+	for a.Cmp(pow10(e)) < 0 {
+		e--
+	}
+	return e
+}
+
+// round rounds v to places decimal places, halves away from zero. places may
+// be negative to round left of the decimal point.
+func round(v *big.Rat, places int) *big.Rat {
+	scaled := new(big.Rat).Mul(v, pow10(places))
+	num := new(big.Int).Abs(scaled.Num())
+	den := scaled.Denom()
+	num.Mul(num, big.NewInt(2)).Add(num, den)
+	num.Quo(num, new(big.Int).Mul(den, big.NewInt(2)))
+	if scaled.Sign() < 0 {
+		num.Neg(num)
+	}
+	r := new(big.Rat).SetInt(num)
+	return r.Quo(r, pow10(places))
+}
+
+// decimalString writes v, which has at most places decimal places, with
+// exactly that many, or as an integer if places is not positive.
+func decimalString(v *big.Rat, places int) string {
+	if places <= 0 {
+		return v.Num().String()
+	}
+	return v.FloatString(places)
+}
+
+func pow10(e int) *big.Rat {
+	if e < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-e)), nil))
+	}
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(e)), nil))
+}
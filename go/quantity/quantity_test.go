@@ -0,0 +1,114 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quantity
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+func ucumQuantity(value, code string) *d4pb.Quantity {
+	return &d4pb.Quantity{
+		Value:  &d4pb.Decimal{Value: value},
+		Unit:   &d4pb.String{Value: code},
+		System: &d4pb.Uri{Value: ucumSystem},
+		Code:   &d4pb.Code{Value: code},
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    *d4pb.Quantity
+		code  string
+		opts  []ConvertOption
+		value string
+	}{
+		{"grams to milligrams", ucumQuantity("1", "g"), "mg", nil, "1000"},
+		{"grams to milligrams, rounded", ucumQuantity("1", "g"), "mg", []ConvertOption{SignificantDigits(2)}, "1000"},
+		{"fraction to milligrams", ucumQuantity("1.23456", "g"), "mg", nil, "1234.56"},
+		{"significant digits", ucumQuantity("1.23456", "g"), "mg", []ConvertOption{SignificantDigits(3)}, "1230"},
+		{"decimal places", ucumQuantity("1.23456", "g"), "mg", []ConvertOption{MaxDecimalPlaces(1)}, "1234.6"},
+		{"both limits", ucumQuantity("1.23456", "g"), "kg", []ConvertOption{SignificantDigits(4), MaxDecimalPlaces(3)}, "0.001"},
+		{"no trailing zeros added", ucumQuantity("1.5", "g"), "mg", []ConvertOption{SignificantDigits(8)}, "1500"},
+		{"carry into new digit", ucumQuantity("9.996", "mg"), "mg", []ConvertOption{SignificantDigits(3)}, "10.0"},
+		{"negative", ucumQuantity("-2.5", "mg"), "g", nil, "-0.0025"},
+		{"repeating decimal", ucumQuantity("1", "g"), "[oz_av]", nil, "0.0352739619495804"},
+		{"repeating decimal, rounded", ucumQuantity("1", "g"), "[oz_av]", []ConvertOption{SignificantDigits(3)}, "0.0353"},
+		{"compound unit", ucumQuantity("100", "mg/dL"), "g/L", nil, "1"},
+		{"exponent", ucumQuantity("1", "m2"), "cm2", nil, "10000"},
+		{"parentheses", ucumQuantity("1", "ug/(kg.min)"), "ug/kg/h", nil, "60"},
+		{"annotation", ucumQuantity("3", "{beats}/min"), "/h", nil, "180"},
+		{"time", ucumQuantity("36", "h"), "d", nil, "1.5"},
+		{"no system", &d4pb.Quantity{Value: &d4pb.Decimal{Value: "2"}, Code: &d4pb.Code{Value: "L"}}, "mL", nil, "2000"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Convert(test.in, test.code, test.opts...)
+			if err != nil {
+				t.Fatalf("Convert(%v, %q) failed: %v", test.in, test.code, err)
+			}
+			want := ucumQuantity(test.value, test.code)
+			if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Convert(%v, %q) returned unexpected diff (-want +got):\n%s", test.in, test.code, diff)
+			}
+		})
+	}
+}
+
+func TestConvert_KeepsComparator(t *testing.T) {
+	in := ucumQuantity("5", "g")
+	in.Comparator = &d4pb.Quantity_ComparatorCode{Value: 1}
+	got, err := Convert(in, "kg")
+	if err != nil {
+		t.Fatalf("Convert() failed: %v", err)
+	}
+	if diff := cmp.Diff(in.GetComparator(), got.GetComparator(), protocmp.Transform()); diff != "" {
+		t.Errorf("Convert() comparator diff (-want +got):\n%s", diff)
+	}
+	if got := in.GetValue().GetValue(); got != "5" {
+		t.Errorf("Convert() modified its input value to %q", got)
+	}
+}
+
+func TestConvert_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *d4pb.Quantity
+		code string
+		opts []ConvertOption
+	}{
+		{"no value", &d4pb.Quantity{Code: &d4pb.Code{Value: "g"}}, "mg", nil},
+		{"invalid value", ucumQuantity("1.", "g"), "mg", nil},
+		{"not UCUM", &d4pb.Quantity{Value: &d4pb.Decimal{Value: "1"}, System: &d4pb.Uri{Value: "http://example.com"}, Code: &d4pb.Code{Value: "g"}}, "mg", nil},
+		{"no code", &d4pb.Quantity{Value: &d4pb.Decimal{Value: "1"}}, "mg", nil},
+		{"unknown unit", ucumQuantity("1", "g"), "zorg", nil},
+		{"non-metric prefix", ucumQuantity("1", "g"), "kmin", nil},
+		{"empty component", ucumQuantity("1", "mg//dL"), "g/L", nil},
+		{"incompatible dimensions", ucumQuantity("1", "g"), "mL", nil},
+		{"negative significant digits", ucumQuantity("1", "g"), "mg", []ConvertOption{SignificantDigits(-1)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := Convert(test.in, test.code, test.opts...); err == nil {
+				t.Errorf("Convert(%v, %q) = %v, want error", test.in, test.code, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rdfformat converts FHIR resources to the Turtle serialization of
+// FHIR RDF, following the node mapping of the FHIR ontology: each element is a
+// node linked by a predicate named after the type that defines the element,
+// e.g. fhir:Patient.birthDate or fhir:Resource.id, primitive values are held
+// by fhir:value, items of repeated elements carry their fhir:index, and
+// literal references are linked to their target with fhir:link.
+//
+// Coverage is currently limited to R4 resources. Every element of a resource
+// is emitted, but the following parts of the FHIR RDF mapping are not:
+//   - Codings are not typed with the concept they name, e.g. "a sct:12345";
+//   - contained resources and Bundle entry resources are emitted as blank
+//     nodes rather than named after their id or fullUrl;
+//   - resources and references are identified by relative IRIs such as
+//     <Patient/p1>, to be resolved against the base of the server the
+//     resource came from.
+package rdfformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/fhir/go/contained"
+	"github.com/google/fhir/go/fhirversion"
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+// header declares the prefixes used in the output.
+const header = `@prefix fhir: <http://hl7.org/fhir/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+`
+
+var (
+	// anyName and containedResource describe the messages resources are
+	// nested in.
+	anyName           = (&anypb.Any{}).ProtoReflect().Descriptor().FullName()
+	containedResource = (&r4pb.ContainedResource{}).ProtoReflect().Descriptor()
+	// extensionDescriptor describes the extensions of primitive values.
+	extensionDescriptor = (&d4pb.Extension{}).ProtoReflect().Descriptor()
+)
+
+// Marshal returns the FHIR RDF Turtle serialization of msg, an R4 resource or
+// a ContainedResource holding one.
+func Marshal(msg proto.Message) ([]byte, error) {
+	if _, ok := msg.(*r4pb.ContainedResource); ok {
+		r, err := contained.Unpack(msg)
+		if err != nil {
+			return nil, err
+		}
+		msg = r
+	}
+	d := msg.ProtoReflect().Descriptor()
+	if !element.IsResource(d) || !strings.HasPrefix(string(d.ParentFile().Package()), "google.fhir.r4.") {
+		return nil, fmt.Errorf("unsupported message %T, want an R4 resource", msg)
+	}
+	// The JSON representation carries the canonical lexical form of every
+	// primitive value, which FHIR RDF shares, so the resource is converted to
+	// JSON first and the JSON read alongside the proto descriptors that give
+	// the types of its elements.
+	m, err := jsonformat.NewMarshaller(false, "", "", fhirversion.R4)
+	if err != nil {
+		return nil, err
+	}
+	data, err := m.MarshalResource(msg)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	root := resourceNode(d, obj)
+	root.props = append([]prop{root.props[0], {pred: "fhir:nodeRole", obj: "fhir:treeRoot"}}, root.props[1:]...)
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	subject := "[]"
+	if id, ok := obj["id"].(string); ok && validIRI(id) {
+		subject = "<" + string(d.Name()) + "/" + id + ">"
+	}
+	buf.WriteString(subject)
+	writeProps(&buf, root, "")
+	buf.WriteString(" .\n")
+	return buf.Bytes(), nil
+}
+
+// resourceNode returns the node for the resource of type d held by obj.
+func resourceNode(d protoreflect.MessageDescriptor, obj map[string]any) *node {
+	n := &node{props: []prop{{pred: "a", obj: "fhir:" + string(d.Name())}}}
+	addElements(n, d, string(d.Name()), obj)
+	return n
+}
+
+// addElements adds to n the elements of obj, a JSON object holding a value of
+// type d, naming them after context, the type or path of backbone element
+// that defines them.
+func addElements(n *node, d protoreflect.MessageDescriptor, context string, obj map[string]any) {
+	if d.Name() == "Reference" {
+		if ref, ok := obj["reference"].(string); ok && !strings.HasPrefix(ref, "#") && validIRI(ref) {
+			n.props = append(n.props, prop{pred: "fhir:link", obj: "<" + ref + ">"})
+		}
+	}
+	fields := d.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.Get(i)
+		if f.Message() == nil {
+			continue
+		}
+		name := f.JSONName()
+		pred := "fhir:" + definingType(d, context, name) + "." + name
+		if element.IsChoice(f.Message()) {
+			oneof := f.Message().Oneofs().Get(0).Fields()
+			for j := 0; j < oneof.Len(); j++ {
+				o := oneof.Get(j)
+				key := name + strings.ToUpper(o.JSONName()[:1]) + o.JSONName()[1:]
+				n.addField(pred+key[len(name):], obj[key], obj["_"+key], elementNode(o.Message(), context+"."+key))
+			}
+			continue
+		}
+		if f.Message().Name() == "Xhtml" {
+			// The narrative div is a literal rather than a primitive node.
+			if div, ok := obj[name].(string); ok {
+				n.props = append(n.props, prop{pred: pred, obj: quote(div)})
+			}
+			continue
+		}
+		n.addField(pred, obj[name], obj["_"+name], elementNode(f.Message(), context+"."+name))
+	}
+}
+
+// definingType returns the name of the type that defines the element name of
+// d, whose own elements are defined by context.
+func definingType(d protoreflect.MessageDescriptor, context, name string) string {
+	switch {
+	case element.IsResource(d):
+		switch name {
+		case "id", "meta", "implicitRules", "language":
+			return "Resource"
+		case "text", "contained", "extension", "modifierExtension":
+			return "DomainResource"
+		}
+	case name == "id" || name == "extension":
+		return "Element"
+	case name == "modifierExtension":
+		return "BackboneElement"
+	}
+	return context
+}
+
+// elementNode returns a function building the node for a value of type d,
+// given its JSON value and, for primitives, its JSON extension object.
+// Backbone elements are named after path.
+func elementNode(d protoreflect.MessageDescriptor, path string) func(v, ext any) *node {
+	return func(v, ext any) *node {
+		if element.IsPrimitive(d) {
+			return primitiveNode(d.Name(), v, ext)
+		}
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		if d.FullName() == anyName || d.FullName() == containedResource.FullName() {
+			rd := resourceDescriptor(obj)
+			if rd == nil {
+				return nil
+			}
+			return resourceNode(rd, obj)
+		}
+		n := &node{}
+		context := path
+		if kind(d) == apb.StructureDefinitionKindValue_KIND_COMPLEX_TYPE {
+			context = string(d.Name())
+			// Quantity's specializations constrain it, so their elements are
+			// defined by, and named after, Quantity.
+			if element.QuantityTypes[d.Name()] {
+				context = "Quantity"
+			}
+		}
+		addElements(n, d, context, obj)
+		return n
+	}
+}
+
+// primitiveNode returns the node for a primitive of type typ given its JSON
+// value v and JSON extension object ext, either of which may be nil, or nil
+// if there is neither.
+func primitiveNode(typ protoreflect.Name, v, ext any) *node {
+	n := &node{}
+	if v != nil {
+		n.props = append(n.props, prop{pred: "fhir:value", obj: literal(typ, v)})
+	}
+	if e, ok := ext.(map[string]any); ok {
+		if id, ok := e["id"]; ok {
+			n.addField("fhir:Element.id", id, nil, func(v, ext any) *node {
+				return primitiveNode("String", v, ext)
+			})
+		}
+		n.addField("fhir:Element.extension", e["extension"], nil, elementNode(extensionDescriptor, "Extension"))
+	}
+	if len(n.props) == 0 {
+		return nil
+	}
+	return n
+}
+
+// resourceDescriptor returns the descriptor of the R4 resource held by obj,
+// as named by its resourceType, or nil if it is not known.
+func resourceDescriptor(obj map[string]any) protoreflect.MessageDescriptor {
+	typ, _ := obj["resourceType"].(string)
+	fields := containedResource.Oneofs().ByName("oneof_resource").Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if d := fields.Get(i).Message(); string(d.Name()) == typ {
+			return d
+		}
+	}
+	return nil
+}
+
+func kind(d protoreflect.MessageDescriptor) apb.StructureDefinitionKindValue {
+	return proto.GetExtension(d.Options(), apb.E_StructureDefinitionKind).(apb.StructureDefinitionKindValue)
+}
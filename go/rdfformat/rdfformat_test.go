@@ -0,0 +1,430 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdfformat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	r3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+const (
+	fhirNS  = "http://hl7.org/fhir/"
+	rdfType = "<http://www.w3.org/1999/02/22-rdf-syntax-ns#type>"
+)
+
+// triple is an RDF statement with terms written as in N-Triples, except that
+// relative IRIs are kept as they are.
+type triple struct {
+	s, p, o string
+}
+
+// parseTurtle parses the subset of Turtle written by Marshal: prefix
+// declarations, IRIs, prefixed names, string literals with an optional
+// datatype, integers and nested blank nodes.
+func parseTurtle(in string) ([]triple, error) {
+	p := &turtleParser{prefixes: map[string]string{}}
+	if err := p.tokenize(in); err != nil {
+		return nil, err
+	}
+	for p.pos < len(p.tokens) {
+		if p.peek() == "@prefix" {
+			p.next()
+			name, iri := p.next(), p.next()
+			if !strings.HasSuffix(name, ":") || !strings.HasPrefix(iri, "<") {
+				return nil, fmt.Errorf("malformed prefix declaration %s %s", name, iri)
+			}
+			p.prefixes[strings.TrimSuffix(name, ":")] = strings.Trim(iri, "<>")
+			if err := p.expect("."); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var subject string
+		var err error
+		if p.peek() == "[" {
+			subject, err = p.blankNode()
+		} else {
+			subject, err = p.term()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != "." {
+			if err := p.predicateObjectList(subject); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect("."); err != nil {
+			return nil, err
+		}
+	}
+	return p.triples, nil
+}
+
+type turtleParser struct {
+	tokens   []string
+	pos      int
+	prefixes map[string]string
+	triples  []triple
+	blanks   int
+}
+
+func (p *turtleParser) tokenize(in string) error {
+	for i := 0; i < len(in); {
+		c := in[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '#':
+			for i < len(in) && in[i] != '\n' {
+				i++
+			}
+		case strings.IndexByte("[];,", c) >= 0:
+			p.tokens = append(p.tokens, string(c))
+			i++
+		case c == '<':
+			end := strings.IndexByte(in[i:], '>')
+			if end < 0 {
+				return fmt.Errorf("unterminated IRI at %d", i)
+			}
+			p.tokens = append(p.tokens, in[i:i+end+1])
+			i += end + 1
+		case c == '"':
+			j := i + 1
+			for ; j < len(in) && in[j] != '"'; j++ {
+				if in[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(in) {
+				return fmt.Errorf("unterminated string at %d", i)
+			}
+			p.tokens = append(p.tokens, in[i:j+1])
+			i = j + 1
+		case c == '^' && strings.HasPrefix(in[i:], "^^"):
+			p.tokens = append(p.tokens, "^^")
+			i += 2
+		default:
+			j := i
+			for j < len(in) && !unicode.IsSpace(rune(in[j])) && strings.IndexByte("[];,<\"", in[j]) < 0 {
+				j++
+			}
+			tok := in[i:j]
+			if len(tok) > 1 && strings.HasSuffix(tok, ".") {
+				// A prefixed name cannot end with a dot; it ends the statement.
+				tok, j = tok[:len(tok)-1], j-1
+			}
+			p.tokens = append(p.tokens, tok)
+			i = j
+		}
+	}
+	return nil
+}
+
+func (p *turtleParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *turtleParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *turtleParser) expect(tok string) error {
+	if got := p.next(); got != tok {
+		return fmt.Errorf("token %d: got %q, want %q", p.pos-1, got, tok)
+	}
+	return nil
+}
+
+func (p *turtleParser) predicateObjectList(subject string) error {
+	for {
+		verb, err := p.term()
+		if err != nil {
+			return err
+		}
+		for {
+			object, err := p.object()
+			if err != nil {
+				return err
+			}
+			p.triples = append(p.triples, triple{subject, verb, object})
+			if p.peek() != "," {
+				break
+			}
+			p.next()
+		}
+		if p.peek() != ";" {
+			return nil
+		}
+		p.next()
+		if tok := p.peek(); tok == "." || tok == "]" {
+			return nil
+		}
+	}
+}
+
+func (p *turtleParser) blankNode() (string, error) {
+	if err := p.expect("["); err != nil {
+		return "", err
+	}
+	p.blanks++
+	b := fmt.Sprintf("_:b%d", p.blanks)
+	if p.peek() != "]" {
+		if err := p.predicateObjectList(b); err != nil {
+			return "", err
+		}
+	}
+	return b, p.expect("]")
+}
+
+func (p *turtleParser) object() (string, error) {
+	switch tok := p.peek(); {
+	case tok == "[":
+		return p.blankNode()
+	case strings.HasPrefix(tok, `"`):
+		p.next()
+		if p.peek() != "^^" {
+			return tok, nil
+		}
+		p.next()
+		datatype, err := p.term()
+		if err != nil {
+			return "", err
+		}
+		return tok + "^^" + datatype, nil
+	case tok != "" && strings.Trim(tok, "0123456789") == "":
+		p.next()
+		return tok, nil
+	}
+	return p.term()
+}
+
+// term parses an IRI, a prefixed name or "a", returning it as an IRI.
+func (p *turtleParser) term() (string, error) {
+	tok := p.next()
+	switch {
+	case tok == "a":
+		return rdfType, nil
+	case strings.HasPrefix(tok, "<"):
+		return tok, nil
+	}
+	prefix, local, ok := strings.Cut(tok, ":")
+	ns, known := p.prefixes[prefix]
+	if !ok || !known {
+		return "", fmt.Errorf("token %d: %q is not an IRI or known prefixed name", p.pos-1, tok)
+	}
+	return "<" + ns + local + ">", nil
+}
+
+// objects returns the objects of the triples with subject s and predicate p.
+func objects(triples []triple, s, p string) []string {
+	var out []string
+	for _, t := range triples {
+		if t.s == s && t.p == p {
+			out = append(out, t.o)
+		}
+	}
+	return out
+}
+
+// values returns the fhir:value of each node linked to s by the FHIR
+// element elem.
+func values(triples []triple, s, elem string) []string {
+	var out []string
+	for _, node := range objects(triples, s, "<"+fhirNS+elem+">") {
+		out = append(out, objects(triples, node, "<"+fhirNS+"value>")...)
+	}
+	return out
+}
+
+func TestMarshal(t *testing.T) {
+	patient := &r4patientpb.Patient{
+		Id:     &d4pb.Id{Value: "p1"},
+		Active: &d4pb.Boolean{Value: true},
+		Name: []*d4pb.HumanName{{
+			Family: &d4pb.String{Value: "Doe"},
+			Given:  []*d4pb.String{{Value: "Jane"}, {Value: `Q "Jo"`}},
+		}},
+		Gender:    &r4patientpb.Patient_GenderCode{Value: c4pb.AdministrativeGenderCode_FEMALE},
+		BirthDate: &d4pb.Date{ValueUs: 156556800000000, Precision: d4pb.Date_DAY, Timezone: "UTC"},
+		ManagingOrganization: &d4pb.Reference{
+			Reference: &d4pb.Reference_OrganizationId{OrganizationId: &d4pb.ReferenceId{Value: "o1"}},
+		},
+	}
+	got, err := Marshal(patient)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	triples, err := parseTurtle(string(got))
+	if err != nil {
+		t.Fatalf("parseTurtle() failed: %v\n%s", err, got)
+	}
+
+	const subject = "<Patient/p1>"
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{
+			name: "type",
+			got:  objects(triples, subject, rdfType),
+			want: []string{"<" + fhirNS + "Patient>"},
+		},
+		{
+			name: "node role",
+			got:  objects(triples, subject, "<"+fhirNS+"nodeRole>"),
+			want: []string{"<" + fhirNS + "treeRoot>"},
+		},
+		{
+			name: "id",
+			got:  values(triples, subject, "Resource.id"),
+			want: []string{`"p1"`},
+		},
+		{
+			name: "boolean",
+			got:  values(triples, subject, "Patient.active"),
+			want: []string{`"true"^^<http://www.w3.org/2001/XMLSchema#boolean>`},
+		},
+		{
+			name: "code",
+			got:  values(triples, subject, "Patient.gender"),
+			want: []string{`"female"`},
+		},
+		{
+			name: "date",
+			got:  values(triples, subject, "Patient.birthDate"),
+			want: []string{`"1974-12-18"^^<http://www.w3.org/2001/XMLSchema#date>`},
+		},
+		{
+			name: "reference link",
+			got:  objects(triples, objects(triples, subject, "<"+fhirNS+"Patient.managingOrganization>")[0], "<"+fhirNS+"link>"),
+			want: []string{"<Organization/o1>"},
+		},
+	}
+	for _, test := range tests {
+		if diff := cmp.Diff(test.want, test.got); diff != "" {
+			t.Errorf("Marshal() %s returned unexpected diff (-want +got):\n%s", test.name, diff)
+		}
+	}
+
+	names := objects(triples, subject, "<"+fhirNS+"Patient.name>")
+	if len(names) != 1 {
+		t.Fatalf("Marshal() has %d names, want 1", len(names))
+	}
+	givens := map[string]string{}
+	for _, given := range objects(triples, names[0], "<"+fhirNS+"HumanName.given>") {
+		index := objects(triples, given, "<"+fhirNS+"index>")
+		value := objects(triples, given, "<"+fhirNS+"value>")
+		if len(index) != 1 || len(value) != 1 {
+			t.Fatalf("Marshal() given name has index %v and value %v, want one of each", index, value)
+		}
+		givens[index[0]] = value[0]
+	}
+	wantGivens := map[string]string{"0": `"Jane"`, "1": `"Q \"Jo\""`}
+	if diff := cmp.Diff(wantGivens, givens); diff != "" {
+		t.Errorf("Marshal() given names returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshal_Format(t *testing.T) {
+	patient := &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{
+		Patient: &r4patientpb.Patient{
+			Id: &d4pb.Id{Value: "p1"},
+			Name: []*d4pb.HumanName{{
+				Family: &d4pb.String{
+					Value: "Doe",
+					Extension: []*d4pb.Extension{{
+						Url: &d4pb.Uri{Value: "http://example.com/source"},
+						Value: &d4pb.Extension_ValueX{
+							Choice: &d4pb.Extension_ValueX_Code{Code: &d4pb.Code{Value: "intake"}},
+						},
+					}},
+				},
+			}},
+		},
+	}}
+	got, err := Marshal(patient)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	want := `@prefix fhir: <http://hl7.org/fhir/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+<Patient/p1> a fhir:Patient ;
+  fhir:nodeRole fhir:treeRoot ;
+  fhir:Resource.id [ fhir:value "p1" ] ;
+  fhir:Patient.name [
+    fhir:index 0 ;
+    fhir:HumanName.family [
+      fhir:value "Doe" ;
+      fhir:Element.extension [
+        fhir:index 0 ;
+        fhir:Extension.url [ fhir:value "http://example.com/source"^^xsd:anyURI ] ;
+        fhir:Extension.valueCode [ fhir:value "intake" ]
+      ]
+    ]
+  ] .
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Marshal() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if _, err := parseTurtle(string(got)); err != nil {
+		t.Errorf("parseTurtle() failed: %v", err)
+	}
+}
+
+func TestMarshal_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+	}{
+		{
+			name: "STU3 resource",
+			msg:  &r3pb.Patient{},
+		},
+		{
+			name: "empty ContainedResource",
+			msg:  &r4pb.ContainedResource{},
+		},
+		{
+			name: "datatype",
+			msg:  &d4pb.HumanName{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Marshal(test.msg); err == nil {
+				t.Errorf("Marshal() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdfformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// A node is a blank node of the RDF graph, described by its properties.
+type node struct {
+	props []prop
+}
+
+// A prop is a predicate of a node with its object, either a nested node or a
+// Turtle term such as an IRI or literal.
+type prop struct {
+	pred string
+	obj  any
+}
+
+// addField adds the element pred of n with JSON value v and, for primitives,
+// JSON extension object ext, building the node of each value with build.
+// Items of repeated elements, given as JSON arrays, carry their fhir:index.
+// Values for which build returns nil are omitted.
+func (n *node) addField(pred string, v, ext any, build func(v, ext any) *node) {
+	values, isList := v.([]any)
+	exts, extIsList := ext.([]any)
+	if !isList && !extIsList {
+		if v == nil && ext == nil {
+			return
+		}
+		if child := build(v, ext); child != nil {
+			n.props = append(n.props, prop{pred: pred, obj: child})
+		}
+		return
+	}
+	count := len(values)
+	if len(exts) > count {
+		count = len(exts)
+	}
+	for i := 0; i < count; i++ {
+		var item, itemExt any
+		if i < len(values) {
+			item = values[i]
+		}
+		if i < len(exts) {
+			itemExt = exts[i]
+		}
+		if item == nil && itemExt == nil {
+			continue
+		}
+		child := build(item, itemExt)
+		if child == nil {
+			continue
+		}
+		child.props = append([]prop{{pred: "fhir:index", obj: strconv.Itoa(i)}}, child.props...)
+		n.props = append(n.props, prop{pred: pred, obj: child})
+	}
+}
+
+// literal returns the Turtle literal for the JSON value v of a primitive of
+// type typ, typed with the XML Schema datatype FHIR RDF maps typ to.
+func literal(typ protoreflect.Name, v any) string {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case json.Number:
+		s = v.String()
+	case bool:
+		s = strconv.FormatBool(v)
+	}
+	var datatype string
+	switch typ {
+	case "Boolean":
+		datatype = "xsd:boolean"
+	case "Integer":
+		datatype = "xsd:int"
+	case "PositiveInt":
+		datatype = "xsd:positiveInteger"
+	case "UnsignedInt":
+		datatype = "xsd:nonNegativeInteger"
+	case "Decimal":
+		datatype = "xsd:decimal"
+	case "Base64Binary":
+		datatype = "xsd:base64Binary"
+	case "Instant":
+		datatype = "xsd:dateTime"
+	case "Time":
+		datatype = "xsd:time"
+	case "Uri", "Url", "Canonical", "Oid", "Uuid":
+		datatype = "xsd:anyURI"
+	case "Date", "DateTime":
+		// Partial dates are typed by their precision.
+		switch len(s) {
+		case len("2006"):
+			datatype = "xsd:gYear"
+		case len("2006-01"):
+			datatype = "xsd:gYearMonth"
+		case len("2006-01-02"):
+			datatype = "xsd:date"
+		default:
+			datatype = "xsd:dateTime"
+		}
+	}
+	if datatype == "" {
+		return quote(s)
+	}
+	return quote(s) + "^^" + datatype
+}
+
+// quote returns s as a Turtle string literal.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// validIRI reports whether s can be written as a Turtle IRI reference.
+func validIRI(s string) bool {
+	return s != "" && !strings.ContainsAny(s, " <>\"{}|^`\\\t\r\n")
+}
+
+// writeProps writes the properties of n, the first on the current line and
+// each following one on its own line indented by indent and two spaces.
+func writeProps(buf *bytes.Buffer, n *node, indent string) {
+	for i, p := range n.props {
+		if i == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteString(" ;\n" + indent + "  ")
+		}
+		buf.WriteString(p.pred)
+		buf.WriteByte(' ')
+		writeObject(buf, p.obj, indent+"  ")
+	}
+}
+
+// writeObject writes obj, a term or a node whose properties are indented by
+// indent and two spaces. Nodes holding only terms, such as primitives, are
+// written on a single line.
+func writeObject(buf *bytes.Buffer, obj any, indent string) {
+	n, ok := obj.(*node)
+	if !ok {
+		buf.WriteString(obj.(string))
+		return
+	}
+	if n.isFlat() {
+		buf.WriteByte('[')
+		for i, p := range n.props {
+			if i > 0 {
+				buf.WriteString(" ;")
+			}
+			buf.WriteString(" " + p.pred + " " + p.obj.(string))
+		}
+		buf.WriteString(" ]")
+		return
+	}
+	buf.WriteByte('[')
+	for i, p := range n.props {
+		if i > 0 {
+			buf.WriteString(" ;")
+		}
+		buf.WriteString("\n" + indent + "  " + p.pred + " ")
+		writeObject(buf, p.obj, indent+"  ")
+	}
+	buf.WriteString("\n" + indent + "]")
+}
+
+// isFlat reports whether n has at most two properties, none of them nodes.
+func (n *node) isFlat() bool {
+	if len(n.props) > 2 {
+		return false
+	}
+	for _, p := range n.props {
+		if _, ok := p.obj.(*node); ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reference
+
+import (
+	"fmt"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/walk"
+	"github.com/google/fhir/go/patient"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// A Resolver fetches the resource a reference refers to, typically from a
+// FHIR server or store.
+type Resolver interface {
+	// Resolve returns the resource ref refers to, or nil if there is none.
+	Resolve(ref *d4pb.Reference) (proto.Message, error)
+}
+
+// PopulateDisplays sets the display of each R4 reference in msg that has
+// none from the resource it refers to, as found with resolve. Patients and
+// Practitioners are displayed by their preferred name, as chosen by
+// patient.PreferredName and formatted by patient.FormatName, and other
+// resources by the text of their code element, falling back to the display
+// of its first coding that has one. References that resolve to nil, or to a resource with
+// nothing to display, are left unchanged. An error from resolve stops the
+// walk and is returned.
+func PopulateDisplays(msg proto.Message, resolve Resolver) error {
+	return walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		ref, ok := m.Interface().(*d4pb.Reference)
+		if !ok || ref.GetDisplay().GetValue() != "" {
+			return nil
+		}
+		if ref.GetReference() == nil && ref.GetIdentifier() == nil {
+			return nil
+		}
+		target, err := resolve.Resolve(ref)
+		if err != nil {
+			return fmt.Errorf("%s: resolving reference: %w", path, err)
+		}
+		rm, ok := element.ResourceOf(target)
+		if !ok {
+			return nil
+		}
+		if display := resourceDisplay(rm); display != "" {
+			ref.Display = &d4pb.String{Value: display}
+		}
+		return nil
+	})
+}
+
+// resourceDisplay returns a human-readable name for the resource rm, or "" if
+// it has none.
+func resourceDisplay(rm protoreflect.Message) string {
+	d := rm.Descriptor()
+	switch d.Name() {
+	case "Patient", "Practitioner":
+		if name, ok := patient.PreferredName(rm.Interface()); ok {
+			return patient.FormatName(name)
+		}
+	default:
+		f := d.Fields().ByName("code")
+		if f == nil || f.IsList() || f.Message() == nil || !rm.Has(f) {
+			return ""
+		}
+		if code, ok := rm.Get(f).Message().Interface().(*d4pb.CodeableConcept); ok {
+			return codeDisplay(code)
+		}
+	}
+	return ""
+}
+
+// codeDisplay returns the text of code, or the display of its first coding
+// that has one if it has no text.
+func codeDisplay(code *d4pb.CodeableConcept) string {
+	if text := code.GetText().GetValue(); text != "" {
+		return text
+	}
+	for _, c := range code.GetCoding() {
+		if display := c.GetDisplay().GetValue(); display != "" {
+			return display
+		}
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reference
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	r4practitionerpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/practitioner_go_proto"
+)
+
+// fakeResolver serves resources by their literal "Type/id" reference.
+type fakeResolver map[string]proto.Message
+
+func (f fakeResolver) Resolve(ref *d4pb.Reference) (proto.Message, error) {
+	switch {
+	case ref.GetPatientId() != nil:
+		return f["Patient/"+ref.GetPatientId().GetValue()], nil
+	case ref.GetPractitionerId() != nil:
+		return f["Practitioner/"+ref.GetPractitionerId().GetValue()], nil
+	case ref.GetObservationId() != nil:
+		return f["Observation/"+ref.GetObservationId().GetValue()], nil
+	}
+	return nil, nil
+}
+
+func TestPopulateDisplays(t *testing.T) {
+	resolver := fakeResolver{
+		"Patient/p1": &r4patientpb.Patient{
+			Id: &d4pb.Id{Value: "p1"},
+			Name: []*d4pb.HumanName{{
+				Use:  &d4pb.HumanName_UseCode{Value: c4pb.NameUseCode_NICKNAME},
+				Text: &d4pb.String{Value: "Janie"},
+			}, {
+				Use:    &d4pb.HumanName_UseCode{Value: c4pb.NameUseCode_OFFICIAL},
+				Given:  []*d4pb.String{{Value: "Jane"}, {Value: "Q"}},
+				Family: &d4pb.String{Value: "Doe"},
+			}},
+		},
+		"Practitioner/dr1": &r4practitionerpb.Practitioner{
+			Id:   &d4pb.Id{Value: "dr1"},
+			Name: []*d4pb.HumanName{{Text: &d4pb.String{Value: "Dr. Who"}}},
+		},
+		"Observation/o2": &r4observationpb.Observation{
+			Id: &d4pb.Id{Value: "o2"},
+			Code: &d4pb.CodeableConcept{Coding: []*d4pb.Coding{
+				{Code: &d4pb.Code{Value: "8310-5"}},
+				{Code: &d4pb.Code{Value: "8310-5"}, Display: &d4pb.String{Value: "Body temperature"}},
+			}},
+		},
+	}
+	obs := &r4observationpb.Observation{
+		Subject: &d4pb.Reference{Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}}},
+		Performer: []*d4pb.Reference{
+			{Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "dr1"}}},
+			{
+				Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "dr1"}},
+				Display:   &d4pb.String{Value: "Kept"},
+			},
+			{Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "missing"}}},
+		},
+		HasMember: []*d4pb.Reference{
+			{Reference: &d4pb.Reference_ObservationId{ObservationId: &d4pb.ReferenceId{Value: "o2"}}},
+		},
+	}
+	if err := PopulateDisplays(obs, resolver); err != nil {
+		t.Fatalf("PopulateDisplays() failed: %v", err)
+	}
+	want := &r4observationpb.Observation{
+		Subject: &d4pb.Reference{
+			Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}},
+			Display:   &d4pb.String{Value: "Jane Q Doe"},
+		},
+		Performer: []*d4pb.Reference{
+			{
+				Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "dr1"}},
+				Display:   &d4pb.String{Value: "Dr. Who"},
+			},
+			{
+				Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "dr1"}},
+				Display:   &d4pb.String{Value: "Kept"},
+			},
+			{Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "missing"}}},
+		},
+		HasMember: []*d4pb.Reference{{
+			Reference: &d4pb.Reference_ObservationId{ObservationId: &d4pb.ReferenceId{Value: "o2"}},
+			Display:   &d4pb.String{Value: "Body temperature"},
+		}},
+	}
+	if diff := cmp.Diff(want, obs, protocmp.Transform()); diff != "" {
+		t.Errorf("PopulateDisplays() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+type failingResolver struct{}
+
+func (failingResolver) Resolve(*d4pb.Reference) (proto.Message, error) {
+	return nil, errors.New("server unavailable")
+}
+
+func TestPopulateDisplays_ResolveError(t *testing.T) {
+	obs := &r4observationpb.Observation{
+		Subject: &d4pb.Reference{Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}}},
+	}
+	if err := PopulateDisplays(obs, failingResolver{}); err == nil {
+		t.Errorf("PopulateDisplays() succeeded, want error")
+	}
+}
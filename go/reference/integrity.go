@@ -0,0 +1,63 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reference
+
+import (
+	"fmt"
+
+	"github.com/google/fhir/go/contained"
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/walk"
+	"github.com/google/fhir/go/validation"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+)
+
+// CheckReferenceIntegrity checks that every local "#id" reference in msg,
+// including those made by its contained resources, names one of msg's
+// contained resources. It returns a validation.Violation for each dangling
+// reference. A bare "#", which refers to the container itself, is always
+// valid.
+func CheckReferenceIntegrity(msg proto.Message) []error {
+	resources, err := contained.Resources(msg)
+	if err != nil {
+		return []error{err}
+	}
+	ids := map[string]bool{}
+	for _, r := range resources {
+		if id := element.PrimitiveString(r.ProtoReflect(), "id"); id != "" {
+			ids[id] = true
+		}
+	}
+	var errs []error
+	err = walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		if !proto.HasExtension(m.Descriptor().Options(), apb.E_FhirReferenceType) {
+			return nil
+		}
+		if id, ok := element.FragmentID(m); ok && !ids[id] {
+			errs = append(errs, validation.Violation{
+				Path:    path,
+				Message: fmt.Sprintf("reference #%s does not match a contained resource", id),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reference
+
+import (
+	"testing"
+
+	"github.com/google/fhir/go/validation"
+	"github.com/google/go-cmp/cmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4specimenpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/specimen_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+func fragmentRef(id string) *d4pb.Reference {
+	return &d4pb.Reference{Reference: &d4pb.Reference_Fragment{Fragment: &d4pb.String{Value: id}}}
+}
+
+func uriRef(uri string) *d4pb.Reference {
+	return &d4pb.Reference{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: uri}}}
+}
+
+func TestCheckReferenceIntegrity(t *testing.T) {
+	specimen, err := anypb.New(&r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Specimen{Specimen: &r4specimenpb.Specimen{
+			Id:     &d4pb.Id{Value: "s1"},
+			Parent: []*d4pb.Reference{uriRef("#"), fragmentRef("gone")},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	obs := &r4observationpb.Observation{
+		Contained: []*anypb.Any{specimen},
+		Specimen:  fragmentRef("s1"),
+		Subject:   uriRef("#missing"),
+		Performer: []*d4pb.Reference{patientRef("p1"), uriRef("#s1")},
+	}
+
+	got := CheckReferenceIntegrity(obs)
+	want := []error{
+		validation.Violation{
+			Path:    "Observation.contained[0].parent[1]",
+			Message: "reference #gone does not match a contained resource",
+		},
+		validation.Violation{
+			Path:    "Observation.subject",
+			Message: "reference #missing does not match a contained resource",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CheckReferenceIntegrity() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckReferenceIntegrity_Valid(t *testing.T) {
+	obs := &r4pb.ContainedResource{
+		OneofResource: &r4pb.ContainedResource_Observation{Observation: &r4observationpb.Observation{
+			Subject: patientRef("p1"),
+		}},
+	}
+	if errs := CheckReferenceIntegrity(obs); len(errs) != 0 {
+		t.Errorf("CheckReferenceIntegrity() = %v, want no errors", errs)
+	}
+}
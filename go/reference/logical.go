@@ -0,0 +1,86 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reference
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// ToLogical returns a copy of ref that refers to its target by identifier
+// rather than by literal reference. The literal reference is dropped, as it
+// is only meaningful on the server that issued it; the resource type it
+// named is kept in the type element unless ref already sets one. ref is not
+// modified.
+func ToLogical(ref *d4pb.Reference, identifier *d4pb.Identifier) *d4pb.Reference {
+	out := &d4pb.Reference{}
+	if ref != nil {
+		out = proto.Clone(ref).(*d4pb.Reference)
+	}
+	if out.GetType() == nil {
+		if typ := literalType(out); typ != "" {
+			out.Type = &d4pb.Uri{Value: typ}
+		}
+	}
+	out.Reference = nil
+	out.Identifier = proto.Clone(identifier).(*d4pb.Identifier)
+	return out
+}
+
+// ToLiteral sets the literal reference of ref by resolving its identifier
+// with lookup, which returns the relative "Type/id" reference of the
+// identified resource. The identifier is kept, as it remains valid across
+// servers. It is an error for ref to have no identifier, for lookup to fail
+// or return anything other than a relative reference, or for the resolved
+// resource type to differ from the type element of ref.
+func ToLiteral(ref *d4pb.Reference, lookup func(*d4pb.Identifier) (string, error)) error {
+	if ref.GetIdentifier() == nil {
+		return errors.New("reference has no identifier")
+	}
+	literal, err := lookup(ref.GetIdentifier())
+	if err != nil {
+		return fmt.Errorf("resolving identifier: %w", err)
+	}
+	resolved := &d4pb.Reference{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: literal}}}
+	if err := jsonformat.NormalizeReference(resolved); err != nil {
+		return fmt.Errorf("resolved reference %q: %w", literal, err)
+	}
+	typ := literalType(resolved)
+	if typ == "" {
+		return fmt.Errorf("resolved reference %q is not of the form Type/id", literal)
+	}
+	if want := ref.GetType().GetValue(); want != "" && want != typ {
+		return fmt.Errorf("resolved reference %q does not match reference type %s", literal, want)
+	}
+	ref.Reference = resolved.Reference
+	return nil
+}
+
+// literalType returns the resource type named by the typed id set in ref, or
+// "" if ref has no typed id.
+func literalType(ref *d4pb.Reference) string {
+	rm := ref.ProtoReflect()
+	f := rm.WhichOneof(rm.Descriptor().Oneofs().ByName("reference"))
+	if f == nil {
+		return ""
+	}
+	return proto.GetExtension(f.Options(), apb.E_ReferencedFhirType).(string)
+}
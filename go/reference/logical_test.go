@@ -0,0 +1,111 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reference
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+var mrn = &d4pb.Identifier{
+	System: &d4pb.Uri{Value: "http://hospital.example/mrn"},
+	Value:  &d4pb.String{Value: "12345"},
+}
+
+func TestToLogical(t *testing.T) {
+	ref := patientRef("p1")
+	ref.Display = &d4pb.String{Value: "Jane Doe"}
+	orig := proto.Clone(ref)
+
+	got := ToLogical(ref, mrn)
+	want := &d4pb.Reference{
+		Type:       &d4pb.Uri{Value: "Patient"},
+		Identifier: mrn,
+		Display:    &d4pb.String{Value: "Jane Doe"},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("ToLogical() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if !proto.Equal(orig, ref) {
+		t.Errorf("ToLogical() modified its input")
+	}
+}
+
+func TestToLiteral(t *testing.T) {
+	lookup := func(id *d4pb.Identifier) (string, error) {
+		if id.GetValue().GetValue() == "12345" {
+			return "Patient/p1", nil
+		}
+		return "", errors.New("not found")
+	}
+	ref := &d4pb.Reference{Type: &d4pb.Uri{Value: "Patient"}, Identifier: mrn}
+	if err := ToLiteral(ref, lookup); err != nil {
+		t.Fatalf("ToLiteral() failed: %v", err)
+	}
+	want := &d4pb.Reference{
+		Type:       &d4pb.Uri{Value: "Patient"},
+		Identifier: mrn,
+		Reference:  &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "p1"}},
+	}
+	if diff := cmp.Diff(want, ref, protocmp.Transform()); diff != "" {
+		t.Errorf("ToLiteral() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestToLiteral_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		ref    *d4pb.Reference
+		lookup func(*d4pb.Identifier) (string, error)
+	}{
+		{
+			name:   "no identifier",
+			ref:    patientRef("p1"),
+			lookup: func(*d4pb.Identifier) (string, error) { return "Patient/p1", nil },
+		},
+		{
+			name:   "lookup fails",
+			ref:    &d4pb.Reference{Identifier: mrn},
+			lookup: func(*d4pb.Identifier) (string, error) { return "", errors.New("not found") },
+		},
+		{
+			name:   "not a relative reference",
+			ref:    &d4pb.Reference{Identifier: mrn},
+			lookup: func(*d4pb.Identifier) (string, error) { return "http://other.example/fhir/Patient/p1", nil },
+		},
+		{
+			name:   "type mismatch",
+			ref:    &d4pb.Reference{Type: &d4pb.Uri{Value: "Practitioner"}, Identifier: mrn},
+			lookup: func(*d4pb.Identifier) (string, error) { return "Patient/p1", nil },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			orig := proto.Clone(test.ref)
+			if err := ToLiteral(test.ref, test.lookup); err == nil {
+				t.Errorf("ToLiteral() succeeded, want error")
+			}
+			if !proto.Equal(orig, test.ref) {
+				t.Errorf("ToLiteral() modified the reference on error")
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reference
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// TargetReferences returns a typed reference to each of resources, which are
+// R4 resources or ContainedResources wrapping them, for use as the targets of
+// a Provenance. As the specification recommends for Provenance.target, a
+// reference is version specific when the resource has a meta.versionId. It
+// is an error for a resource to have no id.
+func TargetReferences(resources []proto.Message) ([]*d4pb.Reference, error) {
+	refs := make([]*d4pb.Reference, 0, len(resources))
+	for i, r := range resources {
+		rm, ok := element.ResourceOf(r)
+		if !ok {
+			return nil, fmt.Errorf("resource %d: not a resource", i)
+		}
+		if !strings.HasPrefix(string(rm.Descriptor().ParentFile().Package()), "google.fhir.r4.") {
+			return nil, fmt.Errorf("resource %d: %v is not an R4 resource", i, rm.Descriptor().FullName())
+		}
+		typ := string(rm.Descriptor().Name())
+		id := element.PrimitiveString(rm, "id")
+		if id == "" {
+			return nil, fmt.Errorf("resource %d: %s has no id", i, typ)
+		}
+		uri := typ + "/" + id
+		if f := rm.Descriptor().Fields().ByName("meta"); f != nil && rm.Has(f) {
+			if version := element.PrimitiveString(rm.Get(f).Message(), "version_id"); version != "" {
+				uri += "/_history/" + version
+			}
+		}
+		ref := &d4pb.Reference{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: uri}}}
+		if err := jsonformat.NormalizeReference(ref); err != nil {
+			return nil, fmt.Errorf("resource %d: %w", i, err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
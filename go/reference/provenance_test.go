@@ -0,0 +1,77 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reference
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4organizationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/organization_go_proto"
+	d3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	r3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+func TestTargetReferences(t *testing.T) {
+	resources := []proto.Message{
+		&r4observationpb.Observation{Id: &d4pb.Id{Value: "obs1"}},
+		&r4organizationpb.Organization{
+			Id:   &d4pb.Id{Value: "org1"},
+			Meta: &d4pb.Meta{VersionId: &d4pb.Id{Value: "3"}},
+		},
+		&r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Observation{
+			Observation: &r4observationpb.Observation{Id: &d4pb.Id{Value: "obs2"}},
+		}},
+	}
+	got, err := TargetReferences(resources)
+	if err != nil {
+		t.Fatalf("TargetReferences() failed: %v", err)
+	}
+	want := []*d4pb.Reference{
+		{Reference: &d4pb.Reference_ObservationId{ObservationId: &d4pb.ReferenceId{Value: "obs1"}}},
+		{Reference: &d4pb.Reference_OrganizationId{OrganizationId: &d4pb.ReferenceId{
+			Value:   "org1",
+			History: &d4pb.Id{Value: "3"},
+		}}},
+		{Reference: &d4pb.Reference_ObservationId{ObservationId: &d4pb.ReferenceId{Value: "obs2"}}},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("TargetReferences() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestTargetReferences_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource proto.Message
+	}{
+		{"no id", &r4observationpb.Observation{}},
+		{"empty ContainedResource", &r4pb.ContainedResource{}},
+		{"STU3 resource", &r3pb.Patient{Id: &d3pb.Id{Value: "p1"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resources := []proto.Message{&r4observationpb.Observation{Id: &d4pb.Id{Value: "ok"}}, test.resource}
+			if got, err := TargetReferences(resources); err == nil {
+				t.Errorf("TargetReferences() = %v, want error", got)
+			}
+		})
+	}
+}
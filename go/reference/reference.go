@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package reference provides helpers for locating, converting and checking
+// references in FHIR R4 resources.
+package reference
+
+import (
+	"strings"
+
+	"github.com/google/fhir/go/compartment"
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// PatientReference returns the reference linking an R4 resource, or a
+// ContainedResource wrapping one, to its patient. The Patient compartment
+// elements of the resource type, such as Observation.subject or
+// Coverage.beneficiary, are checked in turn and the first populated reference
+// to a Patient is returned. It returns false when the resource type is not in
+// the Patient compartment or no such reference is set.
+func PatientReference(msg proto.Message) (*d4pb.Reference, bool) {
+	rm, ok := element.ResourceOf(msg)
+	if !ok {
+		return nil, false
+	}
+	for _, path := range compartment.Elements("Patient", string(rm.Descriptor().Name())) {
+		if ref, ok := findPatientReference(rm, strings.Split(path, ".")); ok {
+			return ref, true
+		}
+	}
+	return nil, false
+}
+
+// findPatientReference returns the first reference to a Patient found by
+// following path, a sequence of FHIR element names, from m.
+func findPatientReference(m protoreflect.Message, path []string) (*d4pb.Reference, bool) {
+	f := m.Descriptor().Fields().ByJSONName(path[0])
+	if f == nil || f.Message() == nil || !m.Has(f) {
+		return nil, false
+	}
+	var values []protoreflect.Message
+	if f.IsList() {
+		l := m.Get(f).List()
+		for i := 0; i < l.Len(); i++ {
+			values = append(values, l.Get(i).Message())
+		}
+	} else {
+		values = append(values, m.Get(f).Message())
+	}
+	for _, v := range values {
+		if len(path) > 1 {
+			if ref, ok := findPatientReference(v, path[1:]); ok {
+				return ref, true
+			}
+			continue
+		}
+		if ref, ok := v.Interface().(*d4pb.Reference); ok && isPatientReference(ref) {
+			return ref, true
+		}
+	}
+	return nil, false
+}
+
+// isPatientReference reports whether ref points to a Patient, either through
+// a typed id, a relative "Patient/..." URI, or its type element.
+func isPatientReference(ref *d4pb.Reference) bool {
+	if ref.GetType().GetValue() == "Patient" {
+		return true
+	}
+	norm := proto.Clone(ref).(*d4pb.Reference)
+	if err := jsonformat.NormalizeReference(norm); err != nil {
+		return false
+	}
+	return norm.GetPatientId() != nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reference
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4coveragepb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/coverage_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4organizationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/organization_go_proto"
+)
+
+func patientRef(id string) *d4pb.Reference {
+	return &d4pb.Reference{
+		Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: id}},
+	}
+}
+
+func TestPatientReference(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    proto.Message
+		want   *d4pb.Reference
+		wantOK bool
+	}{
+		{
+			name:   "observation subject",
+			msg:    &r4observationpb.Observation{Subject: patientRef("p1")},
+			want:   patientRef("p1"),
+			wantOK: true,
+		},
+		{
+			name: "observation subject as uri",
+			msg: &r4observationpb.Observation{
+				Subject: &d4pb.Reference{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: "Patient/p2"}}},
+			},
+			want:   &d4pb.Reference{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: "Patient/p2"}}},
+			wantOK: true,
+		},
+		{
+			name: "observation performer skips practitioner",
+			msg: &r4observationpb.Observation{
+				Performer: []*d4pb.Reference{
+					{Reference: &d4pb.Reference_PractitionerId{PractitionerId: &d4pb.ReferenceId{Value: "dr1"}}},
+					patientRef("p3"),
+				},
+			},
+			want:   patientRef("p3"),
+			wantOK: true,
+		},
+		{
+			name:   "coverage beneficiary",
+			msg:    &r4coveragepb.Coverage{Beneficiary: patientRef("p4")},
+			want:   patientRef("p4"),
+			wantOK: true,
+		},
+		{
+			name: "contained coverage",
+			msg: &r4pb.ContainedResource{
+				OneofResource: &r4pb.ContainedResource_Coverage{
+					Coverage: &r4coveragepb.Coverage{Beneficiary: patientRef("p5")},
+				},
+			},
+			want:   patientRef("p5"),
+			wantOK: true,
+		},
+		{
+			name: "no patient reference",
+			msg:  &r4observationpb.Observation{},
+		},
+		{
+			name: "resource outside compartment",
+			msg:  &r4organizationpb.Organization{},
+		},
+		{
+			name: "nil message",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := PatientReference(test.msg)
+			if ok != test.wantOK || !proto.Equal(got, test.want) {
+				t.Errorf("PatientReference() = %v, %v; want %v, %v", got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
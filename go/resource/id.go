@@ -0,0 +1,105 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/google/fhir/go/internal/walk"
+	"github.com/google/fhir/go/jsonformat"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// idPattern matches the FHIR id datatype.
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9\-.]{1,64}$`)
+
+// SetID sets the id of the resource msg, or of the resource held by a
+// ContainedResource, to id, which must be a valid FHIR id. If fixupReferences
+// is true, R4 references within msg to the resource under its previous id,
+// such as a Provenance targeting itself, are rewritten to the new id, keeping
+// their form: typed references such as patientId get the new id, and
+// "Type/id" URIs, including those with a "/_history/" version, are
+// rewritten.
+func SetID(msg proto.Message, id string, fixupReferences bool) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("invalid id %q", id)
+	}
+	rm := msg.ProtoReflect()
+	if oneof := rm.Descriptor().Oneofs().ByName("oneof_resource"); oneof != nil {
+		f := rm.WhichOneof(oneof)
+		if f == nil {
+			return fmt.Errorf("empty %v", rm.Descriptor().FullName())
+		}
+		rm = rm.Mutable(f).Message()
+	}
+	f := rm.Descriptor().Fields().ByName("id")
+	if f == nil || f.Message() == nil {
+		return fmt.Errorf("%v has no id", rm.Descriptor().FullName())
+	}
+	idm := rm.NewField(f).Message()
+	vf := idm.Descriptor().Fields().ByName("value")
+	if vf == nil || vf.Kind() != protoreflect.StringKind {
+		return fmt.Errorf("unsupported id type %v", idm.Descriptor().FullName())
+	}
+	var oldID string
+	if rm.Has(f) {
+		oldID = rm.Get(f).Message().Get(vf).String()
+	}
+	idm.Set(vf, protoreflect.ValueOfString(id))
+	rm.Set(f, protoreflect.ValueOfMessage(idm))
+	if !fixupReferences || oldID == "" || oldID == id {
+		return nil
+	}
+	typ := string(rm.Descriptor().Name())
+	return walk.Walk(rm.Interface(), func(_ string, m protoreflect.Message) error {
+		ref, ok := m.Interface().(*d4pb.Reference)
+		if !ok {
+			return nil
+		}
+		return retarget(ref, typ, oldID, id)
+	})
+}
+
+// retarget points ref at typ/newID if it points at typ/oldID.
+func retarget(ref *d4pb.Reference, typ, oldID, newID string) error {
+	norm := proto.Clone(ref).(*d4pb.Reference)
+	if err := jsonformat.NormalizeReference(norm); err != nil {
+		// Not a reference to a resource by type and id.
+		return nil
+	}
+	rm := norm.ProtoReflect()
+	f := rm.WhichOneof(rm.Descriptor().Oneofs().ByName("reference"))
+	if f == nil || proto.GetExtension(f.Options(), apb.E_ReferencedFhirType).(string) != typ {
+		return nil
+	}
+	refID, ok := rm.Get(f).Message().Interface().(*d4pb.ReferenceId)
+	if !ok || refID.GetValue() != oldID {
+		return nil
+	}
+	refID.Value = newID
+	if ref.GetUri() != nil {
+		if err := jsonformat.DenormalizeReference(norm); err != nil {
+			return err
+		}
+	}
+	proto.Reset(ref)
+	proto.Merge(ref, norm)
+	return nil
+}
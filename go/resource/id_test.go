@@ -0,0 +1,106 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"testing"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	r4provenancepb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/provenance_go_proto"
+)
+
+func TestSetID(t *testing.T) {
+	p := &r4patientpb.Patient{}
+	if err := SetID(p, "new", true); err != nil {
+		t.Fatalf("SetID(new patient) failed: %v", err)
+	}
+	if got := p.GetId().GetValue(); got != "new" {
+		t.Errorf("id = %q, want new", got)
+	}
+
+	cr := &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: p}}
+	if err := SetID(cr, "other", false); err != nil {
+		t.Fatalf("SetID(ContainedResource) failed: %v", err)
+	}
+	if got := p.GetId().GetValue(); got != "other" {
+		t.Errorf("id = %q, want other", got)
+	}
+}
+
+func TestSetID_FixupReferences(t *testing.T) {
+	newProvenance := func() *r4provenancepb.Provenance {
+		return &r4provenancepb.Provenance{
+			Id: &d4pb.Id{Value: "old"},
+			Target: []*d4pb.Reference{
+				{Reference: &d4pb.Reference_ProvenanceId{ProvenanceId: &d4pb.ReferenceId{Value: "old"}}},
+				{Reference: &d4pb.Reference_Uri{Uri: &d4pb.String{Value: "Provenance/old"}}},
+				{Reference: &d4pb.Reference_ProvenanceId{ProvenanceId: &d4pb.ReferenceId{Value: "unrelated"}}},
+				{Reference: &d4pb.Reference_PatientId{PatientId: &d4pb.ReferenceId{Value: "old"}}},
+			},
+		}
+	}
+
+	p := newProvenance()
+	if err := SetID(p, "new", true); err != nil {
+		t.Fatalf("SetID() failed: %v", err)
+	}
+	if got := p.GetId().GetValue(); got != "new" {
+		t.Errorf("id = %q, want new", got)
+	}
+	targets := p.GetTarget()
+	if got := targets[0].GetProvenanceId().GetValue(); got != "new" {
+		t.Errorf("self reference = %q, want new", got)
+	}
+	if got := targets[1].GetUri().GetValue(); got != "Provenance/new" {
+		t.Errorf("self reference uri = %q, want Provenance/new", got)
+	}
+	if got := targets[2].GetProvenanceId().GetValue(); got != "unrelated" {
+		t.Errorf("unrelated reference = %q, want unrelated", got)
+	}
+	if got := targets[3].GetPatientId().GetValue(); got != "old" {
+		t.Errorf("patient reference = %q, want old", got)
+	}
+
+	p = newProvenance()
+	if err := SetID(p, "new", false); err != nil {
+		t.Fatalf("SetID() failed: %v", err)
+	}
+	if got := p.GetTarget()[0].GetProvenanceId().GetValue(); got != "old" {
+		t.Errorf("self reference without fixup = %q, want old", got)
+	}
+}
+
+func TestSetID_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{"empty", ""},
+		{"invalid character", "a/b"},
+		{"too long", "0123456789012345678901234567890123456789012345678901234567890123456789"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := SetID(&r4patientpb.Patient{}, test.id, true); err == nil {
+				t.Errorf("SetID(%q) succeeded, want error", test.id)
+			}
+		})
+	}
+	if err := SetID(&r4pb.ContainedResource{}, "a", true); err == nil {
+		t.Errorf("SetID(empty ContainedResource) succeeded, want error")
+	}
+}
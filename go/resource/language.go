@@ -0,0 +1,94 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"fmt"
+	"regexp"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// languageTag matches the BCP-47 (RFC 5646) language tag syntax: a language
+// with optional extended language subtags, then optional script, region,
+// variant, extension and private use subtags, or a private use tag alone.
+// The primary language must be a two or three letter ISO 639 code; the
+// reserved four letter and registered five to eight letter forms are not
+// used by any language in the IANA registry, so accepting them would let
+// words such as "english" through.
+var languageTag = regexp.MustCompile(`(?i)^(?:` +
+	`[a-z]{2,3}(?:-[a-z]{3}){0,3}` + // language and extlang
+	`(?:-[a-z]{4})?` + // script
+	`(?:-(?:[a-z]{2}|[0-9]{3}))?` + // region
+	`(?:-(?:[a-z0-9]{5,8}|[0-9][a-z0-9]{3}))*` + // variants
+	`(?:-[0-9a-wyz](?:-[a-z0-9]{2,8})+)*` + // extensions
+	`(?:-x(?:-[a-z0-9]{1,8})+)?` + // private use
+	`|x(?:-[a-z0-9]{1,8})+` +
+	`)$`)
+
+// SetLanguage sets the language of the resource msg, or of the resource held
+// by a ContainedResource, to tag, which must be a well-formed BCP-47 language
+// tag such as "en-US".
+func SetLanguage(msg proto.Message, tag string) error {
+	if !languageTag.MatchString(tag) {
+		return fmt.Errorf("invalid BCP-47 language tag %q", tag)
+	}
+	rm, f, err := languageField(msg)
+	if err != nil {
+		return err
+	}
+	code := rm.NewField(f).Message()
+	vf := code.Descriptor().Fields().ByName("value")
+	if vf == nil || vf.Kind() != protoreflect.StringKind {
+		return fmt.Errorf("unsupported language type %v", code.Descriptor().FullName())
+	}
+	code.Set(vf, protoreflect.ValueOfString(tag))
+	rm.Set(f, protoreflect.ValueOfMessage(code))
+	return nil
+}
+
+// Language returns the language of the resource msg, or of the resource held
+// by a ContainedResource, or false if it has none.
+func Language(msg proto.Message) (string, bool) {
+	rm, f, err := languageField(msg)
+	if err != nil || !rm.Has(f) {
+		return "", false
+	}
+	code := rm.Get(f).Message()
+	vf := code.Descriptor().Fields().ByName("value")
+	if vf == nil || vf.Kind() != protoreflect.StringKind {
+		return "", false
+	}
+	return code.Get(vf).String(), true
+}
+
+// languageField returns the resource held by msg, unwrapping a
+// ContainedResource, and its language field.
+func languageField(msg proto.Message) (protoreflect.Message, protoreflect.FieldDescriptor, error) {
+	rm := msg.ProtoReflect()
+	if oneof := rm.Descriptor().Oneofs().ByName("oneof_resource"); oneof != nil {
+		f := rm.WhichOneof(oneof)
+		if f == nil {
+			return nil, nil, fmt.Errorf("empty %v", rm.Descriptor().FullName())
+		}
+		rm = rm.Mutable(f).Message()
+	}
+	f := rm.Descriptor().Fields().ByName("language")
+	if f == nil || f.Message() == nil || f.IsList() {
+		return nil, nil, fmt.Errorf("%v has no language", rm.Descriptor().FullName())
+	}
+	return rm, f, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func TestSetLanguage(t *testing.T) {
+	p := &r4patientpb.Patient{}
+	if got, ok := Language(p); ok {
+		t.Errorf("Language() of new patient = %q, want none", got)
+	}
+	if err := SetLanguage(p, "en-US"); err != nil {
+		t.Fatalf("SetLanguage(en-US) failed: %v", err)
+	}
+	if got := p.GetLanguage().GetValue(); got != "en-US" {
+		t.Errorf("language = %q, want en-US", got)
+	}
+	if got, ok := Language(p); !ok || got != "en-US" {
+		t.Errorf("Language() = %q, %v; want en-US, true", got, ok)
+	}
+
+	cr := &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: p}}
+	if err := SetLanguage(cr, "zh-Hant-TW"); err != nil {
+		t.Fatalf("SetLanguage(ContainedResource) failed: %v", err)
+	}
+	if got, ok := Language(cr); !ok || got != "zh-Hant-TW" {
+		t.Errorf("Language(ContainedResource) = %q, %v; want zh-Hant-TW, true", got, ok)
+	}
+}
+
+func TestSetLanguage_ValidTags(t *testing.T) {
+	for _, tag := range []string{"en", "fr-CA", "es-419", "sr-Latn-RS", "de-CH-1996", "zh-cmn-Hans-CN", "en-US-u-ca-gregory", "en-x-private", "x-klingon"} {
+		if err := SetLanguage(&r4patientpb.Patient{}, tag); err != nil {
+			t.Errorf("SetLanguage(%q) failed: %v", tag, err)
+		}
+	}
+}
+
+func TestSetLanguage_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+		tag  string
+	}{
+		{name: "word", msg: &r4patientpb.Patient{}, tag: "english"},
+		{name: "empty", msg: &r4patientpb.Patient{}, tag: ""},
+		{name: "underscore", msg: &r4patientpb.Patient{}, tag: "en_US"},
+		{name: "trailing hyphen", msg: &r4patientpb.Patient{}, tag: "en-"},
+		{name: "long region", msg: &r4patientpb.Patient{}, tag: "en-USA1"},
+		{name: "not a resource", msg: &d4pb.HumanName{}, tag: "en"},
+		{name: "empty ContainedResource", msg: &r4pb.ContainedResource{}, tag: "en"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := SetLanguage(test.msg, test.tag); err == nil {
+				t.Errorf("SetLanguage(%q) succeeded, want error", test.tag)
+			}
+		})
+	}
+	p := &r4patientpb.Patient{Language: &d4pb.Code{Value: "en"}}
+	if err := SetLanguage(p, "english"); err == nil || p.GetLanguage().GetValue() != "en" {
+		t.Errorf("SetLanguage(english) = %v and set language %q, want error and unchanged", err, p.GetLanguage().GetValue())
+	}
+}
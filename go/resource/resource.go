@@ -0,0 +1,86 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resource provides generic helpers for FHIR resources.
+package resource
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+
+	"github.com/google/fhir/go/internal/element"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DeterministicID returns an id derived from the content of the resource msg,
+// or a ContainedResource wrapping one, formatted as a version 5 UUID. The
+// resource id and meta are not part of the content, so equivalent resources
+// get the same id however they were previously identified or versioned.
+// namespace separates ids generated for different purposes. An error is
+// returned if msg holds no resource or cannot be marshaled, such as when a
+// string holds invalid UTF-8.
+func DeterministicID(msg proto.Message, namespace string) (string, error) {
+	content, ok := withoutFields(msg, "id", "meta")
+	if !ok {
+		return "", errors.New("nil or empty resource")
+	}
+	// Deterministic marshaling is stable for a given binary, which is what
+	// fixtures and idempotent loads need.
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(content.Interface())
+	if err != nil {
+		return "", fmt.Errorf("marshaling %v: %w", content.Descriptor().FullName(), err)
+	}
+	h := sha1.New()
+	h.Write([]byte(namespace))
+	h.Write([]byte{0})
+	h.Write([]byte(content.Descriptor().FullName()))
+	h.Write([]byte{0})
+	h.Write(b)
+	sum := h.Sum(nil)
+	sum[6] = sum[6]&0x0f | 0x50 // Version 5.
+	sum[8] = sum[8]&0x3f | 0x80 // RFC 4122 variant.
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16]), nil
+}
+
+// Equal reports whether a and b, resources or ContainedResources wrapping
+// them, have the same content. Their meta is ignored, as servers rewrite it
+// on every write without the resource changing. Messages that hold no
+// resource are compared as they are.
+func Equal(a, b proto.Message) bool {
+	ac, aok := withoutFields(a, "meta")
+	bc, bok := withoutFields(b, "meta")
+	if !aok || !bok {
+		return proto.Equal(a, b)
+	}
+	return proto.Equal(ac.Interface(), bc.Interface())
+}
+
+// withoutFields returns a copy of the resource held by msg, unwrapping a
+// ContainedResource, with the given fields cleared. It returns false if msg
+// holds no resource.
+func withoutFields(msg proto.Message, names ...protoreflect.Name) (protoreflect.Message, bool) {
+	rm, ok := element.ResourceOf(msg)
+	if !ok {
+		return nil, false
+	}
+	content := proto.Clone(rm.Interface()).ProtoReflect()
+	for _, name := range names {
+		if f := content.Descriptor().Fields().ByName(name); f != nil {
+			content.Clear(f)
+		}
+	}
+	return content, true
+}
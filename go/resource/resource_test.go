@@ -0,0 +1,146 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"regexp"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+var uuidV5 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func patient(id, family string) *r4patientpb.Patient {
+	p := &r4patientpb.Patient{
+		Name: []*d4pb.HumanName{{Family: &d4pb.String{Value: family}}},
+	}
+	if id != "" {
+		p.Id = &d4pb.Id{Value: id}
+		p.Meta = &d4pb.Meta{VersionId: &d4pb.Id{Value: "1"}}
+	}
+	return p
+}
+
+func deterministicID(t *testing.T, msg proto.Message, namespace string) string {
+	t.Helper()
+	id, err := DeterministicID(msg, namespace)
+	if err != nil {
+		t.Fatalf("DeterministicID() failed: %v", err)
+	}
+	return id
+}
+
+func TestDeterministicID(t *testing.T) {
+	id := deterministicID(t, patient("", "Doe"), "fixtures")
+	if !uuidV5.MatchString(id) {
+		t.Errorf("DeterministicID() = %q, want a version 5 UUID", id)
+	}
+
+	same := []struct {
+		name string
+		id   string
+	}{
+		{"repeated call", deterministicID(t, patient("", "Doe"), "fixtures")},
+		{"id and meta ignored", deterministicID(t, patient("p1", "Doe"), "fixtures")},
+		{"contained resource", deterministicID(t, &r4pb.ContainedResource{
+			OneofResource: &r4pb.ContainedResource_Patient{Patient: patient("", "Doe")},
+		}, "fixtures")},
+	}
+	for _, s := range same {
+		if s.id != id {
+			t.Errorf("DeterministicID() for %s = %q, want %q", s.name, s.id, id)
+		}
+	}
+
+	different := []struct {
+		name string
+		id   string
+	}{
+		{"different content", deterministicID(t, patient("", "Roe"), "fixtures")},
+		{"different namespace", deterministicID(t, patient("", "Doe"), "loads")},
+	}
+	for _, d := range different {
+		if d.id == id {
+			t.Errorf("DeterministicID() for %s = %q, want a different id", d.name, d.id)
+		}
+	}
+}
+
+func TestDeterministicID_DoesNotModifyInput(t *testing.T) {
+	p := patient("p1", "Doe")
+	deterministicID(t, p, "fixtures")
+	if p.GetId().GetValue() != "p1" || p.GetMeta() == nil {
+		t.Errorf("DeterministicID() modified the input resource: %v", p)
+	}
+}
+
+func TestDeterministicID_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+	}{
+		{"nil", nil},
+		{"empty contained resource", &r4pb.ContainedResource{}},
+		{"invalid UTF-8", patient("", "\xff")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if id, err := DeterministicID(test.msg, "fixtures"); err == nil {
+				t.Errorf("DeterministicID() = %q, want error", id)
+			}
+		})
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *r4patientpb.Patient
+		want bool
+	}{
+		{
+			name: "meta ignored",
+			a:    patient("p1", "Doe"),
+			b:    &r4patientpb.Patient{Id: &d4pb.Id{Value: "p1"}, Name: []*d4pb.HumanName{{Family: &d4pb.String{Value: "Doe"}}}},
+			want: true,
+		},
+		{
+			name: "different content",
+			a:    patient("p1", "Doe"),
+			b:    patient("p1", "Roe"),
+		},
+		{
+			name: "different id",
+			a:    patient("p1", "Doe"),
+			b:    patient("p2", "Doe"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Equal(test.a, test.b); got != test.want {
+				t.Errorf("Equal() = %v, want %v", got, test.want)
+			}
+			wrapped := &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: test.b}}
+			if got := Equal(test.a, wrapped); got != test.want {
+				t.Errorf("Equal() with ContainedResource = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
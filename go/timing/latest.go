@@ -0,0 +1,81 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timing
+
+import (
+	"time"
+
+	"github.com/google/fhir/go/internal/fhirtime"
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+type latestOptions struct {
+	skipPartialDates bool
+}
+
+// A LatestOption configures LatestInstant.
+type LatestOption func(*latestOptions)
+
+// SkipPartialDates makes LatestInstant ignore dateTimes given only to the
+// day, month or year, which otherwise count as the end of the period they
+// cover.
+func SkipPartialDates() LatestOption {
+	return func(o *latestOptions) {
+		o.skipPartialDates = true
+	}
+}
+
+// LatestInstant returns the latest of the instant and dateTime values
+// anywhere in msg, an R4 resource or a ContainedResource holding one,
+// including its meta.lastUpdated, extensions and contained resources such as
+// an embedded Provenance. A dateTime counts as the last microsecond of the
+// period its precision covers in its time zone, so that "2023-03-15" is later
+// than "2023-03-15T10:00:00Z"; SkipPartialDates ignores those given only to
+// the day, month or year instead. The time is in the time zone recorded with
+// it. LatestInstant returns false if msg holds no such values.
+func LatestInstant(msg proto.Message, opts ...LatestOption) (time.Time, bool) {
+	var o latestOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var latest time.Time
+	found := false
+	consider := func(t time.Time) {
+		if !found || t.After(latest) {
+			latest, found = t, true
+		}
+	}
+	walk.Walk(msg, func(_ string, m protoreflect.Message) error {
+		switch v := m.Interface().(type) {
+		case *d4pb.Instant:
+			consider(toTime(v.GetValueUs(), v.GetTimezone()))
+		case *d4pb.DateTime:
+			switch v.GetPrecision() {
+			case d4pb.DateTime_YEAR, d4pb.DateTime_MONTH, d4pb.DateTime_DAY:
+				if o.skipPartialDates {
+					return nil
+				}
+			}
+			t := toTime(v.GetValueUs(), v.GetTimezone())
+			consider(fhirtime.End(t, v.GetPrecision().String()).Add(-time.Microsecond))
+		}
+		return nil
+	})
+	return latest, found
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timing
+
+import (
+	"testing"
+	"time"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	r4provenancepb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/provenance_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+func instant(t time.Time) *d4pb.Instant {
+	return &d4pb.Instant{ValueUs: t.UnixMicro(), Timezone: "Z", Precision: d4pb.Instant_SECOND}
+}
+
+func TestLatestInstant(t *testing.T) {
+	base := time.Date(2023, 3, 15, 10, 0, 0, 0, time.UTC)
+	recorded := base.Add(3 * time.Hour)
+	provenance, err := anypb.New(&r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Provenance{
+		Provenance: &r4provenancepb.Provenance{Recorded: instant(recorded)},
+	}})
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	obs := &r4observationpb.Observation{
+		Meta: &d4pb.Meta{LastUpdated: instant(base.Add(time.Hour))},
+		Effective: &r4observationpb.Observation_EffectiveX{
+			Choice: &r4observationpb.Observation_EffectiveX_DateTime{DateTime: &d4pb.DateTime{
+				ValueUs: base.UnixMicro(), Timezone: "Z", Precision: d4pb.DateTime_SECOND,
+			}},
+		},
+		Issued:    instant(base.Add(2 * time.Hour)),
+		Contained: []*anypb.Any{provenance},
+	}
+
+	got, ok := LatestInstant(obs)
+	if !ok || !got.Equal(recorded) {
+		t.Errorf("LatestInstant() = %v, %v; want %v, true", got, ok, recorded)
+	}
+
+	// A date-only value counts as the end of its day.
+	obs.Component = []*r4observationpb.Observation_Component{{
+		Value: &r4observationpb.Observation_Component_ValueX{
+			Choice: &r4observationpb.Observation_Component_ValueX_DateTime{DateTime: &d4pb.DateTime{
+				ValueUs:  time.Date(2023, 3, 15, 0, 0, 0, 0, time.FixedZone("+02:00", 2*60*60)).UnixMicro(),
+				Timezone: "+02:00", Precision: d4pb.DateTime_DAY,
+			}},
+		},
+	}}
+	endOfDay := time.Date(2023, 3, 15, 23, 59, 59, 999999000, time.FixedZone("+02:00", 2*60*60))
+	got, ok = LatestInstant(obs)
+	if !ok || !got.Equal(endOfDay) {
+		t.Errorf("LatestInstant() with a date = %v, %v; want %v, true", got, ok, endOfDay)
+	}
+	if _, offset := got.Zone(); offset != 2*60*60 {
+		t.Errorf("LatestInstant() offset = %d, want the date's +02:00", offset)
+	}
+	got, ok = LatestInstant(obs, SkipPartialDates())
+	if !ok || !got.Equal(recorded) {
+		t.Errorf("LatestInstant(SkipPartialDates) = %v, %v; want %v, true", got, ok, recorded)
+	}
+
+	cr := &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Observation{Observation: obs}}
+	if got, ok := LatestInstant(cr); !ok || !got.Equal(endOfDay) {
+		t.Errorf("LatestInstant(ContainedResource) = %v, %v; want %v, true", got, ok, endOfDay)
+	}
+}
+
+func TestLatestInstant_PartialPrecisions(t *testing.T) {
+	tests := []struct {
+		precision d4pb.DateTime_Precision
+		want      time.Time
+	}{
+		{d4pb.DateTime_YEAR, time.Date(2023, 12, 31, 23, 59, 59, 999999000, time.UTC)},
+		{d4pb.DateTime_MONTH, time.Date(2023, 1, 31, 23, 59, 59, 999999000, time.UTC)},
+		{d4pb.DateTime_DAY, time.Date(2023, 1, 1, 23, 59, 59, 999999000, time.UTC)},
+		{d4pb.DateTime_SECOND, time.Date(2023, 1, 1, 0, 0, 0, 999999000, time.UTC)},
+		{d4pb.DateTime_MILLISECOND, time.Date(2023, 1, 1, 0, 0, 0, 999000, time.UTC)},
+		{d4pb.DateTime_MICROSECOND, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		t.Run(test.precision.String(), func(t *testing.T) {
+			p := &r4patientpb.Patient{
+				Deceased: &r4patientpb.Patient_DeceasedX{
+					Choice: &r4patientpb.Patient_DeceasedX_DateTime{DateTime: &d4pb.DateTime{
+						ValueUs: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).UnixMicro(), Timezone: "UTC", Precision: test.precision,
+					}},
+				},
+			}
+			if got, ok := LatestInstant(p); !ok || !got.Equal(test.want) {
+				t.Errorf("LatestInstant() = %v, %v; want %v, true", got, ok, test.want)
+			}
+		})
+	}
+}
+
+func TestLatestInstant_None(t *testing.T) {
+	p := &r4patientpb.Patient{
+		// Dates are not dateTimes.
+		BirthDate: &d4pb.Date{ValueUs: 0, Timezone: "UTC", Precision: d4pb.Date_DAY},
+	}
+	if got, ok := LatestInstant(p); ok {
+		t.Errorf("LatestInstant() = %v, want none", got)
+	}
+	p.Deceased = &r4patientpb.Patient_DeceasedX{
+		Choice: &r4patientpb.Patient_DeceasedX_DateTime{DateTime: &d4pb.DateTime{Timezone: "UTC", Precision: d4pb.DateTime_YEAR}},
+	}
+	if got, ok := LatestInstant(p, SkipPartialDates()); ok {
+		t.Errorf("LatestInstant(SkipPartialDates) = %v, want none", got)
+	}
+}
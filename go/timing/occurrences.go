@@ -0,0 +1,222 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/fhir/go/internal/fhirtime"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	vspb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/valuesets_go_proto"
+)
+
+// unitDurations are the lengths of the units of time of fixed length.
+var unitDurations = map[vspb.UnitsOfTimeValueSet_Value]time.Duration{
+	vspb.UnitsOfTimeValueSet_S:   time.Second,
+	vspb.UnitsOfTimeValueSet_MIN: time.Minute,
+	vspb.UnitsOfTimeValueSet_H:   time.Hour,
+}
+
+// calendarUnits are the units of time added as calendar days, months and
+// years, so that occurrences keep their time of day across daylight saving
+// changes.
+var calendarUnits = map[vspb.UnitsOfTimeValueSet_Value]struct{ years, months, days int }{
+	vspb.UnitsOfTimeValueSet_D:  {days: 1},
+	vspb.UnitsOfTimeValueSet_WK: {days: 7},
+	vspb.UnitsOfTimeValueSet_MO: {months: 1},
+	vspb.UnitsOfTimeValueSet_A:  {years: 1},
+}
+
+// Occurrences returns the times in [from, to) at which t occurs, in
+// ascending order. These are its events and the occurrences of its repeat,
+// which happen frequency times, evenly spaced, in each period of period
+// periodUnit. Periods start at the start of boundsPeriod, or at from if it
+// has none, and occurrences after the end of boundsPeriod are dropped; count
+// limits the occurrences counted from the start of the bounds. Occurrences of
+// the repeat are in the time zone of the bounds start, and events in their own
+// time zone.
+//
+// Only repeats given by frequency, period, periodUnit, boundsPeriod and count
+// are supported; Occurrences returns an error for other repeat elements, such
+// as dayOfWeek or when, and for a Timing that has only a code. Periods in
+// months or years must be whole.
+func Occurrences(t *d4pb.Timing, from, to time.Time) ([]time.Time, error) {
+	if t == nil {
+		return nil, errors.New("missing timing")
+	}
+	var out []time.Time
+	for _, e := range t.GetEvent() {
+		et := toTime(e.GetValueUs(), e.GetTimezone())
+		if !et.Before(from) && et.Before(to) {
+			out = append(out, et)
+		}
+	}
+	r := t.GetRepeat()
+	if r == nil {
+		if len(t.GetEvent()) == 0 && t.GetCode() != nil {
+			return nil, errors.New("timing with only a code is not supported")
+		}
+		sortTimes(out)
+		return out, nil
+	}
+	repeated, err := expandRepeat(r, from, to)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, repeated...)
+	sortTimes(out)
+	return out, nil
+}
+
+// expandRepeat returns the occurrences of r in [from, to).
+func expandRepeat(r *d4pb.Timing_Repeat, from, to time.Time) ([]time.Time, error) {
+	switch {
+	case r.GetFrequencyMax() != nil, r.GetPeriodMax() != nil:
+		return nil, errors.New("frequencyMax and periodMax are not supported")
+	case r.GetCountMax() != nil:
+		return nil, errors.New("countMax is not supported")
+	case len(r.GetDayOfWeek()) > 0, len(r.GetTimeOfDay()) > 0, len(r.GetWhen()) > 0, r.GetOffset() != nil:
+		return nil, errors.New("dayOfWeek, timeOfDay, when and offset are not supported")
+	case r.GetBounds() != nil && r.GetBounds().GetPeriod() == nil:
+		return nil, errors.New("only boundsPeriod bounds are supported")
+	case r.GetPeriod() == nil || r.GetPeriodUnit() == nil:
+		return nil, errors.New("repeat must have a period and periodUnit")
+	}
+	frequency := 1
+	if f := r.GetFrequency(); f != nil {
+		frequency = int(f.GetValue())
+	}
+	if frequency < 1 {
+		return nil, fmt.Errorf("invalid frequency %d", frequency)
+	}
+	periodStart, err := periodStarts(r.GetPeriod().GetValue(), r.GetPeriodUnit().GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	anchor, end := from, to
+	if bounds := r.GetBounds().GetPeriod(); bounds != nil {
+		if s := bounds.GetStart(); s != nil {
+			anchor = toTime(s.GetValueUs(), s.GetTimezone())
+		}
+		if e := bounds.GetEnd(); e != nil {
+			boundsEnd := endOf(e)
+			if boundsEnd.Before(end) {
+				end = boundsEnd
+			}
+		}
+	}
+	count := -1
+	if c := r.GetCount(); c != nil {
+		count = int(c.GetValue())
+	}
+
+	// Skip the periods that end before the window, counting their occurrences
+	// toward count, rather than stepping through them from a distant anchor.
+	first := firstPeriod(periodStart, anchor, from)
+	var out []time.Time
+	n := first * frequency
+	for k := first; ; k++ {
+		start, next := periodStart(anchor, k), periodStart(anchor, k+1)
+		if !start.Before(end) {
+			break
+		}
+		step := next.Sub(start) / time.Duration(frequency)
+		for i := 0; i < frequency; i++ {
+			if count >= 0 && n >= count {
+				return out, nil
+			}
+			n++
+			occ := start.Add(time.Duration(i) * step)
+			if !occ.Before(end) {
+				return out, nil
+			}
+			if !occ.Before(from) {
+				out = append(out, occ)
+			}
+		}
+	}
+	return out, nil
+}
+
+// firstPeriod returns the index of the first period after anchor that ends
+// after from, or 0 if from is not after anchor. The index is estimated from
+// the length of the first period and then corrected, since calendar periods
+// vary in length.
+func firstPeriod(periodStart func(anchor time.Time, k int) time.Time, anchor, from time.Time) int {
+	length := periodStart(anchor, 1).Sub(anchor)
+	if !from.After(anchor) || length <= 0 {
+		return 0
+	}
+	k := int(from.Sub(anchor) / length)
+	for k > 0 && periodStart(anchor, k).After(from) {
+		k--
+	}
+	for !periodStart(anchor, k+1).After(from) {
+		k++
+	}
+	return k
+}
+
+// periodStarts returns a function giving the start of the k-th period of the
+// given length and unit after anchor.
+func periodStarts(period string, unit vspb.UnitsOfTimeValueSet_Value) (func(anchor time.Time, k int) time.Time, error) {
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return nil, fmt.Errorf("invalid period %q", period)
+	}
+	if c, ok := calendarUnits[unit]; ok && p == math.Trunc(p) {
+		n := int(p)
+		return func(anchor time.Time, k int) time.Time {
+			return anchor.AddDate(k*n*c.years, k*n*c.months, k*n*c.days)
+		}, nil
+	}
+	d, ok := unitDurations[unit]
+	switch {
+	case ok:
+	case unit == vspb.UnitsOfTimeValueSet_D:
+		d = 24 * time.Hour
+	case unit == vspb.UnitsOfTimeValueSet_WK:
+		d = 7 * 24 * time.Hour
+	case unit == vspb.UnitsOfTimeValueSet_MO, unit == vspb.UnitsOfTimeValueSet_A:
+		return nil, fmt.Errorf("period %s %v is not a whole number of months or years", period, unit)
+	default:
+		return nil, fmt.Errorf("unsupported period unit %v", unit)
+	}
+	length := time.Duration(p * float64(d))
+	if length <= 0 {
+		return nil, fmt.Errorf("period %s %v is too short", period, unit)
+	}
+	return func(anchor time.Time, k int) time.Time {
+		return anchor.Add(time.Duration(k) * length)
+	}, nil
+}
+
+// endOf returns the end of the period covered by dt, e.g. the start of the
+// next day for a dateTime of day precision.
+func endOf(dt *d4pb.DateTime) time.Time {
+	return fhirtime.End(toTime(dt.GetValueUs(), dt.GetTimezone()), dt.GetPrecision().String())
+}
+
+// sortTimes sorts ts in ascending order.
+func sortTimes(ts []time.Time) {
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Before(ts[j]) })
+}
@@ -0,0 +1,241 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	vspb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/valuesets_go_proto"
+)
+
+var plusOne = time.FixedZone("+01:00", 60*60)
+
+func at(day, hour int) time.Time {
+	return time.Date(2023, time.March, day, hour, 0, 0, 0, plusOne)
+}
+
+func dateTimeAt(t time.Time, precision d4pb.DateTime_Precision) *d4pb.DateTime {
+	return &d4pb.DateTime{ValueUs: t.UnixMicro(), Timezone: "+01:00", Precision: precision}
+}
+
+func repeat(frequency uint32, period string, unit vspb.UnitsOfTimeValueSet_Value) *d4pb.Timing_Repeat {
+	return &d4pb.Timing_Repeat{
+		Frequency:  &d4pb.PositiveInt{Value: frequency},
+		Period:     &d4pb.Decimal{Value: period},
+		PeriodUnit: &d4pb.Timing_Repeat_PeriodUnitCode{Value: unit},
+	}
+}
+
+func boundedBy(r *d4pb.Timing_Repeat, start, end *d4pb.DateTime) *d4pb.Timing_Repeat {
+	r.Bounds = &d4pb.Timing_Repeat_BoundsX{
+		Choice: &d4pb.Timing_Repeat_BoundsX_Period{Period: &d4pb.Period{Start: start, End: end}},
+	}
+	return r
+}
+
+func TestOccurrences(t *testing.T) {
+	start := dateTimeAt(at(1, 8), d4pb.DateTime_SECOND)
+	tests := []struct {
+		name     string
+		timing   *d4pb.Timing
+		from, to time.Time
+		want     []time.Time
+	}{
+		{
+			name:   "daily",
+			timing: &d4pb.Timing{Repeat: boundedBy(repeat(1, "1", vspb.UnitsOfTimeValueSet_D), start, nil)},
+			from:   at(2, 0),
+			to:     at(5, 0),
+			want:   []time.Time{at(2, 8), at(3, 8), at(4, 8)},
+		},
+		{
+			name:   "twice daily",
+			timing: &d4pb.Timing{Repeat: boundedBy(repeat(2, "1", vspb.UnitsOfTimeValueSet_D), start, nil)},
+			from:   at(2, 0),
+			to:     at(4, 0),
+			want:   []time.Time{at(2, 8), at(2, 20), at(3, 8), at(3, 20)},
+		},
+		{
+			name:   "every 8 hours",
+			timing: &d4pb.Timing{Repeat: boundedBy(repeat(1, "8", vspb.UnitsOfTimeValueSet_H), start, nil)},
+			from:   at(1, 0),
+			to:     at(2, 0),
+			want:   []time.Time{at(1, 8), at(1, 16)},
+		},
+		{
+			name:   "window end is exclusive",
+			timing: &d4pb.Timing{Repeat: boundedBy(repeat(1, "1", vspb.UnitsOfTimeValueSet_D), start, nil)},
+			from:   at(2, 8),
+			to:     at(3, 8),
+			want:   []time.Time{at(2, 8)},
+		},
+		{
+			name: "bounds end of day precision",
+			timing: &d4pb.Timing{Repeat: boundedBy(
+				repeat(1, "1", vspb.UnitsOfTimeValueSet_D), start, dateTimeAt(at(3, 0), d4pb.DateTime_DAY))},
+			from: at(1, 0),
+			to:   at(10, 0),
+			want: []time.Time{at(1, 8), at(2, 8), at(3, 8)},
+		},
+		{
+			name: "bounds end of second precision",
+			timing: &d4pb.Timing{Repeat: boundedBy(
+				repeat(2, "1", vspb.UnitsOfTimeValueSet_S), start, dateTimeAt(at(1, 8).Add(time.Second), d4pb.DateTime_SECOND))},
+			from: at(1, 0),
+			to:   at(2, 0),
+			want: []time.Time{
+				at(1, 8),
+				at(1, 8).Add(500 * time.Millisecond),
+				at(1, 8).Add(time.Second),
+				at(1, 8).Add(1500 * time.Millisecond),
+			},
+		},
+		{
+			name: "count from bounds start",
+			timing: &d4pb.Timing{Repeat: func() *d4pb.Timing_Repeat {
+				r := boundedBy(repeat(2, "1", vspb.UnitsOfTimeValueSet_D), start, nil)
+				r.Count = &d4pb.PositiveInt{Value: 5}
+				return r
+			}()},
+			from: at(2, 0),
+			to:   at(10, 0),
+			want: []time.Time{at(2, 8), at(2, 20), at(3, 8)},
+		},
+		{
+			name:   "monthly",
+			timing: &d4pb.Timing{Repeat: boundedBy(repeat(1, "1", vspb.UnitsOfTimeValueSet_MO), dateTimeAt(at(31, 8), d4pb.DateTime_SECOND), nil)},
+			from:   at(1, 0),
+			to:     time.Date(2023, time.June, 1, 0, 0, 0, 0, plusOne),
+			want: []time.Time{
+				at(31, 8),
+				time.Date(2023, time.May, 1, 8, 0, 0, 0, plusOne),
+				time.Date(2023, time.May, 31, 8, 0, 0, 0, plusOne),
+			},
+		},
+		{
+			name:   "unbounded starts at window",
+			timing: &d4pb.Timing{Repeat: repeat(3, "1", vspb.UnitsOfTimeValueSet_D)},
+			from:   at(2, 0),
+			to:     at(3, 0),
+			want:   []time.Time{at(2, 0), at(2, 8), at(2, 16)},
+		},
+		{
+			name: "events and repeat",
+			timing: &d4pb.Timing{
+				Event: []*d4pb.DateTime{
+					dateTimeAt(at(1, 12), d4pb.DateTime_SECOND),
+					dateTimeAt(at(2, 12), d4pb.DateTime_SECOND),
+				},
+				Repeat: boundedBy(repeat(1, "1", vspb.UnitsOfTimeValueSet_D), start, nil),
+			},
+			from: at(2, 0),
+			to:   at(4, 0),
+			want: []time.Time{at(2, 8), at(2, 12), at(3, 8)},
+		},
+		{
+			name:   "distant bounds start",
+			timing: &d4pb.Timing{Repeat: boundedBy(repeat(1, "1", vspb.UnitsOfTimeValueSet_S), dateTimeAt(at(1, 8).AddDate(-20, 0, 0), d4pb.DateTime_SECOND), nil)},
+			from:   at(2, 8),
+			to:     at(2, 8).Add(3 * time.Second),
+			want:   []time.Time{at(2, 8), at(2, 8).Add(time.Second), at(2, 8).Add(2 * time.Second)},
+		},
+		{
+			name:   "distant monthly bounds start",
+			timing: &d4pb.Timing{Repeat: boundedBy(repeat(1, "1", vspb.UnitsOfTimeValueSet_MO), dateTimeAt(at(31, 8).AddDate(-50, 0, 0), d4pb.DateTime_SECOND), nil)},
+			from:   at(1, 0),
+			to:     time.Date(2023, time.May, 1, 0, 0, 0, 0, plusOne),
+			want:   []time.Time{time.Date(2023, time.March, 3, 8, 0, 0, 0, plusOne), at(31, 8)},
+		},
+		{
+			name: "count exhausted before distant window",
+			timing: &d4pb.Timing{Repeat: func() *d4pb.Timing_Repeat {
+				r := boundedBy(repeat(1, "1", vspb.UnitsOfTimeValueSet_S), dateTimeAt(at(1, 8).AddDate(-20, 0, 0), d4pb.DateTime_SECOND), nil)
+				r.Count = &d4pb.PositiveInt{Value: 5}
+				return r
+			}()},
+			from: at(2, 8),
+			to:   at(3, 8),
+		},
+		{
+			name:   "nothing in window",
+			timing: &d4pb.Timing{Repeat: boundedBy(repeat(1, "1", vspb.UnitsOfTimeValueSet_D), start, nil)},
+			from:   at(1, 9),
+			to:     at(1, 10),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Occurrences(test.timing, test.from, test.to)
+			if err != nil {
+				t.Fatalf("Occurrences() failed: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Occurrences() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOccurrences_Errors(t *testing.T) {
+	withDayOfWeek := repeat(1, "1", vspb.UnitsOfTimeValueSet_D)
+	withDayOfWeek.DayOfWeek = []*d4pb.Timing_Repeat_DayOfWeekCode{{}}
+	withBoundsDuration := repeat(1, "1", vspb.UnitsOfTimeValueSet_D)
+	withBoundsDuration.Bounds = &d4pb.Timing_Repeat_BoundsX{
+		Choice: &d4pb.Timing_Repeat_BoundsX_Duration{Duration: &d4pb.Duration{}},
+	}
+	tests := []struct {
+		name   string
+		timing *d4pb.Timing
+	}{
+		{
+			name: "nil timing",
+		},
+		{
+			name:   "code only",
+			timing: &d4pb.Timing{Code: &d4pb.CodeableConcept{Text: &d4pb.String{Value: "BID"}}},
+		},
+		{
+			name:   "dayOfWeek",
+			timing: &d4pb.Timing{Repeat: withDayOfWeek},
+		},
+		{
+			name:   "boundsDuration",
+			timing: &d4pb.Timing{Repeat: withBoundsDuration},
+		},
+		{
+			name:   "missing period",
+			timing: &d4pb.Timing{Repeat: &d4pb.Timing_Repeat{Frequency: &d4pb.PositiveInt{Value: 1}}},
+		},
+		{
+			name:   "fractional months",
+			timing: &d4pb.Timing{Repeat: repeat(1, "1.5", vspb.UnitsOfTimeValueSet_MO)},
+		},
+		{
+			name:   "invalid period",
+			timing: &d4pb.Timing{Repeat: repeat(1, "-1", vspb.UnitsOfTimeValueSet_D)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Occurrences(test.timing, at(1, 0), at(2, 0)); err == nil {
+				t.Errorf("Occurrences() succeeded, want error")
+			}
+		})
+	}
+}
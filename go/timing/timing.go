@@ -0,0 +1,75 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package timing reads when clinical events described by FHIR R4 resources
+// took place or are scheduled to.
+package timing
+
+import (
+	"time"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/fhirtime"
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+)
+
+// EffectiveTime returns the clinically relevant time of msg, an R4 resource
+// with an effective[x] element such as an Observation or DiagnosticReport, or
+// a ContainedResource holding one. It is read from effectiveDateTime,
+// effectiveInstant, or the start of effectivePeriod, falling back to its end
+// for a period without a start. The time is in the time zone recorded with
+// it. EffectiveTime returns false if msg has no effective[x] or it is an
+// effectiveTiming, which describes a schedule rather than a single time.
+func EffectiveTime(msg proto.Message) (time.Time, bool) {
+	rm := element.UnwrapContained(msg.ProtoReflect())
+	if rm == nil {
+		return time.Time{}, false
+	}
+	f := rm.Descriptor().Fields().ByName("effective")
+	if f == nil || f.Message() == nil || !rm.Has(f) {
+		return time.Time{}, false
+	}
+	choice := rm.Get(f).Message()
+	if choice.Descriptor().Oneofs().Len() == 0 {
+		return time.Time{}, false
+	}
+	active := choice.WhichOneof(choice.Descriptor().Oneofs().Get(0))
+	if active == nil {
+		return time.Time{}, false
+	}
+	switch v := choice.Get(active).Message().Interface().(type) {
+	case *d4pb.DateTime:
+		return toTime(v.GetValueUs(), v.GetTimezone()), true
+	case *d4pb.Instant:
+		return toTime(v.GetValueUs(), v.GetTimezone()), true
+	case *d4pb.Period:
+		if start := v.GetStart(); start != nil {
+			return toTime(start.GetValueUs(), start.GetTimezone()), true
+		}
+		if end := v.GetEnd(); end != nil {
+			return toTime(end.GetValueUs(), end.GetTimezone()), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func toTime(us int64, tz string) time.Time {
+	t := time.UnixMicro(us)
+	if loc, err := fhirtime.Location(tz); err == nil {
+		t = t.In(loc)
+	}
+	return t
+}
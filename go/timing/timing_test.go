@@ -0,0 +1,138 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timing
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4diagnosticreportpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/diagnostic_report_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func TestEffectiveTime(t *testing.T) {
+	when := time.Date(2023, 3, 15, 10, 30, 0, 0, time.FixedZone("+02:00", 2*60*60))
+	later := when.Add(time.Hour)
+	dateTime := func(t time.Time) *d4pb.DateTime {
+		return &d4pb.DateTime{ValueUs: t.UnixMicro(), Timezone: "+02:00", Precision: d4pb.DateTime_SECOND}
+	}
+	tests := []struct {
+		name string
+		msg  proto.Message
+		want time.Time
+	}{
+		{
+			name: "effectiveDateTime",
+			msg: &r4observationpb.Observation{Effective: &r4observationpb.Observation_EffectiveX{
+				Choice: &r4observationpb.Observation_EffectiveX_DateTime{DateTime: dateTime(when)},
+			}},
+			want: when,
+		},
+		{
+			name: "effectiveInstant",
+			msg: &r4observationpb.Observation{Effective: &r4observationpb.Observation_EffectiveX{
+				Choice: &r4observationpb.Observation_EffectiveX_Instant{Instant: &d4pb.Instant{
+					ValueUs: when.UnixMicro(), Timezone: "+02:00", Precision: d4pb.Instant_SECOND,
+				}},
+			}},
+			want: when,
+		},
+		{
+			name: "effectivePeriod",
+			msg: &r4observationpb.Observation{Effective: &r4observationpb.Observation_EffectiveX{
+				Choice: &r4observationpb.Observation_EffectiveX_Period{Period: &d4pb.Period{
+					Start: dateTime(when), End: dateTime(later),
+				}},
+			}},
+			want: when,
+		},
+		{
+			name: "effectivePeriod without start",
+			msg: &r4observationpb.Observation{Effective: &r4observationpb.Observation_EffectiveX{
+				Choice: &r4observationpb.Observation_EffectiveX_Period{Period: &d4pb.Period{End: dateTime(later)}},
+			}},
+			want: later,
+		},
+		{
+			name: "DiagnosticReport in a ContainedResource",
+			msg: &r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_DiagnosticReport{
+				DiagnosticReport: &r4diagnosticreportpb.DiagnosticReport{
+					Effective: &r4diagnosticreportpb.DiagnosticReport_EffectiveX{
+						Choice: &r4diagnosticreportpb.DiagnosticReport_EffectiveX_DateTime{DateTime: dateTime(when)},
+					},
+				},
+			}},
+			want: when,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := EffectiveTime(test.msg)
+			if !ok {
+				t.Fatalf("EffectiveTime() returned false, want %v", test.want)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("EffectiveTime() = %v, want %v", got, test.want)
+			}
+			if _, offset := got.Zone(); offset != 2*60*60 {
+				t.Errorf("EffectiveTime() = %v, want it in the recorded +02:00 zone", got)
+			}
+		})
+	}
+}
+
+func TestEffectiveTime_None(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+	}{
+		{
+			name: "effectiveTiming",
+			msg: &r4observationpb.Observation{Effective: &r4observationpb.Observation_EffectiveX{
+				Choice: &r4observationpb.Observation_EffectiveX_Timing{Timing: &d4pb.Timing{}},
+			}},
+		},
+		{
+			name: "no effective",
+			msg:  &r4observationpb.Observation{},
+		},
+		{
+			name: "empty period",
+			msg: &r4observationpb.Observation{Effective: &r4observationpb.Observation_EffectiveX{
+				Choice: &r4observationpb.Observation_EffectiveX_Period{Period: &d4pb.Period{}},
+			}},
+		},
+		{
+			name: "resource without effective",
+			msg:  &r4patientpb.Patient{},
+		},
+		{
+			name: "empty ContainedResource",
+			msg:  &r4pb.ContainedResource{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, ok := EffectiveTime(test.msg); ok {
+				t.Errorf("EffectiveTime() = %v, want false", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+)
+
+// CheckRequiredBindings checks that each code element of msg with a required
+// binding holds a code from the bound value set, returning a Violation for
+// each one that does not. valueSets holds expanded ValueSet resources keyed
+// by their url, and is typically narrower than the codes the element type
+// admits, e.g. a value set from a profile. Elements bound to value sets
+// missing from valueSets are not checked.
+//
+// Required bindings of code elements are found through the specialized code
+// types generated for them, e.g. Observation.status. Codes are matched on
+// system and code against the codes of the value set expansion, including
+// nested ones.
+func CheckRequiredBindings(msg proto.Message, valueSets map[string]proto.Message) []error {
+	expansions := map[string]map[string]bool{}
+	var errs []error
+	err := walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		url := proto.GetExtension(m.Descriptor().Options(), apb.E_FhirValuesetUrl).(string)
+		if url == "" {
+			return nil
+		}
+		vs, ok := valueSets[url]
+		if !ok {
+			return nil
+		}
+		system, code, ok := boundCode(m)
+		if !ok {
+			return nil
+		}
+		codes, ok := expansions[url]
+		if !ok {
+			codes = expansionCodes(vs.ProtoReflect())
+			expansions[url] = codes
+		}
+		if codes == nil {
+			errs = append(errs, Violation{Path: path, Message: fmt.Sprintf("value set %s has no expansion", url)})
+		} else if !codes[system+"|"+code] {
+			errs = append(errs, Violation{Path: path, Message: fmt.Sprintf("code %q is not in value set %s", code, url)})
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// boundCode returns the system and code held by the specialized code m, or
+// false if its code is not set.
+func boundCode(m protoreflect.Message) (string, string, bool) {
+	f := m.Descriptor().Fields().ByName("value")
+	if f == nil || f.Kind() != protoreflect.EnumKind {
+		return "", "", false
+	}
+	ev := f.Enum().Values().ByNumber(m.Get(f).Enum())
+	if ev == nil || ev.Number() == 0 {
+		return "", "", false
+	}
+	system := proto.GetExtension(ev.Options(), apb.E_SourceCodeSystem).(string)
+	if system == "" {
+		system = proto.GetExtension(f.Enum().Options(), apb.E_FhirCodeSystemUrl).(string)
+	}
+	return system, element.EnumCode(ev), true
+}
+
+// expansionCodes returns the "system|code" keys of the codes in the expansion
+// of the ValueSet vs, or nil if it has no expansion.
+func expansionCodes(vs protoreflect.Message) map[string]bool {
+	f := vs.Descriptor().Fields().ByName("expansion")
+	if f == nil || f.Message() == nil || !vs.Has(f) {
+		return nil
+	}
+	codes := map[string]bool{}
+	addContains(vs.Get(f).Message(), codes)
+	return codes
+}
+
+// addContains adds the codes of the contains elements of m, and those nested
+// within them, to codes.
+func addContains(m protoreflect.Message, codes map[string]bool) {
+	f := m.Descriptor().Fields().ByName("contains")
+	if f == nil || !f.IsList() || f.Message() == nil {
+		return
+	}
+	l := m.Get(f).List()
+	for i := 0; i < l.Len(); i++ {
+		c := l.Get(i).Message()
+		if code := element.PrimitiveString(c, "code"); code != "" {
+			codes[element.PrimitiveString(c, "system")+"|"+code] = true
+		}
+		addContains(c, codes)
+	}
+}
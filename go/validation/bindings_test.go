@@ -0,0 +1,101 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4valuesetpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/value_set_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	observationStatusURL = "http://hl7.org/fhir/ValueSet/observation-status"
+	narrativeStatusURL   = "http://hl7.org/fhir/ValueSet/narrative-status"
+)
+
+func contains(system, code string, nested ...*r4valuesetpb.ValueSet_Expansion_Contains) *r4valuesetpb.ValueSet_Expansion_Contains {
+	return &r4valuesetpb.ValueSet_Expansion_Contains{
+		System:   &d4pb.Uri{Value: system},
+		Code:     &d4pb.Code{Value: code},
+		Contains: nested,
+	}
+}
+
+func TestCheckRequiredBindings(t *testing.T) {
+	const system = "http://hl7.org/fhir/observation-status"
+	// Only final results, with amended ones nested under final.
+	finalStatuses := &r4valuesetpb.ValueSet{
+		Url: &d4pb.Uri{Value: observationStatusURL},
+		Expansion: &r4valuesetpb.ValueSet_Expansion{
+			Contains: []*r4valuesetpb.ValueSet_Expansion_Contains{
+				contains(system, "final", contains(system, "amended")),
+			},
+		},
+	}
+	contained, err := anypb.New(&r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Observation{
+		Observation: &r4observationpb.Observation{
+			Id:     &d4pb.Id{Value: "amended"},
+			Status: &r4observationpb.Observation_StatusCode{Value: c4pb.ObservationStatusCode_AMENDED},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	obs := &r4observationpb.Observation{
+		Status: &r4observationpb.Observation_StatusCode{Value: c4pb.ObservationStatusCode_CANCELLED},
+		// No value set is given for the narrative status, so it is not
+		// checked.
+		Text: &d4pb.Narrative{
+			Status: &d4pb.Narrative_StatusCode{Value: c4pb.NarrativeStatusCode_GENERATED},
+			Div:    &d4pb.Xhtml{Value: `<div xmlns="http://www.w3.org/1999/xhtml">Cancelled</div>`},
+		},
+		Contained: []*anypb.Any{contained},
+	}
+	got := CheckRequiredBindings(obs, map[string]proto.Message{observationStatusURL: finalStatuses})
+	want := []error{Violation{
+		Path:    "Observation.status",
+		Message: `code "cancelled" is not in value set ` + observationStatusURL,
+	}}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("CheckRequiredBindings() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckRequiredBindings_NoExpansion(t *testing.T) {
+	obs := &r4observationpb.Observation{
+		Status: &r4observationpb.Observation_StatusCode{Value: c4pb.ObservationStatusCode_FINAL},
+		Text: &d4pb.Narrative{
+			Status: &d4pb.Narrative_StatusCode{Value: c4pb.NarrativeStatusCode_GENERATED},
+			Div:    &d4pb.Xhtml{Value: `<div xmlns="http://www.w3.org/1999/xhtml">Final</div>`},
+		},
+	}
+	got := CheckRequiredBindings(obs, map[string]proto.Message{
+		narrativeStatusURL: &r4valuesetpb.ValueSet{Url: &d4pb.Uri{Value: narrativeStatusURL}},
+	})
+	want := []error{Violation{
+		Path:    "Observation.text.status",
+		Message: "value set " + narrativeStatusURL + " has no expansion",
+	}}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("CheckRequiredBindings() = %v, want %v", got, want)
+	}
+}
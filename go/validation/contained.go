@@ -0,0 +1,116 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// containedPattern matches the path of a contained resource, capturing the
+// path of its container and its index.
+var containedPattern = regexp.MustCompile(`^(.*)\.contained\[(\d+)\]$`)
+
+// ValidateContained checks the resources contained in msg, which FHIR
+// requires to be versioned with their container rather than independently.
+// It returns a Violation for each contained resource with a meta.versionId or
+// meta.lastUpdated, and for each whose id is not a plain local id: one
+// written as a fragment reference, e.g. "#med1", or as a server reference,
+// e.g. "Medication/123".
+func ValidateContained(msg proto.Message) []error {
+	var errs []error
+	err := walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		if !containedPattern.MatchString(path) {
+			return nil
+		}
+		if meta := messageField(m, "meta"); meta != nil {
+			for _, name := range []protoreflect.Name{"version_id", "last_updated"} {
+				if f := meta.Descriptor().Fields().ByName(name); f != nil && meta.Has(f) {
+					errs = append(errs, Violation{
+						Path:    path + ".meta." + f.JSONName(),
+						Message: "contained resources must not be versioned independently",
+					})
+				}
+			}
+		}
+		switch id := element.PrimitiveString(m, "id"); {
+		case strings.HasPrefix(id, "#"):
+			errs = append(errs, Violation{Path: path + ".id", Message: "contained resource id must not include the '#' of references to it"})
+		case strings.ContainsAny(id, "/:"):
+			errs = append(errs, Violation{Path: path + ".id", Message: "contained resource id must be a local id, not a server reference"})
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// CheckContainedIDs checks that the resources contained in each resource of
+// msg have ids that are unique within their container, as local references
+// to them require. It returns a Violation for each contained resource without
+// an id, and for each whose id was already used by an earlier contained
+// resource of the same container.
+func CheckContainedIDs(msg proto.Message) []error {
+	var errs []error
+	// seen maps container paths to the index of the first contained resource
+	// with each id.
+	seen := map[string]map[string]string{}
+	err := walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		match := containedPattern.FindStringSubmatch(path)
+		if match == nil {
+			return nil
+		}
+		id := element.PrimitiveString(m, "id")
+		if id == "" {
+			errs = append(errs, Violation{Path: path, Message: "contained resource must have an id"})
+			return nil
+		}
+		container, index := match[1], match[2]
+		if seen[container] == nil {
+			seen[container] = map[string]string{}
+		}
+		if first, ok := seen[container][id]; ok {
+			errs = append(errs, Violation{
+				Path:    path + ".id",
+				Message: fmt.Sprintf("contained resource id %q is already used by contained[%s]", id, first),
+			})
+			return nil
+		}
+		seen[container][id] = index
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// messageField returns the value of the message field name of m, or nil if it
+// is not set.
+func messageField(m protoreflect.Message, name protoreflect.Name) protoreflect.Message {
+	f := m.Descriptor().Fields().ByName(name)
+	if f == nil || f.Message() == nil || f.IsList() || !m.Has(f) {
+		return nil
+	}
+	return m.Get(f).Message()
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+func containedPatient(t *testing.T, p *r4patientpb.Patient) *anypb.Any {
+	t.Helper()
+	a, err := anypb.New(&r4pb.ContainedResource{OneofResource: &r4pb.ContainedResource_Patient{Patient: p}})
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	return a
+}
+
+func TestValidateContained(t *testing.T) {
+	p := &r4patientpb.Patient{
+		// The container itself may be versioned.
+		Meta: &d4pb.Meta{VersionId: &d4pb.Id{Value: "3"}},
+		Contained: []*anypb.Any{
+			containedPatient(t, &r4patientpb.Patient{Id: &d4pb.Id{Value: "ok"}}),
+			containedPatient(t, &r4patientpb.Patient{
+				Id:   &d4pb.Id{Value: "versioned"},
+				Meta: &d4pb.Meta{VersionId: &d4pb.Id{Value: "1"}, LastUpdated: &d4pb.Instant{ValueUs: 1}},
+			}),
+			containedPatient(t, &r4patientpb.Patient{Id: &d4pb.Id{Value: "#fragment"}}),
+			containedPatient(t, &r4patientpb.Patient{Id: &d4pb.Id{Value: "Patient/123"}}),
+		},
+	}
+	got := ValidateContained(p)
+	wantPaths := []string{
+		"Patient.contained[1].meta.versionId",
+		"Patient.contained[1].meta.lastUpdated",
+		"Patient.contained[2].id",
+		"Patient.contained[3].id",
+	}
+	if len(got) != len(wantPaths) {
+		t.Fatalf("ValidateContained() = %v, want violations at %v", got, wantPaths)
+	}
+	for i, err := range got {
+		v, ok := err.(Violation)
+		if !ok {
+			t.Fatalf("ValidateContained()[%d] = %T, want Violation", i, err)
+		}
+		if v.Path != wantPaths[i] {
+			t.Errorf("ValidateContained()[%d].Path = %q, want %q", i, v.Path, wantPaths[i])
+		}
+	}
+}
+
+func TestValidateContained_Valid(t *testing.T) {
+	p := &r4patientpb.Patient{
+		Contained: []*anypb.Any{
+			containedPatient(t, &r4patientpb.Patient{
+				Id:   &d4pb.Id{Value: "p1"},
+				Meta: &d4pb.Meta{Profile: []*d4pb.Canonical{{Value: "http://example.com/profile"}}},
+			}),
+		},
+	}
+	if got := ValidateContained(p); len(got) != 0 {
+		t.Errorf("ValidateContained() = %v, want no violations", got)
+	}
+}
+
+func TestCheckContainedIDs(t *testing.T) {
+	p := &r4patientpb.Patient{
+		Contained: []*anypb.Any{
+			containedPatient(t, &r4patientpb.Patient{Id: &d4pb.Id{Value: "x"}}),
+			containedPatient(t, &r4patientpb.Patient{Id: &d4pb.Id{Value: "y"}}),
+			containedPatient(t, &r4patientpb.Patient{Id: &d4pb.Id{Value: "x"}}),
+			containedPatient(t, &r4patientpb.Patient{Active: &d4pb.Boolean{Value: true}}),
+		},
+	}
+	got := CheckContainedIDs(p)
+	want := []Violation{
+		{Path: "Patient.contained[2].id", Message: `contained resource id "x" is already used by contained[0]`},
+		{Path: "Patient.contained[3]", Message: "contained resource must have an id"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("CheckContainedIDs() = %v, want %v", got, want)
+	}
+	for i, err := range got {
+		if v, ok := err.(Violation); !ok || v != want[i] {
+			t.Errorf("CheckContainedIDs()[%d] = %v, want %v", i, err, want[i])
+		}
+	}
+}
+
+func TestCheckContainedIDs_Valid(t *testing.T) {
+	// Ids only need to be unique within their own container.
+	p := &r4patientpb.Patient{
+		Contained: []*anypb.Any{
+			containedPatient(t, &r4patientpb.Patient{
+				Id: &d4pb.Id{Value: "x"},
+				Contained: []*anypb.Any{
+					containedPatient(t, &r4patientpb.Patient{Id: &d4pb.Id{Value: "x"}}),
+				},
+			}),
+			containedPatient(t, &r4patientpb.Patient{Id: &d4pb.Id{Value: "y"}}),
+		},
+	}
+	if got := CheckContainedIDs(p); len(got) != 0 {
+		t.Errorf("CheckContainedIDs() = %v, want no violations", got)
+	}
+}
+
+func TestChecks_UndecodableContained(t *testing.T) {
+	p := &r4patientpb.Patient{
+		Contained: []*anypb.Any{{
+			TypeUrl: "type.googleapis.com/google.fhir.r4.core.ContainedResource",
+			Value:   []byte{0xff},
+		}},
+	}
+	checks := map[string]func(proto.Message) []error{
+		"ValidateUnits":             ValidateUnits,
+		"CheckDateOrdering":         CheckDateOrdering,
+		"ValidateIdentifierSystems": ValidateIdentifierSystems,
+		"ValidateContained":         ValidateContained,
+		"CheckNarrative":            CheckNarrative,
+		"CheckContainedIDs":         CheckContainedIDs,
+		"CheckElementIDs":           CheckElementIDs,
+		"CheckRequiredBindings": func(msg proto.Message) []error {
+			return CheckRequiredBindings(msg, nil)
+		},
+	}
+	for name, check := range checks {
+		t.Run(name, func(t *testing.T) {
+			found := false
+			for _, err := range check(p) {
+				if _, ok := err.(Violation); !ok {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%s() returned no walk error for an undecodable contained resource", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/fhirtime"
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// temporalTypes are the FHIR date and time types that may bound a Period-like
+// element.
+var temporalTypes = map[protoreflect.Name]bool{
+	"Date":     true,
+	"DateTime": true,
+	"Instant":  true,
+}
+
+// CheckDateOrdering checks that every Period-like element in msg, one with
+// start and end dates, does not end before it starts, and that every
+// Range-like element, one with low and high quantities, does not have a high
+// value below its low value. A missing bound leaves the element open-ended
+// and unchecked. Dates are compared at their precision, so a Period from
+// 2023-03-15 to 2023-03 is valid, and quantities are only compared when they
+// have the same units. A Violation is returned for each element out of order.
+func CheckDateOrdering(msg proto.Message) []error {
+	var errs []error
+	err := walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		if start, end, ok := bounds(m, "start", "end", temporalTypes); ok {
+			if earliest(start).After(latest(end)) {
+				errs = append(errs, Violation{Path: path, Message: "end is before start"})
+			}
+		}
+		if low, high, ok := bounds(m, "low", "high", element.QuantityTypes); ok {
+			if quantityLess(high, low) {
+				errs = append(errs, Violation{Path: path, Message: "high is below low"})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// bounds returns the values of the fields lower and upper of m if both are
+// set and have one of the given types.
+func bounds(m protoreflect.Message, lower, upper protoreflect.Name, types map[protoreflect.Name]bool) (protoreflect.Message, protoreflect.Message, bool) {
+	var vals [2]protoreflect.Message
+	for i, name := range []protoreflect.Name{lower, upper} {
+		f := m.Descriptor().Fields().ByName(name)
+		if f == nil || f.IsList() || f.Message() == nil || !types[f.Message().Name()] || !m.Has(f) {
+			return nil, nil, false
+		}
+		vals[i] = m.Get(f).Message()
+	}
+	return vals[0], vals[1], true
+}
+
+// earliest returns the first instant covered by the date or time m.
+func earliest(m protoreflect.Message) time.Time {
+	return time.UnixMicro(m.Get(m.Descriptor().Fields().ByName("value_us")).Int())
+}
+
+// latest returns the last instant covered by the date or time m, given its
+// precision and time zone.
+func latest(m protoreflect.Message) time.Time {
+	t := earliest(m)
+	if loc, err := fhirtime.Location(primitiveField(m, "timezone").String()); err == nil {
+		t = t.In(loc)
+	}
+	return fhirtime.End(t, string(precisionName(m))).Add(-time.Microsecond)
+}
+
+// precisionName returns the name of the precision of the date or time m, e.g.
+// "DAY".
+func precisionName(m protoreflect.Message) protoreflect.Name {
+	f := m.Descriptor().Fields().ByName("precision")
+	if f == nil || f.Enum() == nil {
+		return ""
+	}
+	v := f.Enum().Values().ByNumber(m.Get(f).Enum())
+	if v == nil {
+		return ""
+	}
+	return v.Name()
+}
+
+// primitiveField returns the value of the scalar field name of m.
+func primitiveField(m protoreflect.Message, name protoreflect.Name) protoreflect.Value {
+	f := m.Descriptor().Fields().ByName(name)
+	if f == nil {
+		return protoreflect.ValueOfString("")
+	}
+	return m.Get(f)
+}
+
+// quantityLess reports whether the quantity a is less than b. Quantities
+// without values or with different units are not ordered.
+func quantityLess(a, b protoreflect.Message) bool {
+	if quantityUnit(a) != quantityUnit(b) {
+		return false
+	}
+	av, ok := new(big.Rat).SetString(element.PrimitiveString(a, "value"))
+	if !ok {
+		return false
+	}
+	bv, ok := new(big.Rat).SetString(element.PrimitiveString(b, "value"))
+	if !ok {
+		return false
+	}
+	return av.Cmp(bv) < 0
+}
+
+// quantityUnit identifies the unit of the quantity m by its coded unit if it
+// has one, or its human readable unit otherwise.
+func quantityUnit(m protoreflect.Message) string {
+	if code := element.PrimitiveString(m, "code"); code != "" {
+		return element.PrimitiveString(m, "system") + "|" + code
+	}
+	return element.PrimitiveString(m, "unit")
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func dateTime(t time.Time, p d4pb.DateTime_Precision) *d4pb.DateTime {
+	return &d4pb.DateTime{ValueUs: t.UnixMicro(), Timezone: "Z", Precision: p}
+}
+
+func TestCheckDateOrdering(t *testing.T) {
+	march15 := time.Date(2023, 3, 15, 0, 0, 0, 0, time.UTC)
+	march1 := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	april1 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	patient := &r4patientpb.Patient{
+		Name: []*d4pb.HumanName{
+			// Ends before it starts.
+			{Period: &d4pb.Period{Start: dateTime(april1, d4pb.DateTime_DAY), End: dateTime(march15, d4pb.DateTime_DAY)}},
+			// Ends within the month it starts in.
+			{Period: &d4pb.Period{Start: dateTime(march15, d4pb.DateTime_DAY), End: dateTime(march1, d4pb.DateTime_MONTH)}},
+			// Open-ended.
+			{Period: &d4pb.Period{Start: dateTime(april1, d4pb.DateTime_DAY)}},
+			// Same day, later start time than the end day's midnight.
+			{Period: &d4pb.Period{Start: dateTime(march15.Add(10*time.Hour), d4pb.DateTime_SECOND), End: dateTime(march15, d4pb.DateTime_DAY)}},
+		},
+		Address: []*d4pb.Address{
+			{Period: &d4pb.Period{Start: dateTime(march15, d4pb.DateTime_SECOND), End: dateTime(march1, d4pb.DateTime_SECOND)}},
+		},
+	}
+	got := CheckDateOrdering(patient)
+	want := []error{
+		Violation{Path: "Patient.name[0].period", Message: "end is before start"},
+		Violation{Path: "Patient.address[0].period", Message: "end is before start"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CheckDateOrdering() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckDateOrdering_Ranges(t *testing.T) {
+	sq := func(value, code string) *d4pb.SimpleQuantity {
+		return &d4pb.SimpleQuantity{
+			Value:  &d4pb.Decimal{Value: value},
+			System: &d4pb.Uri{Value: ucumSystem},
+			Code:   &d4pb.Code{Value: code},
+		}
+	}
+	obs := &r4observationpb.Observation{
+		Value: &r4observationpb.Observation_ValueX{
+			Choice: &r4observationpb.Observation_ValueX_Range{Range: &d4pb.Range{Low: sq("10", "mg"), High: sq("9.5", "mg")}},
+		},
+		ReferenceRange: []*r4observationpb.Observation_ReferenceRange{
+			{Low: sq("3.5", "mmol/L"), High: sq("5.1", "mmol/L")},
+			// Units differ, so the values are not compared.
+			{Low: sq("1", "g"), High: sq("500", "mg")},
+			{Low: sq("5.10", "mmol/L"), High: sq("5.1", "mmol/L")},
+			{Low: sq("5.2", "mmol/L"), High: sq("5.1", "mmol/L")},
+		},
+	}
+	got := CheckDateOrdering(obs)
+	want := []error{
+		Violation{Path: "Observation.valueRange", Message: "high is below low"},
+		Violation{Path: "Observation.referenceRange[3]", Message: "high is below low"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CheckDateOrdering() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
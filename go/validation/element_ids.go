@@ -0,0 +1,74 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// CheckElementIDs checks that the ids of the elements of each resource in msg,
+// such as extensions or backbone elements, are unique within the resource, as
+// FHIR requires. Contained resources and resources in Bundle entries are
+// resources of their own, so their element ids may repeat those of the
+// enclosing resource; resource ids are not element ids and are not checked.
+// It returns a Violation for each element whose id was already used by an
+// earlier element of the same resource.
+func CheckElementIDs(msg proto.Message) []error {
+	var errs []error
+	// resources is the stack of paths of the resources enclosing the current
+	// element, and seen maps resource paths to the path of the first element
+	// with each id.
+	var resources []string
+	seen := map[string]map[string]string{}
+	err := walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		for len(resources) > 0 && !within(path, resources[len(resources)-1]) {
+			resources = resources[:len(resources)-1]
+		}
+		if element.IsResource(m.Descriptor()) {
+			resources = append(resources, path)
+			seen[path] = map[string]string{}
+			return nil
+		}
+		id := element.PrimitiveString(m, "id")
+		if id == "" || len(resources) == 0 {
+			return nil
+		}
+		ids := seen[resources[len(resources)-1]]
+		if first, ok := ids[id]; ok {
+			errs = append(errs, Violation{
+				Path:    path + ".id",
+				Message: fmt.Sprintf("element id %q is already used by %s", id, first),
+			})
+			return nil
+		}
+		ids[id] = path
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// within reports whether path is parent or one of its descendants.
+func within(path, parent string) bool {
+	return path == parent || strings.HasPrefix(path, parent+".")
+}
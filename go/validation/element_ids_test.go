@@ -0,0 +1,75 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+func extensionWithID(id, url string) *d4pb.Extension {
+	return &d4pb.Extension{
+		Id:    &d4pb.String{Value: id},
+		Url:   &d4pb.Uri{Value: url},
+		Value: &d4pb.Extension_ValueX{Choice: &d4pb.Extension_ValueX_Boolean{Boolean: &d4pb.Boolean{Value: true}}},
+	}
+}
+
+func TestCheckElementIDs(t *testing.T) {
+	p := &r4patientpb.Patient{
+		// The resource id is not an element id.
+		Id: &d4pb.Id{Value: "ext1"},
+		Extension: []*d4pb.Extension{
+			extensionWithID("ext1", "http://example.com/a"),
+			extensionWithID("ext1", "http://example.com/b"),
+		},
+		Name: []*d4pb.HumanName{{
+			Id:     &d4pb.String{Value: "name1"},
+			Family: &d4pb.String{Value: "Chalmers", Id: &d4pb.String{Value: "ext1"}},
+		}},
+		Contained: []*anypb.Any{
+			// Contained resources have their own element ids.
+			containedPatient(t, &r4patientpb.Patient{
+				Id:        &d4pb.Id{Value: "ok"},
+				Extension: []*d4pb.Extension{extensionWithID("ext1", "http://example.com/a")},
+				Name:      []*d4pb.HumanName{{Id: &d4pb.String{Value: "name1"}}},
+			}),
+			containedPatient(t, &r4patientpb.Patient{
+				Id: &d4pb.Id{Value: "dup"},
+				Name: []*d4pb.HumanName{
+					{Id: &d4pb.String{Value: "name1"}},
+					{Id: &d4pb.String{Value: "name1"}},
+				},
+			}),
+		},
+	}
+	got := CheckElementIDs(p)
+	want := []error{
+		Violation{Path: "Patient.contained[1].name[1].id", Message: `element id "name1" is already used by Patient.contained[1].name[0]`},
+		Violation{Path: "Patient.extension[1].id", Message: `element id "ext1" is already used by Patient.extension[0]`},
+		Violation{Path: "Patient.name[0].family.id", Message: `element id "ext1" is already used by Patient.extension[0]`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("CheckElementIDs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("CheckElementIDs()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
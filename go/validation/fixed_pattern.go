@@ -0,0 +1,129 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/google/fhir/go/fhirpath"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// A FixedPatternRule constrains the elements selected by a FHIRPath
+// expression to a fixed[x] or pattern[x] value from a profile. Exactly one of
+// Fixed and Pattern should be set.
+type FixedPatternRule struct {
+	// Path is a FHIRPath expression selecting the constrained elements, e.g.
+	// "Observation.code".
+	Path string
+	// Fixed requires each selected element to equal it exactly.
+	Fixed proto.Message
+	// Pattern requires each selected element to contain at least the values
+	// it sets. Repeated values in the pattern must each match some value of
+	// the element.
+	Pattern proto.Message
+}
+
+// CheckFixedPatterns checks msg against the given fixed and pattern rules and
+// returns a Violation for each selected element that does not match. Rules
+// whose path selects nothing are satisfied; use cardinality checks to require
+// the element.
+func CheckFixedPatterns(msg proto.Message, rules []FixedPatternRule) []Violation {
+	var violations []Violation
+	for _, rule := range rules {
+		if (rule.Fixed == nil) == (rule.Pattern == nil) {
+			violations = append(violations, Violation{Path: rule.Path, Message: "rule must set exactly one of fixed or pattern"})
+			continue
+		}
+		values, err := fhirpath.Evaluate(msg, rule.Path)
+		if err != nil {
+			violations = append(violations, Violation{Path: rule.Path, Message: err.Error()})
+			continue
+		}
+		for i, v := range values {
+			path := rule.Path
+			if len(values) > 1 {
+				path = fmt.Sprintf("%s[%d]", rule.Path, i)
+			}
+			m, ok := v.(proto.Message)
+			if !ok {
+				violations = append(violations, Violation{Path: path, Message: fmt.Sprintf("unexpected non-element value %v", v)})
+				continue
+			}
+			switch {
+			case rule.Fixed != nil && !proto.Equal(m, rule.Fixed):
+				violations = append(violations, Violation{Path: path, Message: "value does not equal the fixed value"})
+			case rule.Pattern != nil && !matchesPattern(m.ProtoReflect(), rule.Pattern.ProtoReflect()):
+				violations = append(violations, Violation{Path: path, Message: "value does not match the pattern"})
+			}
+		}
+	}
+	return violations
+}
+
+// matchesPattern reports whether every value set in pattern is also present
+// in m.
+func matchesPattern(m, pattern protoreflect.Message) bool {
+	if m.Descriptor().FullName() != pattern.Descriptor().FullName() {
+		return false
+	}
+	matches := true
+	pattern.Range(func(f protoreflect.FieldDescriptor, pv protoreflect.Value) bool {
+		if !m.Has(f) {
+			matches = false
+			return false
+		}
+		mv := m.Get(f)
+		switch {
+		case f.IsList():
+			matches = listMatchesPattern(f, mv.List(), pv.List())
+		case f.Message() != nil:
+			matches = matchesPattern(mv.Message(), pv.Message())
+		default:
+			matches = scalarEqual(mv, pv)
+		}
+		return matches
+	})
+	return matches
+}
+
+// listMatchesPattern reports whether every item of pattern matches some item
+// of l.
+func listMatchesPattern(f protoreflect.FieldDescriptor, l, pattern protoreflect.List) bool {
+	for i := 0; i < pattern.Len(); i++ {
+		found := false
+		for j := 0; j < l.Len() && !found; j++ {
+			if f.Message() != nil {
+				found = matchesPattern(l.Get(j).Message(), pattern.Get(i).Message())
+			} else {
+				found = scalarEqual(l.Get(j), pattern.Get(i))
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func scalarEqual(a, b protoreflect.Value) bool {
+	if ab, ok := a.Interface().([]byte); ok {
+		bb, ok := b.Interface().([]byte)
+		return ok && bytes.Equal(ab, bb)
+	}
+	return a.Interface() == b.Interface()
+}
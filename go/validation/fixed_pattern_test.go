@@ -0,0 +1,109 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+)
+
+func coding(system, code, display string) *d4pb.Coding {
+	c := &d4pb.Coding{
+		System: &d4pb.Uri{Value: system},
+		Code:   &d4pb.Code{Value: code},
+	}
+	if display != "" {
+		c.Display = &d4pb.String{Value: display}
+	}
+	return c
+}
+
+func observationWithCode(codings ...*d4pb.Coding) *r4observationpb.Observation {
+	return &r4observationpb.Observation{
+		Code: &d4pb.CodeableConcept{Coding: codings},
+	}
+}
+
+func TestCheckFixedPatterns(t *testing.T) {
+	heartRate := coding("http://loinc.org", "8867-4", "Heart rate")
+	fixedRule := FixedPatternRule{
+		Path:  "Observation.code",
+		Fixed: &d4pb.CodeableConcept{Coding: []*d4pb.Coding{heartRate}},
+	}
+	patternRule := FixedPatternRule{
+		Path: "Observation.code",
+		Pattern: &d4pb.CodeableConcept{
+			Coding: []*d4pb.Coding{coding("http://loinc.org", "8867-4", "")},
+		},
+	}
+	tests := []struct {
+		name  string
+		obs   *r4observationpb.Observation
+		rules []FixedPatternRule
+		want  []Violation
+	}{
+		{
+			name:  "fixed value matches",
+			obs:   observationWithCode(heartRate),
+			rules: []FixedPatternRule{fixedRule},
+		},
+		{
+			name:  "fixed value differs",
+			obs:   observationWithCode(coding("http://loinc.org", "8310-5", "Body temperature")),
+			rules: []FixedPatternRule{fixedRule},
+			want:  []Violation{{Path: "Observation.code", Message: "value does not equal the fixed value"}},
+		},
+		{
+			name:  "fixed value has extra coding",
+			obs:   observationWithCode(heartRate, coding("http://snomed.info/sct", "364075005", "")),
+			rules: []FixedPatternRule{fixedRule},
+			want:  []Violation{{Path: "Observation.code", Message: "value does not equal the fixed value"}},
+		},
+		{
+			name:  "pattern matches superset",
+			obs:   observationWithCode(coding("http://snomed.info/sct", "364075005", ""), heartRate),
+			rules: []FixedPatternRule{patternRule},
+		},
+		{
+			name:  "pattern does not match",
+			obs:   observationWithCode(coding("http://loinc.org", "8310-5", "")),
+			rules: []FixedPatternRule{patternRule},
+			want:  []Violation{{Path: "Observation.code", Message: "value does not match the pattern"}},
+		},
+		{
+			name:  "absent element",
+			obs:   &r4observationpb.Observation{},
+			rules: []FixedPatternRule{fixedRule, patternRule},
+		},
+		{
+			name:  "rule without value",
+			obs:   observationWithCode(heartRate),
+			rules: []FixedPatternRule{{Path: "Observation.code"}},
+			want:  []Violation{{Path: "Observation.code", Message: "rule must set exactly one of fixed or pattern"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := CheckFixedPatterns(test.obs, test.rules)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("CheckFixedPatterns() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	// oidPattern and uuidPattern match the FHIR oid and uuid datatypes without
+	// their "urn:oid:" and "urn:uuid:" prefixes.
+	oidPattern  = regexp.MustCompile(`^[0-2](\.(0|[1-9][0-9]*))+$`)
+	uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+)
+
+// ValidateIdentifierSystems checks that the system of every Identifier in msg
+// is an absolute URI, returning a Violation for each one that is not. Systems
+// of the form "urn:oid:" and "urn:uuid:" must hold a valid OID or lowercase
+// UUID, and http and https systems must have a host.
+func ValidateIdentifierSystems(msg proto.Message) []error {
+	var errs []error
+	err := walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		if m.Descriptor().Name() != "Identifier" {
+			return nil
+		}
+		f := m.Descriptor().Fields().ByName("system")
+		if f == nil || !m.Has(f) {
+			return nil
+		}
+		system := element.PrimitiveString(m, "system")
+		if err := checkIdentifierSystem(system); err != nil {
+			errs = append(errs, Violation{
+				Path:    path + ".system",
+				Message: fmt.Sprintf("invalid identifier system %q: %v", system, err),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func checkIdentifierSystem(system string) error {
+	if strings.ContainsAny(system, " \t\r\n") {
+		return fmt.Errorf("contains whitespace")
+	}
+	switch {
+	case strings.HasPrefix(system, "urn:oid:"):
+		if !oidPattern.MatchString(strings.TrimPrefix(system, "urn:oid:")) {
+			return fmt.Errorf("malformed OID")
+		}
+		return nil
+	case strings.HasPrefix(system, "urn:uuid:"):
+		if !uuidPattern.MatchString(strings.TrimPrefix(system, "urn:uuid:")) {
+			return fmt.Errorf("malformed UUID")
+		}
+		return nil
+	}
+	u, err := url.Parse(system)
+	if err != nil {
+		return fmt.Errorf("malformed URI")
+	}
+	if !u.IsAbs() {
+		return fmt.Errorf("not an absolute URI")
+	}
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func TestValidateIdentifierSystems(t *testing.T) {
+	identifier := func(system string) *d4pb.Identifier {
+		return &d4pb.Identifier{System: &d4pb.Uri{Value: system}, Value: &d4pb.String{Value: "12345"}}
+	}
+	patient := &r4patientpb.Patient{
+		Identifier: []*d4pb.Identifier{
+			identifier("http://hospital.example.org/mrn"),
+			identifier("mrn"),
+			identifier("urn:oid:2.16.840.1.113883.4.1"),
+			identifier("urn:oid:2.16.840.01"),
+			identifier("urn:uuid:a76d9bbf-f293-4fb7-ad4c-2851cac77162"),
+			identifier("urn:uuid:A76D9BBF-F293-4FB7-AD4C-2851CAC77162"),
+			identifier("http:///mrn"),
+			identifier("http://example.org/medical record"),
+			// Identifiers without a system are not checked.
+			{Value: &d4pb.String{Value: "12345"}},
+		},
+		ManagingOrganization: &d4pb.Reference{Identifier: identifier("urn:ietf:rfc:3986")},
+	}
+	got := ValidateIdentifierSystems(patient)
+	want := []error{
+		Violation{Path: "Patient.identifier[1].system", Message: `invalid identifier system "mrn": not an absolute URI`},
+		Violation{Path: "Patient.identifier[3].system", Message: `invalid identifier system "urn:oid:2.16.840.01": malformed OID`},
+		Violation{Path: "Patient.identifier[5].system", Message: `invalid identifier system "urn:uuid:A76D9BBF-F293-4FB7-AD4C-2851CAC77162": malformed UUID`},
+		Violation{Path: "Patient.identifier[6].system", Message: `invalid identifier system "http:///mrn": missing host`},
+		Violation{Path: "Patient.identifier[7].system", Message: `invalid identifier system "http://example.org/medical record": contains whitespace`},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ValidateIdentifierSystems() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
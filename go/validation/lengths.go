@@ -0,0 +1,85 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// indexPattern matches the list indices of an element path.
+var indexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// CheckStringLengths returns a Violation for each string element of msg that
+// is longer than its limit. Limits are keyed by element path without list
+// indices, e.g. "Patient.name.text", with choice elements named by their
+// type, e.g. "Observation.valueString". Lengths are counted in characters
+// (runes), not bytes.
+func CheckStringLengths(msg proto.Message, limits map[string]int) []error {
+	var errs []error
+	err := walkLimitedStrings(msg, limits, func(path string, m protoreflect.Message, value protoreflect.FieldDescriptor, limit int) {
+		if n := utf8.RuneCountInString(m.Get(value).String()); n > limit {
+			errs = append(errs, Violation{Path: path, Message: fmt.Sprintf("length %d exceeds the maximum of %d", n, limit)})
+		}
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// TruncateStrings shortens each string element of msg that is longer than
+// its limit, keyed as for CheckStringLengths, to the limit. Truncation never
+// splits a multi-byte character.
+func TruncateStrings(msg proto.Message, limits map[string]int) error {
+	for path, limit := range limits {
+		if limit < 0 {
+			return fmt.Errorf("%s: negative length limit %d", path, limit)
+		}
+	}
+	return walkLimitedStrings(msg, limits, func(_ string, m protoreflect.Message, value protoreflect.FieldDescriptor, limit int) {
+		s := m.Get(value).String()
+		if utf8.RuneCountInString(s) <= limit {
+			return
+		}
+		runes := []rune(s)
+		m.Set(value, protoreflect.ValueOfString(string(runes[:limit])))
+	})
+}
+
+// walkLimitedStrings calls fn for each string primitive of msg whose element
+// path has a limit, passing the primitive's value field.
+func walkLimitedStrings(msg proto.Message, limits map[string]int, fn func(path string, m protoreflect.Message, value protoreflect.FieldDescriptor, limit int)) error {
+	if len(limits) == 0 {
+		return nil
+	}
+	return walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		limit, ok := limits[indexPattern.ReplaceAllString(path, "")]
+		if !ok {
+			return nil
+		}
+		value := m.Descriptor().Fields().ByName("value")
+		if value == nil || value.Kind() != protoreflect.StringKind || value.IsList() {
+			return nil
+		}
+		fn(path, m, value, limit)
+		return nil
+	})
+}
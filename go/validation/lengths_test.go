@@ -0,0 +1,93 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func TestCheckStringLengths(t *testing.T) {
+	patient := &r4patientpb.Patient{
+		Name: []*d4pb.HumanName{
+			{Text: &d4pb.String{Value: "Peter James Chalmers"}},
+			{Text: &d4pb.String{Value: "Jim"}},
+			{Text: &d4pb.String{Value: "Zoë Müller"}},
+		},
+		Address: []*d4pb.Address{{Text: &d4pb.String{Value: "534 Erewhon St, PleasantVille"}}},
+	}
+	got := CheckStringLengths(patient, map[string]int{"Patient.name.text": 10})
+	want := []error{
+		Violation{Path: "Patient.name[0].text", Message: "length 20 exceeds the maximum of 10"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CheckStringLengths() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckStringLengths_Choice(t *testing.T) {
+	obs := &r4observationpb.Observation{
+		Value: &r4observationpb.Observation_ValueX{
+			Choice: &r4observationpb.Observation_ValueX_StringValue{StringValue: &d4pb.String{Value: strings.Repeat("x", 300)}},
+		},
+	}
+	got := CheckStringLengths(obs, map[string]int{"Observation.valueString": 255})
+	want := []error{
+		Violation{Path: "Observation.valueString", Message: "length 300 exceeds the maximum of 255"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CheckStringLengths() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestTruncateStrings(t *testing.T) {
+	patient := &r4patientpb.Patient{
+		Name: []*d4pb.HumanName{
+			{Text: &d4pb.String{Value: "Peter James Chalmers"}},
+			{Text: &d4pb.String{Value: "Jim"}},
+			// Truncating by bytes would split the multi-byte "ë".
+			{Text: &d4pb.String{Value: "Zoë Müller"}},
+		},
+		Address: []*d4pb.Address{{Text: &d4pb.String{Value: "534 Erewhon St, PleasantVille"}}},
+	}
+	if err := TruncateStrings(patient, map[string]int{"Patient.name.text": 3}); err != nil {
+		t.Fatalf("TruncateStrings() failed: %v", err)
+	}
+	want := &r4patientpb.Patient{
+		Name: []*d4pb.HumanName{
+			{Text: &d4pb.String{Value: "Pet"}},
+			{Text: &d4pb.String{Value: "Jim"}},
+			{Text: &d4pb.String{Value: "Zoë"}},
+		},
+		Address: []*d4pb.Address{{Text: &d4pb.String{Value: "534 Erewhon St, PleasantVille"}}},
+	}
+	if diff := cmp.Diff(want, patient, protocmp.Transform()); diff != "" {
+		t.Errorf("TruncateStrings() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestTruncateStrings_NegativeLimit(t *testing.T) {
+	patient := &r4patientpb.Patient{Name: []*d4pb.HumanName{{Text: &d4pb.String{Value: "Jim"}}}}
+	if err := TruncateStrings(patient, map[string]int{"Patient.name.text": -1}); err == nil {
+		t.Errorf("TruncateStrings() with a negative limit succeeded, want error")
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// xhtmlTagPattern matches a single XHTML tag, comment or declaration.
+var xhtmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// CheckNarrative checks that the narrative of each resource in msg, including
+// contained resources, declares how it was produced. It returns a Violation
+// for a narrative with a div but no status, and for one whose status is empty
+// but whose div has text content.
+func CheckNarrative(msg proto.Message) []error {
+	var errs []error
+	err := walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		if m.Descriptor().Name() != "Narrative" {
+			return nil
+		}
+		div := element.PrimitiveString(m, "div")
+		status := messageField(m, "status")
+		switch {
+		case status == nil:
+			if div != "" {
+				errs = append(errs, Violation{Path: path + ".status", Message: "narrative with content must have a status"})
+			}
+		case enumName(status) == "EMPTY":
+			if hasText(div) {
+				errs = append(errs, Violation{Path: path + ".div", Message: "narrative with status empty must not have content"})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// enumName returns the name of the enum value held by the code m, or "" if m
+// holds none.
+func enumName(m protoreflect.Message) protoreflect.Name {
+	f := m.Descriptor().Fields().ByName("value")
+	if f == nil || f.Kind() != protoreflect.EnumKind {
+		return ""
+	}
+	if v := f.Enum().Values().ByNumber(m.Get(f).Enum()); v != nil {
+		return v.Name()
+	}
+	return ""
+}
+
+// hasText reports whether the XHTML div has any content other than markup and
+// whitespace.
+func hasText(div string) bool {
+	return strings.TrimSpace(xhtmlTagPattern.ReplaceAllString(div, "")) != ""
+}
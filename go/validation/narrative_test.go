@@ -0,0 +1,68 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	c4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/codes_go_proto"
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+func narrative(status c4pb.NarrativeStatusCode_Value, div string) *d4pb.Narrative {
+	return &d4pb.Narrative{
+		Status: &d4pb.Narrative_StatusCode{Value: status},
+		Div:    &d4pb.Xhtml{Value: div},
+	}
+}
+
+func TestCheckNarrative(t *testing.T) {
+	p := &r4patientpb.Patient{
+		Text: &d4pb.Narrative{Div: &d4pb.Xhtml{Value: `<div xmlns="http://www.w3.org/1999/xhtml">Jane Doe</div>`}},
+		Contained: []*anypb.Any{
+			containedPatient(t, &r4patientpb.Patient{
+				Id:   &d4pb.Id{Value: "empty"},
+				Text: narrative(c4pb.NarrativeStatusCode_EMPTY, `<div xmlns="http://www.w3.org/1999/xhtml">Jane Doe</div>`),
+			}),
+			containedPatient(t, &r4patientpb.Patient{
+				Id:   &d4pb.Id{Value: "ok"},
+				Text: narrative(c4pb.NarrativeStatusCode_EMPTY, `<div xmlns="http://www.w3.org/1999/xhtml"> </div>`),
+			}),
+			containedPatient(t, &r4patientpb.Patient{
+				Id:   &d4pb.Id{Value: "generated"},
+				Text: narrative(c4pb.NarrativeStatusCode_GENERATED, `<div xmlns="http://www.w3.org/1999/xhtml"><p>Jane Doe</p></div>`),
+			}),
+		},
+	}
+	got := CheckNarrative(p)
+	wantPaths := []string{
+		"Patient.text.status",
+		"Patient.contained[0].text.div",
+	}
+	if len(got) != len(wantPaths) {
+		t.Fatalf("CheckNarrative() = %v, want violations at %v", got, wantPaths)
+	}
+	for i, err := range got {
+		v, ok := err.(Violation)
+		if !ok {
+			t.Fatalf("CheckNarrative()[%d] = %T, want Violation", i, err)
+		}
+		if v.Path != wantPaths[i] {
+			t.Errorf("CheckNarrative()[%d].Path = %q, want %q", i, v.Path, wantPaths[i])
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"github.com/google/fhir/go/fhirpath"
+	"google.golang.org/protobuf/proto"
+)
+
+// RequirePaths evaluates each FHIRPath expression against msg and returns a
+// Violation for every path that yields an empty collection. Boolean
+// expressions such as "Patient.name.exists()" fail when they yield false.
+// Paths that cannot be evaluated are reported as errors too.
+func RequirePaths(msg proto.Message, paths []string) []error {
+	var errs []error
+	for _, path := range paths {
+		res, err := fhirpath.Evaluate(msg, path)
+		if err != nil {
+			errs = append(errs, Violation{Path: path, Message: err.Error()})
+			continue
+		}
+		if len(res) == 0 {
+			errs = append(errs, Violation{Path: path, Message: "required element is missing"})
+			continue
+		}
+		if b, ok := res[0].(bool); ok && len(res) == 1 && !b {
+			errs = append(errs, Violation{Path: path, Message: "required condition is false"})
+		}
+	}
+	return errs
+}
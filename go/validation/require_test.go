@@ -0,0 +1,73 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4patientpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+)
+
+func TestRequirePaths(t *testing.T) {
+	paths := []string{"Patient.name.exists()", "Patient.birthDate"}
+	tests := []struct {
+		name    string
+		patient *r4patientpb.Patient
+		want    []error
+	}{
+		{
+			name: "all present",
+			patient: &r4patientpb.Patient{
+				Name:      []*d4pb.HumanName{{Family: &d4pb.String{Value: "Doe"}}},
+				BirthDate: &d4pb.Date{ValueUs: 0, Timezone: "UTC", Precision: d4pb.Date_DAY},
+			},
+		},
+		{
+			name: "missing birth date",
+			patient: &r4patientpb.Patient{
+				Name: []*d4pb.HumanName{{Family: &d4pb.String{Value: "Doe"}}},
+			},
+			want: []error{
+				Violation{Path: "Patient.birthDate", Message: "required element is missing"},
+			},
+		},
+		{
+			name:    "all missing",
+			patient: &r4patientpb.Patient{},
+			want: []error{
+				Violation{Path: "Patient.name.exists()", Message: "required condition is false"},
+				Violation{Path: "Patient.birthDate", Message: "required element is missing"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := RequirePaths(test.patient, paths)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("RequirePaths() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRequirePaths_InvalidPath(t *testing.T) {
+	errs := RequirePaths(&r4patientpb.Patient{}, []string{"Patient.name.("})
+	if len(errs) != 1 {
+		t.Errorf("RequirePaths() with invalid path returned %v, want one error", errs)
+	}
+}
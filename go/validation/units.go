@@ -0,0 +1,59 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+
+	"github.com/google/fhir/go/internal/element"
+	"github.com/google/fhir/go/internal/ucum"
+	"github.com/google/fhir/go/internal/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const ucumSystem = "http://unitsofmeasure.org"
+
+// ValidateUnits checks that the code of every Quantity in msg whose system is
+// UCUM is a valid UCUM unit expression, returning a Violation for each one
+// that is not. Units are checked against the UCUM grammar and a bundled
+// subset of the UCUM unit tables covering units in common clinical use.
+func ValidateUnits(msg proto.Message) []error {
+	var errs []error
+	err := walk.Walk(msg, func(path string, m protoreflect.Message) error {
+		if !element.QuantityTypes[m.Descriptor().Name()] {
+			return nil
+		}
+		if element.PrimitiveString(m, "system") != ucumSystem {
+			return nil
+		}
+		f := m.Descriptor().Fields().ByName("code")
+		if f == nil || !m.Has(f) {
+			return nil
+		}
+		code := element.PrimitiveString(m, "code")
+		if _, err := ucum.Parse(code); err != nil {
+			errs = append(errs, Violation{
+				Path:    path + ".code",
+				Message: fmt.Sprintf("invalid UCUM unit %q: %v", code, err),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
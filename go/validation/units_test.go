@@ -0,0 +1,73 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	d4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4observationpb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/observation_go_proto"
+)
+
+func quantity(system, code string) *d4pb.Quantity {
+	return &d4pb.Quantity{
+		Value:  &d4pb.Decimal{Value: "1"},
+		System: &d4pb.Uri{Value: system},
+		Code:   &d4pb.Code{Value: code},
+	}
+}
+
+func TestValidateUnits(t *testing.T) {
+	obs := &r4observationpb.Observation{
+		Value: &r4observationpb.Observation_ValueX{
+			Choice: &r4observationpb.Observation_ValueX_Quantity{Quantity: quantity(ucumSystem, "mg//dL")},
+		},
+		ReferenceRange: []*r4observationpb.Observation_ReferenceRange{{
+			Low:  &d4pb.SimpleQuantity{System: &d4pb.Uri{Value: ucumSystem}, Code: &d4pb.Code{Value: "mg/dL"}},
+			High: &d4pb.SimpleQuantity{System: &d4pb.Uri{Value: ucumSystem}, Code: &d4pb.Code{Value: "mgg"}},
+		}},
+		Component: []*r4observationpb.Observation_Component{{
+			Value: &r4observationpb.Observation_Component_ValueX{
+				// Units from other systems are not checked.
+				Choice: &r4observationpb.Observation_Component_ValueX_Quantity{Quantity: quantity("http://example.com/units", "mg//dL")},
+			},
+		}},
+	}
+	got := ValidateUnits(obs)
+	wantPaths := []string{"Observation.valueQuantity.code", "Observation.referenceRange[0].high.code"}
+	if len(got) != len(wantPaths) {
+		t.Fatalf("ValidateUnits() = %v, want violations at %v", got, wantPaths)
+	}
+	for i, err := range got {
+		v, ok := err.(Violation)
+		if !ok {
+			t.Fatalf("ValidateUnits()[%d] = %T, want Violation", i, err)
+		}
+		if v.Path != wantPaths[i] {
+			t.Errorf("ValidateUnits()[%d].Path = %q, want %q", i, v.Path, wantPaths[i])
+		}
+	}
+}
+
+func TestValidateUnits_Valid(t *testing.T) {
+	obs := &r4observationpb.Observation{
+		Value: &r4observationpb.Observation_ValueX{
+			Choice: &r4observationpb.Observation_ValueX_Quantity{Quantity: quantity(ucumSystem, "10*3/uL")},
+		},
+	}
+	if got := ValidateUnits(obs); len(got) != 0 {
+		t.Errorf("ValidateUnits() = %v, want no violations", got)
+	}
+}
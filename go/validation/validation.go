@@ -0,0 +1,32 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validation provides checks of FHIR resources against constraints
+// that go beyond the structural validation done while parsing, such as those
+// imposed by profiles.
+package validation
+
+import "fmt"
+
+// A Violation describes an element that does not satisfy a constraint.
+type Violation struct {
+	// Path locates the offending element, e.g. "Observation.code".
+	Path string
+	// Message describes the constraint that was not met.
+	Message string
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package workflow provides checks of the lifecycle of FHIR workflow
+// resources.
+package workflow
+
+import "fmt"
+
+// enteredInError is the status of resources recorded in error, which a
+// resource may move to from any status.
+const enteredInError = "entered-in-error"
+
+// transitions maps resource types to the statuses their resources may move
+// to from each status, following the status state machines described by the
+// FHIR R4 specification. Statuses with no next statuses are final, and
+// statuses absent as keys are not statuses of the resource type.
+var transitions = map[string]map[string][]string{
+	"MedicationRequest": {
+		"draft":            {"active", "cancelled"},
+		"active":           {"on-hold", "completed", "stopped"},
+		"on-hold":          {"active", "completed", "stopped"},
+		"unknown":          {"draft", "active", "on-hold", "cancelled", "completed", "stopped"},
+		"cancelled":        nil,
+		"completed":        nil,
+		"stopped":          nil,
+		"entered-in-error": nil,
+	},
+	"ServiceRequest": {
+		"draft":            {"active", "revoked"},
+		"active":           {"on-hold", "completed", "revoked"},
+		"on-hold":          {"active", "revoked"},
+		"unknown":          {"draft", "active", "on-hold", "revoked", "completed"},
+		"revoked":          nil,
+		"completed":        nil,
+		"entered-in-error": nil,
+	},
+	"Task": {
+		"draft":            {"requested", "cancelled"},
+		"requested":        {"received", "accepted", "rejected", "cancelled"},
+		"received":         {"accepted", "rejected", "cancelled"},
+		"accepted":         {"ready", "in-progress", "cancelled"},
+		"ready":            {"in-progress", "cancelled"},
+		"in-progress":      {"on-hold", "completed", "failed"},
+		"on-hold":          {"in-progress", "failed", "cancelled"},
+		"rejected":         nil,
+		"cancelled":        nil,
+		"failed":           nil,
+		"completed":        nil,
+		"entered-in-error": nil,
+	},
+	"Encounter": {
+		"planned":          {"arrived", "in-progress", "cancelled"},
+		"arrived":          {"triaged", "in-progress", "cancelled"},
+		"triaged":          {"in-progress", "cancelled"},
+		"in-progress":      {"onleave", "finished"},
+		"onleave":          {"in-progress", "finished"},
+		"unknown":          {"planned", "arrived", "triaged", "in-progress", "onleave", "finished", "cancelled"},
+		"finished":         nil,
+		"cancelled":        nil,
+		"entered-in-error": nil,
+	},
+	"Observation": {
+		"registered":       {"preliminary", "final", "cancelled"},
+		"preliminary":      {"final", "cancelled"},
+		"final":            {"amended", "corrected"},
+		"amended":          {"corrected"},
+		"corrected":        {"amended"},
+		"unknown":          {"registered", "preliminary", "final", "amended", "corrected", "cancelled"},
+		"cancelled":        nil,
+		"entered-in-error": nil,
+	},
+}
+
+// CheckStatusTransition checks that a resource of type resourceType, one of
+// MedicationRequest, ServiceRequest, Task, Encounter and Observation, may
+// change its status from from to to. Keeping the same status is always legal,
+// as is marking a resource entered-in-error if its type has that status; the
+// unknown status, recorded when the actual status is not known, may move to
+// any other status its type has. It returns an error for any other
+// transition not in the resource type's state machine, and for unsupported
+// resource types and statuses.
+func CheckStatusTransition(resourceType, from, to string) error {
+	table, ok := transitions[resourceType]
+	if !ok {
+		return fmt.Errorf("no status transitions known for resource type %q", resourceType)
+	}
+	for _, status := range []string{from, to} {
+		if _, ok := table[status]; !ok {
+			return fmt.Errorf("%q is not a %s status", status, resourceType)
+		}
+	}
+	if from == to || to == enteredInError {
+		return nil
+	}
+	for _, next := range table[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s status cannot change from %q to %q", resourceType, from, to)
+}
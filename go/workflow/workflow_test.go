@@ -0,0 +1,105 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workflow
+
+import "testing"
+
+func TestCheckStatusTransition(t *testing.T) {
+	tests := []struct {
+		resourceType, from, to string
+	}{
+		{"MedicationRequest", "draft", "active"},
+		{"MedicationRequest", "active", "completed"},
+		{"MedicationRequest", "on-hold", "active"},
+		{"MedicationRequest", "completed", "completed"},
+		{"MedicationRequest", "completed", "entered-in-error"},
+		{"MedicationRequest", "unknown", "active"},
+		{"ServiceRequest", "active", "revoked"},
+		{"Task", "requested", "accepted"},
+		{"Task", "in-progress", "failed"},
+		{"Task", "on-hold", "entered-in-error"},
+		{"Encounter", "unknown", "finished"},
+		{"Encounter", "arrived", "in-progress"},
+		{"Observation", "final", "amended"},
+	}
+	for _, test := range tests {
+		if err := CheckStatusTransition(test.resourceType, test.from, test.to); err != nil {
+			t.Errorf("CheckStatusTransition(%q, %q, %q) failed: %v", test.resourceType, test.from, test.to, err)
+		}
+	}
+}
+
+func TestCheckStatusTransition_Errors(t *testing.T) {
+	tests := []struct {
+		name                   string
+		resourceType, from, to string
+	}{
+		{
+			name:         "completed to draft",
+			resourceType: "MedicationRequest",
+			from:         "completed",
+			to:           "draft",
+		},
+		{
+			name:         "skipped state",
+			resourceType: "Task",
+			from:         "draft",
+			to:           "completed",
+		},
+		{
+			name:         "out of entered-in-error",
+			resourceType: "Encounter",
+			from:         "entered-in-error",
+			to:           "finished",
+		},
+		{
+			name:         "Task has no unknown status",
+			resourceType: "Task",
+			from:         "unknown",
+			to:           "requested",
+		},
+		{
+			name:         "into unknown Task status",
+			resourceType: "Task",
+			from:         "requested",
+			to:           "unknown",
+		},
+		{
+			name:         "back to unknown",
+			resourceType: "Observation",
+			from:         "final",
+			to:           "unknown",
+		},
+		{
+			name:         "status of another resource type",
+			resourceType: "ServiceRequest",
+			from:         "active",
+			to:           "stopped",
+		},
+		{
+			name:         "unsupported resource type",
+			resourceType: "Patient",
+			from:         "active",
+			to:           "inactive",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CheckStatusTransition(test.resourceType, test.from, test.to); err == nil {
+				t.Errorf("CheckStatusTransition(%q, %q, %q) succeeded, want error", test.resourceType, test.from, test.to)
+			}
+		})
+	}
+}